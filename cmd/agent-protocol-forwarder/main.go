@@ -6,6 +6,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
@@ -13,16 +14,24 @@ import (
 	"github.com/confidential-containers/cloud-api-adaptor/cmd"
 	daemon "github.com/confidential-containers/cloud-api-adaptor/pkg/forwarder"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/forwarder/interceptor"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/forwarder/interruption"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/podnetwork"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/logging"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/tlsutil"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/tracing"
 )
 
 const programName = "agent-protocol-forwarder"
 
+var logger = logging.New("agent-protocol-forwarder")
+
 type Config struct {
 	tlsConfig           *tlsutil.TLSConfig
+	tracingConfig       tracing.Config
+	loggingConfig       logging.Config
 	daemonConfig        daemon.Config
 	configPath          string
+	bootstrapPath       string
 	listenAddr          string
 	kataAgentSocketPath string
 	kataAgentNamespace  string
@@ -54,6 +63,7 @@ func (cfg *Config) Setup() (cmd.Starter, error) {
 	cmd.Parse(programName, os.Args, func(flags *flag.FlagSet) {
 		flags.BoolVar(&showVersion, "version", false, "Show version")
 		flags.StringVar(&cfg.configPath, "config", daemon.DefaultConfigPath, "Path to a deamon config file")
+		flags.StringVar(&cfg.bootstrapPath, "bootstrap-config", daemon.DefaultBootstrapConfigPath, "Path to a bootstrap config file of a pre-provisioned pod VM")
 		flags.StringVar(&cfg.listenAddr, "listen", daemon.DefaultListenAddr, "Listen address")
 		flags.StringVar(&cfg.kataAgentSocketPath, "kata-agent-socket", daemon.DefaultKataAgentSocketPath, "Path to a kata agent socket")
 		flags.StringVar(&cfg.kataAgentNamespace, "kata-agent-namespace", daemon.DefaultKataAgentNamespace, "Path to the network namespace where kata agent runs")
@@ -63,8 +73,17 @@ func (cfg *Config) Setup() (cmd.Starter, error) {
 		flags.StringVar(&tlsConfig.KeyFile, "cert-key", "", "cert key")
 		flags.BoolVar(&tlsConfig.SkipVerify, "tls-skip-verify", false, "Skip TLS certificate verification - use it only for testing")
 		flags.BoolVar(&disableTLS, "disable-tls", false, "Disable TLS encryption - use it only for testing")
+		flags.StringVar(&cfg.tracingConfig.Exporter, "tracing-exporter", tracing.DefaultExporter, "Where to export trace spans (none, otlp or file)")
+		flags.StringVar(&cfg.tracingConfig.Endpoint, "tracing-endpoint", "", "URL or host:port of an OTLP/HTTP trace receiver. Defaults to OTEL_EXPORTER_OTLP_TRACES_ENDPOINT or OTEL_EXPORTER_OTLP_ENDPOINT (tracing-exporter=otlp only)")
+		flags.StringVar(&cfg.tracingConfig.FilePath, "tracing-file", "", "File to write trace spans (tracing-exporter=file only)")
+		flags.StringVar(&cfg.loggingConfig.Format, "log-format", logging.DefaultFormat, "Log output format (text or json)")
+		flags.StringVar(&cfg.loggingConfig.Level, "log-level", logging.DefaultLevel, "Minimum log level (debug, info, warn or error)")
 	})
 
+	if err := logging.Setup(os.Stderr, &cfg.loggingConfig); err != nil {
+		return nil, err
+	}
+
 	if !disableTLS {
 		cfg.tlsConfig = &tlsConfig
 	}
@@ -75,6 +94,10 @@ func (cfg *Config) Setup() (cmd.Starter, error) {
 		cmd.Exit(0)
 	}
 
+	if err := cfg.bootstrap(); err != nil {
+		return nil, err
+	}
+
 	for path, obj := range map[string]interface{}{
 		cfg.configPath: &cfg.daemonConfig,
 	} {
@@ -83,16 +106,48 @@ func (cfg *Config) Setup() (cmd.Starter, error) {
 		}
 	}
 
+	// All log lines of this pod VM are about the same pod
+	logging.AddFields(logging.KeyPod, cfg.daemonConfig.PodName, logging.KeyNamespace, cfg.daemonConfig.PodNamespace)
+	logger.Info("daemon config", "config", cfg.daemonConfig)
+
 	interceptor := interceptor.NewInterceptor(cfg.kataAgentSocketPath, cfg.kataAgentNamespace)
 
 	podNode := podnetwork.NewPodNode(cfg.kataAgentNamespace, cfg.HostInterface, cfg.daemonConfig.PodNetwork)
 
 	daemon := daemon.NewDaemon(&cfg.daemonConfig, cfg.listenAddr, cfg.tlsConfig, interceptor, podNode)
 
+	if cfg.daemonConfig.Spot {
+		// The interceptor reports interruption of this pod VM on spot capacity to cloud-api-adaptor
+		return cmd.NewStarter(daemon, interruption.NewWatcher(interceptor.Interrupt)), nil
+	}
+
 	return cmd.NewStarter(daemon), nil
 }
 
-var config cmd.Config = &Config{}
+// bootstrap waits for a daemon config from cloud-api-adaptor, when this pod VM is pre-provisioned
+// in a warm pool. A pre-provisioned pod VM has a bootstrap config instead of a daemon config.
+func (cfg *Config) bootstrap() error {
+
+	if _, err := os.Stat(cfg.configPath); err == nil || !errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	var bootstrapConfig daemon.BootstrapConfig
+	if err := load(cfg.bootstrapPath, &bootstrapConfig); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	if err := daemon.Bootstrap(context.Background(), &bootstrapConfig, cfg.listenAddr, cfg.tlsConfig, cfg.configPath); err != nil {
+		return fmt.Errorf("failed to receive a daemon config: %w", err)
+	}
+
+	return nil
+}
+
+var config = &Config{}
 
 func main() {
 
@@ -105,7 +160,19 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	if err := starter.Start(ctx); err != nil {
+	shutdownTracing, err := tracing.Setup(ctx, programName, &config.tracingConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", os.Args[0], err)
+		cmd.Exit(1)
+	}
+
+	err = starter.Start(ctx)
+
+	if e := shutdownTracing(context.Background()); e != nil {
+		fmt.Fprintf(os.Stderr, "%s: failed to flush trace spans: %s\n", os.Args[0], e)
+	}
+
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "%s: %s\n", os.Args[0], err)
 		cmd.Exit(1)
 	}
@@ -1,10 +0,0 @@
-//go:build libvirt
-
-// (C) Copyright Confidential Containers Contributors
-// SPDX-License-Identifier: Apache-2.0
-
-package main
-
-import (
-	_ "github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud/libvirt"
-)
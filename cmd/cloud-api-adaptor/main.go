@@ -13,19 +13,38 @@ import (
 	"github.com/confidential-containers/cloud-api-adaptor/cmd"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud/cloudmgr"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/k8sops"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/proxy"
 	daemon "github.com/confidential-containers/cloud-api-adaptor/pkg/forwarder"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/podnetwork/tunneler/vxlan"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/logging"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/tlsutil"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/tracing"
 
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/podnetwork"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/probe"
 )
 
-const programName = "cloud-api-adaptor"
+const (
+	programName                       = "cloud-api-adaptor"
+	defaultPodIndexConfigMapNamespace = "confidential-containers-system"
+	defaultReconcileNamespace         = "confidential-containers-system"
+
+	// The cloud package name is shadowed by a cloud provider in Setup
+	defaultWarmPoolRefillPolicy   = cloud.DefaultWarmPoolRefillPolicy
+	defaultWarmPoolRefillInterval = cloud.DefaultWarmPoolRefillInterval
+	defaultCreateAttempts         = cloud.DefaultCreateAttempts
+)
+
+// The cloud package name is shadowed by a cloud provider in Setup
+var loadPriceCatalog = cloud.LoadPriceCatalog
+var loadOverridePolicy = cloud.LoadOverridePolicy
 
 type daemonConfig struct {
-	serverConfig adaptor.ServerConfig
+	serverConfig  adaptor.ServerConfig
+	tracingConfig tracing.Config
+	loggingConfig logging.Config
 	networkConfig
 }
 
@@ -34,6 +53,11 @@ type networkConfig struct {
 	HostInterface string
 	VXLANPort     int
 	VXLANMinID    int
+
+	PodIndexStore              string
+	PodIndexFile               string
+	PodIndexConfigMapName      string
+	PodIndexConfigMapNamespace string
 }
 
 func printHelp(out io.Writer) {
@@ -41,7 +65,7 @@ func printHelp(out io.Writer) {
 	fmt.Fprintln(out)
 	fmt.Fprintln(out, "Supported cloud providers are:")
 
-	for _, name := range cloud.List() {
+	for _, name := range cloudmgr.List() {
 		fmt.Fprintf(out, "\t%s\n", name)
 	}
 	fmt.Fprintln(out)
@@ -74,7 +98,7 @@ func (cfg *daemonConfig) Setup() (cmd.Starter, error) {
 		cmd.Exit(1)
 	}
 
-	cloud := cloud.Get(cloudName)
+	cloud := cloudmgr.Get(cloudName)
 
 	if cloud == nil {
 		fmt.Fprintf(os.Stderr, "%s: Unsupported cloud provider: %s\n\n", programName, cloudName)
@@ -83,8 +107,10 @@ func (cfg *daemonConfig) Setup() (cmd.Starter, error) {
 	}
 
 	var (
-		disableTLS bool
-		tlsConfig  tlsutil.TLSConfig
+		disableTLS         bool
+		tlsConfig          tlsutil.TLSConfig
+		pricingFile        string
+		overridePolicyFile string
 	)
 
 	cmd.Parse(programName, os.Args[1:], func(flags *flag.FlagSet) {
@@ -111,12 +137,39 @@ func (cfg *daemonConfig) Setup() (cmd.Starter, error) {
 		flags.StringVar(&cfg.networkConfig.HostInterface, "host-interface", "", "Host Interface")
 		flags.IntVar(&cfg.networkConfig.VXLANPort, "vxlan-port", vxlan.DefaultVXLANPort, "VXLAN UDP port number (VXLAN tunnel mode only")
 		flags.IntVar(&cfg.networkConfig.VXLANMinID, "vxlan-min-id", vxlan.DefaultVXLANMinID, "Minimum VXLAN ID (VXLAN tunnel mode only")
+		flags.StringVar(&cfg.networkConfig.PodIndexStore, "pod-index-store", podnetwork.DefaultPodIndexStore, "Where to persist pod indexes used to derive VXLAN IDs (file, configmap or memory)")
+		flags.StringVar(&cfg.networkConfig.PodIndexFile, "pod-index-file", podnetwork.DefaultPodIndexFile, "File to persist pod indexes (pod-index-store=file only)")
+		flags.StringVar(&cfg.networkConfig.PodIndexConfigMapName, "pod-index-configmap", "", "ConfigMap to persist pod indexes. Defaults to peerpod-index-<NODE_NAME> (pod-index-store=configmap only)")
+		flags.StringVar(&cfg.networkConfig.PodIndexConfigMapNamespace, "pod-index-configmap-namespace", defaultPodIndexConfigMapNamespace, "Namespace of the ConfigMap to persist pod indexes (pod-index-store=configmap only)")
 		flags.StringVar(&cfg.serverConfig.AAKBCParams, "aa-kbc-params", "", "attestation-agent KBC parameters")
 		flags.BoolVar(&cfg.serverConfig.EnableCloudConfigVerify, "cloud-config-verify", false, "Enable cloud config verify - should use it for production")
+		flags.BoolVar(&cfg.serverConfig.ReconcileOrphans, "reconcile-orphans", false, "Delete pod VM instances of the cluster whose pod no longer exists. One cloud-api-adaptor per cluster is elected to do it")
+		flags.BoolVar(&cfg.serverConfig.ReconcileDryRun, "reconcile-dry-run", false, "Only report pod VM instances that would be deleted by reconcile-orphans")
+		flags.DurationVar(&cfg.serverConfig.ReconcileInterval, "reconcile-interval", adaptor.DefaultReconcileInterval, "Interval to look for pod VM instances whose pod no longer exists (reconcile-orphans only)")
+		flags.StringVar(&cfg.serverConfig.ReconcileNamespace, "reconcile-namespace", defaultReconcileNamespace, "Namespace of the lease to elect the cloud-api-adaptor that deletes orphaned pod VM instances (reconcile-orphans only)")
+		flags.IntVar(&cfg.serverConfig.PodsLimit, "pods-limit", 0, "Maximum number of peer pods on the node. 0 means no limit")
+		flags.IntVar(&cfg.serverConfig.CreateAttempts, "create-attempts", defaultCreateAttempts, "Maximum number of attempts to create a pod VM, falling back to other instance types, subnets or zones when the cloud has no capacity. 1 disables fallback")
+		flags.StringVar(&pricingFile, "pricing-file", "", "JSON file of hourly prices by instance type. Instance types are selected by the lowest price that fits the vCPUs, memory and GPUs of a pod")
+		flags.StringVar(&overridePolicyFile, "override-policy-file", "", "JSON file of the override annotations that pods may set, with their allowed values. Override annotations are rejected unless specified")
+		flags.BoolVar(&cfg.serverConfig.DevicePlugin, "device-plugin", false, "Advertise the capacity of peer pods on the node by a kubelet device plugin of kata.peerpods.io/vm. Requires pods-limit")
+		flags.StringVar(&cfg.serverConfig.MetricsAddr, "metrics-addr", adaptor.DefaultMetricsAddr, "Listen address of the Prometheus metrics endpoint, e.g. :8001. The endpoint is disabled unless specified")
+		flags.StringVar(&cfg.tracingConfig.Exporter, "tracing-exporter", tracing.DefaultExporter, "Where to export trace spans (none, otlp or file)")
+		flags.StringVar(&cfg.tracingConfig.Endpoint, "tracing-endpoint", "", "URL or host:port of an OTLP/HTTP trace receiver. Defaults to OTEL_EXPORTER_OTLP_TRACES_ENDPOINT or OTEL_EXPORTER_OTLP_ENDPOINT (tracing-exporter=otlp only)")
+		flags.StringVar(&cfg.tracingConfig.FilePath, "tracing-file", "", "File to write trace spans (tracing-exporter=file only)")
+		flags.StringVar(&cfg.loggingConfig.Format, "log-format", logging.DefaultFormat, "Log output format (text or json)")
+		flags.StringVar(&cfg.loggingConfig.Level, "log-level", logging.DefaultLevel, "Minimum log level (debug, info, warn or error)")
+		flags.Var(&cfg.serverConfig.WarmPool.Sizes, "warm-pool", "Number of pre-provisioned pod VMs per instance type, in the form of <instance type>=<size>,... Use \"default\" for the default instance type")
+		flags.IntVar(&cfg.serverConfig.WarmPool.MaxInstances, "warm-pool-max", 0, "Maximum number of pre-provisioned pod VMs of all instance types, including pod VMs being created. 0 means no limit")
+		flags.StringVar(&cfg.serverConfig.WarmPool.RefillPolicy, "warm-pool-refill-policy", defaultWarmPoolRefillPolicy, "When to refill the warm pool (eager or periodic)")
+		flags.DurationVar(&cfg.serverConfig.WarmPool.RefillInterval, "warm-pool-refill-interval", defaultWarmPoolRefillInterval, "Interval to refill the warm pool")
 
 		cloud.ParseCmd(flags)
 	})
 
+	if err := logging.Setup(os.Stderr, &cfg.loggingConfig); err != nil {
+		return nil, err
+	}
+
 	cmd.ShowVersion(programName)
 
 	fmt.Printf("%s: starting Cloud API Adaptor daemon for %q\n", programName, cloudName)
@@ -125,9 +178,54 @@ func (cfg *daemonConfig) Setup() (cmd.Starter, error) {
 		cfg.serverConfig.TLSConfig = &tlsConfig
 	}
 
+	cfg.serverConfig.CloudName = cloudName
+
+	cfg.serverConfig.NodeName = os.Getenv("NODE_NAME")
+	if cfg.serverConfig.NodeName == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("NODE_NAME is not set, and failed to get the host name: %w", err)
+		}
+		cfg.serverConfig.NodeName = hostname
+	}
+	cfg.serverConfig.WarmPool.NodeName = cfg.serverConfig.NodeName
+
 	cloud.LoadEnv()
 
-	workerNode := podnetwork.NewWorkerNode(cfg.TunnelType, cfg.HostInterface, cfg.VXLANPort, cfg.VXLANMinID)
+	if err := cfg.serverConfig.WarmPool.Validate(); err != nil {
+		return nil, err
+	}
+	if disableTLS && cfg.serverConfig.WarmPool.Enabled() {
+		// Pre-provisioned pod VMs accept a daemon config from any client without TLS
+		return nil, fmt.Errorf("warm pool cannot be enabled with disable-tls")
+	}
+
+	if pricingFile != "" {
+		prices, err := loadPriceCatalog(pricingFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.serverConfig.Prices = prices
+	}
+
+	if overridePolicyFile != "" {
+		policy, err := loadOverridePolicy(overridePolicyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.serverConfig.OverridePolicy = policy
+	}
+
+	if cfg.serverConfig.DevicePlugin && cfg.serverConfig.PodsLimit <= 0 {
+		return nil, fmt.Errorf("device-plugin requires pods-limit to be greater than 0")
+	}
+
+	podIndexes, err := cfg.newPodIndexAllocator()
+	if err != nil {
+		return nil, err
+	}
+
+	workerNode := podnetwork.NewWorkerNode(cfg.TunnelType, cfg.HostInterface, cfg.VXLANPort, cfg.VXLANMinID, podIndexes)
 
 	provider, err := cloud.NewProvider()
 	if err != nil {
@@ -139,6 +237,40 @@ func (cfg *daemonConfig) Setup() (cmd.Starter, error) {
 	return cmd.NewStarter(server), nil
 }
 
+func (cfg *networkConfig) newPodIndexAllocator() (podnetwork.PodIndexAllocator, error) {
+
+	if cfg.VXLANMinID < 0 || cfg.VXLANMinID > vxlan.MaxVXLANID {
+		return nil, fmt.Errorf("vxlan-min-id %d is out of the VXLAN ID range [0, %d]", cfg.VXLANMinID, vxlan.MaxVXLANID)
+	}
+
+	var store podnetwork.PodIndexStore
+
+	switch cfg.PodIndexStore {
+	case podnetwork.PodIndexStoreMemory:
+		store = podnetwork.NewMemoryPodIndexStore()
+	case podnetwork.PodIndexStoreFile:
+		store = podnetwork.NewFilePodIndexStore(cfg.PodIndexFile)
+	case podnetwork.PodIndexStoreConfigMap:
+		name := cfg.PodIndexConfigMapName
+		if name == "" {
+			nodeName := os.Getenv("NODE_NAME")
+			if nodeName == "" {
+				return nil, fmt.Errorf("pod-index-configmap is not specified, and NODE_NAME is not set")
+			}
+			name = "peerpod-index-" + nodeName
+		}
+		cmStore, err := k8sops.NewPodIndexConfigMapStore(cfg.PodIndexConfigMapNamespace, name)
+		if err != nil {
+			return nil, err
+		}
+		store = cmStore
+	default:
+		return nil, fmt.Errorf("unknown pod index store: %q", cfg.PodIndexStore)
+	}
+
+	return podnetwork.NewPodIndexAllocator(store, vxlan.MaxVXLANID-cfg.VXLANMinID), nil
+}
+
 var config = &daemonConfig{}
 
 func main() {
@@ -151,9 +283,21 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	shutdownTracing, err := tracing.Setup(ctx, programName, &config.tracingConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", os.Args[0], err)
+		cmd.Exit(1)
+	}
+
 	go probe.Start(config.serverConfig.SocketPath)
 
-	if err := starter.Start(ctx); err != nil {
+	err = starter.Start(ctx)
+
+	if e := shutdownTracing(context.Background()); e != nil {
+		fmt.Fprintf(os.Stderr, "%s: failed to flush trace spans: %s\n", os.Args[0], e)
+	}
+
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "%s: %s\n", os.Args[0], err)
 		cmd.Exit(1)
 	}
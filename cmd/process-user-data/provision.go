@@ -12,19 +12,20 @@ import (
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud/aws"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud/azure"
 	daemon "github.com/confidential-containers/cloud-api-adaptor/pkg/forwarder"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/metadata"
 	"github.com/spf13/cobra"
 )
 
 // Get the provider and the URL to retrieve the userData from the instance metadata service
 func getProviderAndUserDataURL(ctx context.Context) (provider string, userDataUrl string) {
 
-	if azure.IsAzure(ctx) {
+	if metadata.IsAzure(ctx) {
 		provider = providerAzure
 		// If the VM is running on Azure, retrieve the userData from the Azure IMDS endpoint
 		userDataUrl = azure.AzureUserDataImdsUrl
 	}
 
-	if aws.IsAWS(ctx) {
+	if metadata.IsAWS(ctx) {
 		provider = providerAws
 		// If the VM is running on AWS, retrieve the userData from the AWS IMDS endpoint
 		userDataUrl = aws.AWSUserDataImdsUrl
@@ -12,12 +12,31 @@ optionals+=""
 [[ -S ${CRI_RUNTIME_ENDPOINT} ]] && optionals+="-cri-runtime-endpoint ${CRI_RUNTIME_ENDPOINT} "
 [[ "${PAUSE_IMAGE}" ]] && optionals+="-pause-image ${PAUSE_IMAGE} "
 [[ "${VXLAN_PORT}" ]] && optionals+="-vxlan-port ${VXLAN_PORT} "
+[[ "${POD_INDEX_STORE}" ]] && optionals+="-pod-index-store ${POD_INDEX_STORE} "
 [[ "${CACERT_FILE}" ]] && optionals+="-ca-cert-file ${CACERT_FILE} "
 [[ "${CERT_FILE}" ]] && [[ "${CERT_KEY}" ]] && optionals+="-cert-file ${CERT_FILE} -cert-key ${CERT_KEY} "
 [[ "${TLS_SKIP_VERIFY}" ]] && optionals+="-tls-skip-verify "
 [[ "${PROXY_TIMEOUT}" ]] && optionals+="-proxy-timeout ${PROXY_TIMEOUT} "
 [[ "${AA_KBC_PARAMS}" ]] && optionals+="-aa-kbc-params ${AA_KBC_PARAMS} "
 [[ "${CLOUD_CONFIG_VERIFY}" == "true" ]] && optionals+="-cloud-config-verify "
+[[ "${RECONCILE_ORPHANS}" == "true" ]] && optionals+="-reconcile-orphans "
+[[ "${RECONCILE_DRY_RUN}" == "true" ]] && optionals+="-reconcile-dry-run "
+[[ "${RECONCILE_INTERVAL}" ]] && optionals+="-reconcile-interval ${RECONCILE_INTERVAL} "
+[[ "${PEERPODS_LIMIT_PER_NODE}" ]] && optionals+="-pods-limit ${PEERPODS_LIMIT_PER_NODE} "
+[[ "${DEVICE_PLUGIN}" == "true" ]] && optionals+="-device-plugin "
+[[ "${CREATE_ATTEMPTS}" ]] && optionals+="-create-attempts ${CREATE_ATTEMPTS} "
+[[ "${PRICING_FILE}" ]] && optionals+="-pricing-file ${PRICING_FILE} "
+[[ "${OVERRIDE_POLICY_FILE}" ]] && optionals+="-override-policy-file ${OVERRIDE_POLICY_FILE} "
+[[ "${METRICS_ADDR}" ]] && optionals+="-metrics-addr ${METRICS_ADDR} "
+[[ "${WARM_POOL}" ]] && optionals+="-warm-pool ${WARM_POOL} "
+[[ "${WARM_POOL_MAX}" ]] && optionals+="-warm-pool-max ${WARM_POOL_MAX} "
+[[ "${WARM_POOL_REFILL_POLICY}" ]] && optionals+="-warm-pool-refill-policy ${WARM_POOL_REFILL_POLICY} "
+[[ "${WARM_POOL_REFILL_INTERVAL}" ]] && optionals+="-warm-pool-refill-interval ${WARM_POOL_REFILL_INTERVAL} "
+[[ "${TRACING_EXPORTER}" ]] && optionals+="-tracing-exporter ${TRACING_EXPORTER} "
+[[ "${TRACING_ENDPOINT}" ]] && optionals+="-tracing-endpoint ${TRACING_ENDPOINT} "
+[[ "${TRACING_FILE}" ]] && optionals+="-tracing-file ${TRACING_FILE} "
+[[ "${LOG_FORMAT}" ]] && optionals+="-log-format ${LOG_FORMAT} "
+[[ "${LOG_LEVEL}" ]] && optionals+="-log-level ${LOG_LEVEL} "
 
 test_vars() {
     for i in "$@"; do
@@ -36,6 +55,7 @@ one_of() {
 aws() {
     test_vars AWS_ACCESS_KEY_ID AWS_SECRET_ACCESS_KEY
 
+    [[ "${PEERPODS_INSTANCE_TYPE}" ]] && PODVM_INSTANCE_TYPE=${PEERPODS_INSTANCE_TYPE} # set by peerpodconfig-ctrl
 
     [[ "${PODVM_LAUNCHTEMPLATE_NAME}" ]] && optionals+="-use-lt -aws-lt-name ${PODVM_LAUNCHTEMPLATE_NAME} " # has precedence if set
     [[ "${AWS_SG_IDS}" ]] && optionals+="-securitygroupids ${AWS_SG_IDS} "                                  # MUST if template is not used
@@ -44,6 +64,7 @@ aws() {
     [[ "${PODVM_INSTANCE_TYPES}" ]] && optionals+="-instance-types ${PODVM_INSTANCE_TYPES} "
     [[ "${SSH_KP_NAME}" ]] && optionals+="-keyname ${SSH_KP_NAME} "      # if not retrieved from IMDS
     [[ "${AWS_SUBNET_ID}" ]] && optionals+="-subnetid ${AWS_SUBNET_ID} " # if not set retrieved from IMDS
+    [[ "${AWS_FALLBACK_SUBNET_IDS}" ]] && optionals+="-fallback-subnetids ${AWS_FALLBACK_SUBNET_IDS} " # comma separated
     [[ "${AWS_REGION}" ]] && optionals+="-aws-region ${AWS_REGION} "     # if not set retrieved from IMDS
     [[ "${TAGS}" ]] && optionals+="-tags ${TAGS} " # Custom tags applied to pod vm
     [[ "${USE_PUBLIC_IP}" == "true" ]] && optionals+="-use-public-ip " # Use public IP for pod vm
@@ -62,9 +83,12 @@ aws() {
 azure() {
     test_vars AZURE_CLIENT_ID AZURE_TENANT_ID AZURE_SUBSCRIPTION_ID AZURE_RESOURCE_GROUP AZURE_SUBNET_ID AZURE_IMAGE_ID
 
+    [[ "${PEERPODS_INSTANCE_TYPE}" ]] && AZURE_INSTANCE_SIZE=${PEERPODS_INSTANCE_TYPE} # set by peerpodconfig-ctrl
+
     [[ "${SSH_USERNAME}" ]] && optionals+="-ssh-username ${SSH_USERNAME} "
     [[ "${DISABLECVM}" == "true" ]] && optionals+="-disable-cvm "
     [[ "${AZURE_INSTANCE_SIZES}" ]] && optionals+="-instance-sizes ${AZURE_INSTANCE_SIZES} "
+    [[ "${AZURE_FALLBACK_ZONES}" ]] && optionals+="-fallback-zones ${AZURE_FALLBACK_ZONES} " # comma separated
     [[ "${TAGS}" ]] && optionals+="-tags ${TAGS} " # Custom tags applied to pod vm
     [[ "${DISABLE_CLOUD_CONFIG}" == "true" ]] && optionals+="-disable-cloud-config "
     [[ "${ENABLE_SECURE_BOOT}" == "true" ]] && optionals+="-enable-secure-boot "
@@ -85,6 +109,8 @@ azure() {
 ibmcloud() {
     one_of IBMCLOUD_API_KEY IBMCLOUD_IAM_PROFILE_ID
 
+    [[ "${PEERPODS_INSTANCE_TYPE}" ]] && IBMCLOUD_PODVM_INSTANCE_PROFILE_NAME=${PEERPODS_INSTANCE_TYPE} # set by peerpodconfig-ctrl
+
     set -x
     exec cloud-api-adaptor ibmcloud \
         -iam-service-url "${IBMCLOUD_IAM_ENDPOINT}" \
@@ -111,6 +137,9 @@ ibmcloud_powervs() {
     [[ "${POWERVS_PROCESSOR_TYPE}" ]] && optionals+="-proc-type ${POWERVS_PROCESSOR_TYPE} "
     [[ "${POWERVS_SYSTEM_TYPE}" ]] && optionals+="-sys-type ${POWERVS_SYSTEM_TYPE} "
     [[ "${USE_PUBLIC_IP}" == "true" ]] && optionals+="-use-public-ip " # Use public IP for pod vm
+    [[ "${PEERPODS_INSTANCE_TYPE}" ]] && PODVM_INSTANCE_TYPE=${PEERPODS_INSTANCE_TYPE} # set by peerpodconfig-ctrl
+    [[ "${PODVM_INSTANCE_TYPE}" ]] && optionals+="-instance-type ${PODVM_INSTANCE_TYPE} "
+    [[ "${PODVM_INSTANCE_TYPES}" ]] && optionals+="-instance-types ${PODVM_INSTANCE_TYPES} "
 
     set -x
     exec cloud-api-adaptor ibmcloud-powervs \
@@ -128,6 +157,12 @@ libvirt() {
     test_vars LIBVIRT_URI
 
     [[ "${DISABLECVM}" = "true" ]] && optionals+="-disable-cvm "
+    [[ "${PEERPODS_INSTANCE_TYPE}" ]] && PODVM_INSTANCE_TYPE=${PEERPODS_INSTANCE_TYPE} # set by peerpodconfig-ctrl
+    [[ "${PODVM_INSTANCE_TYPE}" ]] && optionals+="-instance-type ${PODVM_INSTANCE_TYPE} "
+    [[ "${PODVM_INSTANCE_TYPES}" ]] && optionals+="-instance-types ${PODVM_INSTANCE_TYPES} "
+    [[ "${LIBVIRT_VCPUS}" ]] && optionals+="-vcpus ${LIBVIRT_VCPUS} "
+    [[ "${LIBVIRT_MEMORY}" ]] && optionals+="-memory ${LIBVIRT_MEMORY} "
+    [[ "${LIBVIRT_ROOT_DISK_SIZE}" ]] && optionals+="-root-disk-size ${LIBVIRT_ROOT_DISK_SIZE} "
     set -x
     exec cloud-api-adaptor libvirt \
         -uri "${LIBVIRT_URI}" \
@@ -148,6 +183,11 @@ vsphere() {
     [[ "${GOVC_HOST}" ]] && optionals+="-host ${GOVC_HOST} "
     [[ "${GOVC_DRS}" ]] && optionals+="-drs ${GOVC_DRS} "
     [[ "${GOVC_DATASTORE}" ]] && optionals+="-data-store ${GOVC_DATASTORE} "
+    [[ "${PEERPODS_INSTANCE_TYPE}" ]] && PODVM_INSTANCE_TYPE=${PEERPODS_INSTANCE_TYPE} # set by peerpodconfig-ctrl
+    [[ "${PODVM_INSTANCE_TYPE}" ]] && optionals+="-instance-type ${PODVM_INSTANCE_TYPE} "
+    [[ "${PODVM_INSTANCE_TYPES}" ]] && optionals+="-instance-types ${PODVM_INSTANCE_TYPES} "
+    [[ "${GOVC_VCPUS}" ]] && optionals+="-vcpus ${GOVC_VCPUS} "
+    [[ "${GOVC_MEMORY}" ]] && optionals+="-memory ${GOVC_MEMORY} "
 
     set -x
     exec cloud-api-adaptor vsphere \
@@ -157,12 +197,24 @@ vsphere() {
         -socket /run/peerpod/hypervisor.sock
 }
 
+external() {
+    [[ "${PLUGIN_PATH}" ]] && optionals+="-plugin-path ${PLUGIN_PATH} "       # if not set, connect to a running plugin
+    [[ "${PLUGIN_SOCKET}" ]] && optionals+="-plugin-socket ${PLUGIN_SOCKET} " # default /run/peerpod/cloud-provider.sock
+    [[ "${PLUGIN_TIMEOUT}" ]] && optionals+="-plugin-timeout ${PLUGIN_TIMEOUT} "
+
+    set -x
+    exec cloud-api-adaptor external \
+        -pods-dir /run/peerpod/pods \
+        ${optionals} \
+        -socket /run/peerpod/hypervisor.sock
+}
+
 help_msg() {
     cat <<EOF
 Usage:
-	CLOUD_PROVIDER=aws|azure|ibmcloud|ibmcloud-powervs|libvirt|vsphere $0
+	CLOUD_PROVIDER=aws|azure|ibmcloud|ibmcloud-powervs|libvirt|vsphere|external $0
 or
-	$0 aws|azure|ibmcloud|ibmcloud-powervs|libvirt|vsphere
+	$0 aws|azure|ibmcloud|ibmcloud-powervs|libvirt|vsphere|external
 in addition all cloud provider specific env variables must be set and valid
 (CLOUD_PROVIDER is currently set to "$CLOUD_PROVIDER")
 EOF
@@ -180,6 +232,8 @@ elif [[ "$CLOUD_PROVIDER" == "libvirt" ]]; then
     libvirt
 elif [[ "$CLOUD_PROVIDER" == "vsphere" ]]; then
     vsphere
+elif [[ "$CLOUD_PROVIDER" == "external" ]]; then
+    external
 else
     help_msg
 fi
@@ -9,3 +9,7 @@ cd "$basedir/proto"
 protoc  --gogottrpc_out=. \
         --gogottrpc_opt=plugins=ttrpc+fieldpath,paths=source_relative \
         podvminfo/podvminfo.proto
+
+protoc  --go_out=. --go_opt=paths=source_relative \
+        --go-grpc_out=. --go-grpc_opt=paths=source_relative \
+        cloudprovider/v1/cloudprovider.proto
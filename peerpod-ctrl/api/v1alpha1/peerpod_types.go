@@ -29,13 +29,90 @@ type PeerPodSpec struct {
 	InstanceID    string `json:"instanceID,omitempty"`
 }
 
+// PeerPodPhase is a phase of the lifecycle of a pod VM instance
+// +kubebuilder:validation:Enum=Provisioning;Running;Deleting;Failed
+type PeerPodPhase string
+
+const (
+	// PeerPodProvisioning means the instance is created, and cloud-api-adaptor is waiting for its agent
+	PeerPodProvisioning PeerPodPhase = "Provisioning"
+	// PeerPodRunning means the agent of the instance is reachable
+	PeerPodRunning PeerPodPhase = "Running"
+	// PeerPodDeleting means the instance is being deleted
+	PeerPodDeleting PeerPodPhase = "Deleting"
+	// PeerPodFailed means the instance failed to start, or vanished from the cloud provider
+	PeerPodFailed PeerPodPhase = "Failed"
+)
+
+// Condition types of PeerPod
+const (
+	// ConditionReady is true when the agent of the instance is reachable from cloud-api-adaptor
+	ConditionReady = "Ready"
+	// ConditionInstanceAvailable is true when the cloud provider lists the instance
+	ConditionInstanceAvailable = "InstanceAvailable"
+	// ConditionStalled is true when the instance stays in the Provisioning or Deleting phase for too long
+	ConditionStalled = "Stalled"
+	// ConditionInstanceDeleted is true when the instance is deleted, and false when deletion failed
+	ConditionInstanceDeleted = "InstanceDeleted"
+)
+
 // PeerPodStatus defines the observed state of PeerPod
 type PeerPodStatus struct {
-	Cleaned bool `json:"cleand,omitempty"`
+	// Cleaned is true when the instance is deleted
+	Cleaned bool `json:"cleaned,omitempty"`
+	// Deprecated: LegacyCleaned is Cleaned as recorded by earlier releases under the misspelled cleand key.
+	// It is only read, use IsCleaned.
+	LegacyCleaned bool `json:"cleand,omitempty"`
+
+	// InstanceName is the name of the instance in the cloud provider
+	InstanceName string `json:"instanceName,omitempty"`
+	// IPs are the IP addresses of the instance
+	IPs []string `json:"ips,omitempty"`
+	// InstanceType is the instance type, or the instance size or profile, of the instance
+	InstanceType string `json:"instanceType,omitempty"`
+	// Zone is the zone the instance runs in
+	Zone string `json:"zone,omitempty"`
+	// Attempt is the attempt of cloud-api-adaptor that created the instance, counting from 1. Attempts after
+	// the first fall back to other instance types or placements when the cloud has no capacity.
+	Attempt int32 `json:"attempt,omitempty"`
+	// Placement is the alternate subnet or zone the instance was created in. Empty means the default placement.
+	Placement string `json:"placement,omitempty"`
+	// Price is the hourly price of the instance type, in the currency of the pricing catalog of cloud-api-adaptor.
+	// It is a decimal string, and empty when the price is unknown.
+	Price string `json:"price,omitempty"`
+
+	// Phase is the phase of the lifecycle of the instance
+	Phase PeerPodPhase `json:"phase,omitempty"`
+	// Conditions are the latest observations of the instance
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+
+	// CreationTime is when the instance was created
+	CreationTime *metav1.Time `json:"creationTime,omitempty"`
+	// ReadyTime is when the agent of the instance became reachable
+	ReadyTime *metav1.Time `json:"readyTime,omitempty"`
+	// DeletionTime is when the instance was deleted
+	DeletionTime *metav1.Time `json:"deletionTime,omitempty"`
+	// LastSyncTime is when a check of the instance with the cloud provider last changed the phase or conditions
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+}
+
+// IsCleaned returns whether the instance is deleted, including PeerPods recorded by earlier releases
+func (s *PeerPodStatus) IsCleaned() bool {
+	return s.Cleaned || s.LegacyCleaned
 }
 
+//+genclient
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Instance",type=string,JSONPath=`.status.instanceName`
+//+kubebuilder:printcolumn:name="Type",type=string,JSONPath=`.status.instanceType`,priority=1
+//+kubebuilder:printcolumn:name="Zone",type=string,JSONPath=`.status.zone`,priority=1
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // PeerPod is the Schema for the peerpods API
 type PeerPod struct {
@@ -0,0 +1,189 @@
+/*
+Copyright Confidential Containers Contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	confidentialcontainersorgv1alpha1 "github.com/confidential-containers/cloud-api-adaptor/peerpod-ctrl/api/v1alpha1"
+)
+
+// instanceGracePeriod is how long a new PeerPod is not reported as drifted.
+// Some clouds do not list an instance right after it is created.
+const instanceGracePeriod = 2 * time.Minute
+
+// Reasons of PeerPod conditions set by the drift checker
+const (
+	reasonInstanceFound       = "InstanceFound"
+	reasonInstanceVanished    = "InstanceVanished"
+	reasonInstanceDeleted     = "InstanceDeleted"
+	reasonProvisioningTimeout = "ProvisioningTimeout"
+	reasonDeletionTimeout     = "DeletionTimeout"
+	reasonProgressing         = "Progressing"
+)
+
+// runDriftChecker periodically checks drift between PeerPods and the instances of the cloud provider until ctx is done
+func (r *PeerPodReconciler) runDriftChecker(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("drift-checker")
+	logger.Info("starting drift checker", "interval", r.DriftCheckInterval, "stuckTimeout", r.StuckTimeout)
+
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := r.checkDrift(ctx); err != nil {
+			logger.Info("skipped drift check", "error", err)
+		}
+	}, r.DriftCheckInterval)
+
+	return nil
+}
+
+// checkDrift lists the instances of the cloud provider once, and records in the status of each PeerPod
+// whether its instance still exists and whether it is stuck in a transitional phase.
+// The status of a PeerPod is patched only when its phase or conditions change.
+func (r *PeerPodReconciler) checkDrift(ctx context.Context) error {
+	logger := log.FromContext(ctx)
+
+	provider, err := r.getProvider(ctx)
+	if err != nil {
+		return fmt.Errorf("cloud provider is not available: %w", err)
+	}
+
+	ppList := confidentialcontainersorgv1alpha1.PeerPodList{}
+	if err := r.List(ctx, &ppList); err != nil {
+		return fmt.Errorf("listing PeerPods: %w", err)
+	}
+	if len(ppList.Items) == 0 {
+		return nil
+	}
+
+	instances, err := provider.ListInstances(ctx)
+	if err != nil {
+		return fmt.Errorf("listing instances: %w", err)
+	}
+
+	found := make(map[string]bool)
+	for _, instance := range instances {
+		found[instance.ID] = true
+	}
+
+	now := time.Now()
+	for i := range ppList.Items {
+		pp := &ppList.Items[i]
+		if pp.Status.IsCleaned() || pp.Spec.InstanceID == "" {
+			continue
+		}
+
+		orig := pp.DeepCopy()
+		reason, message := syncStatus(pp, found[pp.Spec.InstanceID], now, r.StuckTimeout)
+		if reason != "" {
+			logger.Info("detected drift", "PeerPod", pp.Name, "namespace", pp.Namespace, "reason", reason, "message", message)
+			r.recordEvent(pp, corev1.EventTypeWarning, reason, message)
+		}
+
+		if pp.Status.Phase == orig.Status.Phase && equality.Semantic.DeepEqual(pp.Status.Conditions, orig.Status.Conditions) {
+			continue
+		}
+
+		// The optimistic lock keeps conditions set concurrently by cloud-api-adaptor, since a merge patch replaces the whole list
+		patch := client.MergeFromWithOptions(orig, client.MergeFromWithOptimisticLock{})
+		if err := r.Status().Patch(ctx, pp, patch); err != nil && !apierrors.IsNotFound(err) {
+			logger.Info("failed to update PeerPod status", "PeerPod", pp.Name, "namespace", pp.Namespace, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// syncStatus updates the status of a PeerPod with the result of an instance listing at now.
+// It returns the reason and message of a newly detected drift, or an empty reason.
+func syncStatus(pp *confidentialcontainersorgv1alpha1.PeerPod, found bool, now time.Time, stuckTimeout time.Duration) (string, string) {
+	status := &pp.Status
+	status.LastSyncTime = &metav1.Time{Time: now}
+
+	var reason, message string
+
+	switch {
+	case found:
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    confidentialcontainersorgv1alpha1.ConditionInstanceAvailable,
+			Status:  metav1.ConditionTrue,
+			Reason:  reasonInstanceFound,
+			Message: fmt.Sprintf("instance %s is listed by the cloud provider", pp.Spec.InstanceID),
+		})
+	case status.Phase == confidentialcontainersorgv1alpha1.PeerPodDeleting || pp.DeletionTimestamp != nil:
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    confidentialcontainersorgv1alpha1.ConditionInstanceAvailable,
+			Status:  metav1.ConditionFalse,
+			Reason:  reasonInstanceDeleted,
+			Message: fmt.Sprintf("instance %s is no longer listed by the cloud provider", pp.Spec.InstanceID),
+		})
+	case now.Sub(pp.CreationTimestamp.Time) >= instanceGracePeriod:
+		if !meta.IsStatusConditionFalse(status.Conditions, confidentialcontainersorgv1alpha1.ConditionInstanceAvailable) {
+			reason = reasonInstanceVanished
+			message = fmt.Sprintf("instance %s is not listed by the cloud provider", pp.Spec.InstanceID)
+		}
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    confidentialcontainersorgv1alpha1.ConditionInstanceAvailable,
+			Status:  metav1.ConditionFalse,
+			Reason:  reasonInstanceVanished,
+			Message: fmt.Sprintf("instance %s is not listed by the cloud provider", pp.Spec.InstanceID),
+		})
+		status.Phase = confidentialcontainersorgv1alpha1.PeerPodFailed
+	}
+
+	var stalledReason, stalledMessage string
+	if stuckTimeout > 0 {
+		switch {
+		case pp.DeletionTimestamp != nil && now.Sub(pp.DeletionTimestamp.Time) >= stuckTimeout:
+			stalledReason = reasonDeletionTimeout
+			stalledMessage = fmt.Sprintf("instance %s has been deleting for more than %v", pp.Spec.InstanceID, stuckTimeout)
+		case status.Phase == confidentialcontainersorgv1alpha1.PeerPodProvisioning && status.CreationTime != nil && now.Sub(status.CreationTime.Time) >= stuckTimeout:
+			stalledReason = reasonProvisioningTimeout
+			stalledMessage = fmt.Sprintf("instance %s has been provisioning for more than %v", pp.Spec.InstanceID, stuckTimeout)
+		}
+	}
+
+	if stalledReason != "" {
+		if reason == "" && !meta.IsStatusConditionTrue(status.Conditions, confidentialcontainersorgv1alpha1.ConditionStalled) {
+			reason, message = stalledReason, stalledMessage
+		}
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    confidentialcontainersorgv1alpha1.ConditionStalled,
+			Status:  metav1.ConditionTrue,
+			Reason:  stalledReason,
+			Message: stalledMessage,
+		})
+	} else if meta.IsStatusConditionTrue(status.Conditions, confidentialcontainersorgv1alpha1.ConditionStalled) {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:   confidentialcontainersorgv1alpha1.ConditionStalled,
+			Status: metav1.ConditionFalse,
+			Reason: reasonProgressing,
+		})
+	}
+
+	return reason, message
+}
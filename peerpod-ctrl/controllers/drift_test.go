@@ -0,0 +1,153 @@
+/*
+Copyright Confidential Containers Contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	confidentialcontainersorgv1alpha1 "github.com/confidential-containers/cloud-api-adaptor/peerpod-ctrl/api/v1alpha1"
+)
+
+func TestSyncStatus(t *testing.T) {
+	now := time.Now()
+	stuckTimeout := 10 * time.Minute
+
+	newPeerPod := func(age time.Duration, phase confidentialcontainersorgv1alpha1.PeerPodPhase) *confidentialcontainersorgv1alpha1.PeerPod {
+		pp := &confidentialcontainersorgv1alpha1.PeerPod{}
+		pp.CreationTimestamp = metav1.NewTime(now.Add(-age))
+		pp.Spec.InstanceID = "i-1234"
+		pp.Status.Phase = phase
+		pp.Status.CreationTime = &pp.CreationTimestamp
+		return pp
+	}
+
+	deleting := newPeerPod(time.Hour, confidentialcontainersorgv1alpha1.PeerPodRunning)
+	deleting.DeletionTimestamp = &metav1.Time{Time: now.Add(-time.Minute)}
+
+	stalledDeleting := newPeerPod(time.Hour, confidentialcontainersorgv1alpha1.PeerPodDeleting)
+	stalledDeleting.DeletionTimestamp = &metav1.Time{Time: now.Add(-time.Hour)}
+
+	vanishedBefore := newPeerPod(time.Hour, confidentialcontainersorgv1alpha1.PeerPodFailed)
+	meta.SetStatusCondition(&vanishedBefore.Status.Conditions, metav1.Condition{
+		Type:   confidentialcontainersorgv1alpha1.ConditionInstanceAvailable,
+		Status: metav1.ConditionFalse,
+		Reason: reasonInstanceVanished,
+	})
+
+	recovered := newPeerPod(time.Hour, confidentialcontainersorgv1alpha1.PeerPodRunning)
+	meta.SetStatusCondition(&recovered.Status.Conditions, metav1.Condition{
+		Type:   confidentialcontainersorgv1alpha1.ConditionStalled,
+		Status: metav1.ConditionTrue,
+		Reason: reasonProvisioningTimeout,
+	})
+
+	for name, tc := range map[string]struct {
+		pp        *confidentialcontainersorgv1alpha1.PeerPod
+		found     bool
+		reason    string
+		phase     confidentialcontainersorgv1alpha1.PeerPodPhase
+		available metav1.ConditionStatus
+		stalled   metav1.ConditionStatus
+	}{
+		"found": {
+			pp:        newPeerPod(time.Hour, confidentialcontainersorgv1alpha1.PeerPodRunning),
+			found:     true,
+			phase:     confidentialcontainersorgv1alpha1.PeerPodRunning,
+			available: metav1.ConditionTrue,
+		},
+		"vanished": {
+			pp:        newPeerPod(time.Hour, confidentialcontainersorgv1alpha1.PeerPodRunning),
+			reason:    reasonInstanceVanished,
+			phase:     confidentialcontainersorgv1alpha1.PeerPodFailed,
+			available: metav1.ConditionFalse,
+		},
+		"vanished already reported": {
+			pp:        vanishedBefore,
+			phase:     confidentialcontainersorgv1alpha1.PeerPodFailed,
+			available: metav1.ConditionFalse,
+		},
+		"deleting": {
+			pp:        deleting,
+			phase:     confidentialcontainersorgv1alpha1.PeerPodRunning,
+			available: metav1.ConditionFalse,
+		},
+		"grace period": {
+			pp:    newPeerPod(time.Minute, confidentialcontainersorgv1alpha1.PeerPodProvisioning),
+			phase: confidentialcontainersorgv1alpha1.PeerPodProvisioning,
+		},
+		"stalled provisioning": {
+			pp:        newPeerPod(time.Hour, confidentialcontainersorgv1alpha1.PeerPodProvisioning),
+			found:     true,
+			reason:    reasonProvisioningTimeout,
+			phase:     confidentialcontainersorgv1alpha1.PeerPodProvisioning,
+			available: metav1.ConditionTrue,
+			stalled:   metav1.ConditionTrue,
+		},
+		"stalled deleting": {
+			pp:        stalledDeleting,
+			found:     true,
+			reason:    reasonDeletionTimeout,
+			phase:     confidentialcontainersorgv1alpha1.PeerPodDeleting,
+			available: metav1.ConditionTrue,
+			stalled:   metav1.ConditionTrue,
+		},
+		"no longer stalled": {
+			pp:        recovered,
+			found:     true,
+			phase:     confidentialcontainersorgv1alpha1.PeerPodRunning,
+			available: metav1.ConditionTrue,
+			stalled:   metav1.ConditionFalse,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			reason, _ := syncStatus(tc.pp, tc.found, now, stuckTimeout)
+			if reason != tc.reason {
+				t.Errorf("expect reason %q, got %q", tc.reason, reason)
+			}
+			if tc.pp.Status.Phase != tc.phase {
+				t.Errorf("expect phase %q, got %q", tc.phase, tc.pp.Status.Phase)
+			}
+			if tc.pp.Status.LastSyncTime == nil || !tc.pp.Status.LastSyncTime.Time.Equal(now) {
+				t.Errorf("expect last sync time %v, got %v", now, tc.pp.Status.LastSyncTime)
+			}
+			checkCondition(t, tc.pp, confidentialcontainersorgv1alpha1.ConditionInstanceAvailable, tc.available)
+			checkCondition(t, tc.pp, confidentialcontainersorgv1alpha1.ConditionStalled, tc.stalled)
+		})
+	}
+}
+
+// checkCondition checks the status of a condition of a PeerPod. An empty status means the condition is not set.
+func checkCondition(t *testing.T, pp *confidentialcontainersorgv1alpha1.PeerPod, conditionType string, status metav1.ConditionStatus) {
+	t.Helper()
+
+	cond := meta.FindStatusCondition(pp.Status.Conditions, conditionType)
+	if status == "" {
+		if cond != nil {
+			t.Errorf("expect no %s condition, got %s", conditionType, cond.Status)
+		}
+		return
+	}
+	if cond == nil {
+		t.Errorf("expect %s condition %s, got none", conditionType, status)
+	} else if cond.Status != status {
+		t.Errorf("expect %s condition %s, got %s", conditionType, status, cond.Status)
+	}
+}
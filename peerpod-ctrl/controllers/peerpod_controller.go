@@ -20,14 +20,21 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
+	"sync"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	confidentialcontainersorgv1alpha1 "github.com/confidential-containers/cloud-api-adaptor/peerpod-ctrl/api/v1alpha1"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud"
@@ -40,6 +47,13 @@ type PeerPodReconciler struct {
 	client.Client
 	Scheme   *runtime.Scheme
 	Provider cloud.Provider
+	Recorder record.EventRecorder
+	// DriftCheckInterval is the interval of checking PeerPods against the instances of the cloud provider. Zero disables it.
+	DriftCheckInterval time.Duration
+	// StuckTimeout is how long a PeerPod can stay provisioning or deleting before it is marked as stalled
+	StuckTimeout time.Duration
+
+	providerMutex sync.Mutex
 }
 
 const (
@@ -49,6 +63,7 @@ const (
 )
 
 //+kubebuilder:rbac:groups="",resourceNames=peer-pods-cm;peer-pods-secret,resources=configmaps;secrets,verbs=get
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 //+kubebuilder:rbac:groups=confidentialcontainers.org,resources=peerpods,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=confidentialcontainers.org,resources=peerpods/status,verbs=get;update;patch
@@ -58,20 +73,9 @@ func (r *PeerPodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	logger := log.FromContext(ctx)
 	pp := confidentialcontainersorgv1alpha1.PeerPod{}
 
-	// cloud provider was not set, try to fetch cloud provider and its configs dynamically from ConfigMap or Secret
-	// make sure the matching RBAC rules are set
-	if r.Provider == nil {
-		logger.Info("trying to fetch cloud provider configs for peerpod-ctrl")
-		if err := r.cloudConfigsGetter(); err != nil {
-			// don't requeue, if cloud configs are missing it will requeue later
-			logger.Info("cannot fetch cloud configs at the moment", "error", err)
-		}
-
-		var pErr error
-		r.Provider, pErr = SetProvider()
-		if pErr != nil {
-			return ctrl.Result{}, pErr
-		}
+	provider, err := r.getProvider(ctx)
+	if err != nil {
+		return ctrl.Result{}, err
 	}
 
 	if err := r.Get(ctx, req.NamespacedName, &pp); err != nil {
@@ -106,10 +110,37 @@ func (r *PeerPodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 
 	if controllerutil.ContainsFinalizer(&pp, ppFinalizer) {
 		logger.Info("deleting instance", "InstanceID", pp.Spec.InstanceID, "CloudProvider", pp.Spec.CloudProvider)
-		if err := r.Provider.DeleteInstance(ctx, pp.Spec.InstanceID); err != nil {
+		if err := provider.DeleteInstance(ctx, pp.Spec.InstanceID); err != nil {
+			r.recordEvent(&pp, corev1.EventTypeWarning, "DeleteFailed", fmt.Sprintf("failed to delete instance %s: %v", pp.Spec.InstanceID, err))
+			pp.Status.Phase = confidentialcontainersorgv1alpha1.PeerPodDeleting
+			meta.SetStatusCondition(&pp.Status.Conditions, metav1.Condition{
+				Type:    confidentialcontainersorgv1alpha1.ConditionInstanceDeleted,
+				Status:  metav1.ConditionFalse,
+				Reason:  "DeleteFailed",
+				Message: err.Error(),
+			})
+			if err := r.Status().Update(ctx, &pp); err != nil {
+				logger.Info("failed to update PeerPod status", "error", err)
+			}
 			return ctrl.Result{}, err
 		}
 
+		r.recordEvent(&pp, corev1.EventTypeNormal, "Deleted", fmt.Sprintf("deleted instance %s", pp.Spec.InstanceID))
+		now := metav1.Now()
+		pp.Status.Cleaned = true
+		pp.Status.DeletionTime = &now
+		meta.SetStatusCondition(&pp.Status.Conditions, metav1.Condition{
+			Type:    confidentialcontainersorgv1alpha1.ConditionInstanceDeleted,
+			Status:  metav1.ConditionTrue,
+			Reason:  "Deleted",
+			Message: fmt.Sprintf("instance %s is deleted by peerpod-ctrl", pp.Spec.InstanceID),
+		})
+		if err := r.Status().Update(ctx, &pp); err != nil {
+			if !apierrors.IsNotFound(err) { // object exist but fail to update, try again
+				return ctrl.Result{}, err
+			}
+		}
+
 		controllerutil.RemoveFinalizer(&pp, ppFinalizer)
 		if err := r.Update(ctx, &pp); err != nil {
 			if !apierrors.IsNotFound(err) { // object exist but fail to update, try again
@@ -125,53 +156,98 @@ func (r *PeerPodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *PeerPodReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.DriftCheckInterval > 0 {
+		// The drift checker runs only on the leader, like the controller
+		if err := mgr.Add(manager.RunnableFunc(r.runDriftChecker)); err != nil {
+			return err
+		}
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&confidentialcontainersorgv1alpha1.PeerPod{}).
 		Complete(r)
 }
 
-func (r *PeerPodReconciler) cloudConfigsGetter() error {
+// getProvider returns the cloud provider of the controller.
+// If it was not set, it tries to fetch the cloud provider and its configs dynamically from ConfigMap or Secret.
+// Make sure the matching RBAC rules are set.
+func (r *PeerPodReconciler) getProvider(ctx context.Context) (cloud.Provider, error) {
+	r.providerMutex.Lock()
+	defer r.providerMutex.Unlock()
+
+	if r.Provider != nil {
+		return r.Provider, nil
+	}
+
+	logger := log.FromContext(ctx)
+	logger.Info("trying to fetch cloud provider configs for peerpod-ctrl")
+	config, err := r.cloudConfigsGetter()
+	if err != nil {
+		// don't requeue, if cloud configs are missing it will requeue later
+		logger.Info("cannot fetch cloud configs at the moment", "error", err)
+	}
+
+	provider, err := SetProvider(config)
+	if err != nil {
+		return nil, err
+	}
+	r.Provider = provider
+	return provider, nil
+}
+
+// recordEvent records an event of a PeerPod, if an event recorder is set
+func (r *PeerPodReconciler) recordEvent(pp *confidentialcontainersorgv1alpha1.PeerPod, eventType, reason, message string) {
+	if r.Recorder != nil {
+		r.Recorder.Event(pp, eventType, reason, message)
+	}
+}
+
+// cloudConfigsGetter returns the cloud provider configs in the ConfigMap and the Secret on top of the environment variables
+func (r *PeerPodReconciler) cloudConfigsGetter() (map[string]string, error) {
+	config := EnvConfig()
 	peerpodscm := corev1.ConfigMap{}
 	peerpodssecret := corev1.Secret{}
 	ns := os.Getenv("PEERPODS_NAMESPACE")
 	if ns == "" {
-		return fmt.Errorf("PEERPODS_NAMESPACE is not set")
+		return config, fmt.Errorf("PEERPODS_NAMESPACE is not set")
 	}
 
 	var cmErr error
 	if cmErr = r.Client.Get(context.TODO(), types.NamespacedName{Name: ppConfigMap, Namespace: ns}, &peerpodscm); cmErr == nil {
-		// set all configs as env vars to make sure all the required vars for auth are set
+		// load all configs to make sure all the required configs for auth are set
 		for k, v := range peerpodscm.Data {
-			os.Setenv(k, v)
+			config[k] = v
 		}
 	}
 
 	var secretErr error
 	if secretErr = r.Client.Get(context.TODO(), types.NamespacedName{Name: ppSecret, Namespace: ns}, &peerpodssecret); secretErr == nil {
 		for k, v := range peerpodssecret.Data {
-			os.Setenv(k, string(v))
+			config[k] = string(v)
 		}
 	}
 
 	if peerpodscm.Data == nil && peerpodssecret.Data == nil {
-		return fmt.Errorf("ConfigMap Error: %v, Secret Error: %v", cmErr, secretErr)
+		return config, fmt.Errorf("ConfigMap Error: %v, Secret Error: %v", cmErr, secretErr)
 	}
 
-	return nil
+	return config, nil
 }
 
-func SetProvider() (cloud.Provider, error) {
-	cloudName := os.Getenv("CLOUD_PROVIDER")
-	if cloud := cloudmgr.Get(cloudName); cloud != nil {
-		cloud.LoadEnv() // we assume LoadEnv knows to load all necessary configs
-		provider, err := cloud.NewProvider()
-		if err != nil {
-			return nil, err
+// EnvConfig returns the environment variables of the controller as cloud provider configs
+func EnvConfig() map[string]string {
+	config := make(map[string]string)
+	for _, env := range os.Environ() {
+		if k, v, ok := strings.Cut(env, "="); ok {
+			config[k] = v
 		}
-		return provider, nil
 	}
+	return config
+}
 
-	return nil, fmt.Errorf("cloudmgr: %s cloud provider not supported", cloudName)
+// SetProvider creates a provider of the cloud provider specified by CLOUD_PROVIDER in config
+func SetProvider(config map[string]string) (cloud.Provider, error) {
+	return cloudmgr.NewProvider(config["CLOUD_PROVIDER"], config)
 }
 
 func isOldPeerPod(pp, cur confidentialcontainersorgv1alpha1.PeerPod) bool {
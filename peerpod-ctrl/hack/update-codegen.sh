@@ -0,0 +1,66 @@
+#!/usr/bin/env bash
+#
+# Copyright Confidential Containers Contributors
+#
+# SPDX-License-Identifier: Apache-2.0
+#
+# Generates the clientset, listers and informers of the PeerPod API in pkg/generated
+
+set -o errexit
+set -o nounset
+set -o pipefail
+
+SCRIPT_ROOT=$(cd "$(dirname "${BASH_SOURCE[0]}")/.." && pwd)
+CODEGEN_VERSION=${CODEGEN_VERSION:-v0.26.1}
+MODULE=github.com/confidential-containers/cloud-api-adaptor/peerpod-ctrl
+OUTPUT_PKG=${MODULE}/pkg/generated
+HEADER=${SCRIPT_ROOT}/hack/boilerplate.go.txt
+
+TMP_DIR=$(mktemp -d)
+# client-gen expects API packages at <group>/<version>, and takes a group named "api" as the core group.
+# Generate from a copy of the API package at apis/confidentialcontainers/v1alpha1.
+APIS_DIR=${SCRIPT_ROOT}/apis
+trap 'rm -rf "${TMP_DIR}" "${APIS_DIR}"' EXIT
+
+GOBIN=${TMP_DIR}/bin go install \
+	"k8s.io/code-generator/cmd/client-gen@${CODEGEN_VERSION}" \
+	"k8s.io/code-generator/cmd/lister-gen@${CODEGEN_VERSION}" \
+	"k8s.io/code-generator/cmd/informer-gen@${CODEGEN_VERSION}"
+
+mkdir -p "${APIS_DIR}/confidentialcontainers"
+cp -r "${SCRIPT_ROOT}/api/v1alpha1" "${APIS_DIR}/confidentialcontainers/"
+# The generators read the group name from doc.go, not from groupversion_info.go
+cat > "${APIS_DIR}/confidentialcontainers/v1alpha1/doc.go" <<EOF
+// +groupName=confidentialcontainers.org
+package v1alpha1
+EOF
+INPUT_PKG=${MODULE}/apis/confidentialcontainers/v1alpha1
+
+cd "${SCRIPT_ROOT}"
+
+"${TMP_DIR}/bin/client-gen" \
+	--clientset-name versioned \
+	--input-base "${MODULE}/apis" \
+	--input confidentialcontainers/v1alpha1 \
+	--output-package "${OUTPUT_PKG}/clientset" \
+	--output-base "${TMP_DIR}" \
+	--go-header-file "${HEADER}"
+
+"${TMP_DIR}/bin/lister-gen" \
+	--input-dirs "${INPUT_PKG}" \
+	--output-package "${OUTPUT_PKG}/listers" \
+	--output-base "${TMP_DIR}" \
+	--go-header-file "${HEADER}"
+
+"${TMP_DIR}/bin/informer-gen" \
+	--input-dirs "${INPUT_PKG}" \
+	--versioned-clientset-package "${OUTPUT_PKG}/clientset/versioned" \
+	--listers-package "${OUTPUT_PKG}/listers" \
+	--output-package "${OUTPUT_PKG}/informers" \
+	--output-base "${TMP_DIR}" \
+	--go-header-file "${HEADER}"
+
+rm -rf "${SCRIPT_ROOT}/pkg/generated"
+mkdir -p "${SCRIPT_ROOT}/pkg"
+cp -r "${TMP_DIR}/${OUTPUT_PKG}" "${SCRIPT_ROOT}/pkg/"
+grep -rl "${INPUT_PKG}" "${SCRIPT_ROOT}/pkg/generated" | xargs sed -i "s#${INPUT_PKG}#${MODULE}/api/v1alpha1#"
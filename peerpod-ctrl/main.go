@@ -19,6 +19,7 @@ package main
 import (
 	"flag"
 	"os"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -54,11 +55,17 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var driftCheckInterval time.Duration
+	var stuckTimeout time.Duration
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.DurationVar(&driftCheckInterval, "drift-check-interval", 5*time.Minute,
+		"Interval of checking PeerPods against the instances of the cloud provider. Zero disables drift checks.")
+	flag.DurationVar(&stuckTimeout, "stuck-timeout", 10*time.Minute,
+		"Duration after which a PeerPod that is still provisioning or deleting is marked as stalled.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -96,15 +103,18 @@ func main() {
 		os.Exit(1)
 	}
 
-	provider, err := controllers.SetProvider()
+	provider, err := controllers.SetProvider(controllers.EnvConfig())
 	if err != nil {
 		setupLog.Info("unable to set provider at init, will retry at reconcile", "error", err)
 	}
 
 	if err = (&controllers.PeerPodReconciler{
-		Client:   mgr.GetClient(),
-		Scheme:   mgr.GetScheme(),
-		Provider: provider,
+		Client:             mgr.GetClient(),
+		Scheme:             mgr.GetScheme(),
+		Provider:           provider,
+		Recorder:           mgr.GetEventRecorderFor("peerpod-ctrl"),
+		DriftCheckInterval: driftCheckInterval,
+		StuckTimeout:       stuckTimeout,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "PeerPod")
 		os.Exit(1)
@@ -51,8 +51,22 @@ type PeerPodConfigStatus struct {
 
 	// SetupCompleted is set to true when all components have been deployed/created
 	SetupCompleted bool `json:"setupCompleted,omitempty"`
+
+	// Conditions show the progress of deploying the components
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
 }
 
+// Condition types of PeerPodConfig
+const (
+	// ConditionDaemonSetReady is true when the cloud-api-adaptor daemonset is rolled out on all selected nodes
+	ConditionDaemonSetReady = "DaemonSetReady"
+)
+
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 
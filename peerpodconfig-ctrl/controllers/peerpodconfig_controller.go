@@ -28,8 +28,8 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -51,6 +51,20 @@ const (
 	defaultPeerPodsLimitPerNode = "1"
 	// cloud-api-adaptor (CAA) daemonset name
 	caaDsName = "peerpodconfig-ctrl-caa-daemon"
+	// extended resource advertised by the device plugin of cloud-api-adaptor, escaped for JSON patches
+	extendedResourceName     = "kata.peerpods.io/vm"
+	extendedResourceJsonName = "kata.peerpods.io~1vm"
+	// directory of the kubelet registration socket and device plugin sockets
+	devicePluginDir = "/var/lib/kubelet/device-plugins"
+	// finalizer to remove the extended resource from the nodes when a PeerPodConfig is deleted
+	peerPodConfigFinalizer = "confidentialcontainers.org/peerpodconfig-finalizer"
+	// field manager of server-side apply
+	fieldOwner = "peerpodconfig-ctrl"
+
+	// Names of env vars of the CAA container that carry the PeerPodConfig spec
+	instanceTypeEnvName = "PEERPODS_INSTANCE_TYPE"
+	limitPerNodeEnvName = "PEERPODS_LIMIT_PER_NODE"
+	devicePluginEnvName = "DEVICE_PLUGIN"
 )
 
 // PeerPodConfigReconciler reconciles a PeerPodConfig object
@@ -70,14 +84,14 @@ type PeerPodConfigReconciler struct {
 //+kubebuilder:rbac:groups="",resources=secrets,verbs=create;get;update;list;watch
 //+kubebuilder:rbac:groups="";machineconfiguration.openshift.io,resources=nodes;machineconfigs;machineconfigpools;containerruntimeconfigs;pods;services;services/finalizers;endpoints;persistentvolumeclaims;events;configmaps;secrets,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=apps,resources=daemonsets,resourceNames=peerpodconfig-ctrl-caa-daemon,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=apps,resources=daemonsets,verbs=list;watch
 //+kubebuilder:rbac:groups=apps,resources=daemonsets/finalizers,verbs=update
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
-// TODO(user): Modify the Reconcile function to compare the state specified by
-// the PeerPodConfig object against the actual cluster state, and then
-// perform operations to make the cluster state reflect the state specified by
-// the user.
+// It applies the cloud-api-adaptor daemonset rendered from the PeerPodConfig spec, and records the progress in the status.
+// The extended resource is advertised on the selected nodes by the device plugin of cloud-api-adaptor.
+// When the PeerPodConfig is deleted, its finalizer removes the extended resource from the nodes.
 //
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.11.0/pkg/reconcile
@@ -88,7 +102,7 @@ func (r *PeerPodConfigReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 
 	// Fetch the PeerPodConfig instance
 	r.peerPodConfig = &ccv1alpha1.PeerPodConfig{}
-	err := r.Client.Get(context.TODO(), req.NamespacedName, r.peerPodConfig)
+	err := r.Client.Get(ctx, req.NamespacedName, r.peerPodConfig)
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
 			// Request object not found, could have been deleted after reconcile request.
@@ -100,32 +114,96 @@ func (r *PeerPodConfigReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		return ctrl.Result{}, err
 	}
 
-	if err := r.advertiseExtendedResources(); err != nil {
-		return ctrl.Result{}, err
+	if !r.peerPodConfig.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, r.finalize(ctx)
+	}
+
+	if controllerutil.AddFinalizer(r.peerPodConfig, peerPodConfigFinalizer) {
+		if err := r.Client.Update(ctx, r.peerPodConfig); err != nil {
+			return ctrl.Result{}, err
+		}
 	}
 
-	// Create the cloud-api-adapter DaemonSet
+	// Apply the cloud-api-adapter DaemonSet rendered from the spec
 	ds := r.createCaaDaemonset()
 	if err := controllerutil.SetControllerReference(r.peerPodConfig, ds, r.Scheme); err != nil {
 		r.Log.Error(err, "Failed setting ControllerReference for cloud-api-adaptor DS")
 		return ctrl.Result{}, err
 	}
-	foundDs := &appsv1.DaemonSet{}
-	err = r.Client.Get(context.TODO(), types.NamespacedName{Name: ds.Name, Namespace: ds.Namespace}, foundDs)
-	if err != nil && k8serrors.IsNotFound(err) {
-		r.Log.Info("Creating cloud-api-adapter daemonset", "ds.Namespace", ds.Namespace, "ds.Name", ds.Name)
-		err = r.Client.Create(context.TODO(), ds)
-		if err != nil {
-			r.Log.Error(err, "failed to create cloud-api-adaptor")
-			return ctrl.Result{}, err
-		}
+	r.Log.Info("Applying cloud-api-adapter daemonset", "ds.Namespace", ds.Namespace, "ds.Name", ds.Name)
+	if err := r.Client.Patch(ctx, ds, client.Apply, client.FieldOwner(fieldOwner), client.ForceOwnership); err != nil {
+		r.Log.Error(err, "failed to apply cloud-api-adaptor")
+		r.setCondition(ccv1alpha1.ConditionDaemonSetReady, metav1.ConditionFalse, "ApplyFailed", err.Error())
+		return ctrl.Result{}, r.updateStatus(ctx, err)
+	}
+	dsReady := isDaemonSetReady(ds)
+	if dsReady {
+		r.setCondition(ccv1alpha1.ConditionDaemonSetReady, metav1.ConditionTrue, "RolledOut",
+			fmt.Sprintf("%d cloud-api-adaptor pods are ready", ds.Status.NumberReady))
+	} else {
+		r.setCondition(ccv1alpha1.ConditionDaemonSetReady, metav1.ConditionFalse, "RollingOut",
+			fmt.Sprintf("%d of %d cloud-api-adaptor pods are updated and ready", ds.Status.UpdatedNumberScheduled, ds.Status.DesiredNumberScheduled))
 	}
 
-	r.Log.Info("Reconciling PeerPodConfig")
+	r.peerPodConfig.Status.SetupCompleted = dsReady
+	if err := r.updateStatus(ctx, nil); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	r.Log.Info("Reconciling PeerPodConfig", "setupCompleted", dsReady)
 
 	return ctrl.Result{}, nil
 }
 
+// finalize removes the extended resource from all nodes, and then the finalizer of the PeerPodConfig
+func (r *PeerPodConfigReconciler) finalize(ctx context.Context) error {
+	if !controllerutil.ContainsFinalizer(r.peerPodConfig, peerPodConfigFinalizer) {
+		return nil
+	}
+
+	r.Log.Info("removing extended resources of deleted PeerPodConfig")
+	if err := r.removeExtendedResources(ctx); err != nil {
+		return err
+	}
+
+	controllerutil.RemoveFinalizer(r.peerPodConfig, peerPodConfigFinalizer)
+	return r.Client.Update(ctx, r.peerPodConfig)
+}
+
+// setCondition sets a condition of the PeerPodConfig being reconciled
+func (r *PeerPodConfigReconciler) setCondition(conditionType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&r.peerPodConfig.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: r.peerPodConfig.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// updateStatus updates the status of the PeerPodConfig being reconciled. A failed setup is not completed.
+// It returns reconcileErr, if any, so that the request is requeued.
+func (r *PeerPodConfigReconciler) updateStatus(ctx context.Context, reconcileErr error) error {
+	if reconcileErr != nil {
+		r.peerPodConfig.Status.SetupCompleted = false
+	}
+	if err := r.Client.Status().Update(ctx, r.peerPodConfig); err != nil {
+		r.Log.Error(err, "failed to update PeerPodConfig status")
+		if reconcileErr == nil {
+			return err
+		}
+	}
+	return reconcileErr
+}
+
+// isDaemonSetReady returns true when the latest spec of a daemonset is rolled out and ready on all its nodes
+func isDaemonSetReady(ds *appsv1.DaemonSet) bool {
+	status := ds.Status
+	return status.ObservedGeneration >= ds.Generation &&
+		status.UpdatedNumberScheduled == status.DesiredNumberScheduled &&
+		status.NumberReady == status.DesiredNumberScheduled
+}
+
 func MountProgagationRef(mode corev1.MountPropagationMode) *corev1.MountPropagationMode {
 	return &mode
 }
@@ -154,6 +232,14 @@ func (r *PeerPodConfigReconciler) createCaaDaemonset() *appsv1.DaemonSet {
 	}
 	r.Log.Info("cloud-api-adaptor container image was set", "CAA image", imageString)
 
+	// Settings of the spec override the ones of the configmap
+	var env []corev1.EnvVar
+	if r.peerPodConfig.Spec.InstanceType != "" {
+		env = append(env, corev1.EnvVar{Name: instanceTypeEnvName, Value: r.peerPodConfig.Spec.InstanceType})
+	}
+	env = append(env, corev1.EnvVar{Name: limitPerNodeEnvName, Value: r.limitPerNode()})
+	env = append(env, corev1.EnvVar{Name: devicePluginEnvName, Value: "true"})
+
 	return &appsv1.DaemonSet{
 		TypeMeta: metav1.TypeMeta{
 			APIVersion: "apps/v1",
@@ -195,6 +281,7 @@ func (r *PeerPodConfigReconciler) createCaaDaemonset() *appsv1.DaemonSet {
 								RunAsUser:  &runAsUser,
 							},
 							Command: []string{"/usr/local/bin/entrypoint.sh"},
+							Env:     env,
 							EnvFrom: []corev1.EnvFromSource{
 								{
 									SecretRef: &corev1.SecretEnvSource{
@@ -230,6 +317,10 @@ func (r *PeerPodConfigReconciler) createCaaDaemonset() *appsv1.DaemonSet {
 									MountPropagation: MountProgagationRef(corev1.MountPropagationHostToContainer),
 									Name:             "netns",
 								},
+								{
+									MountPath: devicePluginDir,
+									Name:      "device-plugins",
+								},
 							},
 						},
 					},
@@ -269,6 +360,14 @@ func (r *PeerPodConfigReconciler) createCaaDaemonset() *appsv1.DaemonSet {
 								},
 							},
 						},
+						{
+							Name: "device-plugins",
+							VolumeSource: corev1.VolumeSource{
+								HostPath: &corev1.HostPathVolumeSource{
+									Path: devicePluginDir,
+								},
+							},
+						},
 					},
 				},
 			},
@@ -280,67 +379,49 @@ func (r *PeerPodConfigReconciler) createCaaDaemonset() *appsv1.DaemonSet {
 func (r *PeerPodConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&ccv1alpha1.PeerPodConfig{}).
+		Owns(&appsv1.DaemonSet{}).
 		Complete(r)
 }
 
-func (r *PeerPodConfigReconciler) getNodesWithLabels(nodeLabels map[string]string) (*corev1.NodeList, error) {
-	nodes := &corev1.NodeList{}
-	labelSelector := labels.SelectorFromSet(nodeLabels)
-	listOpts := []client.ListOption{
-		client.MatchingLabelsSelector{Selector: labelSelector},
-	}
-
-	if err := r.Client.List(context.TODO(), nodes, listOpts...); err != nil {
-		r.Log.Error(err, "Getting list of nodes having specified labels failed")
-		return &corev1.NodeList{}, err
+// limitPerNode returns PeerPodConfig.Spec.Limit, or defaultPeerPodsLimitPerNode if it is not set
+func (r *PeerPodConfigReconciler) limitPerNode() string {
+	if r.peerPodConfig.Spec.Limit != "" {
+		return r.peerPodConfig.Spec.Limit
 	}
-	return nodes, nil
+	return defaultPeerPodsLimitPerNode
 }
 
-func (r *PeerPodConfigReconciler) advertiseExtendedResources() error {
-
-	nodeSelector := map[string]string{
-		defaultNodeSelectorLabel: "",
-	}
-
-	if r.peerPodConfig.Spec.NodeSelector != nil {
-		nodeSelector = r.peerPodConfig.Spec.NodeSelector
-	}
+// removeExtendedResources removes the extended resource from all nodes that advertise it.
+// Kubelet keeps the resource of a device plugin that is gone, so it is removed once the daemonset is deleted.
+func (r *PeerPodConfigReconciler) removeExtendedResources(ctx context.Context) error {
 
-	r.Log.Info("set up extended resources")
-	nodesList, err := r.getNodesWithLabels(nodeSelector)
-	if err != nil {
-		r.Log.Info("getting node list failed when trying to update nodes with extended resources")
+	nodesList := &corev1.NodeList{}
+	if err := r.Client.List(ctx, nodesList); err != nil {
 		return err
 	}
 
-	// Do nothing for empty nodes list
-	if len(nodesList.Items) == 0 {
-		r.Log.Info("Not advertising extended resources for empty nodelist for nodeSelector", "nodeSelector", nodeSelector)
-		return nil
-	}
-
-	// Parse limit from PeerPodConfig.Spec.Limit.
-	// If not set or in case of error, use defaultPeerPodsLimitPerNode
-	limitPerNode := defaultPeerPodsLimitPerNode
-	if r.peerPodConfig.Spec.Limit != "" {
-		limitPerNode = r.peerPodConfig.Spec.Limit
-	}
-
-	patch := append([]JsonPatch{}, NewJsonPatch("add", "/status/capacity", "kata.peerpods.io~1vm", limitPerNode))
-
 	cli, err := r.GetClient()
 	if err != nil {
 		return fmt.Errorf("failed to get k8s client: %v", err)
 	}
 
+	patch := append([]JsonPatch{}, NewJsonPatch("remove", "/status/capacity", extendedResourceJsonName, ""))
+
+	var failed []string
 	for _, node := range nodesList.Items {
-		err = r.PatchNodeStatus(cli, node.Name, patch)
-		if err != nil {
-			r.Log.Info("Failed to set extended resource for node", "node name", node.Name)
+		if _, ok := node.Status.Capacity[extendedResourceName]; !ok {
 			continue
 		}
-		r.Log.Info("Successfully set extended resource for node", "node name", node.Name)
+		if err := r.PatchNodeStatus(cli, node.Name, patch); err != nil {
+			r.Log.Info("Failed to remove extended resource from node", "node name", node.Name, "error", err)
+			failed = append(failed, node.Name)
+			continue
+		}
+		r.Log.Info("Successfully removed extended resource from node", "node name", node.Name)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to remove extended resource from nodes %s", strings.Join(failed, ", "))
 	}
 	return nil
 }
@@ -10,6 +10,8 @@ import (
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/logging"
 )
 
 type MetadataRetriever struct {
@@ -23,7 +25,7 @@ func newMetadataRetriever() *MetadataRetriever {
 	r.client = imds.New(imds.Options{ClientEnableState: imds.ClientDefaultEnableState}) // use imds.ClientEnabled to enforce enabling
 	mac, err := r.get("mac")
 	if err != nil {
-		logger.Printf("NewMetadataRetriever is initialized without mac (%v)", err)
+		logger.Warn("failed to get the MAC address from IMDS", logging.KeyError, err)
 		return &r
 	}
 	r.mac = mac
@@ -48,48 +50,48 @@ func (r MetadataRetriever) get(path string) (string, error) {
 func retrieveMissingConfig(cfg *Config) error {
 	mdr := newMetadataRetriever()
 	if cfg.SubnetId == "" {
-		logger.Printf("SubnetId was not provided, trying to fetch it from IMDS")
+		logger.Info("subnet ID was not provided, trying to fetch it from IMDS")
 		subnetIdPath := fmt.Sprintf("network/interfaces/macs/%s/subnet-id", mdr.mac)
 		subnetId, err := mdr.get(subnetIdPath)
 		if err != nil {
 			return err
 		}
 		cfg.SubnetId = subnetId
-		logger.Printf("\"%s\" SubnetId retrieved from IMDS", subnetId)
+		logger.Info("retrieved subnet ID from IMDS", "subnet_id", subnetId)
 	}
 	if cfg.Region == "" {
-		logger.Printf("Region was not provided, trying to fetch it from IMDS")
+		logger.Info("region was not provided, trying to fetch it from IMDS")
 		region, err := mdr.get("placement/region")
 		if err != nil {
 			return err
 		}
 		cfg.Region = region
-		logger.Printf("\"%s\" Region retrieved from IMDS", region)
+		logger.Info("retrieved region from IMDS", "region", region)
 	}
 	if cfg.KeyName == "" {
-		logger.Printf("KeyName was not provided, trying to fetch it from IMDS")
+		logger.Info("key name was not provided, trying to fetch it from IMDS")
 		rawKey, err := mdr.get("public-keys")
 		if err != nil {
-			logger.Printf("failed to retrieve key, skipped: %v", err)
+			logger.Warn("failed to retrieve key, skipped", logging.KeyError, err)
 		}
 		var keyName string
 		n, err := fmt.Sscanf(rawKey, "0=%s", &keyName)
 		if err != nil || n < 1 {
-			logger.Printf("failed to retrieve key, skipped")
+			logger.Warn("failed to parse key, skipped")
 		} else {
 			cfg.KeyName = keyName
-			logger.Printf("\"%s\" KeyName retrieved from IMDS", keyName)
+			logger.Info("retrieved key name from IMDS", "key_name", keyName)
 		}
 	}
 	if len(cfg.SecurityGroupIds) < 1 {
-		logger.Printf("SecurityGroupIds was not provided, trying to fetch it from IMDS")
+		logger.Info("security group IDs were not provided, trying to fetch them from IMDS")
 		securityGroupIdsPath := fmt.Sprintf("network/interfaces/macs/%s/security-group-ids", mdr.mac)
 		securityGroupIds, err := mdr.get(securityGroupIdsPath)
 		if err != nil {
 			return err
 		}
 		cfg.SecurityGroupIds = strings.Fields(securityGroupIds)
-		logger.Printf("\"%s\" SecurityGroupIds retrieved from IMDS", &cfg.SecurityGroupIds)
+		logger.Info("retrieved security group IDs from IMDS", "security_group_ids", cfg.SecurityGroupIds)
 	}
 	return nil
 }
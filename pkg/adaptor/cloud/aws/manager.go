@@ -5,6 +5,7 @@ package aws
 
 import (
 	"flag"
+	"os"
 
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud"
 )
@@ -13,43 +14,61 @@ var awscfg Config
 
 type Manager struct{}
 
-func init() {
-	cloud.AddCloud("aws", &Manager{})
+func (_ *Manager) ParseCmd(flags *flag.FlagSet) {
+	parseCmd(flags, &awscfg)
 }
 
-func (_ *Manager) ParseCmd(flags *flag.FlagSet) {
+func parseCmd(flags *flag.FlagSet, cfg *Config) {
 
-	flags.StringVar(&awscfg.AccessKeyId, "aws-access-key-id", "", "Access Key ID, defaults to `AWS_ACCESS_KEY_ID`")
-	flags.StringVar(&awscfg.SecretKey, "aws-secret-key", "", "Secret Key, defaults to `AWS_SECRET_ACCESS_KEY`")
-	flags.StringVar(&awscfg.Region, "aws-region", "", "Region")
-	flags.StringVar(&awscfg.LoginProfile, "aws-profile", "", "AWS Login Profile")
-	flags.StringVar(&awscfg.LaunchTemplateName, "aws-lt-name", "kata", "AWS Launch Template Name")
-	flags.BoolVar(&awscfg.UseLaunchTemplate, "use-lt", false, "Use EC2 Launch Template for the Pod VMs")
-	flags.StringVar(&awscfg.ImageId, "imageid", "", "Pod VM ami id")
-	flags.StringVar(&awscfg.InstanceType, "instance-type", "t3.small", "Pod VM instance type")
-	flags.Var(&awscfg.SecurityGroupIds, "securitygroupids", "Security Group Ids to be used for the Pod VM, comma separated")
-	flags.StringVar(&awscfg.KeyName, "keyname", "", "SSH Keypair name to be used with the Pod VM")
-	flags.StringVar(&awscfg.SubnetId, "subnetid", "", "Subnet ID to be used for the Pod VMs")
+	flags.StringVar(&cfg.AccessKeyId, "aws-access-key-id", "", "Access Key ID, defaults to `AWS_ACCESS_KEY_ID`")
+	flags.StringVar(&cfg.SecretKey, "aws-secret-key", "", "Secret Key, defaults to `AWS_SECRET_ACCESS_KEY`")
+	flags.StringVar(&cfg.Region, "aws-region", "", "Region")
+	flags.StringVar(&cfg.LoginProfile, "aws-profile", "", "AWS Login Profile")
+	flags.StringVar(&cfg.LaunchTemplateName, "aws-lt-name", "kata", "AWS Launch Template Name")
+	flags.BoolVar(&cfg.UseLaunchTemplate, "use-lt", false, "Use EC2 Launch Template for the Pod VMs")
+	flags.StringVar(&cfg.ImageId, "imageid", "", "Pod VM ami id")
+	flags.StringVar(&cfg.InstanceType, "instance-type", "t3.small", "Pod VM instance type")
+	flags.Var(&cfg.SecurityGroupIds, "securitygroupids", "Security Group Ids to be used for the Pod VM, comma separated")
+	flags.StringVar(&cfg.KeyName, "keyname", "", "SSH Keypair name to be used with the Pod VM")
+	flags.StringVar(&cfg.SubnetId, "subnetid", "", "Subnet ID to be used for the Pod VMs")
+	flags.Var(&cfg.FallbackSubnetIds, "fallback-subnetids", "Subnet IDs to try in order when the subnet of subnetid has no capacity for the Pod VM, comma separated")
 	// Add a List parameter to indicate differet type of instance types to be used for the Pod VMs
-	flags.Var(&awscfg.InstanceTypes, "instance-types", "Instance types to be used for the Pod VMs, comma separated")
+	flags.Var(&cfg.InstanceTypes, "instance-types", "Instance types to be used for the Pod VMs, comma separated")
 	// Add a key value list parameter to indicate custom tags to be used for the Pod VMs
-	flags.Var(&awscfg.Tags, "tags", "Custom tags (key=value pairs) to be used for the Pod VMs, comma separated")
-	flags.BoolVar(&awscfg.UsePublicIP, "use-public-ip", false, "Use Public IP for connecting to the kata-agent inside the Pod VM")
+	flags.Var(&cfg.Tags, "tags", "Custom tags (key=value pairs) to be used for the Pod VMs, comma separated")
+	flags.StringVar(&cfg.ClusterID, "cluster-id", "", "ID of the cluster to tag the Pod VMs with, defaults to `CLUSTER_ID`")
+	flags.BoolVar(&cfg.UsePublicIP, "use-public-ip", false, "Use Public IP for connecting to the kata-agent inside the Pod VM")
 	// Add a parameter to indicate the root volume size for the Pod VMs
 	// Default is 30GiBs for free tier. Hence use it as default
-	flags.IntVar(&awscfg.RootVolumeSize, "root-volume-size", 30, "Root volume size (in GiB) for the Pod VMs")
-	flags.BoolVar(&awscfg.DisableCVM, "disable-cvm", false, "Use non-CVMs for peer pods")
+	flags.IntVar(&cfg.RootVolumeSize, "root-volume-size", 30, "Root volume size (in GiB) for the Pod VMs")
+	flags.BoolVar(&cfg.DisableCVM, "disable-cvm", false, "Use non-CVMs for peer pods")
 	// Add a flag to disable cloud config and use userdata via metadata service
-	flags.BoolVar(&awscfg.DisableCloudConfig, "disable-cloud-config", false, "Disable cloud config and use userdata via metadata service")
+	flags.BoolVar(&cfg.DisableCloudConfig, "disable-cloud-config", false, "Disable cloud config and use userdata via metadata service")
 
 }
 
 func (_ *Manager) LoadEnv() {
-	cloud.DefaultToEnv(&awscfg.AccessKeyId, "AWS_ACCESS_KEY_ID", "")
-	cloud.DefaultToEnv(&awscfg.SecretKey, "AWS_SECRET_ACCESS_KEY", "")
-	cloud.DefaultToEnv(&awscfg.InstanceType, "PODVM_INSTANCE_TYPE", "t3.small")
+	loadEnv(&awscfg, os.Getenv)
+}
+
+func loadEnv(cfg *Config, getenv cloud.Getenv) {
+	getenv.DefaultTo(&cfg.AccessKeyId, "AWS_ACCESS_KEY_ID", "")
+	getenv.DefaultTo(&cfg.SecretKey, "AWS_SECRET_ACCESS_KEY", "")
+	getenv.DefaultTo(&cfg.Region, "AWS_REGION", "")
+	getenv.DefaultTo(&cfg.InstanceType, "PODVM_INSTANCE_TYPE", "t3.small")
+	getenv.DefaultTo(&cfg.ClusterID, "CLUSTER_ID", "")
 }
 
 func (_ *Manager) NewProvider() (cloud.Provider, error) {
 	return NewProvider(&awscfg)
 }
+
+func (_ *Manager) NewProviderFromConfig(config map[string]string) (cloud.Provider, error) {
+
+	var cfg Config
+	if err := cloud.LoadConfig(config, func(flags *flag.FlagSet) { parseCmd(flags, &cfg) }, func(getenv cloud.Getenv) { loadEnv(&cfg, getenv) }); err != nil {
+		return nil, err
+	}
+
+	return NewProvider(&cfg)
+}
@@ -7,6 +7,8 @@ import (
 	"flag"
 	"fmt"
 	"testing"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud"
 )
 
 func TestManager_ParseCmd(t *testing.T) {
@@ -236,3 +238,37 @@ func comparestructs(expected, actual Config) bool {
 
 	return true
 }
+
+func TestLoadConfig(t *testing.T) {
+
+	var cfg Config
+	err := cloud.LoadConfig(map[string]string{
+		"AWS_ACCESS_KEY_ID":     "test-access-key",
+		"AWS_SECRET_ACCESS_KEY": "test-secret-key",
+		"AWS_REGION":            "test-region",
+		"imageid":               "test-image-id",
+		"securitygroupids":      "sg-1,sg-2",
+	}, func(flags *flag.FlagSet) { parseCmd(flags, &cfg) }, func(getenv cloud.Getenv) { loadEnv(&cfg, getenv) })
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	expected := Config{
+		AccessKeyId:        "test-access-key",
+		SecretKey:          "test-secret-key",
+		Region:             "test-region",
+		LaunchTemplateName: "kata",
+		ImageId:            "test-image-id",
+		InstanceType:       "t3.small",
+		SecurityGroupIds:   []string{"sg-1", "sg-2"},
+		RootVolumeSize:     30,
+	}
+	if !comparestructs(expected, cfg) {
+		t.Errorf("Expected config: %+v, but got: %+v", expected, cfg)
+	}
+
+	// The global config is not touched
+	if !comparestructs(Config{}, awscfg) {
+		t.Errorf("Global config is modified: %+v", awscfg)
+	}
+}
@@ -8,21 +8,24 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
-	"log"
 	"net/netip"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
 
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud"
+	peerpodannotations "github.com/confidential-containers/cloud-api-adaptor/pkg/annotations"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/util"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/cloudinit"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/logging"
 )
 
-var logger = log.New(log.Writer(), "[adaptor/cloud/aws] ", log.LstdFlags|log.Lmsgprefix)
+var logger = logging.New("adaptor/cloud/aws")
 var errNotReady = errors.New("address not ready")
 
 const (
@@ -71,10 +74,10 @@ type awsProvider struct {
 
 func NewProvider(config *Config) (cloud.Provider, error) {
 
-	logger.Printf("aws config: %#v", config.Redact())
+	logger.Info("aws config", "config", config)
 
 	if err := retrieveMissingConfig(config); err != nil {
-		logger.Printf("Failed to retrieve configuration, some fields may still be missing: %v", err)
+		logger.Warn("failed to retrieve configuration from IMDS, some fields may still be missing", logging.KeyError, err)
 	}
 
 	ec2Client, err := NewEC2Client(*config)
@@ -100,15 +103,15 @@ func NewProvider(config *Config) (cloud.Provider, error) {
 
 		// If RootVolumeSize < deviceSize, then update the RootVolumeSize to deviceSize
 		if config.RootVolumeSize < int(deviceSize) {
-			logger.Printf("RootVolumeSize %d is less than deviceSize %d, hence updating RootVolumeSize to deviceSize",
-				config.RootVolumeSize, deviceSize)
+			logger.Info("root volume size is less than the device size of the image, using the device size",
+				"root_volume_size", config.RootVolumeSize, "device_size", deviceSize)
 			config.RootVolumeSize = int(deviceSize)
 		}
 
 		// Update the serviceConfig with the device name
 		config.RootDeviceName = deviceName
 
-		logger.Printf("RootDeviceName and RootVolumeSize of the image %s is %s, %d", config.ImageId, config.RootDeviceName, config.RootVolumeSize)
+		logger.Info("root device of the image", "image_id", config.ImageId, "root_device_name", config.RootDeviceName, "root_volume_size", config.RootVolumeSize)
 	}
 
 	if err = provider.updateInstanceTypeSpecList(); err != nil {
@@ -134,13 +137,14 @@ func getIPs(instance types.Instance) ([]netip.Addr, error) {
 		}
 		podNodeIPs = append(podNodeIPs, ip)
 
-		logger.Printf("podNodeIP[%d]=%s", i, ip.String())
+		logger.Debug("pod node IP", "index", i, "ip", ip.String())
 	}
 
 	return podNodeIPs, nil
 }
 
 func (p *awsProvider) CreateInstance(ctx context.Context, podName, sandboxID string, cloudConfig cloudinit.CloudConfigGenerator, spec cloud.InstanceTypeSpec) (*cloud.Instance, error) {
+	logger := logger.WithContext(ctx)
 
 	// Public IP address
 	var publicIPAddr netip.Addr
@@ -180,14 +184,28 @@ func (p *awsProvider) CreateInstance(ctx context.Context, podName, sandboxID str
 		},
 	}
 
-	// Add custom tags (k=v) from serviceConfig.Tags to the instance
+	// Add custom tags (k=v) from serviceConfig.Tags to the instance, and the tags of the pod in their place
+	tags := make(map[string]string, len(p.serviceConfig.Tags)+len(spec.Tags))
 	for k, v := range p.serviceConfig.Tags {
+		tags[k] = v
+	}
+	for k, v := range spec.Tags {
+		tags[k] = v
+	}
+	for k, v := range tags {
 		instanceTags = append(instanceTags, types.Tag{
 			Key:   aws.String(k),
 			Value: aws.String(v),
 		})
 	}
 
+	if p.serviceConfig.ClusterID != "" {
+		instanceTags = append(instanceTags, types.Tag{
+			Key:   aws.String(cloud.ClusterIDTag),
+			Value: aws.String(p.serviceConfig.ClusterID),
+		})
+	}
+
 	// Create TagSpecifications for the instance
 	tagSpecifications := []types.TagSpecification{
 		{
@@ -196,6 +214,24 @@ func (p *awsProvider) CreateInstance(ctx context.Context, podName, sandboxID str
 		},
 	}
 
+	subnetId := p.serviceConfig.SubnetId
+	if spec.Placement != "" {
+		subnetId = spec.Placement
+	}
+	if spec.SubnetID != "" {
+		subnetId = spec.SubnetID
+	}
+
+	imageId := p.serviceConfig.ImageId
+	if spec.ImageID != "" {
+		imageId = spec.ImageID
+	}
+
+	sgIds := p.serviceConfig.SecurityGroupIds
+	if len(spec.SecurityGroupIDs) > 0 {
+		sgIds = spec.SecurityGroupIDs
+	}
+
 	var input *ec2.RunInstancesInput
 
 	if p.serviceConfig.UseLaunchTemplate {
@@ -208,14 +244,24 @@ func (p *awsProvider) CreateInstance(ctx context.Context, podName, sandboxID str
 			UserData:          &b64EncData,
 			TagSpecifications: tagSpecifications,
 		}
+		// The overrides of a pod take precedence over the launch template
+		if spec.ImageID != "" {
+			input.ImageId = aws.String(spec.ImageID)
+		}
+		if spec.SubnetID != "" {
+			input.SubnetId = aws.String(spec.SubnetID)
+		}
+		if len(spec.SecurityGroupIDs) > 0 {
+			input.SecurityGroupIds = spec.SecurityGroupIDs
+		}
 	} else {
 		input = &ec2.RunInstancesInput{
 			MinCount:          aws.Int32(1),
 			MaxCount:          aws.Int32(1),
-			ImageId:           aws.String(p.serviceConfig.ImageId),
+			ImageId:           aws.String(imageId),
 			InstanceType:      types.InstanceType(instanceType),
-			SecurityGroupIds:  p.serviceConfig.SecurityGroupIds,
-			SubnetId:          aws.String(p.serviceConfig.SubnetId),
+			SecurityGroupIds:  sgIds,
+			SubnetId:          aws.String(subnetId),
 			UserData:          &b64EncData,
 			TagSpecifications: tagSpecifications,
 		}
@@ -230,8 +276,8 @@ func (p *awsProvider) CreateInstance(ctx context.Context, podName, sandboxID str
 				{
 					AssociatePublicIpAddress: aws.Bool(true),
 					DeviceIndex:              aws.Int32(0),
-					SubnetId:                 aws.String(p.serviceConfig.SubnetId),
-					Groups:                   p.serviceConfig.SecurityGroupIds,
+					SubnetId:                 aws.String(subnetId),
+					Groups:                   sgIds,
 					DeleteOnTermination:      aws.Bool(true),
 				},
 			}
@@ -273,20 +319,25 @@ func (p *awsProvider) CreateInstance(ctx context.Context, podName, sandboxID str
 		}
 	}
 
-	logger.Printf("CreateInstance: name: %q", instanceName)
+	input.InstanceMarketOptions = spotMarketOptions(spec)
+
+	logger.Info("creating an instance", "instance_name", instanceName)
 
 	result, err := p.ec2Client.RunInstances(ctx, input)
 	if err != nil {
+		if capacityErr := capacityError(err, instanceType, spec.Placement); capacityErr != nil {
+			return nil, capacityErr
+		}
 		return nil, fmt.Errorf("Creating instance (%v) returned error: %s", result, err)
 	}
 
-	logger.Printf("created an instance %s for sandbox %s", *result.Instances[0].PublicDnsName, sandboxID)
+	logger.Info("created an instance", "public_dns_name", aws.ToString(result.Instances[0].PublicDnsName), logging.KeySandboxID, sandboxID)
 
 	instanceID := *result.Instances[0].InstanceId
 
 	ips, err := getIPs(result.Instances[0])
 	if err != nil {
-		logger.Printf("failed to get IPs for the instance : %v ", err)
+		logger.Error("failed to get IPs of the instance", logging.KeyError, err)
 		return nil, err
 	}
 
@@ -304,34 +355,114 @@ func (p *awsProvider) CreateInstance(ctx context.Context, podName, sandboxID str
 	}
 
 	instance := &cloud.Instance{
-		ID:   instanceID,
-		Name: instanceName,
-		IPs:  ips,
+		ID:           instanceID,
+		Name:         instanceName,
+		IPs:          ips,
+		InstanceType: string(result.Instances[0].InstanceType),
+	}
+	if placement := result.Instances[0].Placement; placement != nil {
+		instance.Zone = aws.ToString(placement.AvailabilityZone)
 	}
 
 	return instance, nil
 }
 
 func (p *awsProvider) DeleteInstance(ctx context.Context, instanceID string) error {
+	logger := logger.WithContext(ctx)
+
 	terminateInput := &ec2.TerminateInstancesInput{
 		InstanceIds: []string{
 			instanceID,
 		},
 	}
 
-	logger.Printf("Deleting instance (%s)", instanceID)
+	logger.Info("deleting an instance", logging.KeyInstanceID, instanceID)
 
 	resp, err := p.ec2Client.TerminateInstances(ctx, terminateInput)
 
 	if err != nil {
-		logger.Printf("failed to delete an instance: %v and the response is %v", err, resp)
+		logger.Error("failed to delete an instance", logging.KeyInstanceID, instanceID, "response", resp, logging.KeyError, err)
 		return err
 	}
-	logger.Printf("deleted an instance %s", instanceID)
+	logger.Info("deleted an instance", logging.KeyInstanceID, instanceID)
 	return nil
 
 }
 
+func (p *awsProvider) ListInstances(ctx context.Context) ([]*cloud.Instance, error) {
+
+	if p.serviceConfig.ClusterID == "" {
+		return nil, cloud.ErrNoClusterID
+	}
+
+	filters := []types.Filter{
+		{
+			Name:   aws.String("tag:" + cloud.ClusterIDTag),
+			Values: []string{p.serviceConfig.ClusterID},
+		},
+		{
+			Name:   aws.String("tag:Name"),
+			Values: []string{util.PodVMNamePrefix + "*"},
+		},
+		{
+			Name:   aws.String("instance-state-name"),
+			Values: []string{"pending", "running", "stopping", "stopped"},
+		},
+	}
+
+	// Only match instances with the custom tags from serviceConfig.Tags
+	for k, v := range p.serviceConfig.Tags {
+		filters = append(filters, types.Filter{
+			Name:   aws.String("tag:" + k),
+			Values: []string{v},
+		})
+	}
+
+	input := &ec2.DescribeInstancesInput{
+		Filters: filters,
+	}
+
+	var instances []*cloud.Instance
+
+	for {
+		output, err := p.ec2Client.DescribeInstances(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("listing instances: %w", err)
+		}
+
+		for _, reservation := range output.Reservations {
+			for _, instance := range reservation.Instances {
+
+				var name string
+				for _, tag := range instance.Tags {
+					if aws.ToString(tag.Key) == "Name" {
+						name = aws.ToString(tag.Value)
+					}
+				}
+				if !util.IsPodVMName(name) {
+					continue
+				}
+
+				// IP addresses may not be assigned yet
+				ips, _ := getIPs(instance)
+
+				instances = append(instances, &cloud.Instance{
+					ID:   aws.ToString(instance.InstanceId),
+					Name: name,
+					IPs:  ips,
+				})
+			}
+		}
+
+		if output.NextToken == nil {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+
+	return instances, nil
+}
+
 func (p *awsProvider) Teardown() error {
 	return nil
 }
@@ -344,6 +475,72 @@ func (p *awsProvider) ConfigVerifier() error {
 	return nil
 }
 
+// capacityError returns a cloud.CapacityError if err is an EC2 error for lack of capacity or quota, or nil otherwise
+func capacityError(err error, instanceType, placement string) error {
+
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return nil
+	}
+
+	var cause error
+	switch apiErr.ErrorCode() {
+	case "InsufficientInstanceCapacity", "InsufficientHostCapacity", "InsufficientReservedInstanceCapacity", "SpotMaxPriceTooLow":
+		cause = cloud.ErrInsufficientCapacity
+	case "InstanceLimitExceeded", "VcpuLimitExceeded", "MaxSpotInstanceCountExceeded":
+		cause = cloud.ErrQuotaExceeded
+	default:
+		return nil
+	}
+
+	return &cloud.CapacityError{Cause: cause, InstanceType: instanceType, Placement: placement, Err: err}
+}
+
+// spotMarketOptions returns the market options of a one-time Spot instance, if spec requests spot capacity.
+// An interrupted Spot instance is terminated, since the pod on it is failed.
+func spotMarketOptions(spec cloud.InstanceTypeSpec) *types.InstanceMarketOptionsRequest {
+
+	if !spec.Spot {
+		return nil
+	}
+
+	options := &types.InstanceMarketOptionsRequest{
+		MarketType: types.MarketTypeSpot,
+		SpotOptions: &types.SpotMarketOptions{
+			SpotInstanceType:             types.SpotInstanceTypeOneTime,
+			InstanceInterruptionBehavior: types.InstanceInterruptionBehaviorTerminate,
+		},
+	}
+	if spec.SpotMaxPrice > 0 {
+		options.SpotOptions.MaxPrice = aws.String(strconv.FormatFloat(spec.SpotMaxPrice, 'f', -1, 64))
+	}
+
+	return options
+}
+
+// SupportsSpot returns true, since pod VMs can run on Spot instances
+func (p *awsProvider) SupportsSpot() bool {
+	return true
+}
+
+// SupportedOverrides returns the override annotations of the image, subnet, security groups and tags of pod VMs
+func (p *awsProvider) SupportedOverrides() []string {
+	return []string{peerpodannotations.ImageID, peerpodannotations.SubnetID, peerpodannotations.SecurityGroupIDs, peerpodannotations.Tags}
+}
+
+// Placements returns the fallback subnets of the Pod VMs. Launch templates define the subnet themselves.
+func (p *awsProvider) Placements() []string {
+	if p.serviceConfig.UseLaunchTemplate {
+		return nil
+	}
+	return p.serviceConfig.FallbackSubnetIds
+}
+
+// InstanceTypes returns the instance types that pods may request
+func (p *awsProvider) InstanceTypes() []string {
+	return append([]string{p.serviceConfig.InstanceType}, p.serviceConfig.InstanceTypes...)
+}
+
 // Add SelectInstanceType method to select an instance type based on the memory and vcpu requirements
 func (p *awsProvider) selectInstanceType(ctx context.Context, spec cloud.InstanceTypeSpec) (string, error) {
 
@@ -366,21 +563,21 @@ func (p *awsProvider) updateInstanceTypeSpecList() error {
 
 	// Iterate over the instance types and populate the instanceTypeSpecList
 	for _, instanceType := range instanceTypes {
-		vcpus, memory, err := p.getInstanceTypeInformation(instanceType)
+		spec, err := p.getInstanceTypeInformation(instanceType)
 		if err != nil {
 			return err
 		}
-		instanceTypeSpecList = append(instanceTypeSpecList, cloud.InstanceTypeSpec{InstanceType: instanceType, VCPUs: vcpus, Memory: memory})
+		instanceTypeSpecList = append(instanceTypeSpecList, spec)
 	}
 
 	// Sort the instanceTypeSpecList by Memory and update the serviceConfig
 	p.serviceConfig.InstanceTypeSpecList = cloud.SortInstanceTypesOnMemory(instanceTypeSpecList)
-	logger.Printf("InstanceTypeSpecList (%v)", p.serviceConfig.InstanceTypeSpecList)
+	logger.Info("instance types", "instance_type_specs", p.serviceConfig.InstanceTypeSpecList)
 	return nil
 }
 
-// Add a method to retrieve cpu, memory, and storage from the instance type
-func (p *awsProvider) getInstanceTypeInformation(instanceType string) (vcpu int64, memory int64, err error) {
+// Add a method to retrieve cpu, memory, and GPUs from the instance type
+func (p *awsProvider) getInstanceTypeInformation(instanceType string) (spec cloud.InstanceTypeSpec, err error) {
 
 	// Get the instance type information from the instance type using AWS API
 	input := &ec2.DescribeInstanceTypesInput{
@@ -391,16 +588,30 @@ func (p *awsProvider) getInstanceTypeInformation(instanceType string) (vcpu int6
 	// Get the instance type information from the instance type using AWS API
 	result, err := p.ec2Client.DescribeInstanceTypes(context.Background(), input)
 	if err != nil {
-		return 0, 0, err
+		return spec, err
 	}
 
-	// Get the vcpu and memory from the result
+	// Get the vcpu, memory and GPUs from the result
 	if len(result.InstanceTypes) > 0 {
-		vcpu = int64(*result.InstanceTypes[0].VCpuInfo.DefaultVCpus)
-		memory = int64(*result.InstanceTypes[0].MemoryInfo.SizeInMiB)
-		return vcpu, memory, nil
+		info := result.InstanceTypes[0]
+		spec = cloud.InstanceTypeSpec{
+			InstanceType: instanceType,
+			VCPUs:        int64(*info.VCpuInfo.DefaultVCpus),
+			Memory:       *info.MemoryInfo.SizeInMiB,
+		}
+		if info.GpuInfo != nil {
+			for _, gpu := range info.GpuInfo.Gpus {
+				if gpu.Count != nil {
+					spec.GPUs += int64(*gpu.Count)
+				}
+				if gpu.Name != nil && spec.GPUModel == "" {
+					spec.GPUModel = *gpu.Name
+				}
+			}
+		}
+		return spec, nil
 	}
-	return 0, 0, fmt.Errorf("instance type %s not found", instanceType)
+	return spec, fmt.Errorf("instance type %s not found", instanceType)
 
 }
 
@@ -419,7 +630,7 @@ func (p *awsProvider) getPublicIP(ctx context.Context, instanceID string) (netip
 	// Wait for instance to be ready before getting the public IP address
 	err := p.waiter.Wait(ctx, describeInstanceInput, maxWaitTime)
 	if err != nil {
-		logger.Printf("failed to wait for the instance to be ready : %v ", err)
+		logger.WithContext(ctx).Error("failed to wait for the instance to be ready", logging.KeyInstanceID, instanceID, logging.KeyError, err)
 		return netip.Addr{}, err
 
 	}
@@ -427,7 +638,7 @@ func (p *awsProvider) getPublicIP(ctx context.Context, instanceID string) (netip
 	// Add describe instance output
 	describeInstanceOutput, err := p.ec2Client.DescribeInstances(ctx, describeInstanceInput)
 	if err != nil {
-		logger.Printf("failed to describe the instance : %v ", err)
+		logger.WithContext(ctx).Error("failed to describe the instance", logging.KeyInstanceID, instanceID, logging.KeyError, err)
 		return netip.Addr{}, err
 	}
 	// Get the public IP address from InstanceNetworkInterfaceAssociation
@@ -442,7 +653,7 @@ func (p *awsProvider) getPublicIP(ctx context.Context, instanceID string) (netip
 		return netip.Addr{}, fmt.Errorf("public IP address is empty")
 	}
 
-	logger.Printf("public IP address of the instance %s is %s", instanceID, *publicIP)
+	logger.WithContext(ctx).Info("public IP address of the instance", logging.KeyInstanceID, instanceID, "public_ip", *publicIP)
 
 	// Parse the public IP address
 	publicIPAddr, err := netip.ParseAddr(*publicIP)
@@ -462,7 +673,7 @@ func (p *awsProvider) getDeviceNameAndSize(imageID string) (string, int32, error
 	// Add describe images output
 	describeImagesOutput, err := p.ec2Client.DescribeImages(context.Background(), describeImagesInput)
 	if err != nil {
-		logger.Printf("failed to describe the image : %v ", err)
+		logger.Error("failed to describe the image", "image_id", imageID, logging.KeyError, err)
 		return "", 0, err
 	}
 
@@ -482,11 +693,11 @@ func (p *awsProvider) getDeviceNameAndSize(imageID string) (string, int32, error
 	deviceSize := describeImagesOutput.Images[0].BlockDeviceMappings[0].Ebs.VolumeSize
 
 	if deviceSize == nil {
-		logger.Printf("device size of the image %s is not set", imageID)
+		logger.Info("device size of the image is not set", "image_id", imageID)
 		return *deviceName, 0, nil
 	}
 
-	logger.Printf("device name and size of the image %s is %s, %d", imageID, *deviceName, *deviceSize)
+	logger.Info("device of the image", "image_id", imageID, "device_name", *deviceName, "device_size", *deviceSize)
 
 	return *deviceName, *deviceSize, nil
 }
@@ -5,6 +5,7 @@ package aws
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/netip"
 	"reflect"
@@ -14,6 +15,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/cloudinit"
 )
@@ -69,6 +71,33 @@ func (m mockEC2Client) DescribeInstanceTypes(ctx context.Context,
 
 	// Take instance type from params
 	instanceType := params.InstanceTypes[0]
+
+	// Return a mock DescribeInstanceTypesOutput with GPU info for p3.2xlarge
+	if instanceType == "p3.2xlarge" {
+		return &ec2.DescribeInstanceTypesOutput{
+			InstanceTypes: []types.InstanceTypeInfo{
+				{
+					InstanceType: instanceType,
+					VCpuInfo: &types.VCpuInfo{
+						DefaultVCpus: aws.Int32(8),
+					},
+					MemoryInfo: &types.MemoryInfo{
+						SizeInMiB: aws.Int64(62464),
+					},
+					GpuInfo: &types.GpuInfo{
+						Gpus: []types.GpuDeviceInfo{
+							{
+								Count:        aws.Int32(1),
+								Manufacturer: aws.String("NVIDIA"),
+								Name:         aws.String("V100"),
+							},
+						},
+					},
+				},
+			},
+		}, nil
+	}
+
 	// Check if instance type is t2.medium, else return an error
 	if instanceType != "t2.medium" {
 		return nil, fmt.Errorf("Unsupported instance type")
@@ -106,6 +135,13 @@ func (m mockEC2Client) DescribeInstances(ctx context.Context,
 				Instances: []types.Instance{
 					{
 						InstanceId: &mockInstanceID,
+						// Add Name tag to mock instance
+						Tags: []types.Tag{
+							{
+								Key:   aws.String("Name"),
+								Value: aws.String("podvm-nginx-01234567"),
+							},
+						},
 						// Add private IP address to mock instance
 						PrivateIpAddress: aws.String("10.0.0.2"),
 						// Add private IP address to network interface
@@ -481,6 +517,37 @@ func TestDeleteInstance(t *testing.T) {
 	}
 }
 
+func TestListInstances(t *testing.T) {
+
+	provider := &awsProvider{
+		ec2Client:     newMockEC2Client(),
+		serviceConfig: &Config{},
+	}
+
+	// Instances of other clusters must not be listed without a cluster ID
+	if _, err := provider.ListInstances(context.Background()); !errors.Is(err, cloud.ErrNoClusterID) {
+		t.Fatalf("awsProvider.ListInstances() error = %v, want %v", err, cloud.ErrNoClusterID)
+	}
+
+	provider.serviceConfig.ClusterID = "cluster1"
+
+	instances, err := provider.ListInstances(context.Background())
+	if err != nil {
+		t.Fatalf("awsProvider.ListInstances() error = %v", err)
+	}
+
+	want := []*cloud.Instance{
+		{
+			ID:   "i-1234567890abcdef0",
+			Name: "podvm-nginx-01234567",
+			IPs:  []netip.Addr{netip.MustParseAddr("10.0.0.2")},
+		},
+	}
+	if !reflect.DeepEqual(instances, want) {
+		t.Errorf("awsProvider.ListInstances() = %v, want %v", instances, want)
+	}
+}
+
 func TestGetInstanceTypeInformation(t *testing.T) {
 	type fields struct {
 		ec2Client     ec2Client
@@ -490,12 +557,11 @@ func TestGetInstanceTypeInformation(t *testing.T) {
 		instanceType string
 	}
 	tests := []struct {
-		name       string
-		fields     fields
-		args       args
-		wantVcpu   int64
-		wantMemory int64
-		wantErr    bool
+		name     string
+		fields   fields
+		args     args
+		wantSpec cloud.InstanceTypeSpec
+		wantErr  bool
 	}{
 		// Test getting instance type information for a valid instance type
 		{
@@ -507,8 +573,21 @@ func TestGetInstanceTypeInformation(t *testing.T) {
 			args: args{
 				instanceType: "t2.medium",
 			},
-			wantVcpu:   2,
-			wantMemory: 4096,
+			wantSpec: cloud.InstanceTypeSpec{InstanceType: "t2.medium", VCPUs: 2, Memory: 4096},
+			// Test should not return an error
+			wantErr: false,
+		},
+		// Test getting instance type information for a GPU instance type
+		{
+			name: "getInstanceTypeInformationGPUInstanceType",
+			fields: fields{
+				ec2Client:     newMockEC2Client(),
+				serviceConfig: serviceConfig,
+			},
+			args: args{
+				instanceType: "p3.2xlarge",
+			},
+			wantSpec: cloud.InstanceTypeSpec{InstanceType: "p3.2xlarge", VCPUs: 8, Memory: 62464, GPUs: 1, GPUModel: "V100"},
 			// Test should not return an error
 			wantErr: false,
 		},
@@ -522,8 +601,6 @@ func TestGetInstanceTypeInformation(t *testing.T) {
 			args: args{
 				instanceType: "mycustominstance",
 			},
-			wantVcpu:   0,
-			wantMemory: 0,
 			// Test should return an error
 			wantErr: true,
 		},
@@ -534,16 +611,13 @@ func TestGetInstanceTypeInformation(t *testing.T) {
 				ec2Client:     tt.fields.ec2Client,
 				serviceConfig: tt.fields.serviceConfig,
 			}
-			gotVcpu, gotMemory, err := p.getInstanceTypeInformation(tt.args.instanceType)
+			gotSpec, err := p.getInstanceTypeInformation(tt.args.instanceType)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("awsProvider.getInstanceTypeInformation() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if gotVcpu != tt.wantVcpu {
-				t.Errorf("awsProvider.getInstanceTypeInformation() gotVcpu = %v, want %v", gotVcpu, tt.wantVcpu)
-			}
-			if gotMemory != tt.wantMemory {
-				t.Errorf("awsProvider.getInstanceTypeInformation() gotMemory = %v, want %v", gotMemory, tt.wantMemory)
+			if !reflect.DeepEqual(gotSpec, tt.wantSpec) {
+				t.Errorf("awsProvider.getInstanceTypeInformation() gotSpec = %v, want %v", gotSpec, tt.wantSpec)
 			}
 		})
 	}
@@ -594,3 +668,132 @@ func TestConfigVerifier(t *testing.T) {
 		})
 	}
 }
+
+func TestCapacityError(t *testing.T) {
+
+	for name, tc := range map[string]struct {
+		err   error
+		cause error
+	}{
+		"insufficient capacity": {
+			err:   &smithy.GenericAPIError{Code: "InsufficientInstanceCapacity"},
+			cause: cloud.ErrInsufficientCapacity,
+		},
+		"spot max price too low": {
+			err:   &smithy.GenericAPIError{Code: "SpotMaxPriceTooLow"},
+			cause: cloud.ErrInsufficientCapacity,
+		},
+		"vCPU limit": {
+			err:   &smithy.GenericAPIError{Code: "VcpuLimitExceeded"},
+			cause: cloud.ErrQuotaExceeded,
+		},
+		"other API error": {
+			err: &smithy.GenericAPIError{Code: "InvalidAMIID.NotFound"},
+		},
+		"not an API error": {
+			err: errors.New("connection refused"),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := capacityError(fmt.Errorf("wrapped: %w", tc.err), "t3.small", "subnet-1")
+			if tc.cause == nil {
+				if err != nil {
+					t.Fatalf("expect no capacity error, got %v", err)
+				}
+				return
+			}
+			var capErr *cloud.CapacityError
+			if !errors.As(err, &capErr) || !errors.Is(err, tc.cause) {
+				t.Fatalf("expect capacity error caused by %v, got %v", tc.cause, err)
+			}
+			if capErr.InstanceType != "t3.small" || capErr.Placement != "subnet-1" {
+				t.Errorf("expect instance type t3.small and placement subnet-1, got %q and %q", capErr.InstanceType, capErr.Placement)
+			}
+		})
+	}
+}
+
+func TestSpotMarketOptions(t *testing.T) {
+
+	if options := spotMarketOptions(cloud.InstanceTypeSpec{InstanceType: "t3.small"}); options != nil {
+		t.Fatalf("expect no market options for on-demand instances, got %#v", options)
+	}
+
+	options := spotMarketOptions(cloud.InstanceTypeSpec{InstanceType: "t3.small", Spot: true})
+	if options == nil || options.MarketType != types.MarketTypeSpot || options.SpotOptions.MaxPrice != nil {
+		t.Fatalf("expect spot market options without maximum price, got %#v", options)
+	}
+	if options.SpotOptions.InstanceInterruptionBehavior != types.InstanceInterruptionBehaviorTerminate {
+		t.Errorf("expect interrupted instances to be terminated, got %q", options.SpotOptions.InstanceInterruptionBehavior)
+	}
+
+	options = spotMarketOptions(cloud.InstanceTypeSpec{InstanceType: "t3.small", Spot: true, SpotMaxPrice: 0.0125})
+	if maxPrice := aws.ToString(options.SpotOptions.MaxPrice); maxPrice != "0.0125" {
+		t.Errorf("expect maximum price 0.0125, got %q", maxPrice)
+	}
+}
+
+// recordingEC2Client records the input of RunInstances
+type recordingEC2Client struct {
+	mockEC2Client
+	input *ec2.RunInstancesInput
+}
+
+func (m *recordingEC2Client) RunInstances(ctx context.Context,
+	params *ec2.RunInstancesInput,
+	optFns ...func(*ec2.Options)) (*ec2.RunInstancesOutput, error) {
+
+	m.input = params
+	return m.mockEC2Client.RunInstances(ctx, params, optFns...)
+}
+
+func TestCreateInstanceOverrides(t *testing.T) {
+
+	for name, useLaunchTemplate := range map[string]bool{"instance": false, "launch template": true} {
+		t.Run(name, func(t *testing.T) {
+
+			client := &recordingEC2Client{}
+			p := &awsProvider{
+				ec2Client: client,
+				waiter:    newMockAWSInstanceWaiter(),
+				serviceConfig: &Config{
+					InstanceType:       "t2.small",
+					SubnetId:           "subnet-1234567890abcdef0",
+					SecurityGroupIds:   []string{"sg-1234567890abcdef0"},
+					ImageId:            "ami-1234567890abcdef0",
+					Tags:               cloud.KeyValueFlag{"owner": "ops", "team": "platform"},
+					UseLaunchTemplate:  useLaunchTemplate,
+					LaunchTemplateName: "podvm",
+					DisableCVM:         true,
+				},
+			}
+
+			spec := cloud.InstanceTypeSpec{
+				ImageID:          "ami-hardened",
+				SubnetID:         "subnet-tenant",
+				SecurityGroupIDs: []string{"sg-tenant-1", "sg-tenant-2"},
+				Tags:             map[string]string{"team": "tenant", "cost-center": "42"},
+			}
+			if _, err := p.CreateInstance(context.Background(), "podtest", "123", &mockCloudConfig{}, spec); err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+
+			input := client.input
+			if aws.ToString(input.ImageId) != "ami-hardened" || aws.ToString(input.SubnetId) != "subnet-tenant" {
+				t.Errorf("expect image ami-hardened and subnet subnet-tenant, got %q and %q", aws.ToString(input.ImageId), aws.ToString(input.SubnetId))
+			}
+			if !reflect.DeepEqual(input.SecurityGroupIds, spec.SecurityGroupIDs) {
+				t.Errorf("expect security groups %q, got %q", spec.SecurityGroupIDs, input.SecurityGroupIds)
+			}
+
+			tags := map[string]string{}
+			for _, tag := range input.TagSpecifications[0].Tags {
+				tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+			}
+			expected := map[string]string{"Name": "podvm-podtest-123", "owner": "ops", "team": "tenant", "cost-center": "42"}
+			if !reflect.DeepEqual(tags, expected) {
+				t.Errorf("expect tags %v, got %v", expected, tags)
+			}
+		})
+	}
+}
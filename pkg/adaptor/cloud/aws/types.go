@@ -21,6 +21,17 @@ func (i *securityGroupIds) Set(value string) error {
 	return nil
 }
 
+type subnetIds []string
+
+func (i *subnetIds) String() string {
+	return strings.Join(*i, ", ")
+}
+
+func (i *subnetIds) Set(value string) error {
+	*i = append(*i, strings.Split(value, ",")...)
+	return nil
+}
+
 type instanceTypes []string
 
 func (i *instanceTypes) String() string {
@@ -46,11 +57,13 @@ type Config struct {
 	InstanceType         string
 	KeyName              string
 	SubnetId             string
+	FallbackSubnetIds    subnetIds
 	SecurityGroupIds     securityGroupIds
 	UseLaunchTemplate    bool
 	InstanceTypes        instanceTypes
 	InstanceTypeSpecList []cloud.InstanceTypeSpec
 	Tags                 cloud.KeyValueFlag
+	ClusterID            string
 	UsePublicIP          bool
 	RootVolumeSize       int
 	RootDeviceName       string
@@ -5,6 +5,7 @@ package azure
 
 import (
 	"flag"
+	"os"
 
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud"
 )
@@ -13,44 +14,61 @@ var azurecfg Config
 
 type Manager struct{}
 
-func init() {
-	cloud.AddCloud("azure", &Manager{})
+func (_ *Manager) ParseCmd(flags *flag.FlagSet) {
+	parseCmd(flags, &azurecfg)
 }
 
-func (_ *Manager) ParseCmd(flags *flag.FlagSet) {
-	flags.StringVar(&azurecfg.ClientId, "clientid", "", "Client Id, defaults to `AZURE_CLIENT_ID`")
-	flags.StringVar(&azurecfg.ClientSecret, "secret", "", "Client Secret, defaults to `AZURE_CLIENT_SECRET`")
-	flags.StringVar(&azurecfg.TenantId, "tenantid", "", "Tenant Id, defaults to `AZURE_TENANT_ID`")
-	flags.StringVar(&azurecfg.ResourceGroupName, "resourcegroup", "", "Resource Group")
-	flags.StringVar(&azurecfg.Zone, "zone", "", "Zone")
-	flags.StringVar(&azurecfg.Region, "region", "", "Region")
-	flags.StringVar(&azurecfg.SubnetId, "subnetid", "", "Network Subnet Id")
-	flags.StringVar(&azurecfg.SecurityGroupId, "securitygroupid", "", "Security Group Id")
-	flags.StringVar(&azurecfg.Size, "instance-size", "Standard_DC2as_v5", "Instance size")
-	flags.StringVar(&azurecfg.ImageId, "imageid", "", "Image Id")
-	flags.StringVar(&azurecfg.SubscriptionId, "subscriptionid", "", "Subscription ID")
-	flags.StringVar(&azurecfg.SSHKeyPath, "ssh-key-path", "$HOME/.ssh/id_rsa.pub", "Path to SSH public key")
-	flags.StringVar(&azurecfg.SSHUserName, "ssh-username", "peerpod", "SSH User Name")
-	flags.BoolVar(&azurecfg.DisableCVM, "disable-cvm", false, "Use non-CVMs for peer pods")
+func parseCmd(flags *flag.FlagSet, cfg *Config) {
+	flags.StringVar(&cfg.ClientId, "clientid", "", "Client Id, defaults to `AZURE_CLIENT_ID`")
+	flags.StringVar(&cfg.ClientSecret, "secret", "", "Client Secret, defaults to `AZURE_CLIENT_SECRET`")
+	flags.StringVar(&cfg.TenantId, "tenantid", "", "Tenant Id, defaults to `AZURE_TENANT_ID`")
+	flags.StringVar(&cfg.ResourceGroupName, "resourcegroup", "", "Resource Group")
+	flags.StringVar(&cfg.Zone, "zone", "", "Zone")
+	flags.Var(&cfg.FallbackZones, "fallback-zones", "Availability zones to try in order when the region has no capacity for the Pod VM, comma separated")
+	flags.StringVar(&cfg.Region, "region", "", "Region")
+	flags.StringVar(&cfg.SubnetId, "subnetid", "", "Network Subnet Id")
+	flags.StringVar(&cfg.SecurityGroupId, "securitygroupid", "", "Security Group Id")
+	flags.StringVar(&cfg.Size, "instance-size", "Standard_DC2as_v5", "Instance size")
+	flags.StringVar(&cfg.ImageId, "imageid", "", "Image Id")
+	flags.StringVar(&cfg.SubscriptionId, "subscriptionid", "", "Subscription ID")
+	flags.StringVar(&cfg.SSHKeyPath, "ssh-key-path", "$HOME/.ssh/id_rsa.pub", "Path to SSH public key")
+	flags.StringVar(&cfg.SSHUserName, "ssh-username", "peerpod", "SSH User Name")
+	flags.BoolVar(&cfg.DisableCVM, "disable-cvm", false, "Use non-CVMs for peer pods")
 	// Add a List parameter to indicate differet type of instance sizes to be used for the Pod VMs
-	flags.Var(&azurecfg.InstanceSizes, "instance-sizes", "Instance sizes to be used for the Pod VMs, comma separated")
+	flags.Var(&cfg.InstanceSizes, "instance-sizes", "Instance sizes to be used for the Pod VMs, comma separated")
 	// Add a key value list parameter to indicate custom tags to be used for the Pod VMs
-	flags.Var(&azurecfg.Tags, "tags", "Custom tags (key=value pairs) to be used for the Pod VMs, comma separated")
+	flags.Var(&cfg.Tags, "tags", "Custom tags (key=value pairs) to be used for the Pod VMs, comma separated")
+	flags.StringVar(&cfg.ClusterID, "cluster-id", "", "ID of the cluster to tag the Pod VMs with, defaults to `CLUSTER_ID`")
 	// Add a flag to disable cloud config and use userdata via metadata service
-	flags.BoolVar(&azurecfg.DisableCloudConfig, "disable-cloud-config", false, "Disable cloud config and use userdata via metadata service")
-	flags.BoolVar(&azurecfg.EnableSecureBoot, "enable-secure-boot", false, "Enable secure boot for the VMs")
+	flags.BoolVar(&cfg.DisableCloudConfig, "disable-cloud-config", false, "Disable cloud config and use userdata via metadata service")
+	flags.BoolVar(&cfg.EnableSecureBoot, "enable-secure-boot", false, "Enable secure boot for the VMs")
 }
 
 func (_ *Manager) LoadEnv() {
-	cloud.DefaultToEnv(&azurecfg.ClientId, "AZURE_CLIENT_ID", "")
-	cloud.DefaultToEnv(&azurecfg.ClientSecret, "AZURE_CLIENT_SECRET", "")
-	cloud.DefaultToEnv(&azurecfg.TenantId, "AZURE_TENANT_ID", "")
-	cloud.DefaultToEnv(&azurecfg.SubscriptionId, "AZURE_SUBSCRIPTION_ID", "")
-	cloud.DefaultToEnv(&azurecfg.Region, "AZURE_REGION", "")
-	cloud.DefaultToEnv(&azurecfg.ResourceGroupName, "AZURE_RESOURCE_GROUP", "")
-	cloud.DefaultToEnv(&azurecfg.Size, "AZURE_INSTANCE_SIZE", "Standard_DC2as_v5")
+	loadEnv(&azurecfg, os.Getenv)
+}
+
+func loadEnv(cfg *Config, getenv cloud.Getenv) {
+	getenv.DefaultTo(&cfg.ClientId, "AZURE_CLIENT_ID", "")
+	getenv.DefaultTo(&cfg.ClientSecret, "AZURE_CLIENT_SECRET", "")
+	getenv.DefaultTo(&cfg.TenantId, "AZURE_TENANT_ID", "")
+	getenv.DefaultTo(&cfg.SubscriptionId, "AZURE_SUBSCRIPTION_ID", "")
+	getenv.DefaultTo(&cfg.Region, "AZURE_REGION", "")
+	getenv.DefaultTo(&cfg.ResourceGroupName, "AZURE_RESOURCE_GROUP", "")
+	getenv.DefaultTo(&cfg.Size, "AZURE_INSTANCE_SIZE", "Standard_DC2as_v5")
+	getenv.DefaultTo(&cfg.ClusterID, "CLUSTER_ID", "")
 }
 
 func (_ *Manager) NewProvider() (cloud.Provider, error) {
 	return NewProvider(&azurecfg)
 }
+
+func (_ *Manager) NewProviderFromConfig(config map[string]string) (cloud.Provider, error) {
+
+	var cfg Config
+	if err := cloud.LoadConfig(config, func(flags *flag.FlagSet) { parseCmd(flags, &cfg) }, func(getenv cloud.Getenv) { loadEnv(&cfg, getenv) }); err != nil {
+		return nil, err
+	}
+
+	return NewProvider(&cfg)
+}
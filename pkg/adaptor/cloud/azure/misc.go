@@ -9,39 +9,9 @@ import (
 )
 
 const (
-	AzureImdsUrl         = "http://169.254.169.254/metadata/instance/compute?api-version=2021-01-01"
 	AzureUserDataImdsUrl = "http://169.254.169.254/metadata/instance/compute/userData?api-version=2021-01-01&format=text"
 )
 
-// Method to check if the VM is running on Azure
-// by checking if the Azure IMDS endpoint is reachable
-// Set Metadata:true header to confirm that the VM is running on Azure
-// If the VM is running on Azure, return true
-func IsAzure(ctx context.Context) bool {
-
-	// Create a new HTTP client
-	client := &http.Client{}
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, AzureImdsUrl, nil)
-	if err != nil {
-		fmt.Printf("failed to create request: %s\n", err)
-		return false
-	}
-	// Add the required headers to the request
-	req.Header.Add("Metadata", "true")
-
-	// Send the request and retrieve the response
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Printf("failed to send request: %s\n", err)
-		return false
-	}
-	defer resp.Body.Close()
-
-	// Check if the response was successful
-	return resp.StatusCode == http.StatusOK
-}
-
 // Method to retrieve userData from the instance metadata service
 // and return it as a string
 func GetUserData(ctx context.Context, url string) (string, error) {
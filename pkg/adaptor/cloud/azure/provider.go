@@ -8,10 +8,10 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
-	"log"
 	"net/netip"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -21,11 +21,13 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v2"
 	"github.com/avast/retry-go/v4"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud"
+	peerpodannotations "github.com/confidential-containers/cloud-api-adaptor/pkg/annotations"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/util"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/cloudinit"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/logging"
 )
 
-var logger = log.New(log.Writer(), "[adaptor/cloud/azure] ", log.LstdFlags|log.Lmsgprefix)
+var logger = logging.New("adaptor/cloud/azure")
 var errNotReady = errors.New("address not ready")
 var errNotFound = errors.New("VM name not found")
 
@@ -40,7 +42,7 @@ type azureProvider struct {
 
 func NewProvider(config *Config) (cloud.Provider, error) {
 
-	logger.Printf("azure config %+v", config.Redact())
+	logger.Info("azure config", "config", config)
 
 	azureClient, err := NewAzureClient(*config)
 	if err != nil {
@@ -105,12 +107,22 @@ func (p *azureProvider) create(ctx context.Context, parameters *armcompute.Virtu
 	return &resp.VirtualMachine, nil
 }
 
-func (p *azureProvider) createNetworkInterface(ctx context.Context, nicName string) (*armnetwork.Interface, error) {
+func (p *azureProvider) createNetworkInterface(ctx context.Context, nicName string, spec cloud.InstanceTypeSpec) (*armnetwork.Interface, error) {
 	nicClient, err := armnetwork.NewInterfacesClient(p.serviceConfig.SubscriptionId, p.azureClient, nil)
 	if err != nil {
 		return nil, fmt.Errorf("creating network interfaces client: %w", err)
 	}
 
+	subnetId := p.serviceConfig.SubnetId
+	if spec.SubnetID != "" {
+		subnetId = spec.SubnetID
+	}
+
+	securityGroupId := p.serviceConfig.SecurityGroupId
+	if len(spec.SecurityGroupIDs) > 0 {
+		securityGroupId = spec.SecurityGroupIDs[0]
+	}
+
 	parameters := armnetwork.Interface{
 		Location: to.Ptr(p.serviceConfig.Region),
 		Properties: &armnetwork.InterfacePropertiesFormat{
@@ -120,7 +132,7 @@ func (p *azureProvider) createNetworkInterface(ctx context.Context, nicName stri
 					Properties: &armnetwork.InterfaceIPConfigurationPropertiesFormat{
 						PrivateIPAllocationMethod: to.Ptr(armnetwork.IPAllocationMethodDynamic),
 						Subnet: &armnetwork.Subnet{
-							ID: to.Ptr(p.serviceConfig.SubnetId),
+							ID: to.Ptr(subnetId),
 						},
 					},
 				},
@@ -128,9 +140,9 @@ func (p *azureProvider) createNetworkInterface(ctx context.Context, nicName stri
 		},
 	}
 
-	if p.serviceConfig.SecurityGroupId != "" {
+	if securityGroupId != "" {
 		parameters.Properties.NetworkSecurityGroup = &armnetwork.SecurityGroup{
-			ID: to.Ptr(p.serviceConfig.SecurityGroupId),
+			ID: to.Ptr(securityGroupId),
 		}
 	}
 
@@ -148,6 +160,7 @@ func (p *azureProvider) createNetworkInterface(ctx context.Context, nicName stri
 }
 
 func (p *azureProvider) CreateInstance(ctx context.Context, podName, sandboxID string, cloudConfig cloudinit.CloudConfigGenerator, spec cloud.InstanceTypeSpec) (*cloud.Instance, error) {
+	logger := logger.WithContext(ctx)
 
 	var b64EncData string
 
@@ -196,6 +209,11 @@ func (p *azureProvider) CreateInstance(ctx context.Context, podName, sandboxID s
 		return nil, err
 	}
 
+	// A network interface has a single network security group
+	if len(spec.SecurityGroupIDs) > 1 {
+		return nil, fmt.Errorf("a pod VM has a single network security group on Azure, but %d are requested", len(spec.SecurityGroupIDs))
+	}
+
 	diskName := fmt.Sprintf("%s-disk", instanceName)
 	nicName := fmt.Sprintf("%s-net", instanceName)
 
@@ -206,37 +224,42 @@ func (p *azureProvider) CreateInstance(ctx context.Context, podName, sandboxID s
 		sshBytes, err = os.ReadFile(sshPublicKeyPath)
 		if err != nil {
 			err = fmt.Errorf("reading ssh public key file: %w", err)
-			logger.Printf("%v", err)
+			logger.Errorf("%v", err)
 			return nil, err
 		}
 	} else {
 		err = fmt.Errorf("ssh public key: %w", err)
-		logger.Printf("%v", err)
+		logger.Errorf("%v", err)
 		return nil, err
 	}
 
 	// Get NIC using subnet and allow ports on the ssh group
-	vmNIC, err := p.createNetworkInterface(ctx, nicName)
+	vmNIC, err := p.createNetworkInterface(ctx, nicName, spec)
 	if err != nil {
 		err = fmt.Errorf("creating VM network interface: %w", err)
-		logger.Printf("%v", err)
+		logger.Errorf("%v", err)
 		return nil, err
 	}
 
-	vmParameters, err := p.getVMParameters(instanceSize, diskName, b64EncData, sshBytes, instanceName, vmNIC)
+	vmParameters, err := p.getVMParameters(instanceSize, spec.Placement, diskName, b64EncData, sshBytes, instanceName, vmNIC)
 	if err != nil {
 		return nil, err
 	}
+	setSpot(vmParameters, spec)
+	setOverrides(vmParameters, spec)
 
 	logger.Printf("CreateInstance: name: %q", instanceName)
 
 	result, err := p.create(ctx, vmParameters)
 	if err != nil {
 		if err := p.deleteDisk(context.Background(), diskName); err != nil {
-			logger.Printf("deleting disk (%s): %s", diskName, err)
+			logger.Warnf("deleting disk (%s): %s", diskName, err)
 		}
 		if err := p.deleteNetworkInterfaceAsync(context.Background(), nicName); err != nil {
-			logger.Printf("deleting nic async (%s): %s", nicName, err)
+			logger.Warnf("deleting nic async (%s): %s", nicName, err)
+		}
+		if capacityErr := capacityError(err, instanceSize, spec.Placement); capacityErr != nil {
+			return nil, capacityErr
 		}
 		return nil, fmt.Errorf("Creating instance (%v): %s", result, err)
 	}
@@ -245,20 +268,29 @@ func (p *azureProvider) CreateInstance(ctx context.Context, podName, sandboxID s
 
 	ips, err := getIPs(vmNIC)
 	if err != nil {
-		logger.Printf("getting IPs for the instance : %v ", err)
+		logger.Errorf("getting IPs for the instance : %v ", err)
 		return nil, err
 	}
 
+	zone := p.serviceConfig.Zone
+	if spec.Placement != "" {
+		zone = spec.Placement
+	}
+
 	instance := &cloud.Instance{
-		ID:   instanceID,
-		Name: instanceName,
-		IPs:  ips,
+		ID:           instanceID,
+		Name:         instanceName,
+		IPs:          ips,
+		InstanceType: instanceSize,
+		Zone:         zone,
 	}
 
 	return instance, nil
 }
 
 func (p *azureProvider) DeleteInstance(ctx context.Context, instanceID string) error {
+	logger := logger.WithContext(ctx)
+
 	vmClient, err := armcompute.NewVirtualMachinesClient(p.serviceConfig.SubscriptionId, p.azureClient, nil)
 	if err != nil {
 		return fmt.Errorf("creating VM client: %w", err)
@@ -344,6 +376,56 @@ func (p *azureProvider) deleteNetworkInterfaceAsync(ctx context.Context, nicName
 	return nil
 }
 
+func (p *azureProvider) ListInstances(ctx context.Context) ([]*cloud.Instance, error) {
+
+	if p.serviceConfig.ClusterID == "" {
+		return nil, cloud.ErrNoClusterID
+	}
+
+	vmClient, err := armcompute.NewVirtualMachinesClient(p.serviceConfig.SubscriptionId, p.azureClient, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating VM client: %w", err)
+	}
+
+	var instances []*cloud.Instance
+
+	pager := vmClient.NewListPager(p.serviceConfig.ResourceGroupName, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing VMs: %w", err)
+		}
+
+		for _, vm := range page.Value {
+			if vm.Name == nil || vm.ID == nil || !util.IsPodVMName(*vm.Name) {
+				continue
+			}
+
+			// Only match VMs of the cluster with the custom tags from serviceConfig.Tags
+			if tag, ok := vm.Tags[cloud.ClusterIDTag]; !ok || tag == nil || *tag != p.serviceConfig.ClusterID {
+				continue
+			}
+			matched := true
+			for k, v := range p.serviceConfig.Tags {
+				if tag, ok := vm.Tags[k]; !ok || tag == nil || *tag != v {
+					matched = false
+					break
+				}
+			}
+			if !matched {
+				continue
+			}
+
+			instances = append(instances, &cloud.Instance{
+				ID:   *vm.ID,
+				Name: *vm.Name,
+			})
+		}
+	}
+
+	return instances, nil
+}
+
 func (p *azureProvider) Teardown() error {
 	return nil
 }
@@ -356,6 +438,136 @@ func (p *azureProvider) ConfigVerifier() error {
 	return nil
 }
 
+// InstanceTypes returns the instance sizes that pods may request
+func (p *azureProvider) InstanceTypes() []string {
+	return append([]string{p.serviceConfig.Size}, p.serviceConfig.InstanceSizes...)
+}
+
+// Placements returns the fallback availability zones of the Pod VMs
+func (p *azureProvider) Placements() []string {
+	return p.serviceConfig.FallbackZones
+}
+
+// capacityError returns a cloud.CapacityError if err is an Azure error for lack of capacity or quota, or nil otherwise
+func capacityError(err error, instanceSize, zone string) error {
+
+	var respErr *azcore.ResponseError
+	if !errors.As(err, &respErr) {
+		return nil
+	}
+
+	var cause error
+	switch respErr.ErrorCode {
+	case "SkuNotAvailable", "AllocationFailed", "ZonalAllocationFailed", "OverconstrainedAllocationRequest", "OverconstrainedZonalAllocationRequest":
+		cause = cloud.ErrInsufficientCapacity
+	case "QuotaExceeded":
+		cause = cloud.ErrQuotaExceeded
+	default:
+		return nil
+	}
+
+	return &cloud.CapacityError{Cause: cause, InstanceType: instanceSize, Placement: zone, Err: err}
+}
+
+// setSpot runs a VM on Azure Spot capacity, if spec requests spot capacity. An evicted VM is deleted, since the pod
+// on it is failed. Without a maximum price, the VM is evicted only for lack of capacity, not for price.
+func setSpot(vm *armcompute.VirtualMachine, spec cloud.InstanceTypeSpec) {
+
+	if !spec.Spot {
+		return
+	}
+
+	maxPrice := float64(-1)
+	if spec.SpotMaxPrice > 0 {
+		maxPrice = spec.SpotMaxPrice
+	}
+
+	vm.Properties.Priority = to.Ptr(armcompute.VirtualMachinePriorityTypesSpot)
+	vm.Properties.EvictionPolicy = to.Ptr(armcompute.VirtualMachineEvictionPolicyTypesDelete)
+	vm.Properties.BillingProfile = &armcompute.BillingProfile{MaxPrice: to.Ptr(maxPrice)}
+}
+
+// setOverrides sets the image of a VM, and adds tags to it, if spec overrides them
+func setOverrides(vm *armcompute.VirtualMachine, spec cloud.InstanceTypeSpec) {
+
+	if spec.ImageID != "" {
+		vm.Properties.StorageProfile.ImageReference = imageReference(spec.ImageID)
+	}
+	for k, v := range spec.Tags {
+		vm.Tags[k] = to.Ptr(v)
+	}
+}
+
+// imageReference returns a reference to an image by ID, or to a community gallery image
+func imageReference(imageId string) *armcompute.ImageReference {
+	if strings.HasPrefix(imageId, "/CommunityGalleries/") {
+		return &armcompute.ImageReference{
+			CommunityGalleryImageID: to.Ptr(imageId),
+		}
+	}
+	return &armcompute.ImageReference{
+		ID: to.Ptr(imageId),
+	}
+}
+
+// SupportedOverrides returns the override annotations of the image, subnet, network security group and tags of pod VMs
+func (p *azureProvider) SupportedOverrides() []string {
+	return []string{peerpodannotations.ImageID, peerpodannotations.SubnetID, peerpodannotations.SecurityGroupIDs, peerpodannotations.Tags}
+}
+
+// SupportsSpot returns true, since pod VMs can run on Azure Spot VMs
+func (p *azureProvider) SupportsSpot() bool {
+	return true
+}
+
+// RemainingInstances returns the number of pod VMs of the default size that the regional VM and vCPU quotas of
+// the subscription still allow
+func (p *azureProvider) RemainingInstances(ctx context.Context) (int, error) {
+
+	usageClient, err := armcompute.NewUsageClient(p.serviceConfig.SubscriptionId, p.azureClient, nil)
+	if err != nil {
+		return 0, fmt.Errorf("creating usage client: %w", err)
+	}
+
+	vCPUs := int64(1)
+	for _, spec := range p.serviceConfig.InstanceSizeSpecList {
+		if spec.InstanceType == p.serviceConfig.Size && spec.VCPUs > 0 {
+			vCPUs = spec.VCPUs
+		}
+	}
+
+	remaining := int64(-1)
+	pager := usageClient.NewListPager(p.serviceConfig.Region, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("getting next page of usages: %w", err)
+		}
+		for _, usage := range page.Value {
+			if usage.Name == nil || usage.Name.Value == nil || usage.Limit == nil || usage.CurrentValue == nil {
+				continue
+			}
+			var n int64
+			switch *usage.Name.Value {
+			case "virtualMachines":
+				n = *usage.Limit - int64(*usage.CurrentValue)
+			case "cores":
+				n = (*usage.Limit - int64(*usage.CurrentValue)) / vCPUs
+			default:
+				continue
+			}
+			if remaining < 0 || n < remaining {
+				remaining = n
+			}
+		}
+	}
+
+	if remaining < 0 {
+		return 0, cloud.ErrQuotaUnknown
+	}
+	return int(remaining), nil
+}
+
 // Add SelectInstanceType method to select an instance type based on the memory and vcpu requirements
 func (p *azureProvider) selectInstanceType(ctx context.Context, spec cloud.InstanceTypeSpec) (string, error) {
 
@@ -363,13 +575,13 @@ func (p *azureProvider) selectInstanceType(ctx context.Context, spec cloud.Insta
 }
 
 // Add a method to populate InstanceSizeSpecList for all the instanceSizes
-// available in Azure
+// The vCPUs, memory and GPUs of the sizes are taken from the resource SKUs of the region
 func (p *azureProvider) updateInstanceSizeSpecList() error {
 
-	// Create a new instance of the Virtual Machine Sizes client
-	vmSizesClient, err := armcompute.NewVirtualMachineSizesClient(p.serviceConfig.SubscriptionId, p.azureClient, nil)
+	// Create a new instance of the Resource SKUs client
+	skusClient, err := armcompute.NewResourceSKUsClient(p.serviceConfig.SubscriptionId, p.azureClient, nil)
 	if err != nil {
-		return fmt.Errorf("creating VM sizes client: %w", err)
+		return fmt.Errorf("creating resource SKUs client: %w", err)
 	}
 	// Get the instance sizes from the service config
 	instanceSizes := p.serviceConfig.InstanceSizes
@@ -382,20 +594,26 @@ func (p *azureProvider) updateInstanceSizeSpecList() error {
 	// Create a list of instancesizespec
 	var instanceSizeSpecList []cloud.InstanceTypeSpec
 
-	// TODO: Is there an optimal method for this?
-	// Create NewListPager to iterate over the instance types
-	pager := vmSizesClient.NewListPager(p.serviceConfig.Region, &armcompute.VirtualMachineSizesClientListOptions{})
+	// Create NewListPager to iterate over the resource SKUs of the region
+	pager := skusClient.NewListPager(&armcompute.ResourceSKUsClientListOptions{
+		Filter: to.Ptr(fmt.Sprintf("location eq '%s'", p.serviceConfig.Region)),
+	})
 
 	// Iterate over the page and populate the instanceSizeSpecList for all the instanceSizes
 	for pager.More() {
 		nextResult, err := pager.NextPage(context.Background())
 		if err != nil {
-			return fmt.Errorf("getting next page of VM sizes: %w", err)
+			return fmt.Errorf("getting next page of resource SKUs: %w", err)
 		}
-		for _, vmSize := range nextResult.VirtualMachineSizeListResult.Value {
-			if util.Contains(instanceSizes, *vmSize.Name) {
-				instanceSizeSpecList = append(instanceSizeSpecList, cloud.InstanceTypeSpec{InstanceType: *vmSize.Name, VCPUs: int64(*vmSize.NumberOfCores), Memory: int64(*vmSize.MemoryInMB)})
+		for _, sku := range nextResult.Value {
+			if sku.ResourceType == nil || *sku.ResourceType != "virtualMachines" || sku.Name == nil || !util.Contains(instanceSizes, *sku.Name) {
+				continue
 			}
+			spec, err := instanceSizeSpec(sku)
+			if err != nil {
+				return err
+			}
+			instanceSizeSpecList = append(instanceSizeSpecList, spec)
 		}
 	}
 
@@ -405,7 +623,36 @@ func (p *azureProvider) updateInstanceSizeSpecList() error {
 	return nil
 }
 
-func (p *azureProvider) getVMParameters(instanceSize, diskName, b64EncData string, sshBytes []byte, instanceName string, vmNIC *armnetwork.Interface) (*armcompute.VirtualMachine, error) {
+// instanceSizeSpec returns the vCPUs, memory and GPUs of a VM size from the capabilities of its resource SKU.
+// Resource SKUs do not name the GPU model.
+func instanceSizeSpec(sku *armcompute.ResourceSKU) (cloud.InstanceTypeSpec, error) {
+
+	spec := cloud.InstanceTypeSpec{InstanceType: *sku.Name}
+
+	for _, capability := range sku.Capabilities {
+		if capability.Name == nil || capability.Value == nil {
+			continue
+		}
+		var err error
+		switch *capability.Name {
+		case "vCPUs":
+			spec.VCPUs, err = strconv.ParseInt(*capability.Value, 10, 64)
+		case "MemoryGB":
+			var memoryGB float64
+			memoryGB, err = strconv.ParseFloat(*capability.Value, 64)
+			spec.Memory = int64(memoryGB * 1024)
+		case "GPUs":
+			spec.GPUs, err = strconv.ParseInt(*capability.Value, 10, 64)
+		}
+		if err != nil {
+			return spec, fmt.Errorf("invalid capability %s of VM size %s: %w", *capability.Name, *sku.Name, err)
+		}
+	}
+
+	return spec, nil
+}
+
+func (p *azureProvider) getVMParameters(instanceSize, zone, diskName, b64EncData string, sshBytes []byte, instanceName string, vmNIC *armnetwork.Interface) (*armcompute.VirtualMachine, error) {
 	var managedDiskParams *armcompute.ManagedDiskParameters
 	var securityProfile *armcompute.SecurityProfile
 	if !p.serviceConfig.DisableCVM {
@@ -431,15 +678,6 @@ func (p *azureProvider) getVMParameters(instanceSize, diskName, b64EncData strin
 		securityProfile = nil
 	}
 
-	imgRef := &armcompute.ImageReference{
-		ID: to.Ptr(p.serviceConfig.ImageId),
-	}
-	if strings.HasPrefix(p.serviceConfig.ImageId, "/CommunityGalleries/") {
-		imgRef = &armcompute.ImageReference{
-			CommunityGalleryImageID: to.Ptr(p.serviceConfig.ImageId),
-		}
-	}
-
 	// Add tags to the instance
 	tags := map[string]*string{}
 
@@ -448,6 +686,10 @@ func (p *azureProvider) getVMParameters(instanceSize, diskName, b64EncData strin
 		tags[k] = to.Ptr(v)
 	}
 
+	if p.serviceConfig.ClusterID != "" {
+		tags[cloud.ClusterIDTag] = to.Ptr(p.serviceConfig.ClusterID)
+	}
+
 	vmParameters := armcompute.VirtualMachine{
 		Location: to.Ptr(p.serviceConfig.Region),
 		Properties: &armcompute.VirtualMachineProperties{
@@ -455,7 +697,7 @@ func (p *azureProvider) getVMParameters(instanceSize, diskName, b64EncData strin
 				VMSize: to.Ptr(armcompute.VirtualMachineSizeTypes(instanceSize)),
 			},
 			StorageProfile: &armcompute.StorageProfile{
-				ImageReference: imgRef,
+				ImageReference: imageReference(p.serviceConfig.ImageId),
 				OSDisk: &armcompute.OSDisk{
 					Name:         to.Ptr(diskName),
 					CreateOption: to.Ptr(armcompute.DiskCreateOptionTypesFromImage),
@@ -499,6 +741,10 @@ func (p *azureProvider) getVMParameters(instanceSize, diskName, b64EncData strin
 	// If DisableCloudConfig is set to true then set armcompute.VirtualMachine.Properties.UserData to b64EncData and
 	// OSProfile.CustomData to nil
 
+	if zone != "" {
+		vmParameters.Zones = []*string{to.Ptr(zone)}
+	}
+
 	if !p.serviceConfig.DisableCloudConfig {
 		vmParameters.Properties.OSProfile.CustomData = to.Ptr(b64EncData)
 		vmParameters.Properties.UserData = nil
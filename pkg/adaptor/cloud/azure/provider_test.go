@@ -0,0 +1,142 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package azure
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	armcompute "github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v4"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud"
+)
+
+func TestInstanceSizeSpec(t *testing.T) {
+
+	newSKU := func(name string, capabilities map[string]string) *armcompute.ResourceSKU {
+		sku := &armcompute.ResourceSKU{
+			Name:         to.Ptr(name),
+			ResourceType: to.Ptr("virtualMachines"),
+		}
+		for name, value := range capabilities {
+			sku.Capabilities = append(sku.Capabilities, &armcompute.ResourceSKUCapabilities{Name: to.Ptr(name), Value: to.Ptr(value)})
+		}
+		return sku
+	}
+
+	for _, tc := range []struct {
+		sku  *armcompute.ResourceSKU
+		spec cloud.InstanceTypeSpec
+		err  bool
+	}{
+		{
+			sku:  newSKU("Standard_DC2as_v5", map[string]string{"vCPUs": "2", "MemoryGB": "8", "MaxResourceVolumeMB": "0"}),
+			spec: cloud.InstanceTypeSpec{InstanceType: "Standard_DC2as_v5", VCPUs: 2, Memory: 8192},
+		},
+		{
+			sku:  newSKU("Standard_NC6s_v3", map[string]string{"vCPUs": "6", "MemoryGB": "112", "GPUs": "1"}),
+			spec: cloud.InstanceTypeSpec{InstanceType: "Standard_NC6s_v3", VCPUs: 6, Memory: 114688, GPUs: 1},
+		},
+		{
+			sku:  newSKU("Standard_A1_v2", map[string]string{"vCPUs": "1", "MemoryGB": "1.5"}),
+			spec: cloud.InstanceTypeSpec{InstanceType: "Standard_A1_v2", VCPUs: 1, Memory: 1536},
+		},
+		{
+			sku: newSKU("Standard_Invalid", map[string]string{"GPUs": "many"}),
+			err: true,
+		},
+	} {
+		spec, err := instanceSizeSpec(tc.sku)
+		if tc.err {
+			if err == nil {
+				t.Errorf("expect an error for %s", *tc.sku.Name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("expect no error for %s, got %v", *tc.sku.Name, err)
+		}
+		if !reflect.DeepEqual(spec, tc.spec) {
+			t.Errorf("expect %v, got %v", tc.spec, spec)
+		}
+	}
+}
+
+func TestCapacityError(t *testing.T) {
+
+	for errorCode, cause := range map[string]error{
+		"SkuNotAvailable":       cloud.ErrInsufficientCapacity,
+		"ZonalAllocationFailed": cloud.ErrInsufficientCapacity,
+		"QuotaExceeded":         cloud.ErrQuotaExceeded,
+		"InvalidParameter":      nil,
+	} {
+		err := capacityError(fmt.Errorf("waiting for the VM creation: %w", &azcore.ResponseError{ErrorCode: errorCode}), "Standard_DC2as_v5", "2")
+		if cause == nil {
+			if err != nil {
+				t.Errorf("expect no capacity error for %s, got %v", errorCode, err)
+			}
+			continue
+		}
+		var capErr *cloud.CapacityError
+		if !errors.As(err, &capErr) || !errors.Is(err, cause) {
+			t.Errorf("expect capacity error caused by %v for %s, got %v", cause, errorCode, err)
+			continue
+		}
+		if capErr.InstanceType != "Standard_DC2as_v5" || capErr.Placement != "2" {
+			t.Errorf("expect instance size Standard_DC2as_v5 and zone 2, got %q and %q", capErr.InstanceType, capErr.Placement)
+		}
+	}
+
+	if err := capacityError(errors.New("connection refused"), "Standard_DC2as_v5", ""); err != nil {
+		t.Errorf("expect no capacity error, got %v", err)
+	}
+}
+
+func TestSetOverrides(t *testing.T) {
+
+	vm := &armcompute.VirtualMachine{
+		Properties: &armcompute.VirtualMachineProperties{
+			StorageProfile: &armcompute.StorageProfile{ImageReference: imageReference("/subscriptions/s/images/default")},
+		},
+		Tags: map[string]*string{"owner": to.Ptr("ops")},
+	}
+
+	setOverrides(vm, cloud.InstanceTypeSpec{InstanceType: "Standard_D2as_v5"})
+	if id := *vm.Properties.StorageProfile.ImageReference.ID; id != "/subscriptions/s/images/default" || len(vm.Tags) != 1 {
+		t.Fatalf("expect the configured image and tags, got image %s and %d tags", id, len(vm.Tags))
+	}
+
+	setOverrides(vm, cloud.InstanceTypeSpec{ImageID: "/CommunityGalleries/g/images/hardened", Tags: map[string]string{"owner": "tenant", "team": "a"}})
+	if ref := vm.Properties.StorageProfile.ImageReference; ref.ID != nil || *ref.CommunityGalleryImageID != "/CommunityGalleries/g/images/hardened" {
+		t.Errorf("expect the community gallery image, got %#v", ref)
+	}
+	if *vm.Tags["owner"] != "tenant" || *vm.Tags["team"] != "a" {
+		t.Errorf("expect the tags of the pod, got %v", vm.Tags)
+	}
+}
+
+func TestSetSpot(t *testing.T) {
+
+	vm := &armcompute.VirtualMachine{Properties: &armcompute.VirtualMachineProperties{}}
+	setSpot(vm, cloud.InstanceTypeSpec{InstanceType: "Standard_D2as_v5"})
+	if vm.Properties.Priority != nil || vm.Properties.BillingProfile != nil {
+		t.Fatalf("expect a regular VM, got priority %v", vm.Properties.Priority)
+	}
+
+	setSpot(vm, cloud.InstanceTypeSpec{InstanceType: "Standard_D2as_v5", Spot: true})
+	if *vm.Properties.Priority != armcompute.VirtualMachinePriorityTypesSpot || *vm.Properties.EvictionPolicy != armcompute.VirtualMachineEvictionPolicyTypesDelete {
+		t.Fatalf("expect a spot VM that is deleted on eviction, got priority %s and eviction policy %s", *vm.Properties.Priority, *vm.Properties.EvictionPolicy)
+	}
+	if maxPrice := *vm.Properties.BillingProfile.MaxPrice; maxPrice != -1 {
+		t.Errorf("expect maximum price -1, got %v", maxPrice)
+	}
+
+	setSpot(vm, cloud.InstanceTypeSpec{InstanceType: "Standard_D2as_v5", Spot: true, SpotMaxPrice: 0.02})
+	if maxPrice := *vm.Properties.BillingProfile.MaxPrice; maxPrice != 0.02 {
+		t.Errorf("expect maximum price 0.02, got %v", maxPrice)
+	}
+}
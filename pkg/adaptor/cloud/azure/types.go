@@ -25,6 +25,17 @@ func (i *instanceSizes) Set(value string) error {
 	return nil
 }
 
+type zones []string
+
+func (z *zones) String() string {
+	return strings.Join(*z, ", ")
+}
+
+func (z *zones) Set(value string) error {
+	*z = append(*z, strings.Split(value, ",")...)
+	return nil
+}
+
 type Config struct {
 	SubscriptionId       string
 	ClientId             string
@@ -32,6 +43,7 @@ type Config struct {
 	TenantId             string
 	ResourceGroupName    string
 	Zone                 string
+	FallbackZones        zones
 	Region               string
 	SubnetId             string
 	SecurityGroupName    string
@@ -44,6 +56,7 @@ type Config struct {
 	InstanceSizes        instanceSizes
 	InstanceSizeSpecList []cloud.InstanceTypeSpec
 	Tags                 cloud.KeyValueFlag
+	ClusterID            string
 	DisableCloudConfig   bool
 	// Disabled by default, we want to do measured boot.
 	// Secure boot brings no additional security.
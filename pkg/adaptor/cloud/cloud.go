@@ -9,58 +9,72 @@ import (
 	"errors"
 	"flag"
 	"fmt"
-	"log"
 	"net"
+	"net/netip"
 	"net/url"
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/containerd/containerd/pkg/cri/annotations"
 	pb "github.com/kata-containers/kata-containers/src/runtime/protocols/hypervisor"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/k8sops"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/metrics"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/proxy"
+	peerpodannotations "github.com/confidential-containers/cloud-api-adaptor/pkg/annotations"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/forwarder"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/podnetwork"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/util"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/cloudinit"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/logging"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/tracing"
 )
 
 const (
 	Version = "0.0.0"
+
+	// DefaultCreateAttempts is the default maximum number of attempts to create an instance
+	DefaultCreateAttempts = 3
 )
 
-var logger = log.New(log.Writer(), "[adaptor/cloud] ", log.LstdFlags|log.Lmsgprefix)
+var logger = logging.New("adaptor/cloud")
 
+// Cloud is implemented by each cloud provider. Cloud providers are registered in the cloudmgr package.
 type Cloud interface {
 	ParseCmd(flags *flag.FlagSet)
 	LoadEnv()
 	NewProvider() (Provider, error)
+	// NewProviderFromConfig creates a provider with a config loaded from config, a map from option names
+	// or environment variable names to values, without using the state set by ParseCmd and LoadEnv
+	NewProviderFromConfig(config map[string]string) (Provider, error)
 }
 
-var cloudTable map[string]Cloud = make(map[string]Cloud)
-
-func Get(name string) Cloud {
-	return cloudTable[name]
-}
-
-func AddCloud(name string, cloud Cloud) {
-	cloudTable[name] = cloud
-}
+func (s *cloudService) addSandbox(sid sandboxID, sandbox *sandbox) error {
 
-func List() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 
-	var list []string
+	if _, exists := s.sandboxes[sid]; exists {
+		return fmt.Errorf("sandbox %s already exists", sid)
+	}
 
-	for name := range cloudTable {
-		list = append(list, name)
+	delete(s.reserved, sid)
+	s.sandboxes[sid] = sandbox
+	metrics.SetSandboxes(len(s.sandboxes))
+	if sandbox.price > 0 {
+		metrics.AddPodVMHourlyCost(sandbox.instanceType, sandbox.price)
 	}
 
-	return list
+	return nil
 }
 
-func (s *cloudService) addSandbox(sid sandboxID, sandbox *sandbox) error {
+// reserveSandbox reserves the ID of a sandbox before it is created, so that a repeated or concurrent CreateVM
+// with the same ID fails before it allocates the pod network of the sandbox. Reserved sandboxes count towards
+// the limit of peer pods, so that concurrent CreateVMs cannot exceed it.
+func (s *cloudService) reserveSandbox(sid sandboxID) error {
 
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
@@ -68,12 +82,62 @@ func (s *cloudService) addSandbox(sid sandboxID, sandbox *sandbox) error {
 	if _, exists := s.sandboxes[sid]; exists {
 		return fmt.Errorf("sandbox %s already exists", sid)
 	}
+	if _, reserved := s.reserved[sid]; reserved {
+		return fmt.Errorf("sandbox %s is already being created", sid)
+	}
+	if err := s.checkPodsLimit(); err != nil {
+		return err
+	}
+	s.reserved[sid] = struct{}{}
+	return nil
+}
 
-	s.sandboxes[sid] = sandbox
+// unreserveSandbox releases the ID of a sandbox that failed to be created
+func (s *cloudService) unreserveSandbox(sid sandboxID) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.reserved, sid)
+}
+
+// checkPodsLimit returns an error if the number of sandboxes, including reserved ones, reached the limit of peer pods.
+// The caller must hold the mutex.
+func (s *cloudService) checkPodsLimit() error {
 
+	if s.podsLimit <= 0 {
+		return nil
+	}
+	if len(s.sandboxes)+len(s.reserved) >= s.podsLimit {
+		return fmt.Errorf("the limit of %d peer pods on this node is reached", s.podsLimit)
+	}
 	return nil
 }
 
+// PodsCapacity returns the pods limit, reduced when the instance quota of the provider allows fewer peer pods.
+// It returns 0 if there is no pods limit.
+func (s *cloudService) PodsCapacity(ctx context.Context) int {
+
+	if s.podsLimit <= 0 {
+		return 0
+	}
+
+	s.mutex.Lock()
+	sandboxes := len(s.sandboxes) + len(s.reserved)
+	s.mutex.Unlock()
+
+	capacity := s.podsLimit
+	if reporter, ok := s.provider.(QuotaReporter); ok {
+		remaining, err := reporter.RemainingInstances(ctx)
+		if err != nil {
+			if !errors.Is(err, ErrQuotaUnknown) {
+				logger.Warn("failed to get remaining instance quota", logging.KeyError, err)
+			}
+		} else if sandboxes+remaining < capacity {
+			capacity = sandboxes + remaining
+		}
+	}
+	return capacity
+}
+
 func (s *cloudService) getSandbox(sid sandboxID) (*sandbox, error) {
 
 	if sid == "" {
@@ -94,41 +158,364 @@ func (s *cloudService) removeSandbox(id sandboxID) error {
 	}
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
+	if sandbox, ok := s.sandboxes[sid]; ok && sandbox.price > 0 {
+		metrics.AddPodVMHourlyCost(sandbox.instanceType, -sandbox.price)
+	}
 	delete(s.sandboxes, sid)
+	metrics.SetSandboxes(len(s.sandboxes))
 	return nil
 }
 
+// NewService returns a cloud service. When warmPoolConfig is enabled, pod VM instances are pre-provisioned in a warm pool.
+// When podsLimit is positive, no more than podsLimit peer pods are created.
+// Instance types are selected by the lowest price of prices, or of the pricing API of the provider, when they have one.
 func NewService(provider Provider, proxyFactory proxy.Factory, workerNode podnetwork.WorkerNode,
-	podsDir, daemonPort, aaKBCParams string) Service {
+	podsDir, daemonPort, aaKBCParams string, warmPoolConfig *WarmPoolConfig, prices PriceCatalog, overridePolicy OverridePolicy, podsLimit, createAttempts int) Service {
 	var err error
 
 	s := &cloudService{
-		provider:     provider,
-		proxyFactory: proxyFactory,
-		sandboxes:    map[sandboxID]*sandbox{},
-		podsDir:      podsDir,
-		daemonPort:   daemonPort,
-		workerNode:   workerNode,
-		aaKBCParams:  aaKBCParams,
+		provider:       provider,
+		proxyFactory:   proxyFactory,
+		sandboxes:      map[sandboxID]*sandbox{},
+		reserved:       map[sandboxID]struct{}{},
+		podsDir:        podsDir,
+		daemonPort:     daemonPort,
+		workerNode:     workerNode,
+		aaKBCParams:    aaKBCParams,
+		store:          newSandboxStore(podsDir),
+		podsLimit:      podsLimit,
+		createAttempts: createAttempts,
+		prices:         instanceTypePrices(context.Background(), provider, prices),
+		overridePolicy: overridePolicy,
 	}
 	s.cond = sync.NewCond(&s.mutex)
 	s.ppService, err = k8sops.NewPeerPodService()
 	if err != nil {
-		logger.Printf("failed to create PeerPodService, runtime failure may result in dangling resources %s", err)
+		logger.Warn("failed to create PeerPodService, runtime failure may result in dangling resources", logging.KeyError, err)
+	}
+
+	if err := s.restoreSandboxes(); err != nil {
+		logger.Error("failed to restore sandboxes, pod VMs created before restart may be left behind", logging.KeyError, err)
+	}
+
+	if warmPoolConfig.Enabled() && aaKBCParams != "" {
+		logger.Warn("warm pool is disabled, since pre-provisioned pod VMs cannot be configured with aa-kbc-params")
+	} else if warmPoolConfig.Enabled() {
+		// Restored sandboxes record which pre-provisioned instances are assigned to pods
+		assigned := make(map[string]bool)
+		for _, sandbox := range s.sandboxes {
+			if sandbox.instanceID != "" {
+				assigned[sandbox.instanceID] = true
+			}
+		}
+		s.warmPool = newWarmPool(provider, proxyFactory, *warmPoolConfig)
+		s.warmPool.start(assigned)
 	}
 
 	return s
 }
 
+// restoreSandboxes rehydrates sandboxes persisted in the pods directory by a previous
+// instance of cloud-api-adaptor, and reconnects agent proxies to their running pod VMs.
+func (s *cloudService) restoreSandboxes() error {
+
+	states, err := s.store.load()
+	if err != nil {
+		return err
+	}
+
+	for _, state := range states {
+
+		socketPath := filepath.Join(s.store.podDir(state.ID), proxy.SocketName)
+
+		sandbox := &sandbox{
+			id:           state.ID,
+			podName:      state.PodName,
+			podNamespace: state.PodNamespace,
+			netNSPath:    state.NetNSPath,
+			serverName:   state.ServerName,
+			instanceID:   state.InstanceID,
+			instanceName: state.InstanceName,
+			instanceIPs:  state.InstanceIPs,
+			podNetwork:   state.PodNetwork,
+			spec:         state.Spec,
+			instanceType: state.InstanceType,
+			price:        state.Price,
+			agentProxy:   s.proxyFactory.New(state.ServerName, socketPath),
+		}
+
+		if state.InstanceID == "" {
+			// StartVM has not completed before restart. Rebuild cloud config from daemon.json,
+			// so that StartVM can be retried for this sandbox.
+			daemonJSON, err := s.store.loadDaemonJSON(state.ID)
+			if err != nil {
+				logger.With(sandbox.logFields()...).Error("failed to restore sandbox", logging.KeyError, err)
+				continue
+			}
+			sandbox.cloudConfig = newCloudConfig(daemonJSON)
+		}
+
+		if err := s.addSandbox(state.ID, sandbox); err != nil {
+			logger.With(sandbox.logFields()...).Error("failed to restore sandbox", logging.KeyError, err)
+			continue
+		}
+
+		logger.With(sandbox.logFields()...).Info("restored sandbox")
+
+		if state.InstanceID != "" && len(state.InstanceIPs) > 0 {
+			go s.reconnectAgentProxy(sandbox)
+		}
+	}
+
+	return nil
+}
+
+func (s *cloudService) reconnectAgentProxy(sandbox *sandbox) {
+
+	serverURL := s.agentServerURL(sandbox.instanceIPs[0])
+
+	ctx := logging.WithFields(context.Background(), sandbox.logFields()...)
+	logger := logger.WithContext(ctx)
+
+	logger.Info("reconnecting agent proxy", "address", serverURL.Host)
+
+	if sandbox.spec.Spot {
+		go s.watchInterruption(ctx, sandbox)
+	}
+
+	if err := sandbox.agentProxy.Start(ctx, serverURL); err != nil {
+		logger.Error("error running agent proxy of restored sandbox", logging.KeyError, err)
+	}
+}
+
+// watchInterruption fails the pod of a sandbox on spot capacity when its pod VM reports that the cloud provider
+// interrupts its instance. The agent proxy shuts down then, so that the shim fails the sandbox instead of waiting
+// for an agent that is gone.
+func (s *cloudService) watchInterruption(ctx context.Context, sandbox *sandbox) {
+
+	select {
+	case <-sandbox.agentProxy.Interrupted():
+	case <-sandbox.agentProxy.Done():
+		// The agent proxy shuts down after interruption, and when the sandbox is stopped
+		select {
+		case <-sandbox.agentProxy.Interrupted():
+		default:
+			return
+		}
+	}
+
+	logger.WithContext(ctx).Warn("instance on spot capacity is interrupted by the cloud provider, failing the pod")
+	metrics.IncPodVMInterruptions()
+
+	s.updatePeerPod(ctx, "mark interrupted", func(pps *k8sops.PeerPodService) error {
+		return pps.SetPeerPodInterrupted(sandbox.podName, sandbox.podNamespace, sandbox.instanceID)
+	})
+}
+
+func (s *cloudService) agentServerURL(ip netip.Addr) *url.URL {
+	return &url.URL{
+		Scheme: "http",
+		Host:   net.JoinHostPort(ip.String(), s.daemonPort),
+		Path:   forwarder.AgentURLPath,
+	}
+}
+
+func (s *cloudService) bootstrapServerURL(ip netip.Addr) *url.URL {
+	return &url.URL{
+		Scheme: "http",
+		Host:   net.JoinHostPort(ip.String(), s.daemonPort),
+		Path:   forwarder.BootstrapURLPath,
+	}
+}
+
+func newCloudConfig(daemonJSON []byte) *cloudinit.CloudConfig {
+	return &cloudinit.CloudConfig{
+		WriteFiles: []cloudinit.WriteFile{
+			{
+				Path:    forwarder.DefaultConfigPath,
+				Content: string(daemonJSON),
+			},
+		},
+	}
+}
+
 func (s *cloudService) Teardown() error {
+	if s.warmPool != nil {
+		s.warmPool.close()
+	}
 	return s.provider.Teardown()
 }
 
+func (s *cloudService) WarmPoolStats() *WarmPoolStats {
+	if s.warmPool == nil {
+		return nil
+	}
+	return s.warmPool.getStats()
+}
+
+// bootstrapDaemonJSON returns the daemon config in cloudConfig, if a pre-provisioned instance can be bootstrapped
+// with it. The attestation agent and image registry credentials are configured by process-user-data when a pod VM
+// boots, so a pre-provisioned pod VM can receive only a daemon config without them.
+func bootstrapDaemonJSON(cloudConfig *cloudinit.CloudConfig) ([]byte, bool) {
+
+	if len(cloudConfig.WriteFiles) != 1 || cloudConfig.WriteFiles[0].Path != forwarder.DefaultConfigPath {
+		return nil, false
+	}
+	daemonJSON := []byte(cloudConfig.WriteFiles[0].Content)
+
+	var daemonConfig forwarder.Config
+	if err := json.Unmarshal(daemonJSON, &daemonConfig); err != nil {
+		return nil, false
+	}
+	if daemonConfig.AAKBCParams != "" || daemonConfig.AuthJson != "" {
+		return nil, false
+	}
+
+	return daemonJSON, true
+}
+
+// createAttempt is an attempt to create the instance of a sandbox
+type createAttempt struct {
+	// number counts attempts from 1. Warm instances are assigned by attempt 0.
+	number int
+	spec   InstanceTypeSpec
+}
+
+// createInstance assigns a pre-provisioned instance from a warm pool to a sandbox if available,
+// or creates a new instance otherwise
+func (s *cloudService) createInstance(ctx context.Context, sandbox *sandbox) (*Instance, createAttempt, error) {
+
+	if s.warmPool != nil {
+		daemonJSON, ok := bootstrapDaemonJSON(sandbox.cloudConfig)
+		if !ok {
+			logger.WithContext(ctx).Debug("daemon config needs provisioning at boot, creating a new instance")
+		} else if instance := s.warmPool.take(sandbox.spec); instance != nil {
+
+			spanCtx, span := tracing.StartSpan(ctx, "bootstrap warm instance", attribute.String("instance.name", instance.Name))
+			err := sandbox.agentProxy.Bootstrap(spanCtx, s.bootstrapServerURL(instance.IPs[0]), daemonJSON)
+			tracing.EndSpan(span, err)
+			if err == nil {
+				logger.WithContext(ctx).Info("assigned a warm instance", "instance_name", instance.Name)
+				return instance, createAttempt{spec: sandbox.spec}, nil
+			}
+
+			logger.WithContext(ctx).Warn("failed to bootstrap a warm instance, creating a new instance", "instance_name", instance.Name, logging.KeyError, err)
+			s.warmPool.delete(instance)
+		}
+	}
+
+	// Placements of some providers are subnets, which a pod that overrides the subnet must not fall back to
+	var placements []string
+	if lister, ok := s.provider.(PlacementLister); ok && sandbox.spec.SubnetID == "" {
+		placements = lister.Placements()
+	}
+
+	attempt := createAttempt{number: 1, spec: sandbox.spec}
+	attempt.spec.Prices = s.prices
+	for {
+		spanCtx, span := tracing.StartSpan(ctx, "create instance", attribute.String("instance.type", attempt.spec.InstanceType),
+			attribute.Int("attempt", attempt.number), attribute.String("placement", attempt.spec.Placement))
+		instance, err := s.provider.CreateInstance(spanCtx, sandbox.podName, string(sandbox.id), sandbox.cloudConfig, attempt.spec)
+		tracing.EndSpan(span, err)
+		if err == nil {
+			return instance, attempt, nil
+		}
+
+		var capacityErr *CapacityError
+		if !errors.As(err, &capacityErr) || attempt.number >= s.createAttempts || ctx.Err() != nil {
+			return nil, attempt, err
+		}
+		next, ok := nextCreateAttempt(attempt.spec, capacityErr, placements)
+		if !ok {
+			return nil, attempt, err
+		}
+
+		logger.WithContext(ctx).Warn("failed to create an instance for lack of capacity, trying another instance type or placement",
+			"attempt", attempt.number, logging.KeyError, err)
+
+		attempt = createAttempt{number: attempt.number + 1, spec: next}
+	}
+}
+
+// nextCreateAttempt returns the spec to retry an attempt with spec after it failed with a capacity error.
+// Capacity errors move on to the next placement with the same instance types. After the last placement,
+// and after quota errors, the failed instance type is excluded from best fit selection, starting over with
+// the default placement. Instance types requested by name are never replaced. It returns false if no
+// alternative is left.
+func nextCreateAttempt(spec InstanceTypeSpec, capacityErr *CapacityError, placements []string) (InstanceTypeSpec, bool) {
+
+	if !errors.Is(capacityErr, ErrQuotaExceeded) {
+		// The default placement is tried first, followed by the alternate placements in order
+		next := 0
+		for i, placement := range placements {
+			if placement == spec.Placement {
+				next = i + 1
+			}
+		}
+		if next < len(placements) {
+			spec.Placement = placements[next]
+			return spec, true
+		}
+	}
+
+	if !spec.usesBestFit() || capacityErr.InstanceType == "" || util.Contains(spec.ExcludedInstanceTypes, capacityErr.InstanceType) {
+		return spec, false
+	}
+	spec.ExcludedInstanceTypes = append(append([]string{}, spec.ExcludedInstanceTypes...), capacityErr.InstanceType)
+	spec.Placement = ""
+
+	return spec, true
+}
+
+// logFields returns fields that identify a sandbox in log lines
+func (s *sandbox) logFields() []any {
+	fields := []any{
+		logging.KeySandboxID, string(s.id),
+		logging.KeyPod, s.podName,
+		logging.KeyNamespace, s.podNamespace,
+	}
+	if s.instanceID != "" {
+		fields = append(fields, logging.KeyInstanceID, s.instanceID)
+	}
+	return fields
+}
+
+// updatePeerPod updates the PeerPod of a sandbox, if PeerPod objects are enabled.
+// Failures are logged, since PeerPod objects are not needed to run pods.
+func (s *cloudService) updatePeerPod(ctx context.Context, action string, update func(pps *k8sops.PeerPodService) error) {
+	if s.ppService == nil {
+		return
+	}
+	if err := update(s.ppService); err != nil {
+		logger.WithContext(ctx).Warn("failed to "+action+" PeerPod", logging.KeyError, err)
+	}
+}
+
+// peerPodInstance returns the instance information recorded in the status of a PeerPod
+func peerPodInstance(instance *Instance, attempt createAttempt, prices PriceCatalog) *k8sops.PeerPodInstance {
+	ppInstance := &k8sops.PeerPodInstance{
+		ID:           instance.ID,
+		Name:         instance.Name,
+		InstanceType: instance.InstanceType,
+		Zone:         instance.Zone,
+		Attempt:      attempt.number,
+		Placement:    attempt.spec.Placement,
+	}
+	if ppInstance.InstanceType == "" {
+		ppInstance.InstanceType = attempt.spec.InstanceType
+	}
+	ppInstance.Price = prices[ppInstance.InstanceType]
+	for _, ip := range instance.IPs {
+		ppInstance.IPs = append(ppInstance.IPs, ip.String())
+	}
+	return ppInstance
+}
+
 func (s *cloudService) ConfigVerifier() error {
 	return s.provider.ConfigVerifier()
 }
 
-func (s *cloudService) setInstance(sid sandboxID, instanceID, instanceName string) error {
+func (s *cloudService) setInstance(sid sandboxID, instance *Instance, instanceType string, price float64) error {
 
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
@@ -138,11 +525,21 @@ func (s *cloudService) setInstance(sid sandboxID, instanceID, instanceName strin
 		return fmt.Errorf("sandbox %s does not exist", sid)
 	}
 
-	sandbox.instanceID = instanceID
-	sandbox.instanceName = instanceName
+	sandbox.instanceID = instance.ID
+	sandbox.instanceName = instance.Name
+	sandbox.instanceIPs = instance.IPs
+	sandbox.instanceType = instanceType
+	sandbox.price = price
+	if price > 0 {
+		metrics.AddPodVMHourlyCost(instanceType, price)
+	}
 
 	s.cond.Broadcast()
 
+	if err := s.store.save(sandbox); err != nil {
+		return fmt.Errorf("persisting sandbox %s: %w", sid, err)
+	}
+
 	return nil
 }
 
@@ -178,10 +575,12 @@ func (s *cloudService) Version(ctx context.Context, req *pb.VersionRequest) (*pb
 
 func (s *cloudService) CreateVM(ctx context.Context, req *pb.CreateVMRequest) (res *pb.CreateVMResponse, err error) {
 
+	start := time.Now()
 	defer func() {
 		if err != nil {
-			logger.Print(err)
+			logger.Error("CreateVM failed", logging.KeySandboxID, req.Id, logging.KeyError, err)
 		}
+		metrics.ObserveHypervisorRequest("CreateVM", start, err)
 	}()
 
 	sid := sandboxID(req.Id)
@@ -200,19 +599,52 @@ func (s *cloudService) CreateVM(ctx context.Context, req *pb.CreateVMRequest) (r
 		return nil, fmt.Errorf("namespace name %s is missing in annotations", annotations.SandboxNamespace)
 	}
 
-	// Get Pod VM instance type from annotations
-	instanceType := util.GetInstanceTypeFromAnnotation(req.Annotations)
+	// Get Pod VM instance type, cpu, memory and GPUs from annotations
+	podVM, err := peerpodannotations.ParsePodVM(req.Annotations)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pod VM annotations of pod %s/%s: %w", namespace, pod, err)
+	}
 
-	// Get Pod VM cpu and memory from annotations
-	vcpus, memory := util.GetCPUAndMemoryFromAnnotation(req.Annotations)
+	overrides, err := peerpodannotations.ParseOverrides(req.Annotations)
+	if err != nil {
+		return nil, fmt.Errorf("invalid override annotations of pod %s/%s: %w", namespace, pod, err)
+	}
 
 	// Pod VM spec
 	vmSpec := InstanceTypeSpec{
-		InstanceType: instanceType,
-		VCPUs:        vcpus,
-		Memory:       memory,
+		InstanceType: podVM.InstanceType,
+		VCPUs:        podVM.VCPUs,
+		Memory:       podVM.Memory,
+		GPUs:         podVM.GPUs,
+		GPUModel:     podVM.GPUModel,
+		Spot:         podVM.Spot,
+		SpotMaxPrice: podVM.SpotMaxPrice,
+
+		ImageID:          overrides.ImageID,
+		SubnetID:         overrides.SubnetID,
+		SecurityGroupIDs: overrides.SecurityGroupIDs,
+		Tags:             overrides.Tags,
+	}
+
+	if vmSpec.Spot {
+		if spotProvider, ok := s.provider.(SpotProvider); !ok || !spotProvider.SupportsSpot() {
+			return nil, fmt.Errorf("pod %s/%s requests spot capacity with annotation %s, which the cloud provider does not support", namespace, pod, peerpodannotations.Spot)
+		}
 	}
 
+	if err := s.checkOverrides(overrides); err != nil {
+		return nil, fmt.Errorf("pod %s/%s: %w", namespace, pod, err)
+	}
+
+	if err := s.reserveSandbox(sid); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			s.unreserveSandbox(sid)
+		}
+	}()
+
 	// TODO: server name is also generated in each cloud provider, and possibly inconsistent
 	serverName := util.GenerateInstanceName(pod, string(sid), 63)
 
@@ -222,6 +654,14 @@ func (s *cloudService) CreateVM(ctx context.Context, req *pb.CreateVMRequest) (r
 	if err != nil {
 		return nil, fmt.Errorf("failed to inspect netns %s: %w", netNSPath, err)
 	}
+	defer func() {
+		if err != nil {
+			// Inspect allocates a pod index for the reserved sandbox ID, which must be released unless the sandbox is created
+			if err := s.workerNode.Release(podNetworkConfig); err != nil {
+				logger.Error("failed to release pod network", logging.KeySandboxID, req.Id, logging.KeyError, err)
+			}
+		}
+	}()
 
 	podDir := filepath.Join(s.podsDir, string(sid))
 	if err := os.MkdirAll(podDir, os.ModePerm); err != nil {
@@ -236,6 +676,7 @@ func (s *cloudService) CreateVM(ctx context.Context, req *pb.CreateVMRequest) (r
 		PodName:      pod,
 		PodNetwork:   podNetworkConfig,
 		TLSClientCA:  string(agentProxy.ClientCA()),
+		Spot:         vmSpec.Spot,
 	}
 
 	if caService := agentProxy.CAService(); caService != nil {
@@ -257,7 +698,7 @@ func (s *cloudService) CreateVM(ctx context.Context, req *pb.CreateVMRequest) (r
 	if authJSON, err := os.ReadFile(cloudinit.DefaultAuthfileSrcPath); err == nil {
 		daemonConfig.AuthJson = string(authJSON)
 	} else {
-		logger.Printf("Credentials file is not in a valid Json format, ignored")
+		logger.Debug("credentials file is not available, ignored", "path", cloudinit.DefaultAuthfileSrcPath)
 	}
 
 	daemonJSON, err := json.MarshalIndent(daemonConfig, "", "    ")
@@ -270,25 +711,17 @@ func (s *cloudService) CreateVM(ctx context.Context, req *pb.CreateVMRequest) (r
 	if err := os.WriteFile(daemonJSONPath, daemonJSON, 0666); err != nil {
 		return nil, fmt.Errorf("storing %s: %w", daemonJSONPath, err)
 	}
-	logger.Printf("stored %s", daemonJSONPath)
-
-	cloudConfig := &cloudinit.CloudConfig{
-		WriteFiles: []cloudinit.WriteFile{
-			{
-				Path:    forwarder.DefaultConfigPath,
-				Content: string(daemonJSON),
-			},
-		},
-	}
+	logger.Debug("stored daemon config", logging.KeySandboxID, req.Id, "path", daemonJSONPath)
 
 	sandbox := &sandbox{
 		id:           sid,
 		podName:      pod,
 		podNamespace: namespace,
 		netNSPath:    netNSPath,
+		serverName:   serverName,
 		agentProxy:   agentProxy,
 		podNetwork:   podNetworkConfig,
-		cloudConfig:  cloudConfig,
+		cloudConfig:  newCloudConfig(daemonJSON),
 		spec:         vmSpec,
 	}
 
@@ -296,17 +729,27 @@ func (s *cloudService) CreateVM(ctx context.Context, req *pb.CreateVMRequest) (r
 		return nil, fmt.Errorf("adding sandbox: %w", err)
 	}
 
-	logger.Printf("create a sandbox %s for pod %s in namespace %s (netns: %s)", req.Id, pod, namespace, sandbox.netNSPath)
+	if err := s.store.save(sandbox); err != nil {
+		_ = s.removeSandbox(sid)
+		return nil, fmt.Errorf("persisting sandbox: %w", err)
+	}
+
+	logger.With(sandbox.logFields()...).Info("created a sandbox", "netns", sandbox.netNSPath)
 
 	return &pb.CreateVMResponse{AgentSocketPath: socketPath}, nil
 }
 
 func (s *cloudService) StartVM(ctx context.Context, req *pb.StartVMRequest) (res *pb.StartVMResponse, err error) {
 
+	start := time.Now()
+	// Fields of the sandbox are attached to log lines of the agent proxy and the cloud provider
+	ctx = logging.WithFields(ctx, logging.KeySandboxID, req.Id)
+
 	defer func() {
 		if err != nil {
-			logger.Print(err)
+			logger.WithContext(ctx).Error("StartVM failed", logging.KeyError, err)
 		}
+		metrics.ObserveHypervisorRequest("StartVM", start, err)
 	}()
 
 	sid := sandboxID(req.Id)
@@ -316,39 +759,59 @@ func (s *cloudService) StartVM(ctx context.Context, req *pb.StartVMRequest) (res
 		return nil, fmt.Errorf("getting sandbox: %w", err)
 	}
 
-	instance, err := s.provider.CreateInstance(ctx, sandbox.podName, string(sid), sandbox.cloudConfig, sandbox.spec)
+	ctx = logging.WithFields(ctx, logging.KeyPod, sandbox.podName, logging.KeyNamespace, sandbox.podNamespace)
+
+	instance, attempt, err := s.createInstance(ctx, sandbox)
 	if err != nil {
 		return nil, fmt.Errorf("creating an instance : %w", err)
 	}
 
+	ppInstance := peerPodInstance(instance, attempt, s.prices)
+
 	if s.ppService != nil {
-		if err := s.ppService.OwnPeerPod(sandbox.podName, sandbox.podNamespace, instance.ID); err != nil {
-			logger.Printf("failed to create PeerPod: %s", err.Error())
+		if ppErr := s.ppService.OwnPeerPod(sandbox.podName, sandbox.podNamespace, ppInstance); ppErr != nil {
+			logger.WithContext(ctx).Warn("failed to create PeerPod", logging.KeyError, ppErr)
+		} else {
+			// Record failures after this point in the PeerPod status
+			defer func() {
+				if err != nil {
+					s.updatePeerPod(ctx, "mark failed", func(pps *k8sops.PeerPodService) error {
+						return pps.SetPeerPodFailed(sandbox.podName, sandbox.podNamespace, err)
+					})
+				}
+			}()
 		}
 	}
 
-	if err := s.setInstance(sid, instance.ID, instance.Name); err != nil {
+	if err := s.setInstance(sid, instance, ppInstance.InstanceType, ppInstance.Price); err != nil {
 		return nil, fmt.Errorf("setting instance: %w", err)
 	}
 
-	logger.Printf("created an instance %s for sandbox %s", instance.Name, sid)
+	ctx = logging.WithFields(ctx, logging.KeyInstanceID, instance.ID)
 
-	if err := s.workerNode.Setup(sandbox.netNSPath, instance.IPs, sandbox.podNetwork); err != nil {
+	logger.WithContext(ctx).Info("created an instance", "instance_name", instance.Name, "attempt", attempt.number)
+
+	_, span := tracing.StartSpan(ctx, "set up pod network")
+	err = s.workerNode.Setup(sandbox.netNSPath, instance.IPs, sandbox.podNetwork)
+	tracing.EndSpan(span, err)
+	if err != nil {
 		return nil, fmt.Errorf("setting up pod network tunnel on netns %s: %w", sandbox.netNSPath, err)
 	}
 
-	serverURL := &url.URL{
-		Scheme: "http",
-		Host:   net.JoinHostPort(instance.IPs[0].String(), s.daemonPort),
-		Path:   forwarder.AgentURLPath,
-	}
+	serverURL := s.agentServerURL(instance.IPs[0])
+
+	// The agent proxy outlives this request, but its connection attempts belong to the trace of this request
+	proxyCtx := logging.CopyFields(tracing.DetachContext(ctx), ctx)
+
+	_, span = tracing.StartSpan(ctx, "wait for agent proxy")
+	defer span.End()
 
 	errCh := make(chan error)
 	go func() {
 		defer close(errCh)
 
-		if err := sandbox.agentProxy.Start(context.Background(), serverURL); err != nil {
-			logger.Printf("error running agent proxy: %v", err)
+		if err := sandbox.agentProxy.Start(proxyCtx, serverURL); err != nil {
+			logger.WithContext(ctx).Error("error running agent proxy", logging.KeyError, err)
 			errCh <- err
 		}
 	}()
@@ -362,40 +825,70 @@ func (s *cloudService) StartVM(ctx context.Context, req *pb.StartVMRequest) (res
 	case <-sandbox.agentProxy.Ready():
 	}
 
-	logger.Printf("agent proxy is ready")
+	logger.WithContext(ctx).Info("agent proxy is ready")
+
+	s.updatePeerPod(ctx, "mark running", func(pps *k8sops.PeerPodService) error {
+		return pps.SetPeerPodRunning(sandbox.podName, sandbox.podNamespace)
+	})
+
+	if sandbox.spec.Spot {
+		go s.watchInterruption(proxyCtx, sandbox)
+	}
+
 	return &pb.StartVMResponse{}, nil
 }
 
-func (s *cloudService) StopVM(ctx context.Context, req *pb.StopVMRequest) (*pb.StopVMResponse, error) {
+func (s *cloudService) StopVM(ctx context.Context, req *pb.StopVMRequest) (res *pb.StopVMResponse, err error) {
+
+	start := time.Now()
+	defer func() {
+		metrics.ObserveHypervisorRequest("StopVM", start, err)
+	}()
 
 	sid := sandboxID(req.Id)
 
 	sandbox, err := s.getSandbox(sid)
 	if err != nil {
 		err = fmt.Errorf("stopping VM: %v", err)
-		logger.Print(err)
+		logger.Error("StopVM failed", logging.KeySandboxID, req.Id, logging.KeyError, err)
 		return nil, err
 	}
 
+	ctx = logging.WithFields(ctx, sandbox.logFields()...)
+	logger := logger.WithContext(ctx)
+
 	if err := sandbox.agentProxy.Shutdown(); err != nil {
-		logger.Printf("stopping agent proxy: %v", err)
+		logger.Warn("failed to stop agent proxy", logging.KeyError, err)
 	}
 
+	s.updatePeerPod(ctx, "mark deleting", func(pps *k8sops.PeerPodService) error {
+		return pps.SetPeerPodDeleting(sandbox.podName, sandbox.podNamespace)
+	})
+
 	if err := s.provider.DeleteInstance(ctx, sandbox.instanceID); err != nil {
-		logger.Printf("Error deleting an instance %s: %v", sandbox.instanceID, err)
-	} else if s.ppService != nil {
-		if err := s.ppService.ReleasePeerPod(sandbox.podName, sandbox.podNamespace, sandbox.instanceID); err != nil {
-			logger.Printf("failed to release PeerPod %v", err)
-		}
+		logger.Error("failed to delete an instance", logging.KeyError, err)
+		s.updatePeerPod(ctx, "record deletion failure of", func(pps *k8sops.PeerPodService) error {
+			return pps.SetPeerPodDeletionFailed(sandbox.podName, sandbox.podNamespace, err)
+		})
+	} else {
+		s.updatePeerPod(ctx, "release", func(pps *k8sops.PeerPodService) error {
+			return pps.ReleasePeerPod(sandbox.podName, sandbox.podNamespace, sandbox.instanceID)
+		})
 	}
 
 	if err := s.workerNode.Teardown(sandbox.netNSPath, sandbox.podNetwork); err != nil {
-		logger.Printf("tearing down netns %s: %v", sandbox.netNSPath, err)
+		logger.Error("failed to tear down pod network", "netns", sandbox.netNSPath, logging.KeyError, err)
 	}
 
 	if err = s.removeSandbox(sid); err != nil {
-		logger.Printf("removing sandbox %s: %v", sid, err)
+		logger.Error("failed to remove sandbox", logging.KeyError, err)
 	}
 
+	if err = s.store.remove(sid); err != nil {
+		logger.Error("failed to remove persistent state of sandbox", logging.KeyError, err)
+	}
+
+	logger.Info("stopped a sandbox")
+
 	return &pb.StopVMResponse{}, nil
 }
@@ -8,17 +8,24 @@ import (
 	"fmt"
 	"net/netip"
 	"net/url"
+	"os"
+	"path/filepath"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
 	cri "github.com/containerd/containerd/pkg/cri/annotations"
 	pb "github.com/kata-containers/kata-containers/src/runtime/protocols/hypervisor"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/proxy"
+	peerpodannotations "github.com/confidential-containers/cloud-api-adaptor/pkg/annotations"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/forwarder"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/podnetwork/tunneler"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/agentproto"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/cloudinit"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/tlsutil"
 )
@@ -39,6 +46,10 @@ func (p *mockProvider) DeleteInstance(ctx context.Context, instanceID string) er
 	return nil
 }
 
+func (p *mockProvider) ListInstances(ctx context.Context) ([]*Instance, error) {
+	return nil, nil
+}
+
 func (p *mockProvider) Teardown() error {
 	return nil
 }
@@ -52,9 +63,11 @@ func (p *mockProvider) SelectInstanceType(ctx context.Context, vCPU int64, memor
 }
 
 type mockProxy struct {
-	readyCh    chan struct{}
-	stopCh     chan struct{}
-	socketPath string
+	readyCh       chan struct{}
+	stopCh        chan struct{}
+	interruptedCh chan struct{}
+	stopOnce      sync.Once
+	socketPath    string
 }
 
 func (p *mockProxy) Start(ctx context.Context, serverURL *url.URL) error {
@@ -63,15 +76,33 @@ func (p *mockProxy) Start(ctx context.Context, serverURL *url.URL) error {
 	return nil
 }
 
+func (p *mockProxy) Bootstrap(ctx context.Context, serverURL *url.URL, daemonJSON []byte) error {
+	return nil
+}
+
 func (p *mockProxy) Ready() chan struct{} {
 	return p.readyCh
 }
 
 func (p *mockProxy) Shutdown() error {
-	close(p.stopCh)
+	p.stopOnce.Do(func() {
+		close(p.stopCh)
+	})
 	return nil
 }
 
+func (p *mockProxy) Done() <-chan struct{} {
+	return p.stopCh
+}
+
+func (p *mockProxy) Interrupted() <-chan struct{} {
+	return p.interruptedCh
+}
+
+func (p *mockProxy) LinkState() agentproto.LinkState {
+	return agentproto.LinkUp
+}
+
 func (p *mockProxy) ClientCA() (certPEM []byte) {
 	return nil
 }
@@ -86,13 +117,16 @@ type mockProxyFactory struct {
 
 func (f *mockProxyFactory) New(serverName, socketPath string) proxy.AgentProxy {
 	return &mockProxy{
-		socketPath: socketPath,
-		readyCh:    make(chan struct{}),
-		stopCh:     make(chan struct{}),
+		socketPath:    socketPath,
+		readyCh:       make(chan struct{}),
+		stopCh:        make(chan struct{}),
+		interruptedCh: make(chan struct{}),
 	}
 }
 
-type mockWorkerNode struct{}
+type mockWorkerNode struct {
+	released int
+}
 
 func (n mockWorkerNode) Inspect(nsPath string) (*tunneler.Config, error) {
 	return nil, nil
@@ -106,6 +140,11 @@ func (n *mockWorkerNode) Teardown(nsPath string, config *tunneler.Config) error
 	return nil
 }
 
+func (n *mockWorkerNode) Release(config *tunneler.Config) error {
+	n.released++
+	return nil
+}
+
 func TestCloudService(t *testing.T) {
 
 	ctx := context.Background()
@@ -115,7 +154,7 @@ func TestCloudService(t *testing.T) {
 		podsDir: dir,
 	}
 
-	s := NewService(&mockProvider{}, proxyFactory, &mockWorkerNode{}, dir, forwarder.DefaultListenPort, "")
+	s := NewService(&mockProvider{}, proxyFactory, &mockWorkerNode{}, dir, forwarder.DefaultListenPort, "", nil, nil, nil, 0, 1)
 
 	assert.NotNil(t, s)
 
@@ -148,6 +187,597 @@ func TestCloudService(t *testing.T) {
 	assert.NotNil(t, res3)
 }
 
+func TestCloudServiceCreateVMFailure(t *testing.T) {
+
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	workerNode := &mockWorkerNode{}
+	s := NewService(&mockProvider{}, &mockProxyFactory{podsDir: dir}, workerNode, dir, forwarder.DefaultListenPort, "", nil, nil, nil, 0, 1)
+
+	req := &pb.CreateVMRequest{
+		Id: "123",
+		Annotations: map[string]string{
+			cri.SandboxNamespace: "default",
+			cri.SandboxName:      "mypod",
+		},
+	}
+
+	_, err := s.CreateVM(ctx, req)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, workerNode.released)
+
+	// A duplicate sandbox is rejected before a pod index is allocated, so that the pod index of the existing sandbox is kept
+	_, err = s.CreateVM(ctx, req)
+	assert.ErrorContains(t, err, "sandbox 123 already exists")
+	assert.Equal(t, 0, workerNode.released)
+
+	// The pod index allocated for a sandbox that fails to be created is released
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "789"), nil, 0600))
+	_, err = s.CreateVM(ctx, &pb.CreateVMRequest{
+		Id: "789",
+		Annotations: map[string]string{
+			cri.SandboxNamespace: "default",
+			cri.SandboxName:      "mypod",
+		},
+	})
+	assert.ErrorContains(t, err, "creating a pod directory")
+	assert.Equal(t, 1, workerNode.released)
+
+	// The ID of a sandbox that failed to be created can be used again
+	require.NoError(t, os.Remove(filepath.Join(dir, "789")))
+	_, err = s.CreateVM(ctx, &pb.CreateVMRequest{
+		Id: "789",
+		Annotations: map[string]string{
+			cri.SandboxNamespace: "default",
+			cri.SandboxName:      "mypod",
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, workerNode.released)
+
+	// Malformed pod VM annotations are rejected before a pod index is allocated
+	_, err = s.CreateVM(ctx, &pb.CreateVMRequest{
+		Id: "456",
+		Annotations: map[string]string{
+			cri.SandboxNamespace:      "default",
+			cri.SandboxName:           "mypod",
+			peerpodannotations.Memory: "2Gi",
+		},
+	})
+	assert.ErrorContains(t, err, `annotation io.katacontainers.config.hypervisor.default_memory: "2Gi" is not an integer`)
+	assert.Equal(t, 1, workerNode.released)
+}
+
+type mockSpotProvider struct {
+	mockProvider
+	spec InstanceTypeSpec
+}
+
+func (p *mockSpotProvider) CreateInstance(ctx context.Context, podName, sandboxID string, cloudConfig cloudinit.CloudConfigGenerator, spec InstanceTypeSpec) (*Instance, error) {
+	p.spec = spec
+	return p.mockProvider.CreateInstance(ctx, podName, sandboxID, cloudConfig, spec)
+}
+
+func (p *mockSpotProvider) SupportsSpot() bool {
+	return true
+}
+
+func TestCloudServiceSpot(t *testing.T) {
+
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	spotRequest := func(id string) *pb.CreateVMRequest {
+		return &pb.CreateVMRequest{
+			Id: id,
+			Annotations: map[string]string{
+				cri.SandboxNamespace:            "default",
+				cri.SandboxName:                 "mypod",
+				peerpodannotations.Spot:         "true",
+				peerpodannotations.SpotMaxPrice: "0.01",
+			},
+		}
+	}
+
+	// Providers without spot capacity reject spot pods before a pod index is allocated
+	workerNode := &mockWorkerNode{}
+	s := NewService(&mockProvider{}, &mockProxyFactory{podsDir: dir}, workerNode, dir, forwarder.DefaultListenPort, "", nil, nil, nil, 0, 1)
+	_, err := s.CreateVM(ctx, spotRequest("123"))
+	assert.ErrorContains(t, err, "requests spot capacity with annotation kata.peerpods.io/spot, which the cloud provider does not support")
+	assert.Equal(t, 0, workerNode.released)
+
+	provider := &mockSpotProvider{}
+	s = NewService(provider, &mockProxyFactory{podsDir: dir}, &mockWorkerNode{}, dir, forwarder.DefaultListenPort, "", nil, nil, nil, 0, 1)
+
+	_, err = s.CreateVM(ctx, spotRequest("456"))
+	assert.NoError(t, err)
+
+	daemonJSON, err := s.(*cloudService).store.loadDaemonJSON("456")
+	assert.NoError(t, err)
+	assert.Contains(t, string(daemonJSON), `"spot": true`)
+
+	_, err = s.StartVM(ctx, &pb.StartVMRequest{Id: "456"})
+	assert.NoError(t, err)
+	assert.True(t, provider.spec.Spot)
+	assert.Equal(t, 0.01, provider.spec.SpotMaxPrice)
+
+	_, err = s.StopVM(ctx, &pb.StopVMRequest{Id: "456"})
+	assert.NoError(t, err)
+}
+
+func TestCloudServiceWatchInterruption(t *testing.T) {
+
+	s := &cloudService{}
+
+	for name, interrupt := range map[string]bool{"interrupted": true, "stopped": false} {
+		t.Run(name, func(t *testing.T) {
+			agentProxy := (&mockProxyFactory{}).New("podvm", "").(*mockProxy)
+			sandbox := &sandbox{id: "123", podName: "mypod", podNamespace: "default", agentProxy: agentProxy}
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				s.watchInterruption(context.Background(), sandbox)
+			}()
+
+			// The agent proxy shuts down after it reports interruption
+			if interrupt {
+				close(agentProxy.interruptedCh)
+			}
+			assert.NoError(t, agentProxy.Shutdown())
+
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("watchInterruption does not return")
+			}
+		})
+	}
+}
+
+func TestCloudServicePodsLimit(t *testing.T) {
+
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	s := NewService(&mockProvider{}, &mockProxyFactory{podsDir: dir}, &mockWorkerNode{}, dir, forwarder.DefaultListenPort, "", nil, nil, nil, 1, 1)
+
+	newRequest := func(id string) *pb.CreateVMRequest {
+		return &pb.CreateVMRequest{
+			Id: id,
+			Annotations: map[string]string{
+				cri.SandboxNamespace: "default",
+				cri.SandboxName:      "mypod-" + id,
+			},
+		}
+	}
+
+	_, err := s.CreateVM(ctx, newRequest("123"))
+	assert.NoError(t, err)
+
+	_, err = s.CreateVM(ctx, newRequest("456"))
+	assert.ErrorContains(t, err, "limit of 1 peer pods")
+
+	_, err = s.StopVM(ctx, &pb.StopVMRequest{Id: "123"})
+	assert.NoError(t, err)
+
+	_, err = s.CreateVM(ctx, newRequest("456"))
+	assert.NoError(t, err)
+}
+
+func TestCloudServicePodsLimitConcurrent(t *testing.T) {
+
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	s := NewService(&mockProvider{}, &mockProxyFactory{podsDir: dir}, &mockWorkerNode{}, dir, forwarder.DefaultListenPort, "", nil, nil, nil, 2, 1)
+
+	// Concurrent CreateVMs reserve their sandboxes atomically, so that no more than the limit succeed
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	created := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			_, err := s.CreateVM(ctx, &pb.CreateVMRequest{
+				Id: id,
+				Annotations: map[string]string{
+					cri.SandboxNamespace: "default",
+					cri.SandboxName:      "mypod-" + id,
+				},
+			})
+			if err == nil {
+				mutex.Lock()
+				created++
+				mutex.Unlock()
+			} else {
+				assert.ErrorContains(t, err, "limit of 2 peer pods")
+			}
+		}(fmt.Sprint(i))
+	}
+	wg.Wait()
+	assert.Equal(t, 2, created)
+}
+
+type mockQuotaProvider struct {
+	mockProvider
+	remaining int
+	err       error
+}
+
+func (p *mockQuotaProvider) RemainingInstances(ctx context.Context) (int, error) {
+	return p.remaining, p.err
+}
+
+func TestCloudServicePodsCapacity(t *testing.T) {
+
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	s := NewService(&mockProvider{}, &mockProxyFactory{podsDir: dir}, &mockWorkerNode{}, dir, forwarder.DefaultListenPort, "", nil, nil, nil, 0, 1)
+	assert.Equal(t, 0, s.PodsCapacity(ctx))
+
+	provider := &mockQuotaProvider{remaining: 1}
+	s = NewService(provider, &mockProxyFactory{podsDir: dir}, &mockWorkerNode{}, dir, forwarder.DefaultListenPort, "", nil, nil, nil, 3, 1)
+	assert.Equal(t, 1, s.PodsCapacity(ctx))
+
+	_, err := s.CreateVM(ctx, &pb.CreateVMRequest{
+		Id: "123",
+		Annotations: map[string]string{
+			cri.SandboxNamespace: "default",
+			cri.SandboxName:      "mypod",
+		},
+	})
+	assert.NoError(t, err)
+	provider.remaining = 0
+	assert.Equal(t, 1, s.PodsCapacity(ctx))
+
+	provider.remaining = 10
+	assert.Equal(t, 3, s.PodsCapacity(ctx))
+
+	provider.err = ErrQuotaUnknown
+	assert.Equal(t, 3, s.PodsCapacity(ctx))
+}
+
+type mockCapacityProvider struct {
+	mockProvider
+	specList   []InstanceTypeSpec
+	placements []string
+	// available is the instance type and placement that have capacity
+	available string
+	attempts  []string
+}
+
+func (p *mockCapacityProvider) Placements() []string {
+	return p.placements
+}
+
+func (p *mockCapacityProvider) CreateInstance(ctx context.Context, podName, sandboxID string, cloudConfig cloudinit.CloudConfigGenerator, spec InstanceTypeSpec) (*Instance, error) {
+	instanceType, err := SelectInstanceTypeToUse(spec, p.specList, []string{"small", "medium", "large"}, "small")
+	if err != nil {
+		return nil, err
+	}
+	attempt := instanceType + "@" + spec.Placement
+	p.attempts = append(p.attempts, attempt)
+	if attempt != p.available {
+		return nil, &CapacityError{Cause: ErrInsufficientCapacity, InstanceType: instanceType, Placement: spec.Placement, Err: fmt.Errorf("no capacity")}
+	}
+	instance, err := p.mockProvider.CreateInstance(ctx, podName, sandboxID, cloudConfig, spec)
+	if err != nil {
+		return nil, err
+	}
+	instance.InstanceType = instanceType
+	return instance, nil
+}
+
+func TestCloudServiceCapacityFallback(t *testing.T) {
+
+	ctx := context.Background()
+
+	for name, tc := range map[string]struct {
+		annotations    map[string]string
+		available      string
+		createAttempts int
+		attempts       []string
+		err            bool
+	}{
+		"fallback disabled": {
+			annotations:    map[string]string{peerpodannotations.VCPUs: "1", peerpodannotations.Memory: "1024"},
+			available:      "small@subnet-2",
+			createAttempts: 1,
+			attempts:       []string{"small@"},
+			err:            true,
+		},
+		"alternate placement": {
+			annotations:    map[string]string{peerpodannotations.VCPUs: "1", peerpodannotations.Memory: "1024"},
+			available:      "small@subnet-2",
+			createAttempts: 3,
+			attempts:       []string{"small@", "small@subnet-2"},
+		},
+		"next best fit": {
+			annotations:    map[string]string{peerpodannotations.VCPUs: "1", peerpodannotations.Memory: "1024"},
+			available:      "medium@",
+			createAttempts: 5,
+			attempts:       []string{"small@", "small@subnet-2", "small@subnet-3", "medium@"},
+		},
+		"budget exhausted": {
+			annotations:    map[string]string{peerpodannotations.VCPUs: "1", peerpodannotations.Memory: "1024"},
+			available:      "large@",
+			createAttempts: 4,
+			attempts:       []string{"small@", "small@subnet-2", "small@subnet-3", "medium@"},
+			err:            true,
+		},
+		"instance type requested by name": {
+			annotations:    map[string]string{peerpodannotations.MachineType: "small"},
+			available:      "medium@",
+			createAttempts: 10,
+			attempts:       []string{"small@", "small@subnet-2", "small@subnet-3"},
+			err:            true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			provider := &mockCapacityProvider{
+				specList: []InstanceTypeSpec{
+					{InstanceType: "small", VCPUs: 1, Memory: 2048},
+					{InstanceType: "medium", VCPUs: 2, Memory: 4096},
+					{InstanceType: "large", VCPUs: 4, Memory: 8192},
+				},
+				placements: []string{"subnet-2", "subnet-3"},
+				available:  tc.available,
+			}
+			s := NewService(provider, &mockProxyFactory{podsDir: dir}, &mockWorkerNode{}, dir, forwarder.DefaultListenPort, "", nil, nil, nil, 0, tc.createAttempts).(*cloudService)
+
+			annotations := map[string]string{
+				cri.SandboxNamespace: "default",
+				cri.SandboxName:      "mypod",
+			}
+			for k, v := range tc.annotations {
+				annotations[k] = v
+			}
+			_, err := s.CreateVM(ctx, &pb.CreateVMRequest{Id: "123", Annotations: annotations})
+			assert.NoError(t, err)
+
+			sandbox, err := s.getSandbox("123")
+			assert.NoError(t, err)
+
+			instance, attempt, err := s.createInstance(ctx, sandbox)
+			assert.Equal(t, tc.attempts, provider.attempts)
+			if tc.err {
+				assert.ErrorIs(t, err, ErrInsufficientCapacity)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotNil(t, instance)
+			assert.Equal(t, len(tc.attempts), attempt.number)
+			assert.Equal(t, len(tc.attempts), peerPodInstance(instance, attempt, nil).Attempt)
+		})
+	}
+}
+
+func TestNextCreateAttempt(t *testing.T) {
+
+	spec := InstanceTypeSpec{VCPUs: 1, Memory: 1024}
+	placements := []string{"zone-2"}
+
+	capacityErr := &CapacityError{Cause: ErrInsufficientCapacity, InstanceType: "small"}
+	next, ok := nextCreateAttempt(spec, capacityErr, placements)
+	assert.True(t, ok)
+	assert.Equal(t, InstanceTypeSpec{VCPUs: 1, Memory: 1024, Placement: "zone-2"}, next)
+
+	capacityErr = &CapacityError{Cause: ErrInsufficientCapacity, InstanceType: "small", Placement: "zone-2"}
+	next, ok = nextCreateAttempt(next, capacityErr, placements)
+	assert.True(t, ok)
+	assert.Equal(t, InstanceTypeSpec{VCPUs: 1, Memory: 1024, ExcludedInstanceTypes: []string{"small"}}, next)
+
+	// Quota errors skip alternate placements
+	quotaErr := &CapacityError{Cause: ErrQuotaExceeded, InstanceType: "medium"}
+	next, ok = nextCreateAttempt(next, quotaErr, placements)
+	assert.True(t, ok)
+	assert.Equal(t, InstanceTypeSpec{VCPUs: 1, Memory: 1024, ExcludedInstanceTypes: []string{"small", "medium"}}, next)
+
+	// Instance types requested by name are not replaced
+	_, ok = nextCreateAttempt(InstanceTypeSpec{InstanceType: "small"}, quotaErr, placements)
+	assert.False(t, ok)
+}
+
+func TestCloudServiceRestore(t *testing.T) {
+
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	proxyFactory := &mockProxyFactory{
+		podsDir: dir,
+	}
+
+	s1 := NewService(&mockProvider{}, proxyFactory, &mockWorkerNode{}, dir, forwarder.DefaultListenPort, "", nil, nil, nil, 0, 1)
+
+	sandboxID := "123"
+	sandboxNS := "default"
+	sandboxName := "mypod"
+
+	req := &pb.CreateVMRequest{
+		Id: sandboxID,
+		Annotations: map[string]string{
+			cri.SandboxNamespace: sandboxNS,
+			cri.SandboxName:      sandboxName,
+		},
+	}
+
+	_, err := s1.CreateVM(ctx, req)
+	assert.NoError(t, err)
+
+	_, err = s1.StartVM(ctx, &pb.StartVMRequest{Id: sandboxID})
+	assert.NoError(t, err)
+
+	// Simulate a restart of cloud-api-adaptor
+	s2 := NewService(&mockProvider{}, proxyFactory, &mockWorkerNode{}, dir, forwarder.DefaultListenPort, "", nil, nil, nil, 0, 1)
+
+	instanceID, err := s2.GetInstanceID(ctx, sandboxNS, sandboxName, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "mypod-123", instanceID)
+
+	res, err := s2.StopVM(ctx, &pb.StopVMRequest{Id: sandboxID})
+	assert.NoError(t, err)
+	assert.NotNil(t, res)
+
+	// The sandbox state is removed once the pod VM is stopped
+	s3 := NewService(&mockProvider{}, proxyFactory, &mockWorkerNode{}, dir, forwarder.DefaultListenPort, "", nil, nil, nil, 0, 1)
+
+	instanceID, err = s3.GetInstanceID(ctx, sandboxNS, sandboxName, false)
+	assert.NoError(t, err)
+	assert.Empty(t, instanceID)
+}
+
+func TestCloudServiceWarmPool(t *testing.T) {
+
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	proxyFactory := &mockProxyFactory{
+		podsDir: dir,
+	}
+
+	var sizes WarmPoolSizes
+	assert.NoError(t, sizes.Set("default=1"))
+
+	warmPoolConfig := &WarmPoolConfig{
+		Sizes:          sizes,
+		RefillPolicy:   WarmPoolRefillPeriodic,
+		RefillInterval: time.Hour,
+	}
+
+	s := NewService(&mockProvider{}, proxyFactory, &mockWorkerNode{}, dir, forwarder.DefaultListenPort, "", warmPoolConfig, nil, nil, 0, 1)
+	defer func() {
+		assert.NoError(t, s.Teardown())
+	}()
+
+	assert.Eventually(t, func() bool {
+		return s.WarmPoolStats().Idle[""] == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	sandboxID := "123"
+
+	req := &pb.CreateVMRequest{
+		Id: sandboxID,
+		Annotations: map[string]string{
+			cri.SandboxNamespace: "default",
+			cri.SandboxName:      "mypod",
+		},
+	}
+
+	_, err := s.CreateVM(ctx, req)
+	assert.NoError(t, err)
+
+	_, err = s.StartVM(ctx, &pb.StartVMRequest{Id: sandboxID})
+	assert.NoError(t, err)
+
+	// The pod is assigned to the pre-provisioned instance
+	instanceID, err := s.GetInstanceID(ctx, "default", "mypod", false)
+	assert.NoError(t, err)
+	assert.Regexp(t, "^warm-", instanceID)
+
+	stats := s.WarmPoolStats()
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Created)
+	assert.Equal(t, 0, stats.Idle[""])
+
+	_, err = s.StopVM(ctx, &pb.StopVMRequest{Id: sandboxID})
+	assert.NoError(t, err)
+}
+
+type mockListProvider struct {
+	mockProvider
+	instances []*Instance
+	deleted   []string
+	mutex     sync.Mutex
+}
+
+func (p *mockListProvider) ListInstances(ctx context.Context) ([]*Instance, error) {
+	return p.instances, nil
+}
+
+func (p *mockListProvider) DeleteInstance(ctx context.Context, instanceID string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.deleted = append(p.deleted, instanceID)
+	return nil
+}
+
+func TestWarmPoolDeleteLeftovers(t *testing.T) {
+
+	sandboxID := "0123456789abcdef"
+
+	provider := &mockListProvider{
+		instances: []*Instance{
+			{ID: "i-1", Name: util.GenerateInstanceName(util.WarmPoolPodName("worker1"), sandboxID, 63)},
+			{ID: "i-2", Name: util.GenerateInstanceName(util.WarmPoolPodName("worker1"), sandboxID, 63)},
+			{ID: "i-3", Name: util.GenerateInstanceName(util.WarmPoolPodName("worker2"), sandboxID, 63)},
+			{ID: "i-4", Name: util.GenerateInstanceName("mypod", sandboxID, 63)},
+		},
+	}
+
+	pool := newWarmPool(provider, &mockProxyFactory{}, WarmPoolConfig{NodeName: "worker1"})
+
+	// i-2 is assigned to a restored sandbox. i-3 is pre-provisioned on another worker node.
+	pool.deleteLeftovers(map[string]bool{"i-2": true})
+
+	assert.Equal(t, []string{"i-1"}, provider.deleted)
+}
+
+func TestBootstrapDaemonJSON(t *testing.T) {
+
+	for name, tc := range map[string]struct {
+		cloudConfig *cloudinit.CloudConfig
+		ok          bool
+	}{
+		"daemon config": {
+			cloudConfig: newCloudConfig([]byte(`{"pod-name":"mypod","pod-namespace":"default"}`)),
+			ok:          true,
+		},
+		"aa-kbc-params": {
+			cloudConfig: newCloudConfig([]byte(`{"pod-name":"mypod","pod-namespace":"default","aa-kbc-params":"cc_kbc::http://kbs"}`)),
+		},
+		"auth json": {
+			cloudConfig: newCloudConfig([]byte(`{"pod-name":"mypod","pod-namespace":"default","auth-json":"{}"}`)),
+		},
+		"additional files": {
+			cloudConfig: &cloudinit.CloudConfig{
+				WriteFiles: []cloudinit.WriteFile{
+					{Path: forwarder.DefaultConfigPath, Content: `{"pod-name":"mypod","pod-namespace":"default"}`},
+					{Path: "/etc/other.json", Content: "{}"},
+				},
+			},
+		},
+		"no files": {
+			cloudConfig: &cloudinit.CloudConfig{},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			daemonJSON, ok := bootstrapDaemonJSON(tc.cloudConfig)
+			assert.Equal(t, tc.ok, ok)
+			if tc.ok {
+				assert.Equal(t, tc.cloudConfig.WriteFiles[0].Content, string(daemonJSON))
+			}
+		})
+	}
+}
+
+func TestWarmPoolSizes(t *testing.T) {
+
+	var sizes WarmPoolSizes
+
+	assert.NoError(t, sizes.Set("default=2, bx2-2x8=1"))
+	assert.Equal(t, WarmPoolSizes{"": 2, "bx2-2x8": 1}, sizes)
+	assert.Equal(t, "bx2-2x8=1,default=2", sizes.String())
+
+	assert.Error(t, sizes.Set("bx2-2x8"))
+	assert.Error(t, sizes.Set("bx2-2x8=-1"))
+}
+
 func TestVerifyCloudInstanceType(t *testing.T) {
 	type args struct {
 		instanceType        string
@@ -501,7 +1131,7 @@ func TestGetBestFitInstanceType(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// Add benchmark
 			start := time.Now()
-			got, err := GetBestFitInstanceType(tt.args.sortedInstanceTypeSpecList, tt.args.vcpus, tt.args.memory)
+			got, err := GetBestFitInstanceType(tt.args.sortedInstanceTypeSpecList, InstanceTypeSpec{VCPUs: tt.args.vcpus, Memory: tt.args.memory})
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetBestFitInstanceType() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -514,3 +1144,84 @@ func TestGetBestFitInstanceType(t *testing.T) {
 		})
 	}
 }
+
+func TestGetBestFitInstanceTypeGPUs(t *testing.T) {
+
+	// A mocked catalog with GPU instance types of AWS
+	specList := SortInstanceTypesOnMemory([]InstanceTypeSpec{
+		{InstanceType: "p3.8xlarge", VCPUs: 32, Memory: 249856, GPUs: 4, GPUModel: "V100"},
+		{InstanceType: "g4dn.xlarge", VCPUs: 4, Memory: 16384, GPUs: 1, GPUModel: "T4"},
+		{InstanceType: "m5.xlarge", VCPUs: 4, Memory: 16384},
+		{InstanceType: "p3.2xlarge", VCPUs: 8, Memory: 62464, GPUs: 1, GPUModel: "V100"},
+		{InstanceType: "m5.4xlarge", VCPUs: 16, Memory: 65536},
+	})
+
+	for _, tc := range []struct {
+		spec InstanceTypeSpec
+		want string
+		err  string
+	}{
+		{spec: InstanceTypeSpec{VCPUs: 4, Memory: 8192}, want: "m5.xlarge"},
+		{spec: InstanceTypeSpec{VCPUs: 4, Memory: 8192, GPUs: 1}, want: "g4dn.xlarge"},
+		{spec: InstanceTypeSpec{GPUs: 1, GPUModel: "v100"}, want: "p3.2xlarge"},
+		{spec: InstanceTypeSpec{VCPUs: 16, Memory: 8192, GPUs: 1}, want: "p3.8xlarge"},
+		{spec: InstanceTypeSpec{GPUs: 2, GPUModel: "T4"}, err: "no instance type found for the given vcpus (0), memory (0) and GPUs (2 T4)"},
+	} {
+		got, err := GetBestFitInstanceType(specList, tc.spec)
+		if tc.err != "" {
+			assert.EqualError(t, err, tc.err)
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, got, "spec %+v", tc.spec)
+	}
+
+	// GPUs select the instance type unless an instance type is set in annotations
+	validInstanceTypes := []string{"m5.xlarge", "g4dn.xlarge", "p3.8xlarge"}
+	instanceType, err := SelectInstanceTypeToUse(InstanceTypeSpec{GPUs: 1}, specList, validInstanceTypes, "m5.xlarge")
+	assert.NoError(t, err)
+	assert.Equal(t, "g4dn.xlarge", instanceType)
+
+	instanceType, err = SelectInstanceTypeToUse(InstanceTypeSpec{InstanceType: "p3.8xlarge", GPUs: 1}, specList, validInstanceTypes, "m5.xlarge")
+	assert.NoError(t, err)
+	assert.Equal(t, "p3.8xlarge", instanceType)
+}
+
+func TestGetBestFitInstanceTypePrices(t *testing.T) {
+
+	specList := SortInstanceTypesOnMemory([]InstanceTypeSpec{
+		{InstanceType: "m5.large", VCPUs: 2, Memory: 8192, Arch: "amd64", Price: 0.096},
+		{InstanceType: "m6g.large", VCPUs: 2, Memory: 8192, Arch: "arm64", Price: 0.077},
+		{InstanceType: "t3.large", VCPUs: 2, Memory: 8192, Arch: "amd64", Price: 0.0832},
+		{InstanceType: "c5.xlarge", VCPUs: 4, Memory: 8192, Arch: "amd64"},
+		{InstanceType: "r6g.large", VCPUs: 2, Memory: 16384, Arch: "arm64", Price: 0.1008},
+	})
+
+	for _, tc := range []struct {
+		spec InstanceTypeSpec
+		want string
+	}{
+		{spec: InstanceTypeSpec{VCPUs: 2, Memory: 4096}, want: "m6g.large"},
+		{spec: InstanceTypeSpec{VCPUs: 2, Memory: 4096, Arch: "amd64"}, want: "t3.large"},
+		{spec: InstanceTypeSpec{VCPUs: 2, Memory: 12288}, want: "r6g.large"},
+		// Instance types without a price are only selected when no instance type with a price fits
+		{spec: InstanceTypeSpec{VCPUs: 4, Memory: 8192}, want: "c5.xlarge"},
+	} {
+		got, err := GetBestFitInstanceType(specList, tc.spec)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, got, "spec %+v", tc.spec)
+	}
+
+	_, err := GetBestFitInstanceType(specList, InstanceTypeSpec{VCPUs: 4, Memory: 8192, Arch: "arm64"})
+	assert.Error(t, err)
+
+	// Prices of a pod spec apply to instance types without a price
+	spec := InstanceTypeSpec{VCPUs: 2, Memory: 4096, Prices: PriceCatalog{"c5.xlarge": 0.05, "m6g.large": 0.2}}
+	instanceType, err := SelectInstanceTypeToUse(spec, specList, InstanceTypeNames(specList), "m5.large")
+	assert.NoError(t, err)
+	assert.Equal(t, "c5.xlarge", instanceType)
+
+	// The prices of a pod spec are not recorded in the instance types of the provider
+	c5, _ := GetInstanceTypeSpec(specList, "c5.xlarge")
+	assert.Zero(t, c5.Price)
+}
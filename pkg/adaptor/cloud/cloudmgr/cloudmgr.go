@@ -0,0 +1,52 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package cloudmgr manages the cloud providers built in a binary.
+//
+// A cloud provider is built in when the binary is built with the build tag of the provider,
+// e.g. -tags=aws,libvirt. The libvirt provider also requires cgo.
+package cloudmgr
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud"
+)
+
+var cloudTable = make(map[string]cloud.Cloud)
+
+// Get returns the cloud provider named name, or nil if it is not built in
+func Get(name string) cloud.Cloud {
+	return cloudTable[name]
+}
+
+// List returns the sorted names of the cloud providers built in
+func List() []string {
+
+	var list []string
+
+	for name := range cloudTable {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+
+	return list
+}
+
+// NewProvider creates a provider of the cloud provider named name with config, a map from option names
+// or environment variable names to values, e.g. the data of the peer-pods-cm ConfigMap and the peer-pods-secret Secret
+func NewProvider(name string, config map[string]string) (cloud.Provider, error) {
+
+	c := Get(name)
+	if c == nil {
+		return nil, fmt.Errorf("cloudmgr: %s cloud provider not supported", name)
+	}
+
+	provider, err := c.NewProviderFromConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("cloudmgr: creating %s cloud provider: %w", name, err)
+	}
+
+	return provider, nil
+}
@@ -0,0 +1,98 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package cloudmgr
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/cloudinit"
+)
+
+type mockProvider struct {
+	region string
+}
+
+func (p *mockProvider) CreateInstance(ctx context.Context, podName, sandboxID string, cloudConfig cloudinit.CloudConfigGenerator, spec cloud.InstanceTypeSpec) (*cloud.Instance, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (p *mockProvider) DeleteInstance(ctx context.Context, instanceID string) error {
+	return nil
+}
+
+func (p *mockProvider) ListInstances(ctx context.Context) ([]*cloud.Instance, error) {
+	return nil, nil
+}
+
+func (p *mockProvider) Teardown() error {
+	return nil
+}
+
+func (p *mockProvider) ConfigVerifier() error {
+	return nil
+}
+
+type mockCloud struct{}
+
+func (*mockCloud) ParseCmd(flags *flag.FlagSet) {}
+
+func (*mockCloud) LoadEnv() {}
+
+func (*mockCloud) NewProvider() (cloud.Provider, error) {
+	return &mockProvider{}, nil
+}
+
+func (*mockCloud) NewProviderFromConfig(config map[string]string) (cloud.Provider, error) {
+
+	region := config["MOCK_REGION"]
+	if region == "" {
+		return nil, errors.New("MOCK_REGION is not set")
+	}
+
+	return &mockProvider{region: region}, nil
+}
+
+func withMockClouds(t *testing.T, names ...string) {
+
+	saved := cloudTable
+	cloudTable = make(map[string]cloud.Cloud)
+	for _, name := range names {
+		cloudTable[name] = &mockCloud{}
+	}
+
+	t.Cleanup(func() {
+		cloudTable = saved
+	})
+}
+
+func TestGet(t *testing.T) {
+
+	withMockClouds(t, "mock2", "mock1")
+
+	assert.NotNil(t, Get("mock1"))
+	assert.Nil(t, Get("unknown"))
+	assert.Equal(t, []string{"mock1", "mock2"}, List())
+}
+
+func TestNewProvider(t *testing.T) {
+
+	withMockClouds(t, "mock")
+
+	provider, err := NewProvider("mock", map[string]string{"MOCK_REGION": "us-east-1"})
+	require.NoError(t, err)
+	assert.Equal(t, "us-east-1", provider.(*mockProvider).region)
+
+	_, err = NewProvider("mock", map[string]string{})
+	assert.ErrorContains(t, err, "MOCK_REGION is not set")
+
+	_, err = NewProvider("unknown", map[string]string{})
+	assert.ErrorContains(t, err, "unknown cloud provider not supported")
+}
@@ -0,0 +1,14 @@
+//go:build libvirt && cgo
+
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package cloudmgr
+
+import (
+	provider "github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud/libvirt"
+)
+
+func init() {
+	cloudTable["libvirt"] = &provider.Manager{}
+}
@@ -0,0 +1,45 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package external
+
+import (
+	"flag"
+	"time"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/cloudplugin"
+)
+
+var externalcfg Config
+
+type Manager struct{}
+
+func (*Manager) ParseCmd(flags *flag.FlagSet) {
+	parseCmd(flags, &externalcfg)
+}
+
+func parseCmd(flags *flag.FlagSet, cfg *Config) {
+
+	flags.StringVar(&cfg.PluginPath, "plugin-path", "", "Path of a cloud provider plugin binary to launch. If empty, connect to a plugin that is already running")
+	flags.StringVar(&cfg.SocketPath, "plugin-socket", cloudplugin.DefaultSocketPath, "Unix socket of a cloud provider plugin")
+	flags.DurationVar(&cfg.StartTimeout, "plugin-timeout", time.Minute, "Time to wait for a cloud provider plugin to accept connections")
+}
+
+func (*Manager) LoadEnv() {
+	// No environment variables required. A launched plugin inherits the environment of cloud-api-adaptor
+}
+
+func (*Manager) NewProvider() (cloud.Provider, error) {
+	return NewProvider(&externalcfg)
+}
+
+func (*Manager) NewProviderFromConfig(config map[string]string) (cloud.Provider, error) {
+
+	var cfg Config
+	if err := cloud.LoadConfig(config, func(flags *flag.FlagSet) { parseCmd(flags, &cfg) }, func(cloud.Getenv) {}); err != nil {
+		return nil, err
+	}
+
+	return NewProvider(&cfg)
+}
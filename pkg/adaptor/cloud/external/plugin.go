@@ -0,0 +1,206 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package external
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/cloudplugin"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/logging"
+)
+
+const (
+	pluginStopTimeout = 10 * time.Second
+
+	// A crashed plugin is restarted after pluginRestartBackoff, which doubles at every consecutive crash.
+	// A plugin that runs for pluginStableDuration is considered healthy again.
+	pluginRestartBackoff = time.Second
+	pluginMaxRestarts    = 5
+	pluginStableDuration = time.Minute
+)
+
+// pluginProcess is a plugin binary launched by cloud-api-adaptor
+type pluginProcess struct {
+	path           string
+	socketPath     string
+	restartBackoff time.Duration
+
+	mutex     sync.Mutex
+	cmd       *exec.Cmd
+	startedAt time.Time
+	exitCh    chan struct{}
+	err       error
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// startPlugin launches a plugin binary that listens on socketPath.
+// The plugin inherits the environment variables, standard output and standard error of cloud-api-adaptor.
+func startPlugin(path, socketPath string) (*pluginProcess, error) {
+
+	p := &pluginProcess{
+		path:           path,
+		socketPath:     socketPath,
+		restartBackoff: pluginRestartBackoff,
+		stopCh:         make(chan struct{}),
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if err := p.start(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// start launches the plugin binary. p.mutex must be held.
+func (p *pluginProcess) start() error {
+
+	cmd := exec.Command(p.path)
+	cmd.Env = append(os.Environ(), cloudplugin.SocketEnv+"="+p.socketPath)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to launch plugin %s: %w", p.path, err)
+	}
+
+	logger.Info("launched cloud provider plugin", "path", p.path, "pid", cmd.Process.Pid)
+
+	exitCh := make(chan struct{})
+
+	p.cmd = cmd
+	p.startedAt = time.Now()
+	p.exitCh = exitCh
+	p.err = nil
+
+	go func() {
+		err := cmd.Wait()
+		if err != nil {
+			logger.Error("cloud provider plugin exited", "path", p.path, logging.KeyError, err)
+		} else {
+			logger.Info("cloud provider plugin exited", "path", p.path)
+		}
+
+		p.mutex.Lock()
+		if p.cmd == cmd {
+			p.err = err
+		}
+		p.mutex.Unlock()
+
+		close(exitCh)
+	}()
+
+	return nil
+}
+
+// supervise restarts the plugin when it exits until the plugin is stopped. When the plugin crashes
+// more than pluginMaxRestarts times in a row, supervise gives up and calls failed.
+func (p *pluginProcess) supervise(failed func(err error)) {
+
+	go func() {
+		var crashes int
+
+		for {
+			p.mutex.Lock()
+			exitCh, startedAt := p.exitCh, p.startedAt
+			p.mutex.Unlock()
+
+			select {
+			case <-p.stopCh:
+				return
+			case <-exitCh:
+			}
+
+			if time.Since(startedAt) >= pluginStableDuration {
+				crashes = 0
+			}
+			crashes++
+
+			if crashes > pluginMaxRestarts {
+				failed(fmt.Errorf("cloud provider plugin %s exited %d times in a row", p.path, crashes))
+				return
+			}
+
+			backoff := p.restartBackoff << (crashes - 1)
+			logger.Warn("restarting cloud provider plugin", "path", p.path, "backoff", backoff)
+
+			select {
+			case <-p.stopCh:
+				return
+			case <-time.After(backoff):
+			}
+
+			p.mutex.Lock()
+			select {
+			case <-p.stopCh:
+				p.mutex.Unlock()
+				return
+			default:
+			}
+			err := p.start()
+			if err != nil {
+				// Count a failed launch as a crash of a plugin that exited immediately
+				p.startedAt = time.Now()
+			}
+			p.mutex.Unlock()
+
+			if err != nil {
+				logger.Error("failed to restart cloud provider plugin", logging.KeyError, err)
+			}
+		}
+	}()
+}
+
+// exited returns a channel that is closed when the current plugin process exits
+func (p *pluginProcess) exited() <-chan struct{} {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.exitCh
+}
+
+// stop terminates the plugin, and kills it if it does not exit in time. A stopped plugin is not restarted.
+func (p *pluginProcess) stop() error {
+
+	p.mutex.Lock()
+	p.stopOnce.Do(func() {
+		close(p.stopCh)
+	})
+	cmd, exitCh := p.cmd, p.exitCh
+	p.mutex.Unlock()
+
+	select {
+	case <-exitCh:
+		return nil
+	default:
+	}
+
+	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return fmt.Errorf("failed to terminate plugin: %w", err)
+	}
+
+	select {
+	case <-exitCh:
+		return nil
+	case <-time.After(pluginStopTimeout):
+	}
+
+	logger.Warn("cloud provider plugin did not exit in time. killing it", "timeout", pluginStopTimeout)
+
+	if err := cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return fmt.Errorf("failed to kill plugin: %w", err)
+	}
+	<-exitCh
+
+	return nil
+}
@@ -0,0 +1,315 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package external
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/cloudplugin"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/cloudinit"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/logging"
+	pb "github.com/confidential-containers/cloud-api-adaptor/proto/cloudprovider/v1"
+)
+
+const (
+	verifyTimeout   = time.Minute
+	teardownTimeout = time.Minute
+)
+
+var logger = logging.New("adaptor/cloud/external")
+
+// pluginFailed is called when a launched plugin keeps crashing. cloud-api-adaptor exits,
+// so that it is restarted by Kubernetes instead of failing every request.
+var pluginFailed = func(err error) {
+	logger.Fatalf("giving up restarting the plugin: %v", err)
+}
+
+// externalProvider is a cloud provider implemented by a plugin over the plugin protocol
+type externalProvider struct {
+	name   string
+	conn   *grpc.ClientConn
+	client pb.CloudProviderClient
+	plugin *pluginProcess
+	// supportedOverrides are the override annotations the plugin applies, as reported by its handshake
+	supportedOverrides []string
+	// placements are the alternate placements of the plugin, as reported by its handshake
+	placements []string
+	// supportsSpot is true if the plugin creates instances on spot capacity, as reported by its handshake
+	supportsSpot bool
+}
+
+func NewProvider(config *Config) (cloud.Provider, error) {
+
+	logger.Info("external config", "config", config)
+
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
+	var plugin *pluginProcess
+	if config.PluginPath != "" {
+		var err error
+		if plugin, err = startPlugin(config.PluginPath, config.SocketPath); err != nil {
+			return nil, err
+		}
+	}
+
+	provider, err := connect(config, plugin)
+	if err != nil {
+		if plugin != nil {
+			if e := plugin.stop(); e != nil {
+				logger.Warn("failed to stop cloud provider plugin", logging.KeyError, e)
+			}
+		}
+		return nil, err
+	}
+
+	if plugin != nil {
+		plugin.supervise(pluginFailed)
+	}
+
+	return provider, nil
+}
+
+// connect connects to a plugin on the socket, and checks its protocol version
+func connect(config *Config, plugin *pluginProcess) (*externalProvider, error) {
+
+	conn, err := grpc.Dial("unix:"+config.SocketPath, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to plugin socket %s: %w", config.SocketPath, err)
+	}
+
+	client := pb.NewCloudProviderClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.StartTimeout)
+	defer cancel()
+
+	if plugin != nil {
+		// Stop waiting if the plugin exits before it accepts connections
+		go func() {
+			select {
+			case <-plugin.exited():
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	res, err := client.Handshake(ctx, &pb.HandshakeRequest{ProtocolVersion: cloudplugin.ProtocolVersion}, grpc.WaitForReady(true))
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to connect to plugin on %s: %w", config.SocketPath, err)
+	}
+	if res.ProtocolVersion != cloudplugin.ProtocolVersion {
+		conn.Close()
+		return nil, fmt.Errorf("%w: cloud-api-adaptor speaks version %d, plugin %s speaks version %d", cloudplugin.ErrProtocolVersion, cloudplugin.ProtocolVersion, res.Name, res.ProtocolVersion)
+	}
+
+	logger.Info("connected to cloud provider plugin", "plugin", res.Name, "protocol_version", res.ProtocolVersion)
+
+	return &externalProvider{
+		name:               res.Name,
+		conn:               conn,
+		client:             client,
+		plugin:             plugin,
+		supportedOverrides: res.SupportedOverrides,
+		placements:         res.Placements,
+		supportsSpot:       res.SupportsSpot,
+	}, nil
+}
+
+func (p *externalProvider) CreateInstance(ctx context.Context, podName, sandboxID string, cloudConfig cloudinit.CloudConfigGenerator, spec cloud.InstanceTypeSpec) (*cloud.Instance, error) {
+
+	logger := logger.WithContext(ctx)
+
+	userData, err := cloudConfig.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := p.client.CreateInstance(ctx, &pb.CreateInstanceRequest{
+		PodName:   podName,
+		SandboxID: sandboxID,
+		UserData:  userData,
+		Spec: &pb.InstanceTypeSpec{
+			InstanceType: spec.InstanceType,
+			VCPUs:        spec.VCPUs,
+			Memory:       spec.Memory,
+			Arch:         spec.Arch,
+			GPUs:         spec.GPUs,
+			GPUModel:     spec.GPUModel,
+			Placement:    spec.Placement,
+
+			ExcludedInstanceTypes: spec.ExcludedInstanceTypes,
+
+			Spot:         spec.Spot,
+			SpotMaxPrice: spec.SpotMaxPrice,
+
+			ImageID:          spec.ImageID,
+			SubnetID:         spec.SubnetID,
+			SecurityGroupIDs: spec.SecurityGroupIDs,
+			Tags:             spec.Tags,
+		},
+	})
+	if err != nil {
+		logger.Errorf("plugin %s failed to create an instance: %v", p.name, err)
+		return nil, fromStatus(fmt.Errorf("plugin %s: creating an instance: %w", p.name, err), spec)
+	}
+
+	instance, err := fromProto(res.Instance)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: creating an instance: %w", p.name, err)
+	}
+	if len(instance.IPs) == 0 {
+		return nil, fmt.Errorf("plugin %s: instance %s has no IP address", p.name, instance.ID)
+	}
+
+	logger.Infof("plugin %s created an instance %s for sandbox %s", p.name, instance.ID, sandboxID)
+
+	return instance, nil
+}
+
+// SupportedOverrides returns the override annotations that the plugin applies
+func (p *externalProvider) SupportedOverrides() []string {
+	return p.supportedOverrides
+}
+
+// Placements returns the alternate placements of the plugin
+func (p *externalProvider) Placements() []string {
+	return p.placements
+}
+
+// SupportsSpot returns true if the plugin creates instances on spot capacity
+func (p *externalProvider) SupportsSpot() bool {
+	return p.supportsSpot
+}
+
+func (p *externalProvider) DeleteInstance(ctx context.Context, instanceID string) error {
+
+	logger := logger.WithContext(ctx)
+
+	if _, err := p.client.DeleteInstance(ctx, &pb.DeleteInstanceRequest{InstanceID: instanceID}); err != nil {
+		logger.Errorf("plugin %s failed to delete an instance %s: %v", p.name, instanceID, err)
+		return fmt.Errorf("plugin %s: deleting an instance %s: %w", p.name, instanceID, err)
+	}
+
+	logger.Infof("plugin %s deleted an instance %s", p.name, instanceID)
+
+	return nil
+}
+
+func (p *externalProvider) ListInstances(ctx context.Context) ([]*cloud.Instance, error) {
+
+	res, err := p.client.ListInstances(ctx, &pb.ListInstancesRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: listing instances: %w", p.name, err)
+	}
+
+	var instances []*cloud.Instance
+	for _, i := range res.Instances {
+		instance, err := fromProto(i)
+		if err != nil {
+			return nil, fmt.Errorf("plugin %s: listing instances: %w", p.name, err)
+		}
+		instances = append(instances, instance)
+	}
+
+	return instances, nil
+}
+
+func (p *externalProvider) ConfigVerifier() error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), verifyTimeout)
+	defer cancel()
+
+	if _, err := p.client.VerifyConfig(ctx, &pb.VerifyConfigRequest{}); err != nil {
+		return fmt.Errorf("plugin %s: verifying config: %w", p.name, err)
+	}
+	return nil
+}
+
+func (p *externalProvider) Teardown() error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), teardownTimeout)
+	defer cancel()
+
+	var errs []error
+
+	if _, err := p.client.Teardown(ctx, &pb.TeardownRequest{}); err != nil {
+		errs = append(errs, fmt.Errorf("plugin %s: tearing down: %w", p.name, err))
+	}
+
+	if err := p.conn.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("closing connection to plugin %s: %w", p.name, err))
+	}
+
+	if p.plugin != nil {
+		if err := p.plugin.stop(); err != nil {
+			errs = append(errs, fmt.Errorf("stopping plugin %s: %w", p.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// fromStatus returns a capacity error for a ResourceExhausted error of a plugin, or err otherwise.
+// Plugins that do not report the instance type and placement that failed are assumed to have failed with spec.
+func fromStatus(err error, spec cloud.InstanceTypeSpec) error {
+
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.ResourceExhausted {
+		return err
+	}
+
+	capacityErr := &cloud.CapacityError{
+		Cause:        cloud.ErrInsufficientCapacity,
+		InstanceType: spec.InstanceType,
+		Placement:    spec.Placement,
+		Err:          err,
+	}
+	for _, detail := range st.Details() {
+		if d, ok := detail.(*pb.CapacityError); ok {
+			if d.QuotaExceeded {
+				capacityErr.Cause = cloud.ErrQuotaExceeded
+			}
+			capacityErr.InstanceType = d.InstanceType
+			capacityErr.Placement = d.Placement
+		}
+	}
+	return capacityErr
+}
+
+func fromProto(instance *pb.Instance) (*cloud.Instance, error) {
+
+	if instance == nil {
+		return nil, errors.New("instance is missing in a response")
+	}
+
+	var ips []netip.Addr
+	for _, s := range instance.IPs {
+		ip, err := netip.ParseAddr(s)
+		if err != nil {
+			return nil, fmt.Errorf("instance %s has an invalid IP address %q: %w", instance.ID, s, err)
+		}
+		ips = append(ips, ip)
+	}
+
+	return &cloud.Instance{
+		ID:           instance.ID,
+		Name:         instance.Name,
+		IPs:          ips,
+		InstanceType: instance.InstanceType,
+		Zone:         instance.Zone,
+	}, nil
+}
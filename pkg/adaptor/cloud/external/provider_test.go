@@ -0,0 +1,367 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package external
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud"
+	peerpodannotations "github.com/confidential-containers/cloud-api-adaptor/pkg/annotations"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/cloudplugin"
+)
+
+// TestMain runs the test binary as a plugin when it is launched by startPlugin
+func TestMain(m *testing.M) {
+
+	if os.Getenv(cloudplugin.SocketEnv) != "" {
+		if err := cloudplugin.Serve("test", &stubProvider{}); err != nil {
+			fmt.Fprintf(os.Stderr, "plugin failed: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+type stubProvider struct {
+	mutex      sync.Mutex
+	userData   string
+	spec       cloudplugin.InstanceTypeSpec
+	instances  map[string]*cloudplugin.Instance
+	noIP       bool
+	overrides  []string
+	placements []string
+	createErr  error
+	spot       bool
+}
+
+func (p *stubProvider) CreateInstance(ctx context.Context, podName, sandboxID, userData string, spec cloudplugin.InstanceTypeSpec) (*cloudplugin.Instance, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.userData = userData
+	p.spec = spec
+	if p.createErr != nil {
+		return nil, p.createErr
+	}
+
+	instance := &cloudplugin.Instance{
+		ID:           "i-" + sandboxID,
+		Name:         "podvm-" + podName + "-" + sandboxID,
+		InstanceType: spec.InstanceType,
+		Zone:         "zone-1",
+	}
+	if !p.noIP {
+		instance.IPs = []netip.Addr{netip.MustParseAddr("192.0.2.1")}
+	}
+	if p.instances == nil {
+		p.instances = make(map[string]*cloudplugin.Instance)
+	}
+	p.instances[instance.ID] = instance
+
+	return instance, nil
+}
+
+func (p *stubProvider) DeleteInstance(ctx context.Context, instanceID string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if _, ok := p.instances[instanceID]; !ok {
+		return fmt.Errorf("instance %s is not found", instanceID)
+	}
+	delete(p.instances, instanceID)
+	return nil
+}
+
+func (p *stubProvider) ListInstances(ctx context.Context) ([]*cloudplugin.Instance, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var instances []*cloudplugin.Instance
+	for _, instance := range p.instances {
+		instances = append(instances, instance)
+	}
+	return instances, nil
+}
+
+func (p *stubProvider) SupportedOverrides() []string {
+	return p.overrides
+}
+
+func (p *stubProvider) Placements() []string {
+	return p.placements
+}
+
+func (p *stubProvider) SupportsSpot() bool {
+	return p.spot
+}
+
+func (p *stubProvider) Teardown() error {
+	return nil
+}
+
+func (p *stubProvider) ConfigVerifier() error {
+	return nil
+}
+
+type userData string
+
+func (d userData) Generate() (string, error) {
+	return string(d), nil
+}
+
+// servePlugin runs provider as a plugin in the test process
+func servePlugin(t *testing.T, provider cloudplugin.Provider) string {
+
+	socketPath := filepath.Join(t.TempDir(), "plugin.sock")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- cloudplugin.ServeContext(ctx, "stub", socketPath, provider)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, <-errCh)
+	})
+
+	return socketPath
+}
+
+func TestProvider(t *testing.T) {
+
+	ctx := context.Background()
+	stub := &stubProvider{}
+	socketPath := servePlugin(t, stub)
+
+	provider, err := NewProvider(&Config{SocketPath: socketPath, StartTimeout: 10 * time.Second})
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, provider.Teardown())
+	}()
+
+	require.NoError(t, provider.ConfigVerifier())
+
+	spec := cloud.InstanceTypeSpec{InstanceType: "small", VCPUs: 2, Memory: 2048, Arch: "amd64", GPUs: 1, GPUModel: "nvidia-tesla-t4"}
+	instance, err := provider.CreateInstance(ctx, "nginx", "0123456789", userData("#cloud-config\n"), spec)
+	require.NoError(t, err)
+	assert.Equal(t, "i-0123456789", instance.ID)
+	assert.Equal(t, "podvm-nginx-0123456789", instance.Name)
+	assert.Equal(t, []netip.Addr{netip.MustParseAddr("192.0.2.1")}, instance.IPs)
+	assert.Equal(t, "small", instance.InstanceType)
+	assert.Equal(t, "zone-1", instance.Zone)
+	assert.Equal(t, "#cloud-config\n", stub.userData)
+	assert.Equal(t, cloudplugin.InstanceTypeSpec{InstanceType: "small", VCPUs: 2, Memory: 2048, Arch: "amd64", GPUs: 1, GPUModel: "nvidia-tesla-t4"}, stub.spec)
+
+	instances, err := provider.ListInstances(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*cloud.Instance{instance}, instances)
+
+	require.NoError(t, provider.DeleteInstance(ctx, instance.ID))
+	err = provider.DeleteInstance(ctx, instance.ID)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is not found")
+}
+
+func TestProviderOverrides(t *testing.T) {
+
+	ctx := context.Background()
+	stub := &stubProvider{overrides: []string{peerpodannotations.ImageID, peerpodannotations.SubnetID}}
+	socketPath := servePlugin(t, stub)
+
+	provider, err := NewProvider(&Config{SocketPath: socketPath, StartTimeout: 10 * time.Second})
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, provider.Teardown())
+	}()
+
+	overrideProvider, ok := provider.(cloud.OverrideProvider)
+	require.True(t, ok)
+	assert.Equal(t, []string{peerpodannotations.ImageID, peerpodannotations.SubnetID}, overrideProvider.SupportedOverrides())
+
+	spec := cloud.InstanceTypeSpec{InstanceType: "small", ImageID: "image-1", SubnetID: "subnet-1"}
+	_, err = provider.CreateInstance(ctx, "nginx", "0123456789", userData("#cloud-config\n"), spec)
+	require.NoError(t, err)
+	assert.Equal(t, cloudplugin.InstanceTypeSpec{InstanceType: "small", ImageID: "image-1", SubnetID: "subnet-1"}, stub.spec)
+}
+
+func TestProviderSpot(t *testing.T) {
+
+	ctx := context.Background()
+	stub := &stubProvider{spot: true}
+	socketPath := servePlugin(t, stub)
+
+	provider, err := NewProvider(&Config{SocketPath: socketPath, StartTimeout: 10 * time.Second})
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, provider.Teardown())
+	}()
+
+	spotProvider, ok := provider.(cloud.SpotProvider)
+	require.True(t, ok)
+	assert.True(t, spotProvider.SupportsSpot())
+
+	spec := cloud.InstanceTypeSpec{InstanceType: "small", Spot: true, SpotMaxPrice: 0.05}
+	_, err = provider.CreateInstance(ctx, "nginx", "0123456789", userData("#cloud-config\n"), spec)
+	require.NoError(t, err)
+	assert.Equal(t, cloudplugin.InstanceTypeSpec{InstanceType: "small", Spot: true, SpotMaxPrice: 0.05}, stub.spec)
+}
+
+func TestProviderCapacityError(t *testing.T) {
+
+	ctx := context.Background()
+	stub := &stubProvider{
+		placements: []string{"zone-2"},
+		createErr:  &cloudplugin.CapacityError{InstanceType: "large", Placement: "zone-2", Err: errors.New("no capacity")},
+	}
+	socketPath := servePlugin(t, stub)
+
+	provider, err := NewProvider(&Config{SocketPath: socketPath, StartTimeout: 10 * time.Second})
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, provider.Teardown())
+	}()
+
+	lister, ok := provider.(cloud.PlacementLister)
+	require.True(t, ok)
+	assert.Equal(t, []string{"zone-2"}, lister.Placements())
+
+	spec := cloud.InstanceTypeSpec{VCPUs: 4, Placement: "zone-2", ExcludedInstanceTypes: []string{"xlarge"}}
+	_, err = provider.CreateInstance(ctx, "nginx", "0123456789", userData("#cloud-config\n"), spec)
+	var capacityErr *cloud.CapacityError
+	require.ErrorAs(t, err, &capacityErr)
+	assert.ErrorIs(t, err, cloud.ErrInsufficientCapacity)
+	assert.Equal(t, "large", capacityErr.InstanceType)
+	assert.Equal(t, "zone-2", capacityErr.Placement)
+	assert.Equal(t, cloudplugin.InstanceTypeSpec{VCPUs: 4, Placement: "zone-2", ExcludedInstanceTypes: []string{"xlarge"}}, stub.spec)
+
+	// Plugins that report no details fail with the instance type and placement of the spec
+	stub.createErr = status.Error(codes.ResourceExhausted, "quota exceeded")
+	_, err = provider.CreateInstance(ctx, "nginx", "0123456789", userData("#cloud-config\n"), cloud.InstanceTypeSpec{InstanceType: "small"})
+	require.ErrorAs(t, err, &capacityErr)
+	assert.Equal(t, "small", capacityErr.InstanceType)
+
+	// Other errors are not capacity errors
+	stub.createErr = errors.New("invalid image")
+	_, err = provider.CreateInstance(ctx, "nginx", "0123456789", userData("#cloud-config\n"), spec)
+	require.Error(t, err)
+	assert.False(t, errors.As(err, &capacityErr))
+}
+
+func TestProviderNoIP(t *testing.T) {
+
+	socketPath := servePlugin(t, &stubProvider{noIP: true})
+
+	provider, err := NewProvider(&Config{SocketPath: socketPath, StartTimeout: 10 * time.Second})
+	require.NoError(t, err)
+	defer provider.Teardown()
+
+	_, err = provider.CreateInstance(context.Background(), "nginx", "0123456789", userData(""), cloud.InstanceTypeSpec{})
+	assert.ErrorContains(t, err, "has no IP address")
+}
+
+func TestLaunchPlugin(t *testing.T) {
+
+	executable, err := os.Executable()
+	require.NoError(t, err)
+
+	socketPath := filepath.Join(t.TempDir(), "plugin.sock")
+
+	provider, err := NewProvider(&Config{PluginPath: executable, SocketPath: socketPath, StartTimeout: 30 * time.Second})
+	require.NoError(t, err)
+
+	plugin := provider.(*externalProvider).plugin
+	require.NotNil(t, plugin)
+	assert.Equal(t, "test", provider.(*externalProvider).name)
+
+	instance, err := provider.CreateInstance(context.Background(), "nginx", "0123456789", userData(""), cloud.InstanceTypeSpec{})
+	require.NoError(t, err)
+	assert.Equal(t, "i-0123456789", instance.ID)
+
+	require.NoError(t, provider.Teardown())
+
+	select {
+	case <-plugin.exited():
+	default:
+		t.Fatal("plugin is still running after teardown")
+	}
+	assert.NoError(t, plugin.err)
+}
+
+func TestLaunchPluginExit(t *testing.T) {
+
+	// A plugin that exits immediately does not block until the timeout expires
+	start := time.Now()
+	_, err := NewProvider(&Config{PluginPath: "/bin/false", SocketPath: filepath.Join(t.TempDir(), "plugin.sock"), StartTimeout: time.Minute})
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), time.Minute)
+}
+
+func TestLaunchPluginRestart(t *testing.T) {
+
+	executable, err := os.Executable()
+	require.NoError(t, err)
+
+	socketPath := filepath.Join(t.TempDir(), "plugin.sock")
+
+	provider, err := NewProvider(&Config{PluginPath: executable, SocketPath: socketPath, StartTimeout: 30 * time.Second})
+	require.NoError(t, err)
+	defer provider.Teardown()
+
+	plugin := provider.(*externalProvider).plugin
+	exited := plugin.exited()
+
+	// A crashed plugin is restarted, and the connection is re-established
+	plugin.mutex.Lock()
+	require.NoError(t, plugin.cmd.Process.Kill())
+	plugin.mutex.Unlock()
+	<-exited
+
+	require.Eventually(t, func() bool {
+		_, err := provider.CreateInstance(context.Background(), "nginx", "0123456789", userData(""), cloud.InstanceTypeSpec{})
+		return err == nil
+	}, 30*time.Second, 100*time.Millisecond)
+}
+
+func TestSupervisePluginGiveUp(t *testing.T) {
+
+	plugin, err := startPlugin("/bin/false", filepath.Join(t.TempDir(), "plugin.sock"))
+	require.NoError(t, err)
+	plugin.restartBackoff = time.Millisecond
+
+	failed := make(chan error, 1)
+	plugin.supervise(func(err error) {
+		failed <- err
+	})
+
+	select {
+	case err := <-failed:
+		assert.ErrorContains(t, err, "exited 6 times in a row")
+	case <-time.After(10 * time.Second):
+		t.Fatal("supervisor keeps restarting a crashing plugin")
+	}
+	assert.NoError(t, plugin.stop())
+}
+
+func TestConfigValidate(t *testing.T) {
+
+	assert.Error(t, (&Config{StartTimeout: time.Second}).validate())
+	assert.Error(t, (&Config{SocketPath: "/run/plugin.sock"}).validate())
+	assert.NoError(t, (&Config{SocketPath: "/run/plugin.sock", StartTimeout: time.Second}).validate())
+}
@@ -0,0 +1,30 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package external
+
+import (
+	"errors"
+	"time"
+)
+
+type Config struct {
+	// PluginPath is the path of a plugin binary launched by cloud-api-adaptor.
+	// If empty, cloud-api-adaptor connects to a plugin that is already running.
+	PluginPath string
+	// SocketPath is the path of the unix socket the plugin listens on
+	SocketPath string
+	// StartTimeout is the time to wait for the plugin to accept connections
+	StartTimeout time.Duration
+}
+
+func (c *Config) validate() error {
+
+	if c.SocketPath == "" {
+		return errors.New("plugin socket path is not specified")
+	}
+	if c.StartTimeout <= 0 {
+		return errors.New("plugin timeout must be positive")
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package fake
+
+import (
+	"flag"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/forwarder"
+)
+
+var fakecfg Config
+
+type Manager struct{}
+
+func (*Manager) ParseCmd(flags *flag.FlagSet) {
+	parseCmd(flags, &fakecfg)
+}
+
+func parseCmd(flags *flag.FlagSet, cfg *Config) {
+
+	flags.StringVar(&cfg.ForwarderPort, "podvm-port", forwarder.DefaultListenPort, "Port of agent-protocol-forwarder in fake pod VMs. Must be the same as -forwarder-port")
+	flags.StringVar(&cfg.DataDir, "data-dir", "", "Directory for agent sockets and configs of fake pod VMs. Defaults to a temporary directory")
+	flags.DurationVar(&cfg.CreateLatency, "create-latency", 0, "Time to create a fake pod VM instance")
+	flags.DurationVar(&cfg.BootLatency, "boot-latency", 0, "Time until agent-protocol-forwarder of a fake pod VM accepts connections")
+	flags.DurationVar(&cfg.DeleteLatency, "delete-latency", 0, "Time to delete a fake pod VM instance")
+	flags.Float64Var(&cfg.CreateFailureRate, "create-failure-rate", 0, "Probability that creation of a fake pod VM instance fails, from 0 to 1")
+	flags.Float64Var(&cfg.DeleteFailureRate, "delete-failure-rate", 0, "Probability that deletion of a fake pod VM instance fails, from 0 to 1")
+}
+
+func (*Manager) LoadEnv() {
+	// No environment variables required
+}
+
+func (*Manager) NewProvider() (cloud.Provider, error) {
+	return NewProvider(&fakecfg)
+}
+
+func (*Manager) NewProviderFromConfig(config map[string]string) (cloud.Provider, error) {
+
+	var cfg Config
+	if err := cloud.LoadConfig(config, func(flags *flag.FlagSet) { parseCmd(flags, &cfg) }, func(cloud.Getenv) {}); err != nil {
+		return nil, err
+	}
+
+	return NewProvider(&cfg)
+}
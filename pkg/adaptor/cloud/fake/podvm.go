@@ -0,0 +1,154 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package fake
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/forwarder"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/forwarder/interceptor"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/agentproto/agenttest"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/logging"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/tlsutil"
+)
+
+const agentSocketName = "agent.sock"
+
+// podVM is a pod VM that runs agent-protocol-forwarder and a stub kata agent in goroutines
+type podVM struct {
+	id     string
+	name   string
+	ip     netip.Addr
+	dir    string
+	agent  *agenttest.Agent
+	cancel context.CancelFunc
+	doneCh chan struct{}
+}
+
+func newPodVM(id, name string, ip netip.Addr, dir string) *podVM {
+	return &podVM{
+		id:     id,
+		name:   name,
+		ip:     ip,
+		dir:    dir,
+		doneCh: make(chan struct{}),
+	}
+}
+
+func (vm *podVM) instance() *cloud.Instance {
+	return &cloud.Instance{
+		ID:   vm.id,
+		Name: vm.name,
+		IPs:  []netip.Addr{vm.ip},
+	}
+}
+
+// boot starts a stub kata agent, and starts agent-protocol-forwarder after bootLatency.
+// files are the files written by cloud-init, which contain either a daemon config or a bootstrap config.
+func (vm *podVM) boot(files map[string]string, port string, bootLatency time.Duration) error {
+
+	agentSocket := filepath.Join(vm.dir, agentSocketName)
+
+	agent, err := agenttest.Start(agentSocket)
+	if err != nil {
+		return fmt.Errorf("starting a stub kata agent of instance %s: %w", vm.id, err)
+	}
+	vm.agent = agent
+
+	ctx, cancel := context.WithCancel(context.Background())
+	vm.cancel = cancel
+
+	go func() {
+		defer close(vm.doneCh)
+
+		if err := vm.run(ctx, files, net.JoinHostPort(vm.ip.String(), port), agentSocket, bootLatency); err != nil {
+			logger.Error("fake pod VM stopped with error", logging.KeyInstanceID, vm.id, logging.KeyError, err)
+		}
+	}()
+
+	return nil
+}
+
+func (vm *podVM) run(ctx context.Context, files map[string]string, listenAddr, agentSocket string, bootLatency time.Duration) error {
+
+	if err := sleep(ctx, bootLatency); err != nil {
+		return nil
+	}
+
+	daemonJSON := files[forwarder.DefaultConfigPath]
+
+	if daemonJSON == "" {
+		// A pre-provisioned pod VM waits for its daemon config
+		var bootstrapConfig forwarder.BootstrapConfig
+		if err := json.Unmarshal([]byte(files[forwarder.DefaultBootstrapConfigPath]), &bootstrapConfig); err != nil {
+			return fmt.Errorf("decoding a bootstrap config: %w", err)
+		}
+
+		configPath := filepath.Join(vm.dir, "daemon.json")
+		if err := forwarder.Bootstrap(ctx, &bootstrapConfig, listenAddr, tlsConfigFor(bootstrapConfig.TLSServerCert), configPath); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to receive a daemon config: %w", err)
+		}
+
+		data, err := os.ReadFile(configPath)
+		if err != nil {
+			return err
+		}
+		daemonJSON = string(data)
+	}
+
+	var daemonConfig forwarder.Config
+	if err := json.Unmarshal([]byte(daemonJSON), &daemonConfig); err != nil {
+		return fmt.Errorf("decoding a daemon config: %w", err)
+	}
+
+	daemon := forwarder.NewDaemon(&daemonConfig, listenAddr, tlsConfigFor(daemonConfig.TLSServerCert), interceptor.NewInterceptor(agentSocket, ""), &podNode{})
+
+	return daemon.Start(ctx)
+}
+
+// shutdown stops the forwarder and the stub kata agent
+func (vm *podVM) shutdown() {
+
+	if vm.cancel == nil {
+		return
+	}
+
+	vm.cancel()
+	<-vm.doneCh
+
+	if err := vm.agent.Stop(); err != nil {
+		logger.Warnf("failed to stop a stub kata agent of instance %s: %v", vm.id, err)
+	}
+}
+
+// tlsConfigFor returns a TLS config of a forwarder that uses credentials in its daemon or bootstrap config.
+// TLS is disabled if cloud-api-adaptor does not issue a server certificate.
+func tlsConfigFor(serverCert string) *tlsutil.TLSConfig {
+	if serverCert == "" {
+		return nil
+	}
+	return &tlsutil.TLSConfig{}
+}
+
+// podNode does nothing, since fake pod VMs share the network namespace of cloud-api-adaptor
+type podNode struct{}
+
+func (n *podNode) Setup() error {
+	return nil
+}
+
+func (n *podNode) Teardown() error {
+	return nil
+}
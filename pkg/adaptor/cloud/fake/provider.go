@@ -0,0 +1,267 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package fake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/forwarder"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/cloudinit"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/logging"
+)
+
+var logger = logging.New("adaptor/cloud/fake")
+
+const maxInstanceNameLen = 63
+
+// ErrInjected is returned by a cloud operation that fails by failure injection
+var ErrInjected = errors.New("injected failure")
+
+// Pod VMs are assigned loopback addresses from firstIP, so that their forwarders can listen on the same port
+var firstIP = netip.MustParseAddr("127.0.0.2")
+
+type fakeProvider struct {
+	serviceConfig *Config
+	dataDir       string
+	tempDir       bool
+	instances     map[string]*podVM
+	nextID        int
+	rand          *rand.Rand
+	mutex         sync.Mutex
+}
+
+// NewProvider returns a cloud provider whose pod VMs run in the process of cloud-api-adaptor.
+// Each pod VM is an agent-protocol-forwarder daemon in front of a stub kata agent.
+func NewProvider(config *Config) (cloud.Provider, error) {
+
+	logger.Info("fake config", "config", config)
+
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
+	provider := &fakeProvider{
+		serviceConfig: config,
+		dataDir:       config.DataDir,
+		instances:     map[string]*podVM{},
+		rand:          rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+
+	if provider.dataDir == "" {
+		dir, err := os.MkdirTemp("", "fake-podvm-")
+		if err != nil {
+			return nil, fmt.Errorf("creating a data directory: %w", err)
+		}
+		provider.dataDir = dir
+		provider.tempDir = true
+	}
+
+	return provider, nil
+}
+
+func (p *fakeProvider) CreateInstance(ctx context.Context, podName, sandboxID string, cloudConfig cloudinit.CloudConfigGenerator, spec cloud.InstanceTypeSpec) (*cloud.Instance, error) {
+	logger := logger.WithContext(ctx)
+
+	if err := sleep(ctx, p.serviceConfig.CreateLatency); err != nil {
+		return nil, err
+	}
+
+	if p.fails(p.serviceConfig.CreateFailureRate) {
+		logger.Errorf("failed to create an instance: %v", ErrInjected)
+		return nil, ErrInjected
+	}
+
+	userData, err := cloudConfig.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := parseUserData(userData)
+	if err != nil {
+		return nil, err
+	}
+
+	vm, err := p.newPodVM(util.GenerateInstanceName(podName, sandboxID, maxInstanceNameLen))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := vm.boot(files, p.serviceConfig.ForwarderPort, p.serviceConfig.BootLatency); err != nil {
+		p.removePodVM(vm)
+		return nil, err
+	}
+
+	logger.Printf("created an instance %s for sandbox %s", vm.name, sandboxID)
+
+	return vm.instance(), nil
+}
+
+func (p *fakeProvider) DeleteInstance(ctx context.Context, instanceID string) error {
+	logger := logger.WithContext(ctx)
+
+	if err := sleep(ctx, p.serviceConfig.DeleteLatency); err != nil {
+		return err
+	}
+
+	if p.fails(p.serviceConfig.DeleteFailureRate) {
+		logger.Errorf("failed to delete an instance: %v", ErrInjected)
+		return ErrInjected
+	}
+
+	p.mutex.Lock()
+	vm, ok := p.instances[instanceID]
+	p.mutex.Unlock()
+
+	if !ok {
+		return fmt.Errorf("instance %s is not found", instanceID)
+	}
+
+	vm.shutdown()
+	p.removePodVM(vm)
+
+	logger.Printf("deleted an instance %s", instanceID)
+	return nil
+}
+
+func (p *fakeProvider) ListInstances(ctx context.Context) ([]*cloud.Instance, error) {
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var instances []*cloud.Instance
+	for _, vm := range p.instances {
+		instances = append(instances, vm.instance())
+	}
+
+	return instances, nil
+}
+
+func (p *fakeProvider) Teardown() error {
+
+	p.mutex.Lock()
+	var vms []*podVM
+	for _, vm := range p.instances {
+		vms = append(vms, vm)
+	}
+	p.mutex.Unlock()
+
+	for _, vm := range vms {
+		vm.shutdown()
+		p.removePodVM(vm)
+	}
+
+	if p.tempDir {
+		return os.RemoveAll(p.dataDir)
+	}
+	return nil
+}
+
+func (p *fakeProvider) ConfigVerifier() error {
+	return nil
+}
+
+func (p *fakeProvider) fails(rate float64) bool {
+
+	if rate <= 0 {
+		return false
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.rand.Float64() < rate
+}
+
+// newPodVM allocates an ID, a loopback address and a directory to a new pod VM
+func (p *fakeProvider) newPodVM(name string) (*podVM, error) {
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	inUse := map[netip.Addr]bool{}
+	for _, vm := range p.instances {
+		inUse[vm.ip] = true
+	}
+
+	ip := firstIP
+	for inUse[ip] {
+		ip = ip.Next()
+	}
+	if !ip.IsLoopback() {
+		return nil, errors.New("no loopback address is available")
+	}
+
+	p.nextID++
+	id := fmt.Sprintf("fake-%d", p.nextID)
+
+	dir := filepath.Join(p.dataDir, id)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("creating a directory of instance %s: %w", id, err)
+	}
+
+	vm := newPodVM(id, name, ip, dir)
+	p.instances[id] = vm
+
+	return vm, nil
+}
+
+func (p *fakeProvider) removePodVM(vm *podVM) {
+
+	p.mutex.Lock()
+	delete(p.instances, vm.id)
+	p.mutex.Unlock()
+
+	if err := os.RemoveAll(vm.dir); err != nil {
+		logger.Warnf("failed to remove %s: %v", vm.dir, err)
+	}
+}
+
+// parseUserData returns the contents of files written by cloud-init, indexed by their paths
+func parseUserData(userData string) (map[string]string, error) {
+
+	var cloudConfig cloudinit.CloudConfig
+	if err := yaml.Unmarshal([]byte(userData), &cloudConfig); err != nil {
+		return nil, fmt.Errorf("parsing user data: %w", err)
+	}
+
+	files := map[string]string{}
+	for _, file := range cloudConfig.WriteFiles {
+		files[file.Path] = file.Content
+	}
+
+	if files[forwarder.DefaultConfigPath] == "" && files[forwarder.DefaultBootstrapConfigPath] == "" {
+		return nil, fmt.Errorf("user data has neither %s nor %s", forwarder.DefaultConfigPath, forwarder.DefaultBootstrapConfigPath)
+	}
+
+	return files, nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
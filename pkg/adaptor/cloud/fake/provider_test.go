@@ -0,0 +1,250 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package fake
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/containerd/ttrpc"
+	pb "github.com/kata-containers/kata-containers/src/runtime/virtcontainers/pkg/agent/protocols/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/forwarder"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/agentproto/agenttest"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/cloudinit"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/tlsutil"
+)
+
+func newTestProvider(t *testing.T, config *Config) *fakeProvider {
+
+	config.DataDir = t.TempDir()
+	if config.ForwarderPort == "" {
+		config.ForwarderPort = freePort(t)
+	}
+
+	provider, err := NewProvider(config)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		assert.NoError(t, provider.Teardown())
+	})
+
+	return provider.(*fakeProvider)
+}
+
+func freePort(t *testing.T) string {
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+
+	return port
+}
+
+func userData(path string, v interface{}) cloudinit.CloudConfigGenerator {
+
+	data, _ := json.Marshal(v)
+
+	return &cloudinit.CloudConfig{
+		WriteFiles: []cloudinit.WriteFile{
+			{
+				Path:    path,
+				Content: string(data),
+			},
+		},
+	}
+}
+
+// dialForwarder connects to the forwarder of instance, which may be still booting
+func dialForwarder(t *testing.T, instance *cloud.Instance, port string) *ttrpc.Client {
+
+	var conn net.Conn
+	require.Eventually(t, func() bool {
+		var err error
+		conn, err = net.Dial("tcp", net.JoinHostPort(instance.IPs[0].String(), port))
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	client := ttrpc.NewClient(conn)
+	t.Cleanup(func() {
+		client.Close()
+	})
+
+	return client
+}
+
+func TestCreateDeleteInstance(t *testing.T) {
+
+	ctx := context.Background()
+	provider := newTestProvider(t, &Config{BootLatency: 50 * time.Millisecond})
+
+	daemonConfig := forwarder.Config{PodName: "nginx", PodNamespace: "default"}
+
+	instance1, err := provider.CreateInstance(ctx, "nginx", "0123456789", userData(forwarder.DefaultConfigPath, daemonConfig), cloud.InstanceTypeSpec{})
+	require.NoError(t, err)
+	require.Len(t, instance1.IPs, 1)
+	assert.True(t, instance1.IPs[0].IsLoopback())
+	assert.NotEmpty(t, instance1.ID)
+	assert.NotEmpty(t, instance1.Name)
+
+	instance2, err := provider.CreateInstance(ctx, "redis", "abcdefghij", userData(forwarder.DefaultConfigPath, daemonConfig), cloud.InstanceTypeSpec{})
+	require.NoError(t, err)
+	assert.NotEqual(t, instance1.ID, instance2.ID)
+	assert.NotEqual(t, instance1.IPs[0], instance2.IPs[0])
+
+	instances, err := provider.ListInstances(ctx)
+	require.NoError(t, err)
+	assert.Len(t, instances, 2)
+
+	client := dialForwarder(t, instance1, provider.serviceConfig.ForwarderPort)
+
+	res, err := pb.NewHealthClient(client).Check(ctx, &pb.CheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, pb.HealthCheckResponse_SERVING, res.Status)
+
+	agentClient := pb.NewAgentServiceClient(client)
+
+	_, err = agentClient.CreateSandbox(ctx, &pb.CreateSandboxRequest{SandboxId: "0123456789"})
+	assert.NoError(t, err)
+
+	require.NoError(t, provider.DeleteInstance(ctx, instance1.ID))
+	assert.Error(t, provider.DeleteInstance(ctx, instance1.ID))
+
+	instances, err = provider.ListInstances(ctx)
+	require.NoError(t, err)
+	require.Len(t, instances, 1)
+	assert.Equal(t, instance2.ID, instances[0].ID)
+
+	// The address of a deleted instance is reused
+	instance3, err := provider.CreateInstance(ctx, "nginx", "9876543210", userData(forwarder.DefaultConfigPath, daemonConfig), cloud.InstanceTypeSpec{})
+	require.NoError(t, err)
+	assert.Equal(t, instance1.IPs[0], instance3.IPs[0])
+}
+
+func TestBootstrap(t *testing.T) {
+
+	ctx := context.Background()
+	provider := newTestProvider(t, &Config{})
+
+	caService, err := tlsutil.NewCAService("test")
+	require.NoError(t, err)
+	serverCertPEM, serverKeyPEM, err := caService.Issue(forwarder.BootstrapServerName)
+	require.NoError(t, err)
+	clientCertPEM, clientKeyPEM, err := tlsutil.NewClientCertificate("test")
+	require.NoError(t, err)
+
+	bootstrapConfig := forwarder.BootstrapConfig{
+		TLSServerCert: string(serverCertPEM),
+		TLSServerKey:  string(serverKeyPEM),
+		TLSClientCA:   string(clientCertPEM),
+	}
+
+	instance, err := provider.CreateInstance(ctx, "", "", userData(forwarder.DefaultBootstrapConfigPath, bootstrapConfig), cloud.InstanceTypeSpec{})
+	require.NoError(t, err)
+
+	rootCAs := x509.NewCertPool()
+	require.True(t, rootCAs.AppendCertsFromPEM(caService.RootCertificate()))
+	clientCert, err := tls.X509KeyPair(clientCertPEM, clientKeyPEM)
+	require.NoError(t, err)
+
+	httpClient := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				RootCAs:      rootCAs,
+				ServerName:   forwarder.BootstrapServerName,
+				Certificates: []tls.Certificate{clientCert},
+			},
+		},
+	}
+
+	daemonJSON, err := json.Marshal(forwarder.Config{PodName: "nginx", PodNamespace: "default"})
+	require.NoError(t, err)
+
+	bootstrapURL := &url.URL{
+		Scheme: "https",
+		Host:   net.JoinHostPort(instance.IPs[0].String(), provider.serviceConfig.ForwarderPort),
+		Path:   forwarder.BootstrapURLPath,
+	}
+
+	require.Eventually(t, func() bool {
+		req, err := http.NewRequest(http.MethodPut, bootstrapURL.String(), bytes.NewReader(daemonJSON))
+		require.NoError(t, err)
+
+		res, err := httpClient.Do(req)
+		if err != nil {
+			return false
+		}
+		res.Body.Close()
+
+		return res.StatusCode == http.StatusNoContent
+	}, 5*time.Second, 10*time.Millisecond)
+
+	client := dialForwarder(t, instance, provider.serviceConfig.ForwarderPort)
+
+	res, err := pb.NewHealthClient(client).Version(ctx, &pb.CheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, agenttest.AgentVersion, res.AgentVersion)
+}
+
+func TestFailureInjection(t *testing.T) {
+
+	ctx := context.Background()
+	daemonConfig := forwarder.Config{PodName: "nginx", PodNamespace: "default"}
+
+	provider := newTestProvider(t, &Config{CreateFailureRate: 1})
+
+	_, err := provider.CreateInstance(ctx, "nginx", "0123456789", userData(forwarder.DefaultConfigPath, daemonConfig), cloud.InstanceTypeSpec{})
+	assert.ErrorIs(t, err, ErrInjected)
+
+	provider = newTestProvider(t, &Config{DeleteFailureRate: 1})
+
+	instance, err := provider.CreateInstance(ctx, "nginx", "0123456789", userData(forwarder.DefaultConfigPath, daemonConfig), cloud.InstanceTypeSpec{})
+	require.NoError(t, err)
+
+	err = provider.DeleteInstance(ctx, instance.ID)
+	assert.ErrorIs(t, err, ErrInjected)
+
+	instances, err := provider.ListInstances(ctx)
+	require.NoError(t, err)
+	assert.Len(t, instances, 1)
+}
+
+func TestLatency(t *testing.T) {
+
+	provider := newTestProvider(t, &Config{CreateLatency: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := provider.CreateInstance(ctx, "nginx", "0123456789", userData(forwarder.DefaultConfigPath, forwarder.Config{}), cloud.InstanceTypeSpec{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestConfig(t *testing.T) {
+
+	_, err := NewProvider(&Config{CreateFailureRate: 1.5})
+	assert.Error(t, err)
+
+	_, err = NewProvider(&Config{BootLatency: -time.Second})
+	assert.Error(t, err)
+
+	provider := newTestProvider(t, &Config{})
+
+	_, err = provider.CreateInstance(context.Background(), "nginx", "0123456789", &cloudinit.CloudConfig{}, cloud.InstanceTypeSpec{})
+	assert.Error(t, err, "user data has no daemon config")
+}
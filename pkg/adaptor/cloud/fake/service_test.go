@@ -0,0 +1,107 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package fake
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	cri "github.com/containerd/containerd/pkg/cri/annotations"
+	"github.com/containerd/ttrpc"
+	hypervisor "github.com/kata-containers/kata-containers/src/runtime/protocols/hypervisor"
+	pb "github.com/kata-containers/kata-containers/src/runtime/virtcontainers/pkg/agent/protocols/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/proxy"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/podnetwork/tunneler"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/tlsutil"
+)
+
+// workerNode does nothing, since fake pod VMs share the network namespace of cloud-api-adaptor
+type workerNode struct{}
+
+func (n *workerNode) Inspect(nsPath string) (*tunneler.Config, error) {
+	return &tunneler.Config{}, nil
+}
+
+func (n *workerNode) Setup(nsPath string, podNodeIPs []netip.Addr, config *tunneler.Config) error {
+	return nil
+}
+
+func (n *workerNode) Teardown(nsPath string, config *tunneler.Config) error {
+	return nil
+}
+
+func (n *workerNode) Release(config *tunneler.Config) error {
+	return nil
+}
+
+func TestCloudService(t *testing.T) {
+
+	for name, tlsConfig := range map[string]*tlsutil.TLSConfig{
+		"tls":     {},
+		"non-tls": nil,
+	} {
+		t.Run(name, func(t *testing.T) {
+
+			ctx := context.Background()
+			dir := t.TempDir()
+
+			provider := newTestProvider(t, &Config{BootLatency: 100 * time.Millisecond})
+			proxyFactory := proxy.NewFactory("", "", tlsConfig, time.Minute, "")
+
+			s := cloud.NewService(provider, proxyFactory, &workerNode{}, dir, provider.serviceConfig.ForwarderPort, "", nil, nil, nil, 0, 1)
+
+			sandboxID := "0123456789"
+
+			res, err := s.CreateVM(ctx, &hypervisor.CreateVMRequest{
+				Id: sandboxID,
+				Annotations: map[string]string{
+					cri.SandboxNamespace: "default",
+					cri.SandboxName:      "nginx",
+				},
+			})
+			require.NoError(t, err)
+
+			_, err = s.StartVM(ctx, &hypervisor.StartVMRequest{Id: sandboxID})
+			require.NoError(t, err)
+
+			instanceID, err := s.GetInstanceID(ctx, "default", "nginx", false)
+			require.NoError(t, err)
+			assert.NotEmpty(t, instanceID)
+
+			// Send kata agent requests as the Kata shim does
+			conn, err := net.Dial("unix", res.AgentSocketPath)
+			require.NoError(t, err)
+
+			client := ttrpc.NewClient(conn)
+
+			check, err := pb.NewHealthClient(client).Check(ctx, &pb.CheckRequest{})
+			require.NoError(t, err)
+			assert.Equal(t, pb.HealthCheckResponse_SERVING, check.Status)
+
+			agentClient := pb.NewAgentServiceClient(client)
+
+			_, err = agentClient.CreateSandbox(ctx, &pb.CreateSandboxRequest{SandboxId: sandboxID})
+			assert.NoError(t, err)
+
+			_, err = agentClient.DestroySandbox(ctx, &pb.DestroySandboxRequest{})
+			assert.NoError(t, err)
+
+			require.NoError(t, client.Close())
+
+			_, err = s.StopVM(ctx, &hypervisor.StopVMRequest{Id: sandboxID})
+			require.NoError(t, err)
+
+			instances, err := provider.ListInstances(ctx)
+			require.NoError(t, err)
+			assert.Empty(t, instances)
+		})
+	}
+}
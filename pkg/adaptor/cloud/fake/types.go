@@ -0,0 +1,49 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package fake
+
+import (
+	"fmt"
+	"time"
+)
+
+type Config struct {
+	// ForwarderPort is the port of agent-protocol-forwarder in fake pod VMs
+	ForwarderPort string
+	// DataDir is a directory for agent sockets and configs of fake pod VMs
+	DataDir string
+
+	// Latencies of cloud operations and of pod VM boot
+	CreateLatency time.Duration
+	BootLatency   time.Duration
+	DeleteLatency time.Duration
+
+	// Probabilities of failures injected to cloud operations
+	CreateFailureRate float64
+	DeleteFailureRate float64
+}
+
+func (c *Config) validate() error {
+
+	for name, rate := range map[string]float64{
+		"create failure rate": c.CreateFailureRate,
+		"delete failure rate": c.DeleteFailureRate,
+	} {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("%s %v is out of the range [0, 1]", name, rate)
+		}
+	}
+
+	for name, latency := range map[string]time.Duration{
+		"create latency": c.CreateLatency,
+		"boot latency":   c.BootLatency,
+		"delete latency": c.DeleteLatency,
+	} {
+		if latency < 0 {
+			return fmt.Errorf("%s %v is negative", name, latency)
+		}
+	}
+
+	return nil
+}
@@ -5,6 +5,7 @@ package ibmcloud_powervs
 
 import (
 	"flag"
+	"os"
 	"strconv"
 
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud"
@@ -14,50 +15,67 @@ var ibmcloudPowerVSConfig Config
 
 type Manager struct{}
 
-func init() {
-	cloud.AddCloud("ibmcloud-powervs", &Manager{})
+func (_ *Manager) ParseCmd(flags *flag.FlagSet) {
+	parseCmd(flags, &ibmcloudPowerVSConfig)
 }
 
-func (_ *Manager) ParseCmd(flags *flag.FlagSet) {
+func parseCmd(flags *flag.FlagSet, cfg *Config) {
 
-	flags.StringVar(&ibmcloudPowerVSConfig.ApiKey, "api-key", "", "IBM Cloud API key, defaults to `IBMCLOUD_API_KEY`")
-	flags.StringVar(&ibmcloudPowerVSConfig.Zone, "zone", "", "PowerVS zone name")
-	flags.StringVar(&ibmcloudPowerVSConfig.ServiceInstanceID, "service-instance-id", "", "ID of the PowerVS Service Instance")
-	flags.StringVar(&ibmcloudPowerVSConfig.NetworkID, "network-id", "", "ID of the network instance")
-	flags.StringVar(&ibmcloudPowerVSConfig.ImageID, "image-id", "", "ID of the boot image")
-	flags.StringVar(&ibmcloudPowerVSConfig.SSHKey, "ssh-key", "", "Name of the SSH Key")
-	flags.Float64Var(&ibmcloudPowerVSConfig.Memory, "memory", 2, "Amount of memory in GB")
-	flags.Float64Var(&ibmcloudPowerVSConfig.Processors, "cpu", 0.5, "Number of processors allocated")
-	flags.StringVar(&ibmcloudPowerVSConfig.ProcessorType, "proc-type", "shared", "Name of the processor type")
-	flags.StringVar(&ibmcloudPowerVSConfig.SystemType, "sys-type", "s922", "Name of the system type")
-	flags.BoolVar(&ibmcloudPowerVSConfig.UsePublicIP, "use-public-ip", false, "Use Public IP for connecting to the agent-protocol-forwarder inside the Pod VM")
+	flags.StringVar(&cfg.ApiKey, "api-key", "", "IBM Cloud API key, defaults to `IBMCLOUD_API_KEY`")
+	flags.StringVar(&cfg.Zone, "zone", "", "PowerVS zone name")
+	flags.StringVar(&cfg.ServiceInstanceID, "service-instance-id", "", "ID of the PowerVS Service Instance")
+	flags.StringVar(&cfg.NetworkID, "network-id", "", "ID of the network instance")
+	flags.StringVar(&cfg.ImageID, "image-id", "", "ID of the boot image")
+	flags.StringVar(&cfg.SSHKey, "ssh-key", "", "Name of the SSH Key")
+	flags.Float64Var(&cfg.Memory, "memory", 2, "Amount of memory in GB")
+	flags.Float64Var(&cfg.Processors, "cpu", 0.5, "Number of processors allocated")
+	flags.StringVar(&cfg.ProcessorType, "proc-type", "shared", "Name of the processor type")
+	flags.StringVar(&cfg.SystemType, "sys-type", "s922", "Name of the system type")
+	flags.BoolVar(&cfg.UsePublicIP, "use-public-ip", false, "Use Public IP for connecting to the agent-protocol-forwarder inside the Pod VM")
+	flags.StringVar(&cfg.InstanceType, "instance-type", "", "Default instance type of the Pod VMs, defaults to `PODVM_INSTANCE_TYPE`. If it is not in -instance-types, it is sized by -cpu and -memory")
+	flags.Var((*cloud.InstanceTypeSpecFlag)(&cfg.InstanceTypeSpecList), "instance-types", "Instance types that Pod VMs may be sized by, comma separated name:vcpus:memory with memory in MiB")
 
 }
 
 func (_ *Manager) LoadEnv() {
+	loadEnv(&ibmcloudPowerVSConfig, os.Getenv)
+}
+
+func loadEnv(cfg *Config, getenv cloud.Getenv) {
 	// overwrite config set by cmd parameters in oci image with env might come from orchastration platform
-	cloud.DefaultToEnv(&ibmcloudPowerVSConfig.ApiKey, "IBMCLOUD_API_KEY", "")
+	getenv.DefaultTo(&cfg.ApiKey, "IBMCLOUD_API_KEY", "")
 
-	cloud.DefaultToEnv(&ibmcloudPowerVSConfig.Zone, "POWERVS_ZONE", "")
-	cloud.DefaultToEnv(&ibmcloudPowerVSConfig.ServiceInstanceID, "POWERVS_SERVICE_INSTANCE_ID", "")
-	cloud.DefaultToEnv(&ibmcloudPowerVSConfig.NetworkID, "POWERVS_NETWORK_ID", "")
-	cloud.DefaultToEnv(&ibmcloudPowerVSConfig.ImageID, "POWERVS_IMAGE_ID", "")
-	cloud.DefaultToEnv(&ibmcloudPowerVSConfig.SSHKey, "POWERVS_SSH_KEY_NAME", "")
-	cloud.DefaultToEnv(&ibmcloudPowerVSConfig.ProcessorType, "POWERVS_PROCESSOR_TYPE", "")
-	cloud.DefaultToEnv(&ibmcloudPowerVSConfig.SystemType, "POWERVS_SYSTEM_TYPE", "")
+	getenv.DefaultTo(&cfg.Zone, "POWERVS_ZONE", "")
+	getenv.DefaultTo(&cfg.ServiceInstanceID, "POWERVS_SERVICE_INSTANCE_ID", "")
+	getenv.DefaultTo(&cfg.NetworkID, "POWERVS_NETWORK_ID", "")
+	getenv.DefaultTo(&cfg.ImageID, "POWERVS_IMAGE_ID", "")
+	getenv.DefaultTo(&cfg.SSHKey, "POWERVS_SSH_KEY_NAME", "")
+	getenv.DefaultTo(&cfg.ProcessorType, "POWERVS_PROCESSOR_TYPE", "")
+	getenv.DefaultTo(&cfg.SystemType, "POWERVS_SYSTEM_TYPE", "")
+	getenv.DefaultTo(&cfg.InstanceType, "PODVM_INSTANCE_TYPE", "")
 
 	var memoryStr, processorsStr string
-	cloud.DefaultToEnv(&memoryStr, "POWERVS_MEMORY", "")
+	getenv.DefaultTo(&memoryStr, "POWERVS_MEMORY", "")
 	if memoryStr != "" {
-		ibmcloudPowerVSConfig.Memory, _ = strconv.ParseFloat(memoryStr, 64)
+		cfg.Memory, _ = strconv.ParseFloat(memoryStr, 64)
 	}
 
-	cloud.DefaultToEnv(&processorsStr, "POWERVS_MEMORY", "")
+	getenv.DefaultTo(&processorsStr, "POWERVS_PROCESSORS", "")
 	if processorsStr != "" {
-		ibmcloudPowerVSConfig.Processors, _ = strconv.ParseFloat(processorsStr, 64)
+		cfg.Processors, _ = strconv.ParseFloat(processorsStr, 64)
 	}
 }
 
 func (_ *Manager) NewProvider() (cloud.Provider, error) {
 	return NewProvider(&ibmcloudPowerVSConfig)
 }
+
+func (_ *Manager) NewProviderFromConfig(config map[string]string) (cloud.Provider, error) {
+
+	var cfg Config
+	if err := cloud.LoadConfig(config, func(flags *flag.FlagSet) { parseCmd(flags, &cfg) }, func(getenv cloud.Getenv) { loadEnv(&cfg, getenv) }); err != nil {
+		return nil, err
+	}
+
+	return NewProvider(&cfg)
+}
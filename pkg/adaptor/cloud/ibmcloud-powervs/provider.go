@@ -7,7 +7,7 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
-	"log"
+	"math"
 	"net/netip"
 	"time"
 
@@ -15,22 +15,26 @@ import (
 	"github.com/IBM/go-sdk-core/v5/core"
 	"github.com/avast/retry-go/v4"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud"
+	peerpodannotations "github.com/confidential-containers/cloud-api-adaptor/pkg/annotations"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/util"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/cloudinit"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/logging"
 )
 
 const maxInstanceNameLen = 63
 
-var logger = log.New(log.Writer(), "[adaptor/cloud/ibmcloud-powervs] ", log.LstdFlags|log.Lmsgprefix)
+var logger = logging.New("adaptor/cloud/ibmcloud-powervs")
 
 type ibmcloudPowerVSProvider struct {
 	powervsService
 	serviceConfig *Config
+	// instanceTypeSpecList is the sorted catalog of instance types, including the default instance type
+	instanceTypeSpecList []cloud.InstanceTypeSpec
 }
 
 func NewProvider(config *Config) (cloud.Provider, error) {
 
-	logger.Printf("ibmcloud-powervs config: %#v", config.Redact())
+	logger.Info("ibmcloud-powervs config", "config", config)
 
 	powervs, err := newPowervsClient(config.ApiKey, config.ServiceInstanceID, config.Zone)
 	if err != nil {
@@ -38,12 +42,29 @@ func NewProvider(config *Config) (cloud.Provider, error) {
 	}
 
 	return &ibmcloudPowerVSProvider{
-		powervsService: *powervs,
-		serviceConfig:  config,
+		powervsService:       *powervs,
+		serviceConfig:        config,
+		instanceTypeSpecList: instanceTypeSpecList(config),
 	}, nil
 }
 
+// instanceTypeSpecList returns the catalog of instance types of config, including the default instance type
+func instanceTypeSpecList(config *Config) []cloud.InstanceTypeSpec {
+
+	specList := append([]cloud.InstanceTypeSpec{}, config.InstanceTypeSpecList...)
+	if _, ok := cloud.GetInstanceTypeSpec(specList, config.InstanceType); !ok {
+		// Shared processors may be fractional, so round up the vCPUs the default instance type provides
+		specList = append(specList, cloud.InstanceTypeSpec{
+			InstanceType: config.InstanceType,
+			VCPUs:        int64(math.Ceil(config.Processors)),
+			Memory:       int64(config.Memory * 1024),
+		})
+	}
+	return cloud.SortInstanceTypesOnMemory(specList)
+}
+
 func (p *ibmcloudPowerVSProvider) CreateInstance(ctx context.Context, podName, sandboxID string, cloudConfig cloudinit.CloudConfigGenerator, spec cloud.InstanceTypeSpec) (*cloud.Instance, error) {
+	logger := logger.WithContext(ctx)
 
 	instanceName := util.GenerateInstanceName(podName, sandboxID, maxInstanceNameLen)
 
@@ -52,26 +73,46 @@ func (p *ibmcloudPowerVSProvider) CreateInstance(ctx context.Context, podName, s
 		return nil, err
 	}
 
+	instanceType, err := cloud.SelectInstanceTypeToUse(spec, p.instanceTypeSpecList, p.InstanceTypes(), p.serviceConfig.InstanceType)
+	if err != nil {
+		return nil, err
+	}
+
+	// Instance types of the catalog override the processors and memory of the default instance type
+	processors, memory := p.serviceConfig.Processors, p.serviceConfig.Memory
+	if instanceTypeSpec, ok := cloud.GetInstanceTypeSpec(p.serviceConfig.InstanceTypeSpecList, instanceType); ok {
+		processors, memory = float64(instanceTypeSpec.VCPUs), float64(instanceTypeSpec.Memory)/1024
+	}
+
+	// The image and network of a pod take precedence over the configured ones
+	imageID, networkID := p.serviceConfig.ImageID, p.serviceConfig.NetworkID
+	if spec.ImageID != "" {
+		imageID = spec.ImageID
+	}
+	if spec.SubnetID != "" {
+		networkID = spec.SubnetID
+	}
+
 	body := &models.PVMInstanceCreate{
 		ServerName:  &instanceName,
-		ImageID:     &p.serviceConfig.ImageID,
+		ImageID:     &imageID,
 		KeyPairName: p.serviceConfig.SSHKey,
 		Networks: []*models.PVMInstanceAddNetwork{
 			{
-				NetworkID: &p.serviceConfig.NetworkID,
+				NetworkID: &networkID,
 			}},
-		Memory:     core.Float64Ptr(p.serviceConfig.Memory),
-		Processors: core.Float64Ptr(p.serviceConfig.Processors),
+		Memory:     core.Float64Ptr(memory),
+		Processors: core.Float64Ptr(processors),
 		ProcType:   core.StringPtr(p.serviceConfig.ProcessorType),
 		SysType:    p.serviceConfig.SystemType,
 		UserData:   base64.StdEncoding.EncodeToString([]byte(userData)),
 	}
 
-	logger.Printf("CreateInstance: name: %q", instanceName)
+	logger.Info("creating an instance", "instance_name", instanceName, "instance_type", instanceType, "processors", processors, "memory_gb", memory)
 
 	pvsInstances, err := p.powervsService.instanceClient(ctx).Create(body)
 	if err != nil {
-		logger.Printf("failed to create an instance : %v", err)
+		logger.Error("failed to create an instance", logging.KeyError, err)
 		return nil, err
 	}
 
@@ -85,7 +126,7 @@ func (p *ibmcloudPowerVSProvider) CreateInstance(ctx context.Context, podName, s
 	ctx, cancel := context.WithTimeout(ctx, 150*time.Second)
 	defer cancel()
 
-	logger.Printf("Waiting for instance to reach state: ACTIVE")
+	logger.Info("waiting for the instance to reach state ACTIVE", logging.KeyInstanceID, instanceID)
 	err = retry.Do(
 		func() error {
 			in, err := p.powervsService.instanceClient(ctx).Get(*ins.PvmInstanceID)
@@ -98,7 +139,7 @@ func (p *ibmcloudPowerVSProvider) CreateInstance(ctx context.Context, podName, s
 			}
 
 			if *in.Status == "ACTIVE" {
-				logger.Printf("instance is in desired state: %s", *in.Status)
+				logger.Info("instance is in the desired state", logging.KeyInstanceID, instanceID, "status", *in.Status)
 				return nil
 			}
 
@@ -110,34 +151,43 @@ func (p *ibmcloudPowerVSProvider) CreateInstance(ctx context.Context, podName, s
 	)
 
 	if err != nil {
-		logger.Print(err)
+		logger.Error("instance failed to reach state ACTIVE", logging.KeyInstanceID, instanceID, logging.KeyError, err)
 		return nil, err
 	}
 
-	ips, err := p.getVMIPs(ctx, ins)
+	ips, err := p.getVMIPs(ctx, ins, networkID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get IPs for the instance : %v", err)
 	}
 
 	return &cloud.Instance{
-		ID:   instanceID,
-		Name: instanceName,
-		IPs:  ips,
+		ID:           instanceID,
+		Name:         instanceName,
+		IPs:          ips,
+		InstanceType: instanceType,
+		Zone:         p.serviceConfig.Zone,
 	}, nil
 }
 
 func (p *ibmcloudPowerVSProvider) DeleteInstance(ctx context.Context, instanceID string) error {
+	logger := logger.WithContext(ctx)
 
 	err := p.powervsService.instanceClient(ctx).Delete(instanceID)
 	if err != nil {
-		logger.Printf("failed to delete an instance: %v", err)
+		logger.Error("failed to delete an instance", logging.KeyInstanceID, instanceID, logging.KeyError, err)
 		return err
 	}
 
-	logger.Printf("deleted an instance %s", instanceID)
+	logger.Info("deleted an instance", logging.KeyInstanceID, instanceID)
 	return nil
 }
 
+func (p *ibmcloudPowerVSProvider) ListInstances(ctx context.Context) ([]*cloud.Instance, error) {
+	// PowerVS instances are not tagged at creation, so instances of other clusters in the same workspace cannot be told apart.
+	// See the known gaps of docs/orphaned-instances.md.
+	return nil, cloud.ErrListInstancesUnsupported
+}
+
 func (p *ibmcloudPowerVSProvider) Teardown() error {
 	return nil
 }
@@ -150,7 +200,20 @@ func (p *ibmcloudPowerVSProvider) ConfigVerifier() error {
 	return nil
 }
 
-func (p *ibmcloudPowerVSProvider) getVMIPs(ctx context.Context, instance *models.PVMInstance) ([]netip.Addr, error) {
+// SupportedOverrides returns the override annotations of the image and network of pod VMs. A PowerVS network is
+// a subnet. Security groups and tags are not supported.
+func (p *ibmcloudPowerVSProvider) SupportedOverrides() []string {
+	return []string{peerpodannotations.ImageID, peerpodannotations.SubnetID}
+}
+
+// InstanceTypes returns the instance types that pods may request
+func (p *ibmcloudPowerVSProvider) InstanceTypes() []string {
+	return cloud.InstanceTypeNames(p.instanceTypeSpecList)
+}
+
+func (p *ibmcloudPowerVSProvider) getVMIPs(ctx context.Context, instance *models.PVMInstance, networkID string) ([]netip.Addr, error) {
+	logger := logger.WithContext(ctx)
+
 	var ips []netip.Addr
 	ins, err := p.powervsService.instanceClient(ctx).Get(*instance.PvmInstanceID)
 	if err != nil {
@@ -170,7 +233,7 @@ func (p *ibmcloudPowerVSProvider) getVMIPs(ctx context.Context, instance *models
 			}
 
 			ips = append(ips, ip)
-			logger.Printf("podNodeIP[%d]=%s", i, ip.String())
+			logger.Debug("pod node IP", "index", i, "ip", ip.String())
 		}
 	}
 
@@ -182,11 +245,11 @@ func (p *ibmcloudPowerVSProvider) getVMIPs(ctx context.Context, instance *models
 	defer cancel()
 
 	// If IP is not assigned to the instance, fetch it from DHCP server
-	logger.Printf("Trying to fetch IP from DHCP server..")
+	logger.Info("trying to fetch the IP address from the DHCP server", logging.KeyInstanceID, *instance.PvmInstanceID)
 	err = retry.Do(func() error {
-		ip, err := p.getFromDHCPServer(ctx, ins)
+		ip, err := p.getFromDHCPServer(ctx, ins, networkID)
 		if err != nil {
-			logger.Print(err)
+			logger.Warn("failed to get the IP address from the DHCP server, retrying", logging.KeyError, err)
 			return err
 		}
 		if ip == nil {
@@ -199,7 +262,7 @@ func (p *ibmcloudPowerVSProvider) getVMIPs(ctx context.Context, instance *models
 		}
 
 		ips = append(ips, addr)
-		logger.Printf("podNodeIP=%s", addr.String())
+		logger.Debug("pod node IP", "ip", addr.String())
 		return nil
 	},
 		retry.Context(ctx),
@@ -208,16 +271,14 @@ func (p *ibmcloudPowerVSProvider) getVMIPs(ctx context.Context, instance *models
 	)
 
 	if err != nil {
-		logger.Print(err)
+		logger.Error("failed to get the IP address from the DHCP server", logging.KeyError, err)
 		return nil, err
 	}
 
 	return ips, nil
 }
 
-func (p *ibmcloudPowerVSProvider) getFromDHCPServer(ctx context.Context, instance *models.PVMInstance) (*string, error) {
-	networkID := p.serviceConfig.NetworkID
-
+func (p *ibmcloudPowerVSProvider) getFromDHCPServer(ctx context.Context, instance *models.PVMInstance, networkID string) (*string, error) {
 	var pvsNetwork *models.PVMInstanceNetwork
 	for _, net := range instance.Networks {
 		if net.NetworkID == networkID {
@@ -3,7 +3,10 @@
 
 package ibmcloud_powervs
 
-import "github.com/confidential-containers/cloud-api-adaptor/pkg/util"
+import (
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util"
+)
 
 type Config struct {
 	ApiKey            string
@@ -17,6 +20,10 @@ type Config struct {
 	ProcessorType     string
 	SystemType        string
 	UsePublicIP       bool
+	InstanceType      string
+	// InstanceTypeSpecList is the catalog of instance types that pod VMs may be sized by.
+	// The vCPUs of an instance type are allocated as whole processors.
+	InstanceTypeSpecList []cloud.InstanceTypeSpec
 }
 
 func (c Config) Redact() Config {
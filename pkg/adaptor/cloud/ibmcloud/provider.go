@@ -7,7 +7,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"net/netip"
 	"os"
 	"time"
@@ -16,8 +15,10 @@ import (
 	"github.com/IBM/vpc-go-sdk/vpcv1"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/k8sops"
+	peerpodannotations "github.com/confidential-containers/cloud-api-adaptor/pkg/annotations"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/util"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/cloudinit"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/logging"
 )
 
 const (
@@ -25,7 +26,7 @@ const (
 	queryInterval = 2
 )
 
-var logger = log.New(log.Writer(), "[adaptor/cloud/ibmcloud] ", log.LstdFlags|log.Lmsgprefix)
+var logger = logging.New("adaptor/cloud/ibmcloud")
 var errNotReady = errors.New("address not ready")
 
 const maxInstanceNameLen = 63
@@ -68,7 +69,7 @@ func NewProvider(config *Config) (cloud.Provider, error) {
 		var err error
 		nodeLabels, err = k8sops.NodeLabels(context.TODO(), nodeName)
 		if err != nil {
-			logger.Printf("warning, could not find node labels\ndue to: %v\n", err)
+			logger.Warn("could not find node labels", "node", nodeName, logging.KeyError, err)
 		}
 	}
 
@@ -96,7 +97,7 @@ func NewProvider(config *Config) (cloud.Provider, error) {
 		}
 		vpcID, rgID, sgID, err := fetchVPCDetails(vpcV1, primarySubnetID)
 		if err != nil {
-			logger.Printf("warning, unable to automatically populate VPC details\ndue to: %v\n", err)
+			logger.Warn("unable to automatically populate VPC details", "subnet_id", primarySubnetID, logging.KeyError, err)
 		} else {
 			if config.PrimarySubnetID == "" {
 				config.PrimarySubnetID = primarySubnetID
@@ -126,7 +127,7 @@ func NewProvider(config *Config) (cloud.Provider, error) {
 		return nil, err
 	}
 
-	logger.Printf("ibmcloud-vpc config: %#v", config.Redact())
+	logger.Info("ibmcloud-vpc config", "config", config)
 
 	return provider, nil
 }
@@ -198,6 +199,21 @@ func (p *ibmcloudVPCProvider) getInstancePrototype(instanceName, userData, insta
 	return prototype
 }
 
+// setOverrides sets the subnet and security groups of the primary network interface of an instance, if spec overrides them
+func setOverrides(prototype *vpcv1.InstancePrototype, spec cloud.InstanceTypeSpec) {
+
+	if spec.SubnetID != "" {
+		prototype.PrimaryNetworkInterface.Subnet = &vpcv1.SubnetIdentity{ID: core.StringPtr(spec.SubnetID)}
+	}
+	if len(spec.SecurityGroupIDs) > 0 {
+		var securityGroups []vpcv1.SecurityGroupIdentityIntf
+		for _, id := range spec.SecurityGroupIDs {
+			securityGroups = append(securityGroups, &vpcv1.SecurityGroupIdentityByID{ID: core.StringPtr(id)})
+		}
+		prototype.PrimaryNetworkInterface.SecurityGroups = securityGroups
+	}
+}
+
 func getIPs(instance *vpcv1.Instance, instanceID string, numInterfaces int) ([]netip.Addr, error) {
 
 	interfaces := []*vpcv1.NetworkInterfaceInstanceContextReference{instance.PrimaryNetworkInterface}
@@ -225,7 +241,7 @@ func getIPs(instance *vpcv1.Instance, instanceID string, numInterfaces int) ([]n
 		}
 		ips = append(ips, ip)
 
-		logger.Printf("podNodeIP[%d]=%s", i, ip.String())
+		logger.Debug("pod node IP", "index", i, "ip", ip.String())
 	}
 
 	if len(ips) < numInterfaces {
@@ -236,6 +252,7 @@ func getIPs(instance *vpcv1.Instance, instanceID string, numInterfaces int) ([]n
 }
 
 func (p *ibmcloudVPCProvider) CreateInstance(ctx context.Context, podName, sandboxID string, cloudConfig cloudinit.CloudConfigGenerator, spec cloud.InstanceTypeSpec) (*cloud.Instance, error) {
+	logger := logger.WithContext(ctx)
 
 	instanceName := util.GenerateInstanceName(podName, sandboxID, maxInstanceNameLen)
 
@@ -255,12 +272,13 @@ func (p *ibmcloudVPCProvider) CreateInstance(ctx context.Context, podName, sandb
 	}
 
 	prototype := p.getInstancePrototype(instanceName, userData, instanceProfile, imageID)
+	setOverrides(prototype, spec)
 
-	logger.Printf("CreateInstance: name: %q", instanceName)
+	logger.Info("creating an instance", "instance_name", instanceName)
 
 	vpcInstance, resp, err := p.vpc.CreateInstanceWithContext(ctx, &vpcv1.CreateInstanceOptions{InstancePrototype: prototype})
 	if err != nil {
-		logger.Printf("failed to create an instance : %v and the response is %s", err, resp)
+		logger.Error("failed to create an instance", "response", resp, logging.KeyError, err)
 		return nil, err
 	}
 
@@ -284,21 +302,28 @@ func (p *ibmcloudVPCProvider) CreateInstance(ctx context.Context, podName, sandb
 
 		result, resp, err := p.vpc.GetInstanceWithContext(ctx, &vpcv1.GetInstanceOptions{ID: &instanceID})
 		if err != nil {
-			logger.Printf("failed to get an instance : %v and the response is %s", err, resp)
+			logger.Error("failed to get an instance", logging.KeyInstanceID, instanceID, "response", resp, logging.KeyError, err)
 			return nil, err
 		}
 		vpcInstance = result
 	}
 
 	instance := &cloud.Instance{
-		ID:   instanceID,
-		Name: instanceName,
-		IPs:  ips,
+		ID:           instanceID,
+		Name:         instanceName,
+		IPs:          ips,
+		InstanceType: instanceProfile,
+		Zone:         p.serviceConfig.ZoneName,
 	}
 
 	return instance, nil
 }
 
+// InstanceTypes returns the instance profiles that pods may request
+func (p *ibmcloudVPCProvider) InstanceTypes() []string {
+	return append([]string{p.serviceConfig.ProfileName}, p.serviceConfig.InstanceProfiles...)
+}
+
 // Select an instance profile based on the memory and vcpu requirements
 func (p *ibmcloudVPCProvider) selectInstanceProfile(ctx context.Context, spec cloud.InstanceTypeSpec) (string, error) {
 
@@ -321,21 +346,21 @@ func (p *ibmcloudVPCProvider) updateInstanceProfileSpecList() error {
 
 	// Iterate over the instance types and populate the instanceProfileSpecList
 	for _, profileType := range instanceProfiles {
-		vcpus, memory, err := p.getProfileNameInformation(profileType)
+		spec, err := p.getProfileNameInformation(profileType)
 		if err != nil {
 			return err
 		}
-		instanceProfileSpecList = append(instanceProfileSpecList, cloud.InstanceTypeSpec{InstanceType: profileType, VCPUs: vcpus, Memory: memory})
+		instanceProfileSpecList = append(instanceProfileSpecList, spec)
 	}
 
 	// Sort the instanceProfileSpecList by Memory and update the serviceConfig
 	p.serviceConfig.InstanceProfileSpecList = cloud.SortInstanceTypesOnMemory(instanceProfileSpecList)
-	logger.Printf("instanceProfileSpecList (%v)", p.serviceConfig.InstanceProfileSpecList)
+	logger.Info("instance profiles", "instance_profile_specs", p.serviceConfig.InstanceProfileSpecList)
 	return nil
 }
 
-// Add a method to retrieve cpu, memory, and storage from the profile name
-func (p *ibmcloudVPCProvider) getProfileNameInformation(profileName string) (vcpu int64, memory int64, err error) {
+// Add a method to retrieve cpu, memory, and GPUs from the profile name
+func (p *ibmcloudVPCProvider) getProfileNameInformation(profileName string) (spec cloud.InstanceTypeSpec, err error) {
 
 	// Get the profile information from the instance type using IBMCloud API
 	result, details, err := p.vpc.GetInstanceProfileWithContext(context.Background(),
@@ -345,22 +370,44 @@ func (p *ibmcloudVPCProvider) getProfileNameInformation(profileName string) (vcp
 	)
 
 	if err != nil {
-		return 0, 0, fmt.Errorf("instance profile name %s not found, due to %w\nFurther Details:\n%v", profileName, err, details)
+		return spec, fmt.Errorf("instance profile name %s not found, due to %w\nFurther Details:\n%v", profileName, err, details)
+	}
+
+	spec = cloud.InstanceTypeSpec{
+		InstanceType: profileName,
+		VCPUs:        int64(*result.VcpuCount.(*vpcv1.InstanceProfileVcpu).Value),
+		// Value returned is in GiB, convert to MiB
+		Memory: int64(*result.Memory.(*vpcv1.InstanceProfileMemory).Value) * 1024,
 	}
 
-	vcpu = int64(*result.VcpuCount.(*vpcv1.InstanceProfileVcpu).Value)
-	// Value returned is in GiB, convert to MiB
-	memory = int64(*result.Memory.(*vpcv1.InstanceProfileMemory).Value) * 1024
-	return vcpu, memory, nil
+	switch gpuCount := result.GpuCount.(type) {
+	case *vpcv1.InstanceProfileGpu:
+		if gpuCount.Value != nil {
+			spec.GPUs = *gpuCount.Value
+		} else if gpuCount.Default != nil {
+			spec.GPUs = *gpuCount.Default
+		}
+	case *vpcv1.InstanceProfileGpuFixed:
+		spec.GPUs = *gpuCount.Value
+	}
+	if result.GpuModel != nil && len(result.GpuModel.Values) > 0 {
+		spec.GPUModel = result.GpuModel.Values[0]
+	}
+
+	return spec, nil
 }
 
-// Select Image from list, invalid image IDs should have already been removed
+// Select Image from list, invalid image IDs should have already been removed. An image of spec takes precedence.
 func (p *ibmcloudVPCProvider) selectImage(ctx context.Context, spec cloud.InstanceTypeSpec) (string, error) {
+	if spec.ImageID != "" {
+		logger.WithContext(ctx).Info("selected the image of the pod", "image_id", spec.ImageID)
+		return spec.ImageID, nil
+	}
 	for _, image := range p.serviceConfig.Images {
 		if spec.Arch != "" && image.Arch != spec.Arch {
 			continue
 		}
-		logger.Printf("selected image with ID <%s> out of %d images", image.ID, len(p.serviceConfig.Images))
+		logger.WithContext(ctx).Info("selected an image", "image_id", image.ID, "images", len(p.serviceConfig.Images))
 		return image.ID, nil
 	}
 	return "", fmt.Errorf("unable to find matching image to use")
@@ -372,7 +419,7 @@ func (p *ibmcloudVPCProvider) updateImageList(ctx context.Context) error {
 	for _, image := range p.serviceConfig.Images {
 		arch, os, err := p.getImageDetails(ctx, image.ID)
 		if err != nil {
-			logger.Printf("skipping image (%s), due to %v", image.ID, err)
+			logger.WithContext(ctx).Warn("skipping image", "image_id", image.ID, logging.KeyError, err)
 			continue
 		}
 		image.Arch = arch
@@ -398,19 +445,32 @@ func (p *ibmcloudVPCProvider) getImageDetails(ctx context.Context, imageID strin
 }
 
 func (p *ibmcloudVPCProvider) DeleteInstance(ctx context.Context, instanceID string) error {
+	logger := logger.WithContext(ctx)
 
 	options := &vpcv1.DeleteInstanceOptions{}
 	options.SetID(instanceID)
 	resp, err := p.vpc.DeleteInstanceWithContext(ctx, options)
 	if err != nil {
-		logger.Printf("failed to delete an instance: %v and the response is %v", err, resp)
+		logger.Error("failed to delete an instance", logging.KeyInstanceID, instanceID, "response", resp, logging.KeyError, err)
 		return err
 	}
 
-	logger.Printf("deleted an instance %s", instanceID)
+	logger.Info("deleted an instance", logging.KeyInstanceID, instanceID)
 	return nil
 }
 
+func (p *ibmcloudVPCProvider) ListInstances(ctx context.Context) ([]*cloud.Instance, error) {
+	// VPC instances are not tagged at creation, so instances of other clusters in the same VPC cannot be told apart.
+	// See the known gaps of docs/orphaned-instances.md.
+	return nil, cloud.ErrListInstancesUnsupported
+}
+
+// SupportedOverrides returns the override annotations of the image, subnet and security groups of pod VMs.
+// Tags are not supported, since VPC instances are not tagged at creation.
+func (p *ibmcloudVPCProvider) SupportedOverrides() []string {
+	return []string{peerpodannotations.ImageID, peerpodannotations.SubnetID, peerpodannotations.SecurityGroupIDs}
+}
+
 func (p *ibmcloudVPCProvider) Teardown() error {
 	return nil
 }
@@ -7,6 +7,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -87,6 +88,16 @@ func (v *mockVPC) GetInstanceWithContext(ctx context.Context, opt *vpcv1.GetInst
 func (v *mockVPC) GetInstanceProfileWithContext(context context.Context, options *vpcv1.GetInstanceProfileOptions) (*vpcv1.InstanceProfile, *core.DetailedResponse, error) {
 	profileType := options.Name
 
+	if *profileType == "gx2-8x64x1v100" {
+		vcpu, mem, gpus := int64(8), int64(64), int64(1)
+		return &vpcv1.InstanceProfile{
+			VcpuCount: &vpcv1.InstanceProfileVcpu{Value: &vcpu},
+			Memory:    &vpcv1.InstanceProfileMemory{Value: &mem},
+			GpuCount:  &vpcv1.InstanceProfileGpu{Value: &gpus},
+			GpuModel:  &vpcv1.InstanceProfileGpuModel{Values: []string{"Tesla V100"}},
+		}, nil, nil
+	}
+
 	if *profileType != "bx2-2x8" {
 		return nil, nil, fmt.Errorf("Unsupported instance type")
 	}
@@ -178,12 +189,11 @@ func TestGetInstanceTypeInformation(t *testing.T) {
 		instanceType string
 	}
 	tests := []struct {
-		name       string
-		provider   *ibmcloudVPCProvider
-		args       args
-		wantVcpu   int64
-		wantMemory int64
-		wantErr    bool
+		name     string
+		provider *ibmcloudVPCProvider
+		args     args
+		wantSpec cloud.InstanceTypeSpec
+		wantErr  bool
 	}{
 		// Test getting instance type information for a valid instance type
 		{
@@ -195,8 +205,21 @@ func TestGetInstanceTypeInformation(t *testing.T) {
 			args: args{
 				instanceType: "bx2-2x8",
 			},
-			wantVcpu:   2,
-			wantMemory: 8192,
+			wantSpec: cloud.InstanceTypeSpec{InstanceType: "bx2-2x8", VCPUs: 2, Memory: 8192},
+			// Test should not return an error
+			wantErr: false,
+		},
+		// Test getting instance type information for a GPU instance type
+		{
+			name: "getInstanceTypeInformationGPUInstanceType",
+			provider: &ibmcloudVPCProvider{
+				vpc:           &mockVPC{},
+				serviceConfig: &Config{},
+			},
+			args: args{
+				instanceType: "gx2-8x64x1v100",
+			},
+			wantSpec: cloud.InstanceTypeSpec{InstanceType: "gx2-8x64x1v100", VCPUs: 8, Memory: 65536, GPUs: 1, GPUModel: "Tesla V100"},
 			// Test should not return an error
 			wantErr: false,
 		},
@@ -210,24 +233,19 @@ func TestGetInstanceTypeInformation(t *testing.T) {
 			args: args{
 				instanceType: "mycustominstance",
 			},
-			wantVcpu:   0,
-			wantMemory: 0,
 			// Test should return an error
 			wantErr: true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotVcpu, gotMemory, err := tt.provider.getProfileNameInformation(tt.args.instanceType)
+			gotSpec, err := tt.provider.getProfileNameInformation(tt.args.instanceType)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ibmcloudProvider.getProfileNameInformation() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if gotVcpu != tt.wantVcpu {
-				t.Errorf("ibmcloudProvider.getProfileNameInformation() gotVcpu = %v, want %v", gotVcpu, tt.wantVcpu)
-			}
-			if gotMemory != tt.wantMemory {
-				t.Errorf("ibmcloudProvider.getProfileNameInformation() gotMemory = %v, want %v", gotMemory, tt.wantMemory)
+			if !reflect.DeepEqual(gotSpec, tt.wantSpec) {
+				t.Errorf("ibmcloudProvider.getProfileNameInformation() gotSpec = %v, want %v", gotSpec, tt.wantSpec)
 			}
 		})
 	}
@@ -319,6 +337,23 @@ func TestGetImageDetails(t *testing.T) {
 			expectSelectErr: true,
 			wantID:          "",
 		},
+		// Test selecting the image of a pod, which takes precedence over the image list
+		{
+			name: "selectImageOfPod",
+			provider: &ibmcloudVPCProvider{
+				vpc: &mockVPC{},
+				serviceConfig: &Config{
+					Images: validImageList,
+				},
+			},
+			instanceSpec: cloud.InstanceTypeSpec{
+				Arch:    "amd64",
+				ImageID: "hardened-id",
+			},
+			expectListErr:   false,
+			expectSelectErr: false,
+			wantID:          "hardened-id",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -343,6 +378,34 @@ func TestGetImageDetails(t *testing.T) {
 	}
 }
 
+func TestSetOverrides(t *testing.T) {
+
+	p := &ibmcloudVPCProvider{
+		serviceConfig: &Config{
+			PrimarySubnetID:        "subnet-default",
+			PrimarySecurityGroupID: "sg-default",
+		},
+	}
+
+	prototype := p.getInstancePrototype("podvm", "user data", "bx2-2x8", "image-id")
+	setOverrides(prototype, cloud.InstanceTypeSpec{InstanceType: "bx2-2x8"})
+	if id := *prototype.PrimaryNetworkInterface.Subnet.(*vpcv1.SubnetIdentity).ID; id != "subnet-default" {
+		t.Fatalf("expect the configured subnet, got %s", id)
+	}
+
+	setOverrides(prototype, cloud.InstanceTypeSpec{SubnetID: "subnet-tenant", SecurityGroupIDs: []string{"sg-1", "sg-2"}})
+	if id := *prototype.PrimaryNetworkInterface.Subnet.(*vpcv1.SubnetIdentity).ID; id != "subnet-tenant" {
+		t.Errorf("expect subnet subnet-tenant, got %s", id)
+	}
+	var securityGroups []string
+	for _, sg := range prototype.PrimaryNetworkInterface.SecurityGroups {
+		securityGroups = append(securityGroups, *sg.(*vpcv1.SecurityGroupIdentityByID).ID)
+	}
+	if !reflect.DeepEqual(securityGroups, []string{"sg-1", "sg-2"}) {
+		t.Errorf("expect security groups sg-1 and sg-2, got %q", securityGroups)
+	}
+}
+
 func TestConfigVerifier(t *testing.T) {
 
 	validImageList := make(Images, 0)
@@ -0,0 +1,145 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package cloud
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/metrics"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/cloudinit"
+)
+
+// instrumentedProvider records metrics of cloud provider operations. The same metrics
+// are recorded for every cloud provider, labeled by cloud name. Optional interfaces of
+// a provider must be forwarded explicitly, since they are hidden by the embedded Provider.
+type instrumentedProvider struct {
+	Provider
+	cloudName     string
+	instanceTypes map[string]string
+	mutex         sync.Mutex
+}
+
+// NewInstrumentedProvider returns a provider that records metrics of operations of provider
+func NewInstrumentedProvider(cloudName string, provider Provider) Provider {
+	return &instrumentedProvider{
+		Provider:      provider,
+		cloudName:     cloudName,
+		instanceTypes: make(map[string]string),
+	}
+}
+
+func (p *instrumentedProvider) CreateInstance(ctx context.Context, podName, sandboxID string, cloudConfig cloudinit.CloudConfigGenerator, spec InstanceTypeSpec) (*Instance, error) {
+
+	start := time.Now()
+	instance, err := p.Provider.CreateInstance(ctx, podName, sandboxID, cloudConfig, spec)
+
+	// The instance type of the spec is empty when the provider selects the best fit
+	instanceType := spec.InstanceType
+	if err == nil && instance.InstanceType != "" {
+		instanceType = instance.InstanceType
+	}
+	instanceType = p.instanceTypeLabel(instanceType)
+	metrics.ObserveProviderOperation(p.cloudName, metrics.OperationCreateInstance, instanceType, start, err)
+
+	if err == nil {
+		p.mutex.Lock()
+		p.instanceTypes[instance.ID] = instanceType
+		p.mutex.Unlock()
+	}
+
+	return instance, err
+}
+
+func (p *instrumentedProvider) DeleteInstance(ctx context.Context, instanceID string) error {
+
+	// The instance type is unknown for instances created before a restart
+	p.mutex.Lock()
+	instanceType := p.instanceTypes[instanceID]
+	p.mutex.Unlock()
+
+	start := time.Now()
+	err := p.Provider.DeleteInstance(ctx, instanceID)
+	metrics.ObserveProviderOperation(p.cloudName, metrics.OperationDeleteInstance, instanceType, start, err)
+
+	if err == nil {
+		p.mutex.Lock()
+		delete(p.instanceTypes, instanceID)
+		p.mutex.Unlock()
+	}
+
+	return err
+}
+
+func (p *instrumentedProvider) ListInstances(ctx context.Context) ([]*Instance, error) {
+
+	start := time.Now()
+	instances, err := p.Provider.ListInstances(ctx)
+	metrics.ObserveProviderOperation(p.cloudName, metrics.OperationListInstances, "", start, err)
+
+	return instances, err
+}
+
+// InstanceTypes forwards InstanceTypeLister of the provider
+func (p *instrumentedProvider) InstanceTypes() []string {
+	if lister, ok := p.Provider.(InstanceTypeLister); ok {
+		return lister.InstanceTypes()
+	}
+	return nil
+}
+
+// Placements forwards PlacementLister of the provider
+func (p *instrumentedProvider) Placements() []string {
+	if lister, ok := p.Provider.(PlacementLister); ok {
+		return lister.Placements()
+	}
+	return nil
+}
+
+// InstanceTypePrices forwards PriceLister of the provider
+func (p *instrumentedProvider) InstanceTypePrices(ctx context.Context) (PriceCatalog, error) {
+	if lister, ok := p.Provider.(PriceLister); ok {
+		return lister.InstanceTypePrices(ctx)
+	}
+	return nil, nil
+}
+
+// SupportsSpot forwards SpotProvider of the provider
+func (p *instrumentedProvider) SupportsSpot() bool {
+	if provider, ok := p.Provider.(SpotProvider); ok {
+		return provider.SupportsSpot()
+	}
+	return false
+}
+
+// SupportedOverrides forwards OverrideProvider of the provider
+func (p *instrumentedProvider) SupportedOverrides() []string {
+	if provider, ok := p.Provider.(OverrideProvider); ok {
+		return provider.SupportedOverrides()
+	}
+	return nil
+}
+
+// RemainingInstances forwards QuotaReporter of the provider
+func (p *instrumentedProvider) RemainingInstances(ctx context.Context) (int, error) {
+	if reporter, ok := p.Provider.(QuotaReporter); ok {
+		return reporter.RemainingInstances(ctx)
+	}
+	return 0, ErrQuotaUnknown
+}
+
+// instanceTypeLabel returns the instance_type label of instanceType. Instance types are requested by pod
+// annotations, so only instance types accepted by the provider are used as labels to bound the number of series.
+func (p *instrumentedProvider) instanceTypeLabel(instanceType string) string {
+	if instanceType == "" {
+		return ""
+	}
+	for _, t := range p.InstanceTypes() {
+		if t == instanceType {
+			return instanceType
+		}
+	}
+	return metrics.InstanceTypeOther
+}
@@ -0,0 +1,76 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package cloud
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/metrics"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/cloudinit"
+)
+
+type mockInstanceTypeProvider struct {
+	mockProvider
+}
+
+func (p *mockInstanceTypeProvider) InstanceTypes() []string {
+	return []string{"t3.small", "t3.large"}
+}
+
+func (p *mockInstanceTypeProvider) CreateInstance(ctx context.Context, podName, sandboxID string, cloudConfig cloudinit.CloudConfigGenerator, spec InstanceTypeSpec) (*Instance, error) {
+	if spec.InstanceType == "invalid" {
+		return nil, errors.New("invalid instance type")
+	}
+	instance, err := p.mockProvider.CreateInstance(ctx, podName, sandboxID, cloudConfig, spec)
+	if err != nil {
+		return nil, err
+	}
+	instance.InstanceType = "t3.large"
+	return instance, nil
+}
+
+func TestInstrumentedProviderCreateInstance(t *testing.T) {
+
+	ctx := context.Background()
+	p := NewInstrumentedProvider("aws", &mockInstanceTypeProvider{}).(*instrumentedProvider)
+
+	// The label of a best fit instance is the instance type selected by the provider
+	instance, err := p.CreateInstance(ctx, "mypod", "123", nil, InstanceTypeSpec{VCPUs: 2})
+	require.NoError(t, err)
+	assert.Equal(t, "t3.large", p.instanceTypes[instance.ID])
+
+	_, err = p.CreateInstance(ctx, "mypod", "456", nil, InstanceTypeSpec{InstanceType: "invalid"})
+	assert.Error(t, err)
+	assert.Len(t, p.instanceTypes, 1)
+}
+
+func TestInstrumentedProviderInstanceTypes(t *testing.T) {
+
+	provider := NewInstrumentedProvider("aws", &mockInstanceTypeProvider{})
+
+	lister, ok := provider.(InstanceTypeLister)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"t3.small", "t3.large"}, lister.InstanceTypes())
+
+	p := provider.(*instrumentedProvider)
+	assert.Equal(t, "", p.instanceTypeLabel(""))
+	assert.Equal(t, "t3.large", p.instanceTypeLabel("t3.large"))
+	assert.Equal(t, metrics.InstanceTypeOther, p.instanceTypeLabel("m5.24xlarge"))
+
+	// Providers that do not validate instance types have only the default instance type as a label
+	p = NewInstrumentedProvider("libvirt", &mockProvider{}).(*instrumentedProvider)
+	assert.Nil(t, p.InstanceTypes())
+	assert.Nil(t, p.Placements())
+	prices, err := p.InstanceTypePrices(context.Background())
+	assert.Nil(t, prices)
+	assert.NoError(t, err)
+	assert.False(t, p.SupportsSpot())
+	assert.Empty(t, p.SupportedOverrides())
+	assert.Equal(t, metrics.InstanceTypeOther, p.instanceTypeLabel("t3.large"))
+}
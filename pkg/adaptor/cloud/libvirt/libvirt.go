@@ -16,6 +16,8 @@ import (
 	"github.com/avast/retry-go/v4"
 	libvirt "libvirt.org/go/libvirt"
 	libvirtxml "libvirt.org/go/libvirtxml"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/logging"
 )
 
 const (
@@ -64,7 +66,7 @@ func (s *sevGuestPolicy) getGuestPolicy() uint {
 
 // createCloudInitISO creates an ISO file with a userdata and a metadata file. The ISO image will be created in-memory since it is small
 func createCloudInitISO(v *vmConfig) ([]byte, error) {
-	logger.Println("Create cloudInit iso")
+	logger.Debug("creating cloud-init ISO", "instance_name", v.name)
 
 	userData := v.userData
 	metaData := fmt.Sprintf("local-hostname: %s", v.name)
@@ -74,7 +76,7 @@ func createCloudInitISO(v *vmConfig) ([]byte, error) {
 
 func checkDomainExistsByName(name string, libvirtClient *libvirtClient) (exist bool, err error) {
 
-	logger.Printf("Checking if instance (%s) exists", name)
+	logger.Debug("checking if instance exists", "instance_name", name)
 	domain, err := libvirtClient.connection.LookupDomainByName(name)
 	if err != nil {
 		if err.(libvirt.Error).Code == libvirt.ERR_NO_DOMAIN {
@@ -90,7 +92,7 @@ func checkDomainExistsByName(name string, libvirtClient *libvirtClient) (exist b
 
 func checkDomainExistsById(id uint32, libvirtClient *libvirtClient) (exist bool, err error) {
 
-	logger.Printf("Checking if instance (%d) exists", id)
+	logger.Debug("checking if instance exists", logging.KeyInstanceID, id)
 	domain, err := libvirtClient.connection.LookupDomainById(id)
 	if err != nil {
 		if err.(libvirt.Error).Code == libvirt.ERR_NO_DOMAIN {
@@ -106,7 +108,7 @@ func checkDomainExistsById(id uint32, libvirtClient *libvirtClient) (exist bool,
 
 func uploadIso(isoData []byte, isoVolName string, libvirtClient *libvirtClient) (string, error) {
 
-	logger.Printf("Uploading iso file: %s\n", isoVolName)
+	logger.Info("uploading ISO file", "volume", isoVolName)
 	volumeDef := newDefVolume(isoVolName)
 
 	img, err := newImageFromBytes(isoData)
@@ -266,10 +268,10 @@ func createDomainXMLs390x(client *libvirtClient, cfg *domainConfig, vm *vmConfig
 		},
 		Metadata: &libvirtxml.DomainMetadata{},
 		Memory: &libvirtxml.DomainMemory{
-			Value: cfg.mem, Unit: "GiB",
+			Value: cfg.mem, Unit: "MiB",
 		},
 		CurrentMemory: &libvirtxml.DomainCurrentMemory{
-			Value: cfg.mem, Unit: "GiB",
+			Value: cfg.mem, Unit: "MiB",
 		},
 		VCPU: &libvirtxml.DomainVCPU{
 			Value: cfg.cpu,
@@ -330,7 +332,7 @@ func createDomainXMLx86_64(client *libvirtClient, cfg *domainConfig, vm *vmConfi
 		Type:        "kvm",
 		Name:        cfg.name,
 		Description: "This Virtual Machine is the peer-pod VM",
-		Memory:      &libvirtxml.DomainMemory{Value: cfg.mem, Unit: "GiB", DumpCore: "on"},
+		Memory:      &libvirtxml.DomainMemory{Value: cfg.mem, Unit: "MiB", DumpCore: "on"},
 		VCPU:        &libvirtxml.DomainVCPU{Value: cfg.cpu},
 		OS: &libvirtxml.DomainOS{
 			Type: &libvirtxml.DomainOSType{Arch: "x86_64", Type: typeHardwareVirtualMachine},
@@ -527,25 +529,50 @@ func getDomainIPs(dom *libvirt.Domain) ([]netip.Addr, error) {
 	return ips, nil
 }
 
-func CreateDomain(ctx context.Context, libvirtClient *libvirtClient, v *vmConfig) (result *createDomainOutput, err error) {
+// getDomainClusterID returns the cluster ID recorded in the metadata of a domain,
+// or an empty string if the domain has no cluster ID
+func getDomainClusterID(dom *libvirt.Domain) (string, error) {
 
-	v.cpu = uint(2)
-	v.mem = uint(8)
-	v.rootDiskSize = uint64(10)
+	metadataXML, err := dom.GetMetadata(libvirt.DOMAIN_METADATA_ELEMENT, clusterMetadataURI, libvirt.DOMAIN_AFFECT_CONFIG)
+	if err != nil {
+		if lverr, ok := err.(libvirt.Error); ok && lverr.Code == libvirt.ERR_NO_DOMAIN_METADATA {
+			return "", nil
+		}
+		return "", err
+	}
+
+	var metadata clusterMetadata
+	if err := xml.Unmarshal([]byte(metadataXML), &metadata); err != nil {
+		return "", fmt.Errorf("Failed to parse cluster metadata: %s", err)
+	}
+
+	return metadata.ID, nil
+}
+
+func CreateDomain(ctx context.Context, libvirtClient *libvirtClient, v *vmConfig) (result *createDomainOutput, err error) {
+	logger := logger.WithContext(ctx)
 
 	exists, err := checkDomainExistsByName(v.name, libvirtClient)
 	if err != nil {
 		return nil, fmt.Errorf("Error in checking instance: %s", err)
 	}
 	if exists {
-		logger.Printf("Instance already exists ")
+		logger.Info("instance already exists", "instance_name", v.name)
 		return &createDomainOutput{
 			instance: v,
 		}, nil
 	}
 
+	baseVolName, networkName := libvirtClient.volName, libvirtClient.networkName
+	if v.baseVolName != "" {
+		baseVolName = v.baseVolName
+	}
+	if v.networkName != "" {
+		networkName = v.networkName
+	}
+
 	rootVolName := v.name + "-root.qcow2"
-	err = createVolume(rootVolName, v.rootDiskSize, libvirtClient.volName, libvirtClient)
+	err = createVolume(rootVolName, v.rootDiskSize, baseVolName, libvirtClient)
 	if err != nil {
 		return nil, fmt.Errorf("Error in creating volume: %s", err)
 	}
@@ -575,7 +602,7 @@ func CreateDomain(ctx context.Context, libvirtClient *libvirtClient, v *vmConfig
 		name:        v.name,
 		cpu:         v.cpu,
 		mem:         v.mem,
-		networkName: libvirtClient.networkName,
+		networkName: networkName,
 		bootDisk:    rootVolFile,
 		cidataDisk:  isoVolFile,
 	}
@@ -585,20 +612,31 @@ func CreateDomain(ctx context.Context, libvirtClient *libvirtClient, v *vmConfig
 		return nil, fmt.Errorf("error building the libvirt XML, cause: %w", err)
 	}
 
-	logger.Printf("Create XML for '%s'", v.name)
+	if v.clusterID != "" {
+		metadata, err := xml.Marshal(&clusterMetadata{
+			XMLName: xml.Name{Space: clusterMetadataURI, Local: "cluster"},
+			ID:      v.clusterID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create cluster metadata: %s", err)
+		}
+		domCfg.Metadata = &libvirtxml.DomainMetadata{XML: string(metadata)}
+	}
+
+	logger.Debug("creating domain XML", "instance_name", v.name)
 	domXML, err := domCfg.Marshal()
 	if err != nil {
 		return nil, fmt.Errorf("Failed to create domain xml: %s", err)
 	}
 
-	logger.Printf("Creating VM '%s'", v.name)
+	logger.Info("creating VM", "instance_name", v.name)
 	dom, err := libvirtClient.connection.DomainDefineXML(domXML)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to define domain: %s", err)
 	}
 
 	// Start Domain.
-	logger.Printf("Starting VM '%s'", v.name)
+	logger.Info("starting VM", "instance_name", v.name)
 	err = dom.Create()
 	if err != nil {
 		return nil, fmt.Errorf("Failed to start VM: %s", err)
@@ -610,7 +648,7 @@ func CreateDomain(ctx context.Context, libvirtClient *libvirtClient, v *vmConfig
 	}
 
 	v.instanceId = strconv.FormatUint(uint64(id), 10)
-	logger.Printf("VM id %s", v.instanceId)
+	logger.Info("started VM", "instance_name", v.name, logging.KeyInstanceID, v.instanceId)
 
 	// Wait for sometime for the IP to be visible
 	if err := retry.Do(
@@ -629,8 +667,8 @@ func CreateDomain(ctx context.Context, libvirtClient *libvirtClient, v *vmConfig
 		retry.Attempts(GetDomainIPsRetries),
 		retry.Delay(GetDomainIPsSleep),
 	); err != nil {
-		logger.Printf("Unable to get IP addresses after %d retries (sleep time=%ds): %s",
-			GetDomainIPsRetries, GetDomainIPsSleep, err)
+		logger.Error("unable to get IP addresses", logging.KeyInstanceID, v.instanceId,
+			"retries", GetDomainIPsRetries, "sleep", GetDomainIPsSleep, logging.KeyError, err)
 		return nil, fmt.Errorf("Domain (id=%d) IP addresses not found", id)
 	}
 
@@ -638,24 +676,25 @@ func CreateDomain(ctx context.Context, libvirtClient *libvirtClient, v *vmConfig
 		return nil, fmt.Errorf("Internal error on getting domain IPs: %s", err)
 	}
 
-	logger.Printf("Instance created successfully")
+	logger.Info("instance created successfully", logging.KeyInstanceID, v.instanceId)
 	return &createDomainOutput{
 		instance: v,
 	}, nil
 }
 
 func DeleteDomain(ctx context.Context, libvirtClient *libvirtClient, id string) (err error) {
+	logger := logger.WithContext(ctx).With(logging.KeyInstanceID, id)
 
-	logger.Printf("Deleting instance (%s)", id)
+	logger.Info("deleting instance")
 	idUint, _ := strconv.ParseUint(id, 10, 64)
 	// libvirt API takes uint32
 	exists, err := checkDomainExistsById(uint32(idUint), libvirtClient)
 	if err != nil {
-		logger.Printf("Unable to check instance (%s)", id)
+		logger.Error("unable to check instance", logging.KeyError, err)
 		return err
 	}
 	if !exists {
-		logger.Printf("Instance (%s) not found", id)
+		logger.Info("instance not found")
 		return err
 	}
 	// Stop and undefine domain
@@ -665,20 +704,20 @@ func DeleteDomain(ctx context.Context, libvirtClient *libvirtClient, id string)
 
 	domain, err := libvirtClient.connection.LookupDomainById(uint32(idUint))
 	if err != nil {
-		logger.Printf("Error retrieving libvirt domain: %s", err)
+		logger.Error("error retrieving libvirt domain", logging.KeyError, err)
 		return err
 	}
 	defer freeDomain(domain, &err)
 
 	state, _, err := domain.GetState()
 	if err != nil {
-		logger.Printf("Couldn't get info about domain: %s", err)
+		logger.Error("couldn't get info about domain", logging.KeyError, err)
 		return err
 	}
 
 	if state == libvirt.DOMAIN_RUNNING || state == libvirt.DOMAIN_PAUSED {
 		if err = domain.Destroy(); err != nil {
-			logger.Printf("Couldn't destroy libvirt domain: %s", err)
+			logger.Error("couldn't destroy libvirt domain", logging.KeyError, err)
 			return err
 		}
 	}
@@ -686,38 +725,38 @@ func DeleteDomain(ctx context.Context, libvirtClient *libvirtClient, id string)
 	// Delete volumes
 	domainXMLDesc, err := domain.GetXMLDesc(0)
 	if err != nil {
-		logger.Printf("Error retrieving libvirt domain XML description: %s", err)
+		logger.Error("error retrieving libvirt domain XML description", logging.KeyError, err)
 		return err
 	}
 	domainDef := libvirtxml.Domain{}
 	err = xml.Unmarshal([]byte(domainXMLDesc), &domainDef)
 	if err != nil {
-		logger.Printf("Unable to get the domain XML: %s", err)
+		logger.Error("unable to get the domain XML", logging.KeyError, err)
 	}
 
 	// Get the volume path from the XML
-	logger.Printf("domainDef %v", domainDef.Devices.Disks)
+	logger.Debug("domain disks", "disks", domainDef.Devices.Disks)
 	vol1File := domainDef.Devices.Disks[0].Source.File.File
 	vol2File := domainDef.Devices.Disks[1].Source.File.File
 
 	err = deleteVolumeByPath(libvirtClient, vol1File)
 	if err != nil {
-		logger.Printf("Deleting volume (%s) returned error: %s", vol1File, err)
+		logger.Warn("deleting volume returned error", "volume", vol1File, logging.KeyError, err)
 	}
 	err = deleteVolumeByPath(libvirtClient, vol2File)
 	if err != nil {
-		logger.Printf("Deleting volume (%s) returned error: %s", vol2File, err)
+		logger.Warn("deleting volume returned error", "volume", vol2File, logging.KeyError, err)
 	}
 	// Undefine the domain
 	if err := domain.UndefineFlags(libvirt.DOMAIN_UNDEFINE_NVRAM); err != nil {
 		if e := err.(libvirt.Error); e.Code == libvirt.ERR_NO_SUPPORT || e.Code == libvirt.ERR_INVALID_ARG {
-			logger.Printf("libvirt does not support undefine flags: will try again without flags")
+			logger.Info("libvirt does not support undefine flags, trying again without flags")
 			if err = domain.Undefine(); err != nil {
-				logger.Printf("couldn't undefine libvirt domain: %v", err)
+				logger.Error("couldn't undefine libvirt domain", logging.KeyError, err)
 				return err
 			}
 		} else {
-			logger.Printf("couldn't undefine libvirt domain with flags: %v", err)
+			logger.Error("couldn't undefine libvirt domain with flags", logging.KeyError, err)
 			return err
 		}
 	}
@@ -725,6 +764,60 @@ func DeleteDomain(ctx context.Context, libvirtClient *libvirtClient, id string)
 	return nil
 }
 
+// ListDomains returns active domains whose name matches filter
+func ListDomains(ctx context.Context, libvirtClient *libvirtClient, filter func(name string) bool) ([]*vmConfig, error) {
+	logger := logger.WithContext(ctx)
+
+	domains, err := libvirtClient.connection.ListAllDomains(libvirt.CONNECT_LIST_DOMAINS_ACTIVE)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list domains: %s", err)
+	}
+
+	var vms []*vmConfig
+
+	for i := range domains {
+		dom := &domains[i]
+
+		name, err := dom.GetName()
+		if err != nil {
+			logger.Warn("couldn't get domain name", logging.KeyError, err)
+			_ = dom.Free()
+			continue
+		}
+		if !filter(name) {
+			_ = dom.Free()
+			continue
+		}
+
+		id, err := dom.GetID()
+		if err != nil {
+			logger.Warn("couldn't get ID of domain", "instance_name", name, logging.KeyError, err)
+			_ = dom.Free()
+			continue
+		}
+
+		ips, err := getDomainIPs(dom)
+		if err != nil {
+			logger.Warn("couldn't get IPs of domain", "instance_name", name, logging.KeyError, err)
+		}
+
+		clusterID, err := getDomainClusterID(dom)
+		if err != nil {
+			logger.Warn("couldn't get cluster ID of domain", "instance_name", name, logging.KeyError, err)
+		}
+
+		vms = append(vms, &vmConfig{
+			name:       name,
+			instanceId: strconv.FormatUint(uint64(id), 10),
+			ips:        ips,
+			clusterID:  clusterID,
+		})
+		_ = dom.Free()
+	}
+
+	return vms, nil
+}
+
 func NewLibvirtClient(libvirtCfg Config) (*libvirtClient, error) {
 
 	// Define Domain via XML created before.
@@ -748,7 +841,7 @@ func NewLibvirtClient(libvirtCfg Config) (*libvirtClient, error) {
 		return nil, err
 	}
 
-	logger.Println("Created libvirt connection")
+	logger.Info("created libvirt connection", "uri", libvirtCfg.URI)
 
 	return &libvirtClient{
 		connection:  conn,
@@ -4,6 +4,7 @@
 package libvirt
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -94,7 +95,7 @@ func TestCreateDomainXMLs390x(t *testing.T) {
 	domainCfg := domainConfig{
 		name:        "TestCreateDomainS390x",
 		cpu:         2,
-		mem:         2,
+		mem:         2048,
 		networkName: client.networkName,
 		bootDisk:    "/var/lib/libvirt/images/root.qcow2",
 		cidataDisk:  "/var/lib/libvirt/images/cidata.iso",
@@ -116,3 +117,58 @@ func TestCreateDomainXMLs390x(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestSelectInstanceType(t *testing.T) {
+
+	cfg := Config{
+		InstanceTypeSpecList: []cloud.InstanceTypeSpec{
+			{InstanceType: "large", VCPUs: 8, Memory: 16384},
+			{InstanceType: "small", VCPUs: 1, Memory: 2048},
+		},
+		VCPUs:  defaultVCPUs,
+		Memory: defaultMemory,
+	}
+	p := &libvirtProvider{serviceConfig: &cfg, instanceTypeSpecList: instanceTypeSpecList(&cfg)}
+
+	assert.Equal(t, []string{"small", "large"}, p.InstanceTypes())
+
+	for name, tc := range map[string]struct {
+		spec     cloud.InstanceTypeSpec
+		expected cloud.InstanceTypeSpec
+		err      bool
+	}{
+		"default": {
+			expected: cloud.InstanceTypeSpec{VCPUs: defaultVCPUs, Memory: defaultMemory},
+		},
+		"instance type": {
+			spec:     cloud.InstanceTypeSpec{InstanceType: "small"},
+			expected: cloud.InstanceTypeSpec{InstanceType: "small", VCPUs: 1, Memory: 2048},
+		},
+		"best fit of the default": {
+			spec:     cloud.InstanceTypeSpec{VCPUs: 2, Memory: 4096},
+			expected: cloud.InstanceTypeSpec{VCPUs: defaultVCPUs, Memory: defaultMemory},
+		},
+		"best fit": {
+			spec:     cloud.InstanceTypeSpec{VCPUs: 4, Memory: 4096},
+			expected: cloud.InstanceTypeSpec{InstanceType: "large", VCPUs: 8, Memory: 16384},
+		},
+		"unknown instance type": {
+			spec: cloud.InstanceTypeSpec{InstanceType: "medium"},
+			err:  true,
+		},
+		"too large": {
+			spec: cloud.InstanceTypeSpec{VCPUs: 16, Memory: 4096},
+			err:  true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			spec, err := p.selectInstanceType(context.Background(), tc.spec)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, spec)
+		})
+	}
+}
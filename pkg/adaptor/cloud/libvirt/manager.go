@@ -7,6 +7,7 @@ package libvirt
 
 import (
 	"flag"
+	"os"
 
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud"
 )
@@ -23,33 +24,58 @@ const (
 	defaultVolName        = "podvm-base.qcow2"
 	defaultLaunchSecurity = ""
 	defaultFirmware       = "/usr/share/edk2/ovmf/OVMF_CODE.fd"
+	defaultVCPUs          = 2
+	defaultMemory         = 8192
+	defaultRootDiskSize   = 10
 )
 
-func init() {
-	cloud.AddCloud("libvirt", &Manager{})
+func (*Manager) ParseCmd(flags *flag.FlagSet) {
+	parseCmd(flags, &libvirtcfg)
 }
 
-func (*Manager) ParseCmd(flags *flag.FlagSet) {
+func parseCmd(flags *flag.FlagSet, cfg *Config) {
 
-	flags.StringVar(&libvirtcfg.URI, "uri", defaultURI, "libvirt URI")
-	flags.StringVar(&libvirtcfg.PoolName, "pool-name", defaultPoolName, "libvirt storage pool")
-	flags.StringVar(&libvirtcfg.NetworkName, "network-name", defaultNetworkName, "libvirt network pool")
-	flags.StringVar(&libvirtcfg.DataDir, "data-dir", defaultDataDir, "libvirt storage dir")
-	flags.BoolVar(&libvirtcfg.DisableCVM, "disable-cvm", false, "Use non-CVMs for peer pods")
-	flags.StringVar(&libvirtcfg.LaunchSecurity, "launch-security", defaultLaunchSecurity, "Libvirt's LaunchSecurity element for Confidential VMs. SEV or s390-pv. If omitted, will automatically determine.")
-	flags.StringVar(&libvirtcfg.Firmware, "firmware", defaultFirmware, "Path to OVMF")
+	flags.StringVar(&cfg.URI, "uri", defaultURI, "libvirt URI")
+	flags.StringVar(&cfg.PoolName, "pool-name", defaultPoolName, "libvirt storage pool")
+	flags.StringVar(&cfg.NetworkName, "network-name", defaultNetworkName, "libvirt network pool")
+	flags.StringVar(&cfg.DataDir, "data-dir", defaultDataDir, "libvirt storage dir")
+	flags.BoolVar(&cfg.DisableCVM, "disable-cvm", false, "Use non-CVMs for peer pods")
+	flags.StringVar(&cfg.LaunchSecurity, "launch-security", defaultLaunchSecurity, "Libvirt's LaunchSecurity element for Confidential VMs. SEV or s390-pv. If omitted, will automatically determine.")
+	flags.StringVar(&cfg.Firmware, "firmware", defaultFirmware, "Path to OVMF")
+	flags.StringVar(&cfg.ClusterID, "cluster-id", "", "ID of the cluster to record in the metadata of the Pod VMs, defaults to `CLUSTER_ID`")
+	flags.StringVar(&cfg.InstanceType, "instance-type", "", "Default instance type of the Pod VMs, defaults to `PODVM_INSTANCE_TYPE`. If it is not in -instance-types, it is sized by -vcpus and -memory")
+	flags.Var((*cloud.InstanceTypeSpecFlag)(&cfg.InstanceTypeSpecList), "instance-types", "Instance types that Pod VMs may be sized by, comma separated name:vcpus:memory with memory in MiB")
+	flags.UintVar(&cfg.VCPUs, "vcpus", defaultVCPUs, "Number of vCPUs of Pod VMs of the default instance type")
+	flags.UintVar(&cfg.Memory, "memory", defaultMemory, "Memory (in MiB) of Pod VMs of the default instance type")
+	flags.Uint64Var(&cfg.RootDiskSize, "root-disk-size", defaultRootDiskSize, "Root disk size (in GiB) of the Pod VMs")
 
 }
 
 func (*Manager) LoadEnv() {
-	cloud.DefaultToEnv(&libvirtcfg.URI, "LIBVIRT_URI", defaultURI)
-	cloud.DefaultToEnv(&libvirtcfg.PoolName, "LIBVIRT_POOL", defaultPoolName)
-	cloud.DefaultToEnv(&libvirtcfg.NetworkName, "LIBVIRT_NET", defaultNetworkName)
-	cloud.DefaultToEnv(&libvirtcfg.VolName, "LIBVIRT_VOL_NAME", defaultVolName)
-	cloud.DefaultToEnv(&libvirtcfg.LaunchSecurity, "LIBVIRT_LAUNCH_SECURITY", defaultLaunchSecurity)
-	cloud.DefaultToEnv(&libvirtcfg.Firmware, "LIBVIRT_FIRMWARE", defaultFirmware)
+	loadEnv(&libvirtcfg, os.Getenv)
+}
+
+func loadEnv(cfg *Config, getenv cloud.Getenv) {
+	getenv.DefaultTo(&cfg.URI, "LIBVIRT_URI", defaultURI)
+	getenv.DefaultTo(&cfg.PoolName, "LIBVIRT_POOL", defaultPoolName)
+	getenv.DefaultTo(&cfg.NetworkName, "LIBVIRT_NET", defaultNetworkName)
+	getenv.DefaultTo(&cfg.VolName, "LIBVIRT_VOL_NAME", defaultVolName)
+	getenv.DefaultTo(&cfg.LaunchSecurity, "LIBVIRT_LAUNCH_SECURITY", defaultLaunchSecurity)
+	getenv.DefaultTo(&cfg.Firmware, "LIBVIRT_FIRMWARE", defaultFirmware)
+	getenv.DefaultTo(&cfg.ClusterID, "CLUSTER_ID", "")
+	getenv.DefaultTo(&cfg.InstanceType, "PODVM_INSTANCE_TYPE", "")
 }
 
 func (*Manager) NewProvider() (cloud.Provider, error) {
 	return NewProvider(&libvirtcfg)
 }
+
+func (*Manager) NewProviderFromConfig(config map[string]string) (cloud.Provider, error) {
+
+	var cfg Config
+	if err := cloud.LoadConfig(config, func(flags *flag.FlagSet) { parseCmd(flags, &cfg) }, func(getenv cloud.Getenv) { loadEnv(&cfg, getenv) }); err != nil {
+		return nil, err
+	}
+
+	return NewProvider(&cfg)
+}
@@ -8,46 +8,65 @@ package libvirt
 import (
 	"context"
 	"fmt"
-	"log"
 	"net/netip"
 
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud"
+	peerpodannotations "github.com/confidential-containers/cloud-api-adaptor/pkg/annotations"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/util"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/cloudinit"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/logging"
 )
 
-var logger = log.New(log.Writer(), "[adaptor/cloud/libvirt] ", log.LstdFlags|log.Lmsgprefix)
+var logger = logging.New("adaptor/cloud/libvirt")
 
 const maxInstanceNameLen = 63
 
 type libvirtProvider struct {
 	libvirtClient *libvirtClient
 	serviceConfig *Config
+	// instanceTypeSpecList is the sorted catalog of instance types, including the default instance type
+	instanceTypeSpecList []cloud.InstanceTypeSpec
 }
 
 func NewProvider(config *Config) (cloud.Provider, error) {
 
-	logger.Printf("libvirt config: %#v", config)
+	logger.Info("libvirt config", "config", config)
 
 	libvirtClient, err := NewLibvirtClient(*config)
 	if err != nil {
-		logger.Printf("Unable to create libvirt connection: %v", err)
+		logger.Error("unable to create libvirt connection", logging.KeyError, err)
 		return nil, err
 	}
 
 	provider := &libvirtProvider{
-		libvirtClient: libvirtClient,
-		serviceConfig: config,
+		libvirtClient:        libvirtClient,
+		serviceConfig:        config,
+		instanceTypeSpecList: instanceTypeSpecList(config),
 	}
 
 	return provider, nil
 }
 
+// instanceTypeSpecList returns the catalog of instance types of config, including the default instance type
+func instanceTypeSpecList(config *Config) []cloud.InstanceTypeSpec {
+
+	specList := append([]cloud.InstanceTypeSpec{}, config.InstanceTypeSpecList...)
+	if _, ok := cloud.GetInstanceTypeSpec(specList, config.InstanceType); !ok {
+		specList = append(specList, cloud.InstanceTypeSpec{
+			InstanceType: config.InstanceType,
+			VCPUs:        int64(config.VCPUs),
+			Memory:       int64(config.Memory),
+		})
+	}
+	return cloud.SortInstanceTypesOnMemory(specList)
+}
+
 func getIPs(instance *vmConfig) ([]netip.Addr, error) {
 	return instance.ips, nil
 }
 
 func (p *libvirtProvider) CreateInstance(ctx context.Context, podName, sandboxID string, cloudConfig cloudinit.CloudConfigGenerator, spec cloud.InstanceTypeSpec) (*cloud.Instance, error) {
+	logger := logger.WithContext(ctx)
 
 	instanceName := util.GenerateInstanceName(podName, sandboxID, maxInstanceNameLen)
 
@@ -56,8 +75,24 @@ func (p *libvirtProvider) CreateInstance(ctx context.Context, podName, sandboxID
 		return nil, err
 	}
 
+	instanceTypeSpec, err := p.selectInstanceType(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+
 	// TODO: Specify the maximum instance name length in Libvirt
-	vm := &vmConfig{name: instanceName, userData: userData, firmware: p.serviceConfig.Firmware}
+	vm := &vmConfig{
+		name:         instanceName,
+		cpu:          uint(instanceTypeSpec.VCPUs),
+		mem:          uint(instanceTypeSpec.Memory),
+		rootDiskSize: p.serviceConfig.RootDiskSize,
+		userData:     userData,
+		firmware:     p.serviceConfig.Firmware,
+		clusterID:    p.serviceConfig.ClusterID,
+		// The image of a pod is a volume of the storage pool, and its subnet is a libvirt network
+		baseVolName: spec.ImageID,
+		networkName: spec.SubnetID,
+	}
 
 	if p.serviceConfig.DisableCVM {
 		vm.launchSecurityType = NoLaunchSecurity
@@ -73,49 +108,79 @@ func (p *libvirtProvider) CreateInstance(ctx context.Context, podName, sandboxID
 	} else {
 		vm.launchSecurityType, err = GetLaunchSecurityType(p.serviceConfig.URI)
 		if err != nil {
-			logger.Printf("unable to determine launch security type [%v]", err)
+			logger.Error("unable to determine launch security type", logging.KeyError, err)
 			return nil, err
 		}
 	}
-	logger.Printf("LaunchSecurityType: %s", vm.launchSecurityType.String())
+	logger.Info("launch security type", "launch_security_type", vm.launchSecurityType.String())
 
 	result, err := CreateDomain(ctx, p.libvirtClient, vm)
 	if err != nil {
-		logger.Printf("failed to create an instance : %v", err)
+		logger.Error("failed to create an instance", logging.KeyError, err)
 		return nil, err
 	}
 
 	instanceID := result.instance.instanceId
 
-	logger.Printf("created an instance %s for sandbox %s", result.instance.name, sandboxID)
+	logger.Info("created an instance", "instance_name", result.instance.name, logging.KeySandboxID, sandboxID)
 
 	//Get Libvirt VM IP
 	ips, err := getIPs(result.instance)
 	if err != nil {
-		logger.Printf("failed to get IPs for the instance : %v ", err)
+		logger.Error("failed to get IPs of the instance", logging.KeyError, err)
 		return nil, err
 	}
 
 	instance := &cloud.Instance{
-		ID:   instanceID,
-		Name: instanceName,
-		IPs:  ips,
+		ID:           instanceID,
+		Name:         instanceName,
+		IPs:          ips,
+		InstanceType: instanceTypeSpec.InstanceType,
 	}
 
 	return instance, nil
 }
 
 func (p *libvirtProvider) DeleteInstance(ctx context.Context, instanceID string) error {
+	logger := logger.WithContext(ctx)
+
 	err := DeleteDomain(ctx, p.libvirtClient, instanceID)
 	if err != nil {
-		logger.Printf("failed to delete instance : %v", err)
+		logger.Error("failed to delete an instance", logging.KeyInstanceID, instanceID, logging.KeyError, err)
 		return err
 	}
-	logger.Printf("deleted an instance %s", instanceID)
+	logger.Info("deleted an instance", logging.KeyInstanceID, instanceID)
 	return nil
 
 }
 
+func (p *libvirtProvider) ListInstances(ctx context.Context) ([]*cloud.Instance, error) {
+
+	if p.serviceConfig.ClusterID == "" {
+		return nil, cloud.ErrNoClusterID
+	}
+
+	vms, err := ListDomains(ctx, p.libvirtClient, util.IsPodVMName)
+	if err != nil {
+		logger.WithContext(ctx).Error("failed to list instances", logging.KeyError, err)
+		return nil, err
+	}
+
+	var instances []*cloud.Instance
+	for _, vm := range vms {
+		if vm.clusterID != p.serviceConfig.ClusterID {
+			continue
+		}
+		instances = append(instances, &cloud.Instance{
+			ID:   vm.instanceId,
+			Name: vm.name,
+			IPs:  vm.ips,
+		})
+	}
+
+	return instances, nil
+}
+
 func (p *libvirtProvider) Teardown() error {
 	return nil
 }
@@ -127,3 +192,29 @@ func (p *libvirtProvider) ConfigVerifier() error {
 	}
 	return nil
 }
+
+// SupportedOverrides returns the override annotations of the image volume and network of pod VMs.
+// Domains have no security groups or tags.
+func (p *libvirtProvider) SupportedOverrides() []string {
+	return []string{peerpodannotations.ImageID, peerpodannotations.SubnetID}
+}
+
+// InstanceTypes returns the instance types that pods may request
+func (p *libvirtProvider) InstanceTypes() []string {
+	return cloud.InstanceTypeNames(p.instanceTypeSpecList)
+}
+
+// selectInstanceType selects the instance type of a pod VM from the catalog, based on the instance type,
+// vCPU and memory requirements of spec
+func (p *libvirtProvider) selectInstanceType(ctx context.Context, spec cloud.InstanceTypeSpec) (cloud.InstanceTypeSpec, error) {
+
+	instanceType, err := cloud.SelectInstanceTypeToUse(spec, p.instanceTypeSpecList, p.InstanceTypes(), p.serviceConfig.InstanceType)
+	if err != nil {
+		return cloud.InstanceTypeSpec{}, err
+	}
+
+	instanceTypeSpec, _ := cloud.GetInstanceTypeSpec(p.instanceTypeSpecList, instanceType)
+	logger.WithContext(ctx).Info("selected an instance type", "instance_type", instanceType, "vcpus", instanceTypeSpec.VCPUs, "memory_mib", instanceTypeSpec.Memory)
+
+	return instanceTypeSpec, nil
+}
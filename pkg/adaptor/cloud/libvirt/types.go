@@ -6,8 +6,10 @@
 package libvirt
 
 import (
+	"encoding/xml"
 	"net/netip"
 
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud"
 	libvirt "libvirt.org/go/libvirt"
 	libvirtxml "libvirt.org/go/libvirtxml"
 )
@@ -21,11 +23,28 @@ type Config struct {
 	VolName        string
 	LaunchSecurity string
 	Firmware       string
+	ClusterID      string
+	InstanceType   string
+	// InstanceTypeSpecList is the catalog of instance types that pod VMs may be sized by
+	InstanceTypeSpecList []cloud.InstanceTypeSpec
+	// VCPUs, Memory in MiB and RootDiskSize in GiB of pod VMs of the default instance type, unless it is in InstanceTypeSpecList
+	VCPUs        uint
+	Memory       uint
+	RootDiskSize uint64
+}
+
+// clusterMetadataURI is the namespace of the domain metadata element that holds the cluster ID of a pod VM
+const clusterMetadataURI = "https://confidentialcontainers.org/peerpod/cluster"
+
+type clusterMetadata struct {
+	XMLName xml.Name
+	ID      string `xml:"id,attr"`
 }
 
 type vmConfig struct {
-	name               string
-	cpu                uint
+	name string
+	cpu  uint
+	// mem is the memory in MiB
 	mem                uint
 	rootDiskSize       uint64
 	userData           string
@@ -33,6 +52,10 @@ type vmConfig struct {
 	instanceId         string //keeping it consistent with sandbox.vsi
 	launchSecurityType LaunchSecurityType
 	firmware           string
+	clusterID          string
+	// baseVolName and networkName override the base volume and network of the client, unless empty
+	baseVolName string
+	networkName string
 }
 
 type createDomainOutput struct {
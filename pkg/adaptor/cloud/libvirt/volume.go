@@ -16,6 +16,8 @@ import (
 
 	libvirt "libvirt.org/go/libvirt"
 	libvirtxml "libvirt.org/go/libvirtxml"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/logging"
 )
 
 // ErrVolumeNotFound is returned when a domain is not found
@@ -34,7 +36,7 @@ func waitForSuccess(errorMessage string, f func() error) error {
 		if err == nil {
 			return nil
 		}
-		logger.Printf("%s. Re-trying.\n", err)
+		logger.Warn("re-trying", logging.KeyError, err)
 
 		time.Sleep(waitSleepInterval)
 		if time.Since(start) > waitTimeout {
@@ -137,7 +139,7 @@ func uploadVolume(libvirtClient *libvirtClient, volumeDef libvirtxml.StorageVolu
 	if err != nil {
 		return "", fmt.Errorf("Error retrieving volume key: %s", err)
 	}
-	logger.Printf("Volume ID: %s", volumeKey)
+	logger.Info("uploaded volume", "volume_key", volumeKey)
 	return volumeKey, nil
 }
 
@@ -176,7 +178,7 @@ func newCopier(conn *libvirt.Connect, volume *libvirt.StorageVol, size uint64) f
 		if err != nil {
 			return err
 		}
-		logger.Printf("%d bytes uploaded\n", bytesCopied)
+		logger.Debug("bytes uploaded", "bytes", bytesCopied)
 		return nil
 	}
 	return copier
@@ -238,7 +240,7 @@ func createVolume(volName string, volSize uint64, baseVolName string, libvirtCli
 		return fmt.Errorf("Error retrieving volume key: %s", err)
 	}
 
-	logger.Printf("Uploaded volume key %s", key)
+	logger.Info("created volume", "volume", volName, "volume_key", key)
 	return nil
 
 }
@@ -260,7 +262,7 @@ func getVolume(libvirtClient *libvirtClient, volumeName string) (*libvirt.Storag
 // VolumeExists checks if a volume exists
 func volumeExists(libvirtClient *libvirtClient, volumeName string) (exist bool, err error) {
 
-	logger.Printf("Check if %s volume exists", volumeName)
+	logger.Debug("checking if volume exists", "volume", volumeName)
 	volume, err := getVolume(libvirtClient, volumeName)
 	if err != nil {
 		return false, nil
@@ -276,7 +278,7 @@ func deleteVolumeByPath(libvirtClient *libvirtClient, path string) (err error) {
 
 	volume, err := libvirtClient.connection.LookupStorageVolByPath(path)
 	if err != nil {
-		logger.Printf("can't retrieve volume %q: %v", path, err)
+		logger.Error("can't retrieve volume", "path", path, logging.KeyError, err)
 		return err
 	}
 
@@ -285,7 +287,7 @@ func deleteVolumeByPath(libvirtClient *libvirtClient, path string) (err error) {
 	// Get name
 	name, err := volume.GetName()
 	if err != nil {
-		logger.Printf("Error retrieving volume name: %s", err)
+		logger.Error("error retrieving volume name", "path", path, logging.KeyError, err)
 		return err
 	}
 
@@ -296,14 +298,14 @@ func deleteVolumeByPath(libvirtClient *libvirtClient, path string) (err error) {
 func deleteVolume(libvirtClient *libvirtClient, name string) (err error) {
 	exists, err := volumeExists(libvirtClient, name)
 	if err != nil {
-		logger.Printf("Unable to check if volume (%s) exists", name)
+		logger.Error("unable to check if volume exists", "volume", name, logging.KeyError, err)
 		return err
 	}
 	if !exists {
-		logger.Printf("Volume %s does not exists", name)
+		logger.Info("volume does not exist", "volume", name)
 		return ErrVolumeNotFound
 	}
-	logger.Printf("Deleting volume %s", name)
+	logger.Info("deleting volume", "volume", name)
 
 	volume, err := getVolume(libvirtClient, name)
 	if err != nil {
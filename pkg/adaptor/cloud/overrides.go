@@ -0,0 +1,124 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package cloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	peerpodannotations "github.com/confidential-containers/cloud-api-adaptor/pkg/annotations"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util"
+)
+
+// AnyValue allows any value of an override annotation in an OverridePolicy
+const AnyValue = "*"
+
+// OverridePolicy maps override annotations to the values that pods may set, or to the tag keys that pods may set for
+// the tags annotation. Override annotations that are not in the policy are rejected, so an empty policy rejects all.
+type OverridePolicy map[string][]string
+
+// LoadOverridePolicy loads an override policy from a JSON file, e.g. {"kata.peerpods.io/subnet-id": ["subnet-1", "subnet-2"]}
+func LoadOverridePolicy(path string) (OverridePolicy, error) {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading override policy file: %w", err)
+	}
+
+	var policy OverridePolicy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("parsing override policy file %s: %w", path, err)
+	}
+
+	for key, values := range policy {
+		if !peerpodannotations.IsOverride(key) {
+			return nil, fmt.Errorf("override policy file %s: %q is not an override annotation", path, key)
+		}
+		for _, value := range values {
+			if value == "" {
+				return nil, fmt.Errorf("override policy file %s: empty value of annotation %s", path, key)
+			}
+		}
+	}
+
+	return policy, nil
+}
+
+// check returns an error if the policy does not allow an override of a pod
+func (p OverridePolicy) check(overrides peerpodannotations.Overrides) error {
+
+	if overrides.ImageID != "" {
+		if err := p.allow(peerpodannotations.ImageID, "image", overrides.ImageID); err != nil {
+			return err
+		}
+	}
+	if overrides.SubnetID != "" {
+		if err := p.allow(peerpodannotations.SubnetID, "subnet", overrides.SubnetID); err != nil {
+			return err
+		}
+	}
+	for _, id := range overrides.SecurityGroupIDs {
+		if err := p.allow(peerpodannotations.SecurityGroupIDs, "security group", id); err != nil {
+			return err
+		}
+	}
+	// Sort keys so that an error is reported deterministically
+	keys := make([]string, 0, len(overrides.Tags))
+	for key := range overrides.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		// The cluster ID tag tells which instances the orphan reconciler of a cluster may delete
+		if key == ClusterIDTag {
+			return fmt.Errorf("annotation %s: tag %q is reserved", peerpodannotations.Tags, key)
+		}
+		if err := p.allow(peerpodannotations.Tags, "tag", key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p OverridePolicy) allow(key, kind, value string) error {
+	allowed, ok := p[key]
+	if !ok {
+		return fmt.Errorf("annotation %s is not allowed by the override policy", key)
+	}
+	for _, a := range allowed {
+		if a == AnyValue || a == value {
+			return nil
+		}
+	}
+	return fmt.Errorf("annotation %s: %s %q is not allowed by the override policy", key, kind, value)
+}
+
+// checkOverrides returns an error if the provider does not support an override of a pod, or the override policy does not allow it
+func (s *cloudService) checkOverrides(overrides peerpodannotations.Overrides) error {
+
+	keys := overrides.Keys()
+	if len(keys) == 0 {
+		return nil
+	}
+
+	var supported []string
+	if provider, ok := s.provider.(OverrideProvider); ok {
+		supported = provider.SupportedOverrides()
+	}
+	for _, key := range keys {
+		if !util.Contains(supported, key) {
+			return fmt.Errorf("annotation %s overrides a setting that the cloud provider does not support", key)
+		}
+	}
+
+	return s.overridePolicy.check(overrides)
+}
+
+// hasOverrides returns true if spec overrides a setting of the provider
+func (spec InstanceTypeSpec) hasOverrides() bool {
+	return spec.ImageID != "" || spec.SubnetID != "" || len(spec.SecurityGroupIDs) > 0 || len(spec.Tags) > 0
+}
@@ -0,0 +1,136 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package cloud
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	cri "github.com/containerd/containerd/pkg/cri/annotations"
+	pb "github.com/kata-containers/kata-containers/src/runtime/protocols/hypervisor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	peerpodannotations "github.com/confidential-containers/cloud-api-adaptor/pkg/annotations"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/forwarder"
+)
+
+func TestLoadOverridePolicy(t *testing.T) {
+
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "policy.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"kata.peerpods.io/subnet-id": ["subnet-1"], "kata.peerpods.io/tags": ["*"]}`), 0600))
+
+	policy, err := LoadOverridePolicy(path)
+	require.NoError(t, err)
+	assert.Equal(t, OverridePolicy{peerpodannotations.SubnetID: {"subnet-1"}, peerpodannotations.Tags: {AnyValue}}, policy)
+
+	for name, content := range map[string]string{
+		"unknown.json": `{"kata.peerpods.io/vcpus": ["2"]}`,
+		"empty.json":   `{"kata.peerpods.io/image-id": [""]}`,
+		"invalid.json": `["kata.peerpods.io/image-id"]`,
+	} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+		_, err := LoadOverridePolicy(path)
+		assert.Error(t, err, name)
+	}
+
+	_, err = LoadOverridePolicy(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+}
+
+type mockOverrideProvider struct {
+	mockSpotProvider
+}
+
+func (p *mockOverrideProvider) SupportedOverrides() []string {
+	return []string{peerpodannotations.ImageID, peerpodannotations.SubnetID, peerpodannotations.Tags}
+}
+
+func TestCloudServiceOverrides(t *testing.T) {
+
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	request := func(id string, overrides map[string]string) *pb.CreateVMRequest {
+		annotations := map[string]string{
+			cri.SandboxNamespace: "default",
+			cri.SandboxName:      "mypod",
+		}
+		for key, value := range overrides {
+			annotations[key] = value
+		}
+		return &pb.CreateVMRequest{Id: id, Annotations: annotations}
+	}
+
+	policy := OverridePolicy{
+		peerpodannotations.ImageID:  {AnyValue},
+		peerpodannotations.SubnetID: {"subnet-1", "subnet-2"},
+		peerpodannotations.Tags:     {"team", ClusterIDTag},
+	}
+
+	// Providers without overrides reject override annotations before a pod index is allocated
+	workerNode := &mockWorkerNode{}
+	s := NewService(&mockProvider{}, &mockProxyFactory{podsDir: dir}, workerNode, dir, forwarder.DefaultListenPort, "", nil, nil, policy, 0, 1)
+	_, err := s.CreateVM(ctx, request("123", map[string]string{peerpodannotations.SubnetID: "subnet-1"}))
+	assert.ErrorContains(t, err, "annotation kata.peerpods.io/subnet-id overrides a setting that the cloud provider does not support")
+	assert.Equal(t, 0, workerNode.released)
+
+	provider := &mockOverrideProvider{}
+	s = NewService(provider, &mockProxyFactory{podsDir: dir}, &mockWorkerNode{}, dir, forwarder.DefaultListenPort, "", nil, nil, policy, 0, 1)
+
+	for name, tc := range map[string]struct {
+		annotations map[string]string
+		err         string
+	}{
+		"unsupported": {
+			annotations: map[string]string{peerpodannotations.SecurityGroupIDs: "sg-1"},
+			err:         "annotation kata.peerpods.io/security-group-ids overrides a setting that the cloud provider does not support",
+		},
+		"not allowed value": {
+			annotations: map[string]string{peerpodannotations.SubnetID: "subnet-3"},
+			err:         `annotation kata.peerpods.io/subnet-id: subnet "subnet-3" is not allowed by the override policy`,
+		},
+		"not allowed tag": {
+			annotations: map[string]string{peerpodannotations.Tags: "owner=alice"},
+			err:         `annotation kata.peerpods.io/tags: tag "owner" is not allowed by the override policy`,
+		},
+		"reserved tag": {
+			annotations: map[string]string{peerpodannotations.Tags: ClusterIDTag + "=other"},
+			err:         `annotation kata.peerpods.io/tags: tag "` + ClusterIDTag + `" is reserved`,
+		},
+		"malformed": {
+			annotations: map[string]string{peerpodannotations.Tags: "team"},
+			err:         "invalid override annotations of pod default/mypod",
+		},
+	} {
+		_, err := s.CreateVM(ctx, request("456", tc.annotations))
+		assert.ErrorContains(t, err, tc.err, name)
+	}
+
+	// An empty policy rejects all override annotations
+	s2 := NewService(provider, &mockProxyFactory{podsDir: dir}, &mockWorkerNode{}, dir, forwarder.DefaultListenPort, "", nil, nil, nil, 0, 1)
+	_, err = s2.CreateVM(ctx, request("456", map[string]string{peerpodannotations.ImageID: "image-1"}))
+	assert.ErrorContains(t, err, "annotation kata.peerpods.io/image-id is not allowed by the override policy")
+
+	_, err = s.CreateVM(ctx, request("789", map[string]string{
+		peerpodannotations.ImageID:  "image-1",
+		peerpodannotations.SubnetID: "subnet-2",
+		peerpodannotations.Tags:     "team=a",
+	}))
+	require.NoError(t, err)
+
+	_, err = s.StartVM(ctx, &pb.StartVMRequest{Id: "789"})
+	require.NoError(t, err)
+	assert.Equal(t, "image-1", provider.spec.ImageID)
+	assert.Equal(t, "subnet-2", provider.spec.SubnetID)
+	assert.Equal(t, map[string]string{"team": "a"}, provider.spec.Tags)
+
+	_, err = s.StopVM(ctx, &pb.StopVMRequest{Id: "789"})
+	assert.NoError(t, err)
+}
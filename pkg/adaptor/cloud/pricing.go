@@ -0,0 +1,77 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/logging"
+)
+
+// PriceCatalog maps instance type names to their hourly prices. Prices are in any currency, as long as all prices use the same one.
+type PriceCatalog map[string]float64
+
+// LoadPriceCatalog loads a price catalog from a JSON file that maps instance type names to hourly prices, e.g. {"t3.small": 0.0208}
+func LoadPriceCatalog(path string) (PriceCatalog, error) {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading pricing file: %w", err)
+	}
+
+	var catalog PriceCatalog
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		return nil, fmt.Errorf("parsing pricing file %s: %w", path, err)
+	}
+
+	for instanceType, price := range catalog {
+		if instanceType == "" || price <= 0 {
+			return nil, fmt.Errorf("pricing file %s: invalid price %v of instance type %q", path, price, instanceType)
+		}
+	}
+
+	return catalog, nil
+}
+
+// setPrices returns a copy of specList, with the prices of the catalog set on instance types whose price is unknown
+func (c PriceCatalog) setPrices(specList []InstanceTypeSpec) []InstanceTypeSpec {
+	if len(c) == 0 {
+		return specList
+	}
+	priced := make([]InstanceTypeSpec, len(specList))
+	for i, spec := range specList {
+		if spec.Price == 0 {
+			spec.Price = c[spec.InstanceType]
+		}
+		priced[i] = spec
+	}
+	return priced
+}
+
+// instanceTypePrices returns the prices of the pricing API of provider, if any, overridden by the prices of catalog
+func instanceTypePrices(ctx context.Context, provider Provider, catalog PriceCatalog) PriceCatalog {
+
+	lister, ok := provider.(PriceLister)
+	if !ok {
+		return catalog
+	}
+
+	prices, err := lister.InstanceTypePrices(ctx)
+	if err != nil {
+		logger.Warn("failed to get instance type prices of the provider", logging.KeyError, err)
+		return catalog
+	}
+
+	merged := make(PriceCatalog, len(prices)+len(catalog))
+	for instanceType, price := range prices {
+		merged[instanceType] = price
+	}
+	for instanceType, price := range catalog {
+		merged[instanceType] = price
+	}
+	return merged
+}
@@ -0,0 +1,105 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package cloud
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	cri "github.com/containerd/containerd/pkg/cri/annotations"
+	pb "github.com/kata-containers/kata-containers/src/runtime/protocols/hypervisor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	peerpodannotations "github.com/confidential-containers/cloud-api-adaptor/pkg/annotations"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/forwarder"
+)
+
+func TestLoadPriceCatalog(t *testing.T) {
+
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "prices.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"t3.small": 0.0208, "t3.medium": 0.0416}`), 0600))
+
+	catalog, err := LoadPriceCatalog(path)
+	require.NoError(t, err)
+	assert.Equal(t, PriceCatalog{"t3.small": 0.0208, "t3.medium": 0.0416}, catalog)
+
+	for name, content := range map[string]string{
+		"negative.json": `{"t3.small": -1}`,
+		"empty.json":    `{"": 0.1}`,
+		"invalid.json":  `["t3.small"]`,
+	} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+		_, err := LoadPriceCatalog(path)
+		assert.Error(t, err, name)
+	}
+
+	_, err = LoadPriceCatalog(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+}
+
+type mockPriceProvider struct {
+	mockCapacityProvider
+	prices PriceCatalog
+}
+
+func (p *mockPriceProvider) InstanceTypePrices(ctx context.Context) (PriceCatalog, error) {
+	return p.prices, nil
+}
+
+func TestCloudServicePrices(t *testing.T) {
+
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	provider := &mockPriceProvider{
+		mockCapacityProvider: mockCapacityProvider{
+			specList: []InstanceTypeSpec{
+				{InstanceType: "small", VCPUs: 1, Memory: 2048},
+				{InstanceType: "medium", VCPUs: 2, Memory: 4096},
+				{InstanceType: "large", VCPUs: 4, Memory: 8192},
+			},
+			available: "large@",
+		},
+		prices: PriceCatalog{"small": 0.1, "medium": 0.4, "large": 0.3},
+	}
+
+	// Prices of the pricing file override prices of the provider
+	s := NewService(provider, &mockProxyFactory{podsDir: dir}, &mockWorkerNode{}, dir, forwarder.DefaultListenPort, "", nil, PriceCatalog{"large": 0.2}, nil, 0, 1).(*cloudService)
+	assert.Equal(t, PriceCatalog{"small": 0.1, "medium": 0.4, "large": 0.2}, s.prices)
+
+	_, err := s.CreateVM(ctx, &pb.CreateVMRequest{Id: "123", Annotations: map[string]string{
+		cri.SandboxNamespace:      "default",
+		cri.SandboxName:           "mypod",
+		peerpodannotations.VCPUs:  "2",
+		peerpodannotations.Memory: "2048",
+	}})
+	require.NoError(t, err)
+
+	sandbox, err := s.getSandbox("123")
+	require.NoError(t, err)
+
+	instance, attempt, err := s.createInstance(ctx, sandbox)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"large@"}, provider.attempts)
+
+	ppInstance := peerPodInstance(instance, attempt, s.prices)
+	assert.Equal(t, "large", ppInstance.InstanceType)
+	assert.Equal(t, 0.2, ppInstance.Price)
+
+	require.NoError(t, s.setInstance("123", instance, ppInstance.InstanceType, ppInstance.Price))
+	assert.Equal(t, 0.2, sandbox.price)
+
+	// The price is restored with the sandbox
+	s2 := NewService(provider, &mockProxyFactory{podsDir: dir}, &mockWorkerNode{}, dir, forwarder.DefaultListenPort, "", nil, nil, nil, 0, 1).(*cloudService)
+	restored, err := s2.getSandbox("123")
+	require.NoError(t, err)
+	assert.Equal(t, "large", restored.instanceType)
+	assert.Equal(t, 0.2, restored.price)
+}
@@ -0,0 +1,148 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package cloud
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/podnetwork/tunneler"
+)
+
+const sandboxStateFileName = "sandbox.json"
+
+// sandboxState is the persistent part of a sandbox. It is stored as sandbox.json
+// next to daemon.json in the pod directory, so that cloud-api-adaptor can recover
+// sandboxes and their pod VMs after a restart.
+type sandboxState struct {
+	ID           sandboxID        `json:"id"`
+	PodName      string           `json:"pod-name"`
+	PodNamespace string           `json:"pod-namespace"`
+	NetNSPath    string           `json:"netns-path"`
+	ServerName   string           `json:"server-name"`
+	InstanceID   string           `json:"instance-id,omitempty"`
+	InstanceName string           `json:"instance-name,omitempty"`
+	InstanceIPs  []netip.Addr     `json:"instance-ips,omitempty"`
+	PodNetwork   *tunneler.Config `json:"pod-network"`
+	Spec         InstanceTypeSpec `json:"spec"`
+	InstanceType string           `json:"instance-type,omitempty"`
+	Price        float64          `json:"price,omitempty"`
+}
+
+type sandboxStore struct {
+	podsDir string
+}
+
+func newSandboxStore(podsDir string) *sandboxStore {
+	return &sandboxStore{podsDir: podsDir}
+}
+
+func (st *sandboxStore) podDir(sid sandboxID) string {
+	return filepath.Join(st.podsDir, string(sid))
+}
+
+// save atomically writes the state of a sandbox to its pod directory
+func (st *sandboxStore) save(sandbox *sandbox) error {
+
+	state := &sandboxState{
+		ID:           sandbox.id,
+		PodName:      sandbox.podName,
+		PodNamespace: sandbox.podNamespace,
+		NetNSPath:    sandbox.netNSPath,
+		ServerName:   sandbox.serverName,
+		InstanceID:   sandbox.instanceID,
+		InstanceName: sandbox.instanceName,
+		InstanceIPs:  sandbox.instanceIPs,
+		PodNetwork:   sandbox.podNetwork,
+		Spec:         sandbox.spec,
+		InstanceType: sandbox.instanceType,
+		Price:        sandbox.price,
+	}
+
+	data, err := json.MarshalIndent(state, "", "    ")
+	if err != nil {
+		return fmt.Errorf("generating JSON data of sandbox %s: %w", sandbox.id, err)
+	}
+
+	path := filepath.Join(st.podDir(sandbox.id), sandboxStateFileName)
+	tmpPath := path + ".tmp"
+
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("storing %s: %w", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("renaming %s to %s: %w", tmpPath, path, err)
+	}
+
+	return nil
+}
+
+// remove deletes the persistent state of a sandbox. daemon.json is kept for debugging.
+func (st *sandboxStore) remove(sid sandboxID) error {
+
+	path := filepath.Join(st.podDir(sid), sandboxStateFileName)
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("removing %s: %w", path, err)
+	}
+	return nil
+}
+
+// load reads the states of all the sandboxes stored under the pods directory.
+// Pod directories without a sandbox state are ignored.
+func (st *sandboxStore) load() ([]*sandboxState, error) {
+
+	entries, err := os.ReadDir(st.podsDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading %s: %w", st.podsDir, err)
+	}
+
+	var states []*sandboxState
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(st.podsDir, entry.Name(), sandboxStateFileName)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				logger.Warnf("reading %s: %v", path, err)
+			}
+			continue
+		}
+
+		var state sandboxState
+		if err := json.Unmarshal(data, &state); err != nil {
+			logger.Warnf("decoding %s: %v", path, err)
+			continue
+		}
+		if state.ID != sandboxID(entry.Name()) {
+			logger.Warnf("sandbox ID %q in %s does not match its pod directory, ignored", state.ID, path)
+			continue
+		}
+
+		states = append(states, &state)
+	}
+
+	return states, nil
+}
+
+// loadDaemonJSON reads daemon.json stored by CreateVM in the pod directory
+func (st *sandboxStore) loadDaemonJSON(sid sandboxID) ([]byte, error) {
+
+	path := filepath.Join(st.podDir(sid), "daemon.json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return data, nil
+}
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"net/netip"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -22,19 +23,104 @@ import (
 type Provider interface {
 	CreateInstance(ctx context.Context, podName, sandboxID string, cloudConfig cloudinit.CloudConfigGenerator, spec InstanceTypeSpec) (instance *Instance, err error)
 	DeleteInstance(ctx context.Context, instanceID string) error
+	// ListInstances returns pod VM instances created by the provider for the cluster of cloud-api-adaptor.
+	// Instances are identified by the name prefix of util.GenerateInstanceName, and by the cluster ID
+	// the provider attaches to instances at creation. It returns ErrNoClusterID if no cluster ID is configured,
+	// and ErrListInstancesUnsupported if the provider cannot attach a cluster ID to instances.
+	ListInstances(ctx context.Context) ([]*Instance, error)
 	Teardown() error
 	ConfigVerifier() error
 }
 
+// InstanceTypeLister is implemented by providers that validate instance types requested by pods
+type InstanceTypeLister interface {
+	// InstanceTypes returns the instance types that pods may request, including the default instance type
+	InstanceTypes() []string
+}
+
+// QuotaReporter is implemented by providers that know how many more instances the cloud account can create
+type QuotaReporter interface {
+	// RemainingInstances returns the number of instances that can still be created, or ErrQuotaUnknown
+	RemainingInstances(ctx context.Context) (int, error)
+}
+
+// PlacementLister is implemented by providers that can create instances in alternate placements, such as subnets or zones
+type PlacementLister interface {
+	// Placements returns the alternate placements to try, in order, when the default placement has no capacity
+	Placements() []string
+}
+
+// PriceLister is implemented by providers that get the prices of instance types from a pricing API of the cloud
+type PriceLister interface {
+	// InstanceTypePrices returns the hourly prices of the instance types of the provider
+	InstanceTypePrices(ctx context.Context) (PriceCatalog, error)
+}
+
+// SpotProvider is implemented by providers that can create instances on spot capacity
+type SpotProvider interface {
+	// SupportsSpot returns true if CreateInstance creates an instance on spot capacity when InstanceTypeSpec.Spot is set
+	SupportsSpot() bool
+}
+
+// OverrideProvider is implemented by providers that can override their settings for the pod VM of a pod
+type OverrideProvider interface {
+	// SupportedOverrides returns the override annotations whose fields of InstanceTypeSpec CreateInstance applies
+	SupportedOverrides() []string
+}
+
+// ClusterIDTag is the key of the tag, or of the equivalent metadata of a provider, that holds the cluster ID of an instance
+const ClusterIDTag = "peerpod-cluster-id"
+
+var (
+	// ErrNoClusterID is returned by ListInstances when no cluster ID is configured
+	ErrNoClusterID = errors.New("no cluster ID is configured, instances of the cluster cannot be identified")
+	// ErrListInstancesUnsupported is returned by ListInstances of providers that cannot attach a cluster ID to instances
+	ErrListInstancesUnsupported = errors.New("listing instances of a cluster is not supported by the provider")
+	// ErrQuotaUnknown is returned by RemainingInstances when the remaining instance quota is not known
+	ErrQuotaUnknown = errors.New("remaining instance quota is unknown")
+	// ErrInsufficientCapacity is the cause of a CapacityError when the cloud has no capacity for an instance type
+	ErrInsufficientCapacity = errors.New("insufficient capacity")
+	// ErrQuotaExceeded is the cause of a CapacityError when creating an instance exceeds a quota of the account
+	ErrQuotaExceeded = errors.New("quota exceeded")
+)
+
+// CapacityError is returned by CreateInstance when an instance cannot be created for lack of capacity or quota.
+// cloudService retries such failures with other instance types and placements.
+type CapacityError struct {
+	// Cause is ErrInsufficientCapacity or ErrQuotaExceeded
+	Cause error
+	// InstanceType and Placement are the instance type and placement of the failed attempt
+	InstanceType string
+	Placement    string
+	// Err is the error returned by the cloud
+	Err error
+}
+
+func (e *CapacityError) Error() string {
+	return fmt.Sprintf("%v for instance type %q in placement %q: %v", e.Cause, e.InstanceType, e.Placement, e.Err)
+}
+
+// Unwrap makes both the cause and the error of the cloud match errors.Is and errors.As
+func (e *CapacityError) Unwrap() []error {
+	return []error{e.Cause, e.Err}
+}
+
 type Instance struct {
 	ID   string
 	Name string
 	IPs  []netip.Addr
+	// InstanceType and Zone are set by providers that know the actual instance type and zone of an instance
+	InstanceType string
+	Zone         string
 }
 
 type Service interface {
 	pb.HypervisorService
 	GetInstanceID(ctx context.Context, podNamespace, podName string, wait bool) (string, error)
+	// WarmPoolStats returns metrics of a warm pool, or nil if no warm pool is configured
+	WarmPoolStats() *WarmPoolStats
+	// PodsCapacity returns the number of peer pods the node can run, including running ones
+	PodsCapacity(ctx context.Context) int
 	ConfigVerifier() error
 	Teardown() error
 }
@@ -50,6 +136,17 @@ type cloudService struct {
 	mutex        sync.Mutex
 	ppService    *k8sops.PeerPodService
 	aaKBCParams  string
+	store        *sandboxStore
+	warmPool     *warmPool
+	podsLimit    int
+	// createAttempts is the maximum number of attempts to create an instance, falling back to other
+	// instance types and placements after capacity errors
+	createAttempts int
+	prices         PriceCatalog
+	// overridePolicy allows the override annotations of pods
+	overridePolicy OverridePolicy
+	// reserved are the IDs of the sandboxes that CreateVM is creating
+	reserved map[sandboxID]struct{}
 }
 
 type InstanceTypeSpec struct {
@@ -58,6 +155,26 @@ type InstanceTypeSpec struct {
 	Memory       int64
 	Arch         string
 	GPUs         int64
+	// GPUModel is the GPU model of an instance type, or the GPU model requested by a pod
+	GPUModel string
+	// ExcludedInstanceTypes are skipped by best fit selection, after they failed for lack of capacity
+	ExcludedInstanceTypes []string
+	// Placement is one of the alternate placements of a PlacementLister. Empty means the default placement.
+	Placement string
+	// Price is the hourly price of an instance type. Zero means unknown.
+	Price float64
+	// Prices of instance types, with which best fit selection minimizes the price of the instance type for a pod
+	Prices PriceCatalog `json:"-"`
+	// Spot requests an instance on interruptible capacity at up to SpotMaxPrice per hour.
+	// Zero SpotMaxPrice means up to the on-demand price.
+	Spot         bool
+	SpotMaxPrice float64
+	// ImageID, SubnetID, SecurityGroupIDs and Tags override the settings of the provider for the pod VM of a pod.
+	// Empty values keep the settings of the provider. Tags are added to the tags of the provider.
+	ImageID          string
+	SubnetID         string
+	SecurityGroupIDs []string
+	Tags             map[string]string
 }
 
 type sandboxID string
@@ -71,8 +188,13 @@ type sandbox struct {
 	podNamespace string
 	instanceName string
 	instanceID   string
+	instanceIPs  []netip.Addr
 	netNSPath    string
+	serverName   string
 	spec         InstanceTypeSpec
+	// instanceType and price of the instance, if its price is known
+	instanceType string
+	price        float64
 }
 
 // keyValueFlag represents a flag of key-value pairs
@@ -108,3 +230,39 @@ func (k *KeyValueFlag) Set(value string) error {
 
 	return nil
 }
+
+// InstanceTypeSpecFlag represents a flag of a catalog of instance types, for providers that size pod VMs themselves
+type InstanceTypeSpecFlag []InstanceTypeSpec
+
+// String returns the string representation of the InstanceTypeSpecFlag
+func (f *InstanceTypeSpecFlag) String() string {
+	var specs []string
+	for _, spec := range *f {
+		specs = append(specs, fmt.Sprintf("%s:%d:%d", spec.InstanceType, spec.VCPUs, spec.Memory))
+	}
+	return strings.Join(specs, ",")
+}
+
+// Set parses comma separated instance types in the form name:vcpus:memory, with memory in MiB,
+// and appends them to the InstanceTypeSpecFlag
+func (f *InstanceTypeSpecFlag) Set(value string) error {
+	for _, s := range strings.Split(value, ",") {
+		fields := strings.Split(strings.TrimSpace(s), ":")
+		if len(fields) != 3 || fields[0] == "" {
+			return fmt.Errorf("invalid instance type %q, expected name:vcpus:memory", s)
+		}
+		vcpus, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil || vcpus <= 0 {
+			return fmt.Errorf("invalid number of vCPUs of instance type %q", s)
+		}
+		memory, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil || memory <= 0 {
+			return fmt.Errorf("invalid memory of instance type %q", s)
+		}
+		if _, ok := GetInstanceTypeSpec(*f, fields[0]); ok {
+			return fmt.Errorf("duplicate instance type %q", fields[0])
+		}
+		*f = append(*f, InstanceTypeSpec{InstanceType: fields[0], VCPUs: vcpus, Memory: memory})
+	}
+	return nil
+}
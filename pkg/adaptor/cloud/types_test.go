@@ -3,7 +3,10 @@
 
 package cloud
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+)
 
 func TestEmptyKeyValueFlag_Set(t *testing.T) {
 	// Empty KeyValueFlag will result in error
@@ -114,3 +117,68 @@ func isEqual(a, b KeyValueFlag) bool {
 
 	return true
 }
+
+func TestInstanceTypeSpecFlag_Set(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		expectedValue InstanceTypeSpecFlag
+		expectedError bool
+	}{
+		{
+			name:  "valid instance types",
+			input: "small:2:4096, large:8:16384",
+			expectedValue: InstanceTypeSpecFlag{
+				{InstanceType: "small", VCPUs: 2, Memory: 4096},
+				{InstanceType: "large", VCPUs: 8, Memory: 16384},
+			},
+		},
+		{
+			name:          "missing memory",
+			input:         "small:2",
+			expectedError: true,
+		},
+		{
+			name:          "missing name",
+			input:         ":2:4096",
+			expectedError: true,
+		},
+		{
+			name:          "invalid vCPUs",
+			input:         "small:two:4096",
+			expectedError: true,
+		},
+		{
+			name:          "zero memory",
+			input:         "small:2:0",
+			expectedError: true,
+		},
+		{
+			name:          "duplicate instance type",
+			input:         "small:2:4096,small:4:4096",
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var f InstanceTypeSpecFlag
+			err := f.Set(test.input)
+
+			if (err != nil) != test.expectedError {
+				t.Errorf("Unexpected error, got: %v, expected error: %v, received value: %v", err, test.expectedError, f)
+			}
+
+			if test.expectedError {
+				return
+			}
+
+			if !reflect.DeepEqual(f, test.expectedValue) {
+				t.Errorf("Unexpected InstanceTypeSpecFlag value, got: %v, expected: %v", f, test.expectedValue)
+			}
+			if s := f.String(); s != "small:2:4096,large:8:16384" {
+				t.Errorf("Unexpected InstanceTypeSpecFlag string, got: %q", s)
+			}
+		})
+	}
+}
@@ -4,20 +4,30 @@
 package cloud
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"sort"
+	"strings"
 
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/util"
 )
 
 func DefaultToEnv(field *string, env, fallback string) {
+	Getenv(os.Getenv).DefaultTo(field, env, fallback)
+}
+
+// Getenv looks up the value of an environment variable, like os.Getenv
+type Getenv func(key string) string
+
+// DefaultTo sets field to the value of env, or fallback if the value is empty, unless field is already set
+func (getenv Getenv) DefaultTo(field *string, env, fallback string) {
 
 	if *field != "" {
 		return
 	}
 
-	val := os.Getenv(env)
+	val := getenv(env)
 	if val == "" {
 		val = fallback
 	}
@@ -25,6 +35,35 @@ func DefaultToEnv(field *string, env, fallback string) {
 	*field = val
 }
 
+// LoadConfig loads a cloud provider config from config, a map from option names or environment variable names to values.
+// parseCmd registers the options of the config, and loadEnv reads environment variables with the getenv passed to it.
+// LoadConfig neither reads the environment variables of the process, nor touches the command line flags.
+func LoadConfig(config map[string]string, parseCmd func(flags *flag.FlagSet), loadEnv func(getenv Getenv)) error {
+
+	flags := flag.NewFlagSet("config", flag.ContinueOnError)
+	parseCmd(flags)
+
+	// Sort keys so that an error is reported deterministically
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if flags.Lookup(key) == nil {
+			continue
+		}
+		if err := flags.Set(key, config[key]); err != nil {
+			return fmt.Errorf("invalid value %q for option %s: %w", config[key], key, err)
+		}
+	}
+
+	loadEnv(func(key string) string { return config[key] })
+
+	return nil
+}
+
 // Method to verify the correct instanceType to be used for Pod VM
 func VerifyCloudInstanceType(instanceType string, validInstanceTypes []string, defaultInstanceType string) (string, error) {
 	// If instanceType is empty, set instanceType to default.
@@ -50,12 +89,19 @@ func VerifyCloudInstanceType(instanceType string, validInstanceTypes []string, d
 	return instanceType, nil
 }
 
-// Method to sort InstanceTypeSpec into ascending order based on memory
+// Method to sort InstanceTypeSpec into ascending order based on memory, then on vCPUs and GPUs
 func SortInstanceTypesOnMemory(instanceTypeSpecList []InstanceTypeSpec) []InstanceTypeSpec {
 
-	// Use sort.Slice to sort the instanceTypeTupleList slice and return the sorted slice
-	sort.Slice(instanceTypeSpecList, func(i, j int) bool {
-		return instanceTypeSpecList[i].Memory < instanceTypeSpecList[j].Memory
+	// Use sort.SliceStable to sort the instanceTypeTupleList slice and return the sorted slice
+	sort.SliceStable(instanceTypeSpecList, func(i, j int) bool {
+		a, b := instanceTypeSpecList[i], instanceTypeSpecList[j]
+		if a.Memory != b.Memory {
+			return a.Memory < b.Memory
+		}
+		if a.VCPUs != b.VCPUs {
+			return a.VCPUs < b.VCPUs
+		}
+		return a.GPUs < b.GPUs
 	})
 
 	return instanceTypeSpecList
@@ -69,12 +115,13 @@ func SelectInstanceTypeToUse(spec InstanceTypeSpec, specList []InstanceTypeSpec,
 	// If vCPU and memory are set in annotations then find the best fit instance type
 	// from the cloud provider
 	// vCPU and Memory gets higher priority than instance type from annotation
-	if spec.VCPUs != 0 && spec.Memory != 0 {
-		instanceType, err = GetBestFitInstanceType(specList, spec.VCPUs, spec.Memory)
+	// GPUs alone only select the instance type when no instance type is set in annotations
+	if spec.usesBestFit() {
+		instanceType, err = GetBestFitInstanceType(spec.Prices.setPrices(excludeInstanceTypes(specList, spec.ExcludedInstanceTypes)), spec)
 		if err != nil {
-			return "", fmt.Errorf("failed to get instance type based on vCPU and memory annotations: %w", err)
+			return "", fmt.Errorf("failed to get instance type based on vCPU, memory and GPU annotations: %w", err)
 		}
-		logger.Printf("Instance type selected by the cloud provider based on vCPU and memory annotations: %s", instanceType)
+		logger.Printf("Instance type selected by the cloud provider based on vCPU, memory and GPU annotations: %s", instanceType)
 	} else if spec.InstanceType != "" {
 		instanceType = spec.InstanceType
 		logger.Printf("Instance type selected by the cloud provider based on instance type annotation: %s", instanceType)
@@ -91,22 +138,79 @@ func SelectInstanceTypeToUse(spec InstanceTypeSpec, specList []InstanceTypeSpec,
 
 }
 
-// Method to find the best fit instance type for the given memory and vcpus
-// The sortedInstanceTypeSpecList slice is a sorted list of instance types based on ascending order of supported memory
-func GetBestFitInstanceType(sortedInstanceTypeSpecList []InstanceTypeSpec, vcpus int64, memory int64) (string, error) {
+// usesBestFit returns true if SelectInstanceTypeToUse selects the instance type of spec by best fit
+func (spec InstanceTypeSpec) usesBestFit() bool {
+	return (spec.VCPUs != 0 && spec.Memory != 0) || (spec.GPUs != 0 && spec.InstanceType == "")
+}
 
-	// Use sort.Search to find the index of the first element in the sortedMachineTypeList slice
-	// that is greater than or equal to the given memory and vcpus
-	index := sort.Search(len(sortedInstanceTypeSpecList), func(i int) bool {
-		return sortedInstanceTypeSpecList[i].Memory >= memory && sortedInstanceTypeSpecList[i].VCPUs >= vcpus
-	})
+// excludeInstanceTypes returns the instance types of specList that are not excluded
+func excludeInstanceTypes(specList []InstanceTypeSpec, excluded []string) []InstanceTypeSpec {
+	if len(excluded) == 0 {
+		return specList
+	}
+	var filtered []InstanceTypeSpec
+	for _, spec := range specList {
+		if !util.Contains(excluded, spec.InstanceType) {
+			filtered = append(filtered, spec)
+		}
+	}
+	return filtered
+}
 
-	// If binary search fails to find a match, return error
-	if index == len(sortedInstanceTypeSpecList) {
-		return "", fmt.Errorf("no instance type found for the given vcpus (%d) and memory (%d)", vcpus, memory)
+// Method to find the best fit instance type for the vCPUs, memory, GPUs and architecture of spec
+// The sortedInstanceTypeSpecList slice is a sorted list of instance types as returned by SortInstanceTypesOnMemory
+// The cheapest instance type with a price is the best fit. Without prices, the smallest instance type is.
+func GetBestFitInstanceType(sortedInstanceTypeSpecList []InstanceTypeSpec, spec InstanceTypeSpec) (string, error) {
+
+	// The requirements are not monotonic over the sorted list, so the first instance type that
+	// satisfies all of them is the smallest one. A cheaper instance type with a price replaces it.
+	var best *InstanceTypeSpec
+	for i, candidate := range sortedInstanceTypeSpecList {
+		if candidate.Memory >= spec.Memory && candidate.VCPUs >= spec.VCPUs && candidate.GPUs >= spec.GPUs &&
+			matchGPUModel(candidate.GPUModel, spec.GPUModel) && matchArch(candidate.Arch, spec.Arch) {
+			if best == nil || candidate.Price > 0 && (best.Price == 0 || candidate.Price < best.Price) {
+				best = &sortedInstanceTypeSpecList[i]
+			}
+		}
 	}
+	if best != nil {
+		return best.InstanceType, nil
+	}
+
+	if spec.GPUs > 0 {
+		return "", fmt.Errorf("no instance type found for the given vcpus (%d), memory (%d) and GPUs (%d %s)", spec.VCPUs, spec.Memory, spec.GPUs, spec.GPUModel)
+	}
+	return "", fmt.Errorf("no instance type found for the given vcpus (%d) and memory (%d)", spec.VCPUs, spec.Memory)
+}
 
-	// If binary search finds a match, return the instance type
-	return sortedInstanceTypeSpecList[index].InstanceType, nil
+// matchGPUModel returns true if the GPU model of an instance type contains the requested GPU model, ignoring case.
+// Cloud providers name the same model differently, e.g. "V100" and "Tesla V100".
+func matchGPUModel(model, requested string) bool {
+	return strings.Contains(strings.ToLower(model), strings.ToLower(requested))
+}
+
+// matchArch returns true if the architecture of an instance type is the requested architecture, or either is unknown
+func matchArch(arch, requested string) bool {
+	return arch == "" || requested == "" || arch == requested
+}
 
+// GetInstanceTypeSpec returns the spec of instanceType in specList, and whether it is found
+func GetInstanceTypeSpec(specList []InstanceTypeSpec, instanceType string) (InstanceTypeSpec, bool) {
+	for _, spec := range specList {
+		if spec.InstanceType == instanceType {
+			return spec, true
+		}
+	}
+	return InstanceTypeSpec{}, false
+}
+
+// InstanceTypeNames returns the names of the instance types of specList, skipping an unnamed default instance type
+func InstanceTypeNames(specList []InstanceTypeSpec) []string {
+	var names []string
+	for _, spec := range specList {
+		if spec.InstanceType != "" {
+			names = append(names, spec.InstanceType)
+		}
+	}
+	return names
 }
@@ -0,0 +1,77 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package cloud
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testConfig struct {
+	Region   string
+	Size     string
+	Secret   string
+	Public   bool
+	Timeout  time.Duration
+	Endpoint string
+}
+
+func (cfg *testConfig) parseCmd(flags *flag.FlagSet) {
+	flags.StringVar(&cfg.Region, "region", "", "")
+	flags.StringVar(&cfg.Size, "size", "small", "")
+	flags.BoolVar(&cfg.Public, "use-public-ip", false, "")
+	flags.DurationVar(&cfg.Timeout, "timeout", time.Minute, "")
+}
+
+func (cfg *testConfig) loadEnv(getenv Getenv) {
+	getenv.DefaultTo(&cfg.Region, "TEST_REGION", "")
+	getenv.DefaultTo(&cfg.Secret, "TEST_SECRET", "")
+	getenv.DefaultTo(&cfg.Endpoint, "TEST_ENDPOINT", "https://example.com")
+}
+
+func TestLoadConfig(t *testing.T) {
+
+	t.Setenv("TEST_SECRET", "from-process-env")
+
+	var cfg testConfig
+	err := LoadConfig(map[string]string{
+		"region":        "us-east-1",
+		"use-public-ip": "true",
+		"TEST_REGION":   "eu-west-1",
+		"TEST_SECRET":   "secret",
+		"UNKNOWN":       "ignored",
+	}, cfg.parseCmd, cfg.loadEnv)
+	require.NoError(t, err)
+
+	assert.Equal(t, testConfig{
+		Region:   "us-east-1", // An option has precedence over an environment variable
+		Size:     "small",
+		Secret:   "secret",
+		Public:   true,
+		Timeout:  time.Minute,
+		Endpoint: "https://example.com",
+	}, cfg)
+
+	err = LoadConfig(map[string]string{"timeout": "forever"}, (&testConfig{}).parseCmd, (&testConfig{}).loadEnv)
+	assert.ErrorContains(t, err, "option timeout")
+}
+
+func TestDefaultToEnv(t *testing.T) {
+
+	t.Setenv("TEST_REGION", "eu-west-1")
+
+	var region, size string
+	DefaultToEnv(&region, "TEST_REGION", "us-east-1")
+	DefaultToEnv(&size, "TEST_SIZE", "small")
+	assert.Equal(t, "eu-west-1", region)
+	assert.Equal(t, "small", size)
+
+	region = "ap-northeast-1"
+	DefaultToEnv(&region, "TEST_REGION", "us-east-1")
+	assert.Equal(t, "ap-northeast-1", region)
+}
@@ -5,6 +5,7 @@ package vsphere
 
 import (
 	"flag"
+	"os"
 
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud"
 )
@@ -13,31 +14,54 @@ var vspherecfg Config
 
 type Manager struct{}
 
-func init() {
-	cloud.AddCloud("vsphere", &Manager{})
+func (_ *Manager) ParseCmd(flags *flag.FlagSet) {
+	parseCmd(flags, &vspherecfg)
 }
 
-func (_ *Manager) ParseCmd(flags *flag.FlagSet) {
+func parseCmd(flags *flag.FlagSet, cfg *Config) {
 
-	flags.StringVar(&vspherecfg.VcenterURL, "vcenter-url", "", "URL of vCenter instance to connect to")
-	flags.StringVar(&vspherecfg.UserName, "user-name", "", "vCenter Username")
-	flags.StringVar(&vspherecfg.Password, "password", "", "vCenter Password")
-	flags.StringVar(&vspherecfg.Thumbprint, "thumbprint", "", "SHA1 thumbprint of the vcenter certificate. Enable verification of certificate chain and host name.")
-	flags.StringVar(&vspherecfg.Template, "template", "podvm-template", "vCenter template to deploy")
-	flags.StringVar(&vspherecfg.Datacenter, "data-center", "", "vCenter destination datacenter name")
-	flags.StringVar(&vspherecfg.Datastore, "data-store", "", "vCenter datastore")
-	flags.StringVar(&vspherecfg.Deployfolder, "deploy-folder", "", "vCenter vm destination folder relative to the vm inventory path (your-data-center/vm). \nExample '-deploy-folder peerods' will create or use the existing folder peerpods as the \ndeploy-folder in /datacenter/vm/peerpods")
-	flags.StringVar(&vspherecfg.Cluster, "cluster", "", "vCenter destination cluster name ")
-	flags.StringVar(&vspherecfg.DRS, "drs", "false", "Use DRS for clone placement in destination Vcenter cluster")
-	flags.StringVar(&vspherecfg.Host, "host", "", "vCenter host name of resource pool destination")
+	flags.StringVar(&cfg.VcenterURL, "vcenter-url", "", "URL of vCenter instance to connect to")
+	flags.StringVar(&cfg.UserName, "user-name", "", "vCenter Username")
+	flags.StringVar(&cfg.Password, "password", "", "vCenter Password")
+	flags.StringVar(&cfg.Thumbprint, "thumbprint", "", "SHA1 thumbprint of the vcenter certificate. Enable verification of certificate chain and host name.")
+	flags.StringVar(&cfg.Template, "template", "podvm-template", "vCenter template to deploy")
+	flags.StringVar(&cfg.Datacenter, "data-center", "", "vCenter destination datacenter name")
+	flags.StringVar(&cfg.Datastore, "data-store", "", "vCenter datastore")
+	flags.StringVar(&cfg.Deployfolder, "deploy-folder", "", "vCenter vm destination folder relative to the vm inventory path (your-data-center/vm). \nExample '-deploy-folder peerods' will create or use the existing folder peerpods as the \ndeploy-folder in /datacenter/vm/peerpods")
+	flags.StringVar(&cfg.Cluster, "cluster", "", "vCenter destination cluster name ")
+	flags.StringVar(&cfg.DRS, "drs", "false", "Use DRS for clone placement in destination Vcenter cluster")
+	flags.StringVar(&cfg.Host, "host", "", "vCenter host name of resource pool destination")
+	flags.StringVar(&cfg.ClusterID, "cluster-id", "", "ID of the Kubernetes cluster to record in the extra config of the Pod VMs, defaults to `CLUSTER_ID`")
+	flags.StringVar(&cfg.InstanceType, "instance-type", "", "Default instance type of the Pod VMs, defaults to `PODVM_INSTANCE_TYPE`. If it is not in -instance-types, it is sized by -vcpus and -memory")
+	flags.Var((*cloud.InstanceTypeSpecFlag)(&cfg.InstanceTypeSpecList), "instance-types", "Instance types that Pod VMs may be sized by, comma separated name:vcpus:memory with memory in MiB")
+	flags.IntVar(&cfg.VCPUs, "vcpus", 0, "Number of vCPUs of Pod VMs of the default instance type, defaults to the vCPUs of the template")
+	flags.IntVar(&cfg.Memory, "memory", 0, "Memory (in MiB) of Pod VMs of the default instance type, defaults to the memory of the template")
 }
 
 func (_ *Manager) LoadEnv() {
-	cloud.DefaultToEnv(&vspherecfg.UserName, "GOVC_USERNAME", "")
-	cloud.DefaultToEnv(&vspherecfg.Password, "GOVC_PASSWORD", "")
-	cloud.DefaultToEnv(&vspherecfg.Thumbprint, "GOVC_THUMBPRINT", "")
+	loadEnv(&vspherecfg, os.Getenv)
+}
+
+func loadEnv(cfg *Config, getenv cloud.Getenv) {
+	getenv.DefaultTo(&cfg.UserName, "GOVC_USERNAME", "")
+	getenv.DefaultTo(&cfg.Password, "GOVC_PASSWORD", "")
+	getenv.DefaultTo(&cfg.Thumbprint, "GOVC_THUMBPRINT", "")
+	getenv.DefaultTo(&cfg.VcenterURL, "GOVC_URL", "")
+	getenv.DefaultTo(&cfg.Datacenter, "GOVC_DATACENTER", "")
+	getenv.DefaultTo(&cfg.ClusterID, "CLUSTER_ID", "")
+	getenv.DefaultTo(&cfg.InstanceType, "PODVM_INSTANCE_TYPE", "")
 }
 
 func (_ *Manager) NewProvider() (cloud.Provider, error) {
 	return NewProvider(&vspherecfg)
 }
+
+func (_ *Manager) NewProviderFromConfig(config map[string]string) (cloud.Provider, error) {
+
+	var cfg Config
+	if err := cloud.LoadConfig(config, func(flags *flag.FlagSet) { parseCmd(flags, &cfg) }, func(getenv cloud.Getenv) { loadEnv(&cfg, getenv) }); err != nil {
+		return nil, err
+	}
+
+	return NewProvider(&cfg)
+}
@@ -7,33 +7,42 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
-	"log"
 	"net/netip"
 	"path"
 	"strings"
 	"time"
 
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud"
+	peerpodannotations "github.com/confidential-containers/cloud-api-adaptor/pkg/annotations"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/util"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/cloudinit"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/logging"
 	"github.com/vmware/govmomi"
 	"github.com/vmware/govmomi/find"
 	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/types"
 )
 
-var logger = log.New(log.Writer(), "[adaptor/cloud/vsphere] ", log.LstdFlags|log.Lmsgprefix)
+var logger = logging.New("adaptor/cloud/vsphere")
 
-const maxInstanceNameLen = 63
+const (
+	maxInstanceNameLen = 63
+
+	// clusterIDKey is the key of the extra config option that holds the cluster ID of a pod VM
+	clusterIDKey = "peerpod.cluster-id"
+)
 
 type vsphereProvider struct {
 	gclient       *govmomi.Client
 	serviceConfig *Config
+	// instanceTypeSpecList is the sorted catalog of instance types, including the default instance type
+	instanceTypeSpecList []cloud.InstanceTypeSpec
 }
 
 func NewProvider(config *Config) (cloud.Provider, error) {
 
-	logger.Printf("vsphere config: %#v", config.Redact())
+	logger.Info("vsphere config", "config", config)
 
 	err := checkConfig(config)
 	if err != nil {
@@ -46,8 +55,9 @@ func NewProvider(config *Config) (cloud.Provider, error) {
 	}
 
 	provider := &vsphereProvider{
-		gclient:       govmomiClient,
-		serviceConfig: config,
+		gclient:              govmomiClient,
+		serviceConfig:        config,
+		instanceTypeSpecList: instanceTypeSpecList(config),
 	}
 
 	return provider, nil
@@ -74,17 +84,37 @@ func checkConfig(config *Config) error {
 	return nil
 }
 
+// instanceTypeSpecList returns the catalog of instance types of config, including the default instance type
+func instanceTypeSpecList(config *Config) []cloud.InstanceTypeSpec {
+
+	specList := append([]cloud.InstanceTypeSpec{}, config.InstanceTypeSpecList...)
+	if _, ok := cloud.GetInstanceTypeSpec(specList, config.InstanceType); !ok {
+		specList = append(specList, cloud.InstanceTypeSpec{
+			InstanceType: config.InstanceType,
+			VCPUs:        int64(config.VCPUs),
+			Memory:       int64(config.Memory),
+		})
+	}
+	return cloud.SortInstanceTypesOnMemory(specList)
+}
+
 type VmConfig []types.BaseOptionValue
 
 func (p *vsphereProvider) CreateInstance(ctx context.Context, podName, sandboxID string, cloudConfig cloudinit.CloudConfigGenerator, requirement cloud.InstanceTypeSpec) (*cloud.Instance, error) {
+	logger := logger.WithContext(ctx)
 
 	vmname := util.GenerateInstanceName(podName, sandboxID, maxInstanceNameLen)
 
-	logger.Printf("Start CreateInstance VM name %s", vmname)
+	logger.Info("creating a VM", "instance_name", vmname)
 
-	err := CheckSessionWithRestore(ctx, p.serviceConfig, p.gclient)
+	instanceTypeSpec, err := p.selectInstanceType(ctx, requirement)
 	if err != nil {
-		logger.Printf("CreateInstance cannot find or create a new vcenter session")
+		return nil, err
+	}
+
+	err = CheckSessionWithRestore(ctx, p.serviceConfig, p.gclient)
+	if err != nil {
+		logger.Error("cannot find or create a new vcenter session", logging.KeyError, err)
 		return nil, err
 	}
 
@@ -92,26 +122,32 @@ func (p *vsphereProvider) CreateInstance(ctx context.Context, podName, sandboxID
 
 	dc, err := finder.Datacenter(ctx, p.serviceConfig.Datacenter)
 	if err != nil {
-		logger.Printf("Cannot find vcenter datacenter %s error: %s", p.serviceConfig.Datacenter, err)
+		logger.Error("cannot find vcenter datacenter", "datacenter", p.serviceConfig.Datacenter, logging.KeyError, err)
 		return nil, err
 	}
 
 	finder.SetDatacenter(dc)
 
-	vm, err := finder.VirtualMachine(ctx, p.serviceConfig.Template)
+	// The template of a pod takes precedence over the configured one
+	templateName := p.serviceConfig.Template
+	if requirement.ImageID != "" {
+		templateName = requirement.ImageID
+	}
+
+	vm, err := finder.VirtualMachine(ctx, templateName)
 	if err != nil {
-		logger.Printf("Cannot find VM template %s error: %s", p.serviceConfig.Template, err)
+		logger.Error("cannot find VM template", "template", templateName, logging.KeyError, err)
 		return nil, err
 	}
 
 	template, err := vm.IsTemplate(ctx)
 	if err != nil {
-		logger.Printf("VM template %s error: %s", p.serviceConfig.Template, err)
+		logger.Error("VM template error", "template", templateName, logging.KeyError, err)
 		return nil, err
 	}
 	if !template {
 		err = fmt.Errorf("template not valid")
-		logger.Printf("VM template %s error: %s", p.serviceConfig.Template, err)
+		logger.Error("VM template error", "template", templateName, logging.KeyError, err)
 		return nil, err
 	}
 
@@ -124,7 +160,7 @@ func (p *vsphereProvider) CreateInstance(ctx context.Context, podName, sandboxID
 
 	vmfolder, err := finder.Folder(ctx, inventory_path)
 	if err != nil {
-		logger.Printf("Cannot find inventory folder %s error: %s", inventory_path, err)
+		logger.Error("cannot find inventory folder", "folder", inventory_path, logging.KeyError, err)
 		return nil, err
 	}
 
@@ -168,11 +204,11 @@ func (p *vsphereProvider) CreateInstance(ctx context.Context, podName, sandboxID
 		// clone placement. The user does not need to indicate a host or datastore and those
 		// inputs will be ignored if present.
 
-		logger.Printf("Looking for cluster %s DRS recommendations", p.serviceConfig.Cluster)
+		logger.Info("looking for DRS recommendations", "cluster", p.serviceConfig.Cluster)
 
 		cluster, err := finder.ClusterComputeResourceOrDefault(ctx, p.serviceConfig.Cluster)
 		if err != nil {
-			logger.Printf("Cluster %s compute resource error: %s", p.serviceConfig.Cluster, err)
+			logger.Error("cluster compute resource error", "cluster", p.serviceConfig.Cluster, logging.KeyError, err)
 			return nil, err
 		}
 
@@ -187,7 +223,7 @@ func (p *vsphereProvider) CreateInstance(ctx context.Context, podName, sandboxID
 
 		result, err := cluster.PlaceVm(ctx, spec)
 		if err != nil {
-			logger.Printf("Cluster %s placement error: %s", p.serviceConfig.Cluster, err)
+			logger.Error("cluster placement error", "cluster", p.serviceConfig.Cluster, logging.KeyError, err)
 			return nil, err
 		}
 
@@ -229,7 +265,7 @@ func (p *vsphereProvider) CreateInstance(ctx context.Context, podName, sandboxID
 
 		pool, err := host.ResourcePool(ctx)
 		if err != nil {
-			logger.Printf("Host %s Resource Pool error: %s", p.serviceConfig.Host, err)
+			logger.Error("host resource pool error", "host", p.serviceConfig.Host, logging.KeyError, err)
 			return nil, err
 		}
 		poolref = types.NewReference(pool.Reference())
@@ -240,7 +276,7 @@ func (p *vsphereProvider) CreateInstance(ctx context.Context, podName, sandboxID
 		datastorepath := fmt.Sprintf("/%s/datastore/%s", p.serviceConfig.Datacenter, p.serviceConfig.Datastore)
 		datastore, err := finder.Datastore(ctx, datastorepath)
 		if err != nil {
-			logger.Printf("Datastore %s error: %s", p.serviceConfig.Datastore, err)
+			logger.Error("datastore error", "datastore", p.serviceConfig.Datastore, logging.KeyError, err)
 			return nil, err
 		}
 		datastoreref := types.NewReference(datastore.Reference())
@@ -249,7 +285,7 @@ func (p *vsphereProvider) CreateInstance(ctx context.Context, podName, sandboxID
 
 	userData, err := cloudConfig.Generate()
 	if err != nil {
-		logger.Printf("cloud config error: %s", err)
+		logger.Error("cloud config error", logging.KeyError, err)
 		return nil, err
 	}
 
@@ -269,22 +305,33 @@ func (p *vsphereProvider) CreateInstance(ctx context.Context, podName, sandboxID
 		},
 	)
 
+	if p.serviceConfig.ClusterID != "" {
+		extraconfig = append(extraconfig, &types.OptionValue{
+			Key:   clusterIDKey,
+			Value: p.serviceConfig.ClusterID,
+		})
+	}
+
 	configSpec := types.VirtualMachineConfigSpec{
 		ExtraConfig: extraconfig,
 	}
 
+	// Zero values keep the vCPUs and memory of the template
+	configSpec.NumCPUs = int32(instanceTypeSpec.VCPUs)
+	configSpec.MemoryMB = instanceTypeSpec.Memory
+
 	cloneSpec.Location = relocateSpec
 	cloneSpec.Config = &configSpec
 
 	task, err := vm.Clone(ctx, vmfolder, vmname, *cloneSpec)
 	if err != nil {
-		logger.Printf("Can't clone to vm %s: %s", vmname, err)
+		logger.Error("cannot clone the template to a VM", "instance_name", vmname, logging.KeyError, err)
 		return nil, err
 	}
 
 	info, err := task.WaitForResult(ctx, nil) // TODO Fix to have a timeout
 	if err != nil {
-		logger.Printf("wait for clone task failed")
+		logger.Error("wait for clone task failed", "instance_name", vmname, logging.KeyError, err)
 		return nil, err
 	}
 
@@ -294,21 +341,22 @@ func (p *vsphereProvider) CreateInstance(ctx context.Context, podName, sandboxID
 		return nil, err
 	}
 
-	logger.Printf("VM %s, UUID %s created", name, clone.UUID(ctx))
+	logger.Info("created a VM", "instance_name", name, logging.KeyInstanceID, clone.UUID(ctx))
 
 	ips, err := getIPs(clone) // TODO Fix to get all ips
 	if err != nil {
-		logger.Printf("Failed to get IPs for the instance : %v ", err)
+		logger.Error("failed to get IPs of the instance", logging.KeyError, err)
 		return nil, err
 	}
 
 	instance := &cloud.Instance{
-		ID:   clone.UUID(ctx),
-		Name: vmname,
-		IPs:  ips,
+		ID:           clone.UUID(ctx),
+		Name:         vmname,
+		IPs:          ips,
+		InstanceType: instanceTypeSpec.InstanceType,
 	}
 
-	logger.Printf("CreateInstance VM name %s UUID %s done", vmname, clone.UUID(ctx))
+	logger.Info("created an instance", "instance_name", vmname, logging.KeyInstanceID, clone.UUID(ctx))
 	return instance, nil
 }
 
@@ -318,13 +366,13 @@ func getIPs(vm *object.VirtualMachine) ([]netip.Addr, error) { // TODO Fix to ge
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(600*time.Second))
 	defer cancel()
 
-	logger.Printf("Start waiting for cloned vm ip")
+	logger.Info("waiting for the IP address of the cloned VM")
 	ip, err := vm.WaitForIP(ctx, true)
 	if err != nil {
 		return nil, err
 	}
 
-	logger.Printf("VM IP = %s", ip)
+	logger.Info("IP address of the cloned VM", "ip", ip)
 	ip_item, err := netip.ParseAddr(ip)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse pod node IP %q: %w", ip, err)
@@ -335,6 +383,7 @@ func getIPs(vm *object.VirtualMachine) ([]netip.Addr, error) { // TODO Fix to ge
 }
 
 func (p *vsphereProvider) DeleteInstance(ctx context.Context, instanceID string) error {
+	logger := logger.WithContext(ctx)
 
 	if instanceID == "" {
 		return fmt.Errorf("DeleteInstance no VM UUID available")
@@ -342,7 +391,7 @@ func (p *vsphereProvider) DeleteInstance(ctx context.Context, instanceID string)
 
 	instanceID = strings.ToLower(strings.TrimSpace(instanceID))
 
-	logger.Printf("Deleting VM UUID %s", instanceID)
+	logger.Info("deleting a VM", logging.KeyInstanceID, instanceID)
 
 	var (
 		task  *object.Task
@@ -351,7 +400,7 @@ func (p *vsphereProvider) DeleteInstance(ctx context.Context, instanceID string)
 
 	err := CheckSessionWithRestore(ctx, p.serviceConfig, p.gclient)
 	if err != nil {
-		logger.Printf("Cannot find or create a new vcenter session")
+		logger.Error("cannot find or create a new vcenter session", logging.KeyError, err)
 		return err
 	}
 
@@ -360,7 +409,7 @@ func (p *vsphereProvider) DeleteInstance(ctx context.Context, instanceID string)
 	dc, err := finder.Datacenter(ctx, p.serviceConfig.Datacenter)
 
 	if err != nil {
-		logger.Printf("Cannot get vcenter datacenter %s", p.serviceConfig.Datacenter)
+		logger.Error("cannot find vcenter datacenter", "datacenter", p.serviceConfig.Datacenter, logging.KeyError, err)
 		return err
 	}
 
@@ -368,7 +417,7 @@ func (p *vsphereProvider) DeleteInstance(ctx context.Context, instanceID string)
 
 	vmref, err := s.FindByUuid(ctx, dc, instanceID, true, nil)
 	if err != nil {
-		logger.Printf("Delete VM can't find VM UUID %s to delete it", instanceID)
+		logger.Error("cannot find the VM to delete", logging.KeyInstanceID, instanceID, logging.KeyError, err)
 		return err
 	}
 
@@ -397,13 +446,86 @@ func (p *vsphereProvider) DeleteInstance(ctx context.Context, instanceID string)
 
 	_ = task.Wait(ctx)
 
-	logger.Printf("DeleteInstance VM UUID %s done", instanceID)
+	logger.Info("deleted an instance", logging.KeyInstanceID, instanceID)
 
 	return nil
 }
 
+func (p *vsphereProvider) ListInstances(ctx context.Context) ([]*cloud.Instance, error) {
+	logger := logger.WithContext(ctx)
+
+	if p.serviceConfig.ClusterID == "" {
+		return nil, cloud.ErrNoClusterID
+	}
+
+	err := CheckSessionWithRestore(ctx, p.serviceConfig, p.gclient)
+	if err != nil {
+		logger.Error("cannot find or create a new vcenter session", logging.KeyError, err)
+		return nil, err
+	}
+
+	finder := find.NewFinder(p.gclient.Client)
+
+	dc, err := finder.Datacenter(ctx, p.serviceConfig.Datacenter)
+	if err != nil {
+		logger.Error("cannot find vcenter datacenter", "datacenter", p.serviceConfig.Datacenter, logging.KeyError, err)
+		return nil, err
+	}
+
+	finder.SetDatacenter(dc)
+
+	// Pod VMs are cloned into the deploy folder
+	vmpath := path.Join(dc.InventoryPath, "vm", p.serviceConfig.Deployfolder, util.PodVMNamePrefix+"*")
+
+	vms, err := finder.VirtualMachineList(ctx, vmpath)
+	if err != nil {
+		if _, ok := err.(*find.NotFoundError); ok {
+			return nil, nil
+		}
+		logger.Error("cannot list VMs", "path", vmpath, logging.KeyError, err)
+		return nil, err
+	}
+
+	var instances []*cloud.Instance
+
+	for _, vm := range vms {
+		name := vm.Name()
+		if !util.IsPodVMName(name) {
+			continue
+		}
+
+		var mvm mo.VirtualMachine
+		if err := vm.Properties(ctx, vm.Reference(), []string{"config.extraConfig"}, &mvm); err != nil {
+			logger.Warn("cannot get extra config of VM", "instance_name", name, logging.KeyError, err)
+			continue
+		}
+		if mvm.Config == nil || getExtraConfig(mvm.Config.ExtraConfig, clusterIDKey) != p.serviceConfig.ClusterID {
+			continue
+		}
+
+		instances = append(instances, &cloud.Instance{
+			ID:   vm.UUID(ctx),
+			Name: name,
+		})
+	}
+
+	return instances, nil
+}
+
+// getExtraConfig returns the value of an extra config option, or an empty string if the option is not set
+func getExtraConfig(extraConfig []types.BaseOptionValue, key string) string {
+	for _, option := range extraConfig {
+		if value := option.GetOptionValue(); value.Key == key {
+			if s, ok := value.Value.(string); ok {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
 func (p *vsphereProvider) Teardown() error {
-	logger.Printf("Logout user %s", p.serviceConfig.UserName)
+	logger.Info("logging out", "user", p.serviceConfig.UserName)
 	return DeleteGovmomiClient(p.gclient)
 }
 
@@ -414,3 +536,29 @@ func (p *vsphereProvider) ConfigVerifier() error {
 	}
 	return nil
 }
+
+// SupportedOverrides returns the override annotation of the template of pod VMs. Pod VMs are on the network of
+// their template, and are not tagged.
+func (p *vsphereProvider) SupportedOverrides() []string {
+	return []string{peerpodannotations.ImageID}
+}
+
+// InstanceTypes returns the instance types that pods may request
+func (p *vsphereProvider) InstanceTypes() []string {
+	return cloud.InstanceTypeNames(p.instanceTypeSpecList)
+}
+
+// selectInstanceType selects the instance type of a pod VM from the catalog, based on the instance type,
+// vCPU and memory requirements of spec
+func (p *vsphereProvider) selectInstanceType(ctx context.Context, spec cloud.InstanceTypeSpec) (cloud.InstanceTypeSpec, error) {
+
+	instanceType, err := cloud.SelectInstanceTypeToUse(spec, p.instanceTypeSpecList, p.InstanceTypes(), p.serviceConfig.InstanceType)
+	if err != nil {
+		return cloud.InstanceTypeSpec{}, err
+	}
+
+	instanceTypeSpec, _ := cloud.GetInstanceTypeSpec(p.instanceTypeSpecList, instanceType)
+	logger.WithContext(ctx).Info("selected an instance type", "instance_type", instanceType, "vcpus", instanceTypeSpec.VCPUs, "memory_mib", instanceTypeSpec.Memory)
+
+	return instanceTypeSpec, nil
+}
@@ -4,6 +4,7 @@
 package vsphere
 
 import (
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/util"
 )
 
@@ -19,6 +20,14 @@ type Config struct {
 	Deployfolder string
 	Template     string
 	Host         string
+	ClusterID    string
+	InstanceType string
+	// InstanceTypeSpecList is the catalog of instance types that pod VMs may be sized by
+	InstanceTypeSpecList []cloud.InstanceTypeSpec
+	// VCPUs and Memory in MiB of pod VMs of the default instance type, unless it is in InstanceTypeSpecList.
+	// Zero values keep the size of the template.
+	VCPUs  int
+	Memory int
 }
 
 func (c Config) Redact() Config {
@@ -15,6 +15,8 @@ import (
 	"github.com/vmware/govmomi/vim25"
 	"github.com/vmware/govmomi/vim25/methods"
 	"github.com/vmware/govmomi/vim25/soap"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/logging"
 )
 
 var glock sync.Mutex
@@ -38,7 +40,7 @@ func NewGovmomiClient(vmcfg Config) (*govmomi.Client, error) {
 
 	vim25Client, err := vim25.NewClient(ctx, soapClient)
 	if err != nil {
-		logger.Printf("Error creating vcenter session for user %s: error %s", vmcfg.UserName, err)
+		logger.Error("failed to create a vcenter session", "user", vmcfg.UserName, logging.KeyError, err)
 		return nil, err
 	}
 
@@ -47,11 +49,11 @@ func NewGovmomiClient(vmcfg Config) (*govmomi.Client, error) {
 	manager := session.NewManager(vim25Client)
 	err = manager.Login(ctx, urlinfo.User)
 	if err != nil {
-		logger.Printf("Error logging in user %s: error %s", vmcfg.UserName, err)
+		logger.Error("failed to log in", "user", vmcfg.UserName, logging.KeyError, err)
 		return nil, err
 	}
 
-	logger.Printf("Created session for user %s", vmcfg.UserName)
+	logger.Info("created a vcenter session", "user", vmcfg.UserName)
 
 	gclient := govmomi.Client{
 		Client:         vim25Client,
@@ -67,7 +69,7 @@ func soapKeepAliveHandler(ctx context.Context, c *vim25.Client) func() error {
 
 		_, err := methods.GetCurrentTime(ctx, c)
 		if err != nil {
-			logger.Printf("SOAP keep-alive handler error %s", err)
+			logger.WithContext(ctx).Warn("SOAP keep-alive handler error", logging.KeyError, err)
 			return err
 		}
 
@@ -83,7 +85,7 @@ func DeleteGovmomiClient(gclient *govmomi.Client) error {
 
 	err := gclient.SessionManager.Logout(context.Background())
 	if err != nil {
-		logger.Printf("Vcenter logout failed error: %s", err)
+		logger.Error("vcenter logout failed", logging.KeyError, err)
 	}
 
 	return err
@@ -104,7 +106,7 @@ func CheckSessionWithRestore(ctx context.Context, vmcfg *Config, gclient *govmom
 	}
 
 	if err != nil {
-		logger.Printf("Creating new sesssion for user %s due to current session error: %s", vmcfg.UserName, err)
+		logger.WithContext(ctx).Warn("creating a new vcenter session due to an error of the current session", "user", vmcfg.UserName, logging.KeyError, err)
 	}
 
 	_ = gclient.SessionManager.Logout(ctx) // Cleanup purposes
@@ -115,11 +117,11 @@ func CheckSessionWithRestore(ctx context.Context, vmcfg *Config, gclient *govmom
 
 	err = gclient.SessionManager.Login(ctx, urlinfo.User)
 	if err != nil {
-		logger.Printf("Vcenter login failed error: %s", err)
+		logger.WithContext(ctx).Error("vcenter login failed", "user", vmcfg.UserName, logging.KeyError, err)
 		return err
 	}
 
-	logger.Printf("Created new session for user %s", vmcfg.UserName)
+	logger.WithContext(ctx).Info("created a new vcenter session", "user", vmcfg.UserName)
 
 	return nil
 }
@@ -0,0 +1,460 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package cloud
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/metrics"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/proxy"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/forwarder"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/cloudinit"
+)
+
+const (
+	// WarmPoolRefillEager refills a warm pool as soon as an instance is taken from the pool
+	WarmPoolRefillEager = "eager"
+	// WarmPoolRefillPeriodic refills a warm pool only at every refill interval
+	WarmPoolRefillPeriodic = "periodic"
+
+	DefaultWarmPoolRefillPolicy   = WarmPoolRefillEager
+	DefaultWarmPoolRefillInterval = 1 * time.Minute
+
+	// defaultWarmPoolInstanceType is the key of WarmPoolSizes for the default instance type of a provider
+	defaultWarmPoolInstanceType = "default"
+
+	warmInstanceCreateTimeout = 10 * time.Minute
+	warmInstanceDeleteTimeout = 1 * time.Minute
+	warmInstanceListTimeout   = 1 * time.Minute
+)
+
+// WarmPoolSizes is the number of pre-provisioned instances per instance type.
+// An empty instance type represents the default instance type of a provider.
+type WarmPoolSizes map[string]int
+
+// String returns the string representation of WarmPoolSizes
+func (s *WarmPoolSizes) String() string {
+	var pairs []string
+	for instanceType, size := range *s {
+		if instanceType == "" {
+			instanceType = defaultWarmPoolInstanceType
+		}
+		pairs = append(pairs, fmt.Sprintf("%s=%d", instanceType, size))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// Set parses a comma-separated list of <instance type>=<size>. The instance type
+// "default" stands for the default instance type of a provider.
+func (s *WarmPoolSizes) Set(value string) error {
+	if *s == nil {
+		*s = make(WarmPoolSizes)
+	}
+	for _, pair := range strings.Split(value, ",") {
+		keyValue := strings.SplitN(pair, "=", 2)
+		if len(keyValue) != 2 {
+			return fmt.Errorf("invalid warm pool size: %s", pair)
+		}
+		instanceType := strings.TrimSpace(keyValue[0])
+		size, err := strconv.Atoi(strings.TrimSpace(keyValue[1]))
+		if err != nil || size < 0 {
+			return fmt.Errorf("invalid warm pool size: %s", pair)
+		}
+		if instanceType == defaultWarmPoolInstanceType {
+			instanceType = ""
+		}
+		(*s)[instanceType] = size
+	}
+	return nil
+}
+
+// WarmPoolConfig configures a pool of pre-provisioned pod VM instances
+type WarmPoolConfig struct {
+	Sizes WarmPoolSizes
+	// MaxInstances limits the total number of idle and in-flight instances of all instance types. Zero means no limit.
+	MaxInstances   int
+	RefillPolicy   string
+	RefillInterval time.Duration
+	// NodeName is the name of the worker node, which is encoded in names of pre-provisioned instances
+	NodeName string
+}
+
+// Enabled reports whether any instance is pre-provisioned with this configuration
+func (c *WarmPoolConfig) Enabled() bool {
+	if c == nil {
+		return false
+	}
+	for _, size := range c.Sizes {
+		if size > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the configuration of a warm pool
+func (c *WarmPoolConfig) Validate() error {
+	switch c.RefillPolicy {
+	case "", WarmPoolRefillEager, WarmPoolRefillPeriodic:
+	default:
+		return fmt.Errorf("invalid warm pool refill policy: %q", c.RefillPolicy)
+	}
+	if c.MaxInstances < 0 {
+		return fmt.Errorf("invalid maximum number of warm pool instances: %d", c.MaxInstances)
+	}
+	return nil
+}
+
+// WarmPoolStats is a snapshot of warm pool metrics
+type WarmPoolStats struct {
+	Hits     uint64
+	Misses   uint64
+	Created  uint64
+	Failed   uint64
+	Idle     map[string]int
+	Creating map[string]int
+}
+
+type warmPool struct {
+	provider     Provider
+	proxyFactory proxy.Factory
+	config       WarmPoolConfig
+	podName      string
+	idle         map[string][]*Instance
+	creating     map[string]int
+	stats        WarmPoolStats
+	mutex        sync.Mutex
+	refillCh     chan struct{}
+	stopCh       chan struct{}
+	stopOnce     sync.Once
+	wg           sync.WaitGroup
+}
+
+func newWarmPool(provider Provider, proxyFactory proxy.Factory, config WarmPoolConfig) *warmPool {
+
+	if config.RefillPolicy == "" {
+		config.RefillPolicy = DefaultWarmPoolRefillPolicy
+	}
+	if config.RefillInterval <= 0 {
+		config.RefillInterval = DefaultWarmPoolRefillInterval
+	}
+
+	return &warmPool{
+		provider:     provider,
+		proxyFactory: proxyFactory,
+		config:       config,
+		podName:      util.WarmPoolPodName(config.NodeName),
+		idle:         make(map[string][]*Instance),
+		creating:     make(map[string]int),
+		refillCh:     make(chan struct{}, 1),
+		stopCh:       make(chan struct{}),
+	}
+}
+
+// start runs a refill loop until the pool is closed. Pre-provisioned instances of this worker node left behind by
+// a previous cloud-api-adaptor are deleted first, unless their IDs are in assigned.
+func (p *warmPool) start(assigned map[string]bool) {
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		p.deleteLeftovers(assigned)
+
+		ticker := time.NewTicker(p.config.RefillInterval)
+		defer ticker.Stop()
+
+		for {
+			p.refill()
+
+			select {
+			case <-p.stopCh:
+				return
+			case <-ticker.C:
+			case <-p.refillCh:
+			}
+		}
+	}()
+}
+
+func (p *warmPool) triggerRefill() {
+	select {
+	case p.refillCh <- struct{}{}:
+	default:
+	}
+}
+
+// deleteLeftovers deletes pre-provisioned instances of this worker node that are neither idle in the pool
+// nor assigned to a sandbox. Such instances are left behind when cloud-api-adaptor is restarted, since idle
+// instances are not persisted. The orphan reconciler does not delete them as long as the worker node exists.
+func (p *warmPool) deleteLeftovers(assigned map[string]bool) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), warmInstanceListTimeout)
+	defer cancel()
+
+	instances, err := p.provider.ListInstances(ctx)
+	if err != nil {
+		logger.Warnf("warm pool: failed to list instances, pre-provisioned instances created before restart may be left behind: %v", err)
+		return
+	}
+
+	for _, instance := range instances {
+		if !util.InstanceNameMatchesPod(instance.Name, p.podName) || assigned[instance.ID] {
+			continue
+		}
+		logger.Printf("warm pool: deleting instance %s (%s) created before restart", instance.Name, instance.ID)
+		p.delete(instance)
+	}
+}
+
+// refill starts creating instances up to the configured pool sizes
+func (p *warmPool) refill() {
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	select {
+	case <-p.stopCh:
+		return
+	default:
+	}
+
+	total := 0
+	for instanceType := range p.config.Sizes {
+		total += len(p.idle[instanceType]) + p.creating[instanceType]
+	}
+
+	instanceTypes := make([]string, 0, len(p.config.Sizes))
+	for instanceType := range p.config.Sizes {
+		instanceTypes = append(instanceTypes, instanceType)
+	}
+	sort.Strings(instanceTypes)
+
+	for _, instanceType := range instanceTypes {
+
+		deficit := p.config.Sizes[instanceType] - len(p.idle[instanceType]) - p.creating[instanceType]
+
+		for ; deficit > 0; deficit-- {
+			if p.config.MaxInstances > 0 && total >= p.config.MaxInstances {
+				return
+			}
+			total++
+			p.creating[instanceType]++
+
+			p.wg.Add(1)
+			go func(instanceType string) {
+				defer p.wg.Done()
+				p.create(instanceType)
+			}(instanceType)
+		}
+	}
+}
+
+func (p *warmPool) create(instanceType string) {
+
+	var instance *Instance
+
+	defer func() {
+		p.mutex.Lock()
+
+		p.creating[instanceType]--
+
+		var closed bool
+		select {
+		case <-p.stopCh:
+			closed = true
+		default:
+		}
+
+		switch {
+		case instance == nil:
+			p.stats.Failed++
+		case !closed:
+			p.stats.Created++
+			p.idle[instanceType] = append(p.idle[instanceType], instance)
+			metrics.SetWarmPoolIdleInstances(instanceType, len(p.idle[instanceType]))
+		}
+		metrics.ObserveWarmPoolCreation(instanceType, instance != nil)
+
+		p.mutex.Unlock()
+
+		if instance != nil && closed {
+			// The pool is closed while the instance was being created
+			p.delete(instance)
+		}
+	}()
+
+	id, err := newWarmInstanceID()
+	if err != nil {
+		logger.Errorf("warm pool: %v", err)
+		return
+	}
+
+	cloudConfig, err := p.bootstrapCloudConfig()
+	if err != nil {
+		logger.Errorf("warm pool: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), warmInstanceCreateTimeout)
+	defer cancel()
+
+	created, err := p.provider.CreateInstance(ctx, p.podName, id, cloudConfig, InstanceTypeSpec{InstanceType: instanceType})
+	if err != nil {
+		logger.Errorf("warm pool: failed to create an instance of type %q: %v", instanceType, err)
+		return
+	}
+	if len(created.IPs) == 0 {
+		logger.Errorf("warm pool: instance %s has no IP address", created.Name)
+		p.delete(created)
+		return
+	}
+
+	logger.Printf("warm pool: created an instance %s (%s) of type %q", created.Name, created.ID, instanceType)
+
+	instance = created
+}
+
+// bootstrapCloudConfig generates cloud config of a pre-provisioned instance. The instance receives
+// a TLS certificate for the bootstrap endpoint, and waits for its daemon config.
+func (p *warmPool) bootstrapCloudConfig() (*cloudinit.CloudConfig, error) {
+
+	agentProxy := p.proxyFactory.New(forwarder.BootstrapServerName, "")
+
+	bootstrapConfig := forwarder.BootstrapConfig{
+		TLSClientCA: string(agentProxy.ClientCA()),
+	}
+
+	if caService := agentProxy.CAService(); caService != nil {
+
+		certPEM, keyPEM, err := caService.Issue(forwarder.BootstrapServerName)
+		if err != nil {
+			return nil, fmt.Errorf("creating TLS certificate for a bootstrap endpoint: %w", err)
+		}
+
+		bootstrapConfig.TLSServerCert = string(certPEM)
+		bootstrapConfig.TLSServerKey = string(keyPEM)
+	}
+
+	bootstrapJSON, err := json.MarshalIndent(bootstrapConfig, "", "    ")
+	if err != nil {
+		return nil, fmt.Errorf("generating JSON data: %w", err)
+	}
+
+	return &cloudinit.CloudConfig{
+		WriteFiles: []cloudinit.WriteFile{
+			{
+				Path:    forwarder.DefaultBootstrapConfigPath,
+				Content: string(bootstrapJSON),
+			},
+		},
+	}, nil
+}
+
+// take removes an idle instance that satisfies spec from the pool. It returns nil when no instance is available.
+func (p *warmPool) take(spec InstanceTypeSpec) *Instance {
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	// Only instance types on regular capacity are pooled. Instances for specific vCPU, memory or GPU requirements,
+	// instances on spot capacity, and instances that override settings of the provider are always created on demand.
+	if spec.VCPUs != 0 || spec.Memory != 0 || spec.GPUs != 0 || spec.Spot || spec.hasOverrides() {
+		p.stats.Misses++
+		metrics.ObserveWarmPoolRequest(false)
+		return nil
+	}
+
+	instances := p.idle[spec.InstanceType]
+	if len(instances) == 0 {
+		p.stats.Misses++
+		metrics.ObserveWarmPoolRequest(false)
+		if _, ok := p.config.Sizes[spec.InstanceType]; ok && p.config.RefillPolicy == WarmPoolRefillEager {
+			p.triggerRefill()
+		}
+		return nil
+	}
+
+	instance := instances[0]
+	p.idle[spec.InstanceType] = instances[1:]
+	p.stats.Hits++
+	metrics.ObserveWarmPoolRequest(true)
+	metrics.SetWarmPoolIdleInstances(spec.InstanceType, len(p.idle[spec.InstanceType]))
+
+	if p.config.RefillPolicy == WarmPoolRefillEager {
+		p.triggerRefill()
+	}
+
+	return instance
+}
+
+func (p *warmPool) delete(instance *Instance) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), warmInstanceDeleteTimeout)
+	defer cancel()
+
+	if err := p.provider.DeleteInstance(ctx, instance.ID); err != nil {
+		logger.Errorf("warm pool: failed to delete instance %s (%s): %v", instance.Name, instance.ID, err)
+	}
+}
+
+// getStats returns a snapshot of warm pool metrics
+func (p *warmPool) getStats() *WarmPoolStats {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	stats := p.stats
+	stats.Idle = make(map[string]int, len(p.idle))
+	stats.Creating = make(map[string]int, len(p.creating))
+	for instanceType, instances := range p.idle {
+		stats.Idle[instanceType] = len(instances)
+	}
+	for instanceType, n := range p.creating {
+		stats.Creating[instanceType] = n
+	}
+	return &stats
+}
+
+// close stops refilling, and deletes idle instances. Instances being created are deleted when their creation completes.
+func (p *warmPool) close() {
+
+	p.mutex.Lock()
+	p.stopOnce.Do(func() {
+		close(p.stopCh)
+	})
+	idle := p.idle
+	p.idle = make(map[string][]*Instance)
+	for instanceType := range idle {
+		metrics.SetWarmPoolIdleInstances(instanceType, 0)
+	}
+	p.mutex.Unlock()
+
+	for _, instances := range idle {
+		for _, instance := range instances {
+			logger.Printf("warm pool: deleting idle instance %s (%s)", instance.Name, instance.ID)
+			p.delete(instance)
+		}
+	}
+
+	p.wg.Wait()
+}
+
+func newWarmInstanceID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating an ID of a warm instance: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
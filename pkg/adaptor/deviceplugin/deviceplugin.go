@@ -0,0 +1,261 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package deviceplugin advertises the peer pods a node can run as an extended resource by a Kubernetes device plugin
+package deviceplugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/logging"
+)
+
+const (
+	// ResourceName is the extended resource requested by peer pods
+	ResourceName = "kata.peerpods.io/vm"
+	// DefaultPluginDir is the directory of the kubelet registration socket and device plugin sockets
+	DefaultPluginDir = pluginapi.DevicePluginPath
+	// DefaultCheckInterval is the interval to check the capacity of peer pods and whether kubelet restarted
+	DefaultCheckInterval = 10 * time.Second
+
+	socketName      = "peerpods.sock"
+	registerTimeout = 10 * time.Second
+)
+
+var logger = logging.New("adaptor/deviceplugin")
+
+// CapacityFunc returns the number of peer pods the node can run, including running ones
+type CapacityFunc func(ctx context.Context) int
+
+// Plugin is a device plugin that exposes one device per peer pod the node can run.
+// Devices beyond the capacity of peer pods are reported unhealthy, so that kubelet does not schedule pods on them.
+type Plugin struct {
+	pluginDir     string
+	limit         int
+	capacity      CapacityFunc
+	checkInterval time.Duration
+
+	mutex    sync.Mutex
+	healthy  int
+	updateCh chan struct{}
+}
+
+// New creates a device plugin that exposes limit devices, and registers it with the kubelet socket in pluginDir
+func New(pluginDir string, limit int, capacity CapacityFunc, checkInterval time.Duration) *Plugin {
+	return &Plugin{
+		pluginDir:     pluginDir,
+		limit:         limit,
+		capacity:      capacity,
+		checkInterval: checkInterval,
+		healthy:       limit,
+		updateCh:      make(chan struct{}),
+	}
+}
+
+// Run serves the device plugin and registers it with kubelet until ctx is done.
+// Kubelet removes the sockets in the plugin directory when it restarts, so the plugin is registered again then.
+func (p *Plugin) Run(ctx context.Context) error {
+
+	p.update(ctx)
+
+	for {
+		server, err := p.serve()
+		if err != nil {
+			return err
+		}
+
+		if err := p.register(ctx); err != nil {
+			server.Stop()
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		logger.Info("registered device plugin", "resource", ResourceName, "devices", p.limit)
+
+		restarted := p.watch(ctx)
+		server.Stop()
+		if !restarted {
+			return nil
+		}
+		logger.Info("device plugin socket is removed by kubelet, registering the device plugin again")
+	}
+}
+
+func (p *Plugin) socketPath() string {
+	return filepath.Join(p.pluginDir, socketName)
+}
+
+// serve starts a gRPC server of the device plugin on its socket
+func (p *Plugin) serve() (*grpc.Server, error) {
+
+	socketPath := p.socketPath()
+	if err := os.Remove(socketPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to remove %s: %w", socketPath, err)
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", socketPath, err)
+	}
+
+	server := grpc.NewServer()
+	pluginapi.RegisterDevicePluginServer(server, p)
+
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			logger.Error("device plugin server stopped", logging.KeyError, err)
+		}
+	}()
+
+	return server, nil
+}
+
+// register registers the device plugin with kubelet. It retries until kubelet accepts the registration or ctx is done.
+func (p *Plugin) register(ctx context.Context) error {
+
+	kubeletSocket := filepath.Join(p.pluginDir, filepath.Base(pluginapi.KubeletSocket))
+	req := &pluginapi.RegisterRequest{
+		Version:      pluginapi.Version,
+		Endpoint:     socketName,
+		ResourceName: ResourceName,
+	}
+
+	for {
+		err := func() error {
+			ctx, cancel := context.WithTimeout(ctx, registerTimeout)
+			defer cancel()
+
+			conn, err := grpc.DialContext(ctx, "unix:"+kubeletSocket, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+			if err != nil {
+				return fmt.Errorf("failed to connect to kubelet at %s: %w", kubeletSocket, err)
+			}
+			defer conn.Close()
+
+			_, err = pluginapi.NewRegistrationClient(conn).Register(ctx, req)
+			return err
+		}()
+		if err == nil {
+			return nil
+		}
+		logger.Warn("failed to register device plugin (retrying...)", logging.KeyError, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(p.checkInterval):
+		}
+	}
+}
+
+// watch updates the health of devices every check interval. It returns true when the device plugin socket is removed,
+// and false when ctx is done.
+func (p *Plugin) watch(ctx context.Context) bool {
+
+	ticker := time.NewTicker(p.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+
+		if _, err := os.Stat(p.socketPath()); err != nil {
+			return true
+		}
+		p.update(ctx)
+	}
+}
+
+// update sets the number of healthy devices to the capacity of peer pods, and notifies ListAndWatch streams of a change
+func (p *Plugin) update(ctx context.Context) {
+
+	healthy := p.capacity(ctx)
+	if healthy > p.limit {
+		healthy = p.limit
+	}
+	if healthy < 0 {
+		healthy = 0
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if healthy == p.healthy {
+		return
+	}
+	logger.Info("capacity of peer pods changed", "resource", ResourceName, "from", p.healthy, "to", healthy)
+	p.healthy = healthy
+	close(p.updateCh)
+	p.updateCh = make(chan struct{})
+}
+
+// devices returns the devices of the plugin, and a channel that is closed when they change
+func (p *Plugin) devices() ([]*pluginapi.Device, <-chan struct{}) {
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	devices := make([]*pluginapi.Device, p.limit)
+	for i := range devices {
+		health := pluginapi.Healthy
+		if i >= p.healthy {
+			health = pluginapi.Unhealthy
+		}
+		devices[i] = &pluginapi.Device{ID: fmt.Sprintf("peerpod-%d", i), Health: health}
+	}
+	return devices, p.updateCh
+}
+
+// GetDevicePluginOptions returns options of the device plugin
+func (p *Plugin) GetDevicePluginOptions(context.Context, *pluginapi.Empty) (*pluginapi.DevicePluginOptions, error) {
+	return &pluginapi.DevicePluginOptions{}, nil
+}
+
+// ListAndWatch sends the devices of the plugin to kubelet whenever their health changes
+func (p *Plugin) ListAndWatch(_ *pluginapi.Empty, stream pluginapi.DevicePlugin_ListAndWatchServer) error {
+	for {
+		devices, updateCh := p.devices()
+		if err := stream.Send(&pluginapi.ListAndWatchResponse{Devices: devices}); err != nil {
+			return err
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-updateCh:
+		}
+	}
+}
+
+// GetPreferredAllocation is not used, since the device plugin does not set GetPreferredAllocationAvailable
+func (p *Plugin) GetPreferredAllocation(context.Context, *pluginapi.PreferredAllocationRequest) (*pluginapi.PreferredAllocationResponse, error) {
+	return &pluginapi.PreferredAllocationResponse{}, nil
+}
+
+// Allocate accepts any allocation. Devices are only slots of peer pods, and nothing is exposed to containers.
+func (p *Plugin) Allocate(_ context.Context, req *pluginapi.AllocateRequest) (*pluginapi.AllocateResponse, error) {
+	res := &pluginapi.AllocateResponse{}
+	for range req.ContainerRequests {
+		res.ContainerResponses = append(res.ContainerResponses, &pluginapi.ContainerAllocateResponse{})
+	}
+	return res, nil
+}
+
+// PreStartContainer is not used, since the device plugin does not set PreStartRequired
+func (p *Plugin) PreStartContainer(context.Context, *pluginapi.PreStartContainerRequest) (*pluginapi.PreStartContainerResponse, error) {
+	return &pluginapi.PreStartContainerResponse{}, nil
+}
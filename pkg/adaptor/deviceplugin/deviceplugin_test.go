@@ -0,0 +1,154 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package deviceplugin
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// fakeKubelet accepts registrations of device plugins, and watches devices of registered plugins
+type fakeKubelet struct {
+	pluginapi.UnimplementedRegistrationServer
+	dir       string
+	requests  chan *pluginapi.RegisterRequest
+	responses chan *pluginapi.ListAndWatchResponse
+}
+
+func startFakeKubelet(t *testing.T, dir string) *fakeKubelet {
+
+	kubelet := &fakeKubelet{
+		dir:       dir,
+		requests:  make(chan *pluginapi.RegisterRequest, 10),
+		responses: make(chan *pluginapi.ListAndWatchResponse, 10),
+	}
+
+	listener, err := net.Listen("unix", filepath.Join(dir, "kubelet.sock"))
+	require.NoError(t, err)
+
+	server := grpc.NewServer()
+	pluginapi.RegisterRegistrationServer(server, kubelet)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	return kubelet
+}
+
+func (k *fakeKubelet) Register(ctx context.Context, req *pluginapi.RegisterRequest) (*pluginapi.Empty, error) {
+	k.requests <- req
+
+	conn, err := grpc.Dial("unix:"+filepath.Join(k.dir, req.Endpoint), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	stream, err := pluginapi.NewDevicePluginClient(conn).ListAndWatch(context.Background(), &pluginapi.Empty{})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	go func() {
+		defer conn.Close()
+		for {
+			res, err := stream.Recv()
+			if err != nil {
+				return
+			}
+			k.responses <- res
+		}
+	}()
+
+	return &pluginapi.Empty{}, nil
+}
+
+func healthyDevices(res *pluginapi.ListAndWatchResponse) (healthy int) {
+	for _, device := range res.Devices {
+		if device.Health == pluginapi.Healthy {
+			healthy++
+		}
+	}
+	return healthy
+}
+
+func receive[T any](t *testing.T, ch chan T) T {
+	t.Helper()
+
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out")
+	}
+	panic("unreachable")
+}
+
+func TestDevicePlugin(t *testing.T) {
+
+	dir := t.TempDir()
+	kubelet := startFakeKubelet(t, dir)
+
+	var capacity atomic.Int64
+	capacity.Store(3)
+
+	plugin := New(dir, 3, func(context.Context) int { return int(capacity.Load()) }, 10*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- plugin.Run(ctx)
+	}()
+
+	req := receive(t, kubelet.requests)
+	assert.Equal(t, pluginapi.Version, req.Version)
+	assert.Equal(t, ResourceName, req.ResourceName)
+	assert.Equal(t, socketName, req.Endpoint)
+
+	res := receive(t, kubelet.responses)
+	assert.Len(t, res.Devices, 3)
+	assert.Equal(t, 3, healthyDevices(res))
+
+	// Quota or sandboxes allow only one more peer pod
+	capacity.Store(1)
+	res = receive(t, kubelet.responses)
+	assert.Len(t, res.Devices, 3)
+	assert.Equal(t, 1, healthyDevices(res))
+
+	// Kubelet removes device plugin sockets when it restarts
+	require.NoError(t, os.Remove(filepath.Join(dir, socketName)))
+	req = receive(t, kubelet.requests)
+	assert.Equal(t, ResourceName, req.ResourceName)
+	res = receive(t, kubelet.responses)
+	assert.Equal(t, 1, healthyDevices(res))
+
+	cancel()
+	assert.NoError(t, receive(t, done))
+}
+
+func TestDevicePluginAllocate(t *testing.T) {
+
+	plugin := New(t.TempDir(), 2, func(context.Context) int { return 5 }, time.Second)
+
+	res, err := plugin.Allocate(context.Background(), &pluginapi.AllocateRequest{
+		ContainerRequests: []*pluginapi.ContainerAllocateRequest{{DevicesIDs: []string{"peerpod-0"}}, {DevicesIDs: []string{"peerpod-1"}}},
+	})
+	require.NoError(t, err)
+	assert.Len(t, res.ContainerResponses, 2)
+
+	// Capacity beyond the limit is ignored
+	plugin.update(context.Background())
+	devices, _ := plugin.devices()
+	assert.Len(t, devices, 2)
+}
@@ -7,29 +7,81 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"os"
+	"strconv"
+	"time"
 
 	peerPodV1alpha1 "github.com/confidential-containers/cloud-api-adaptor/peerpod-ctrl/api/v1alpha1"
+	ppclientset "github.com/confidential-containers/cloud-api-adaptor/peerpod-ctrl/pkg/generated/clientset/versioned"
+	ppinformers "github.com/confidential-containers/cloud-api-adaptor/peerpod-ctrl/pkg/generated/informers/externalversions"
+	pplisters "github.com/confidential-containers/cloud-api-adaptor/peerpod-ctrl/pkg/generated/listers/confidentialcontainers/v1alpha1"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/logging"
 
 	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
 )
 
-var logger = log.New(log.Writer(), "[util/k8sops] ", log.LstdFlags|log.Lmsgprefix)
+var logger = logging.New("util/k8sops")
 var ppFinalizer string = "peer.pod/finalizer"
 
+// PodUIDLabel is the label of a PeerPod object that holds the UID of the pod owning it
+const PodUIDLabel = "confidentialcontainers.org/pod-uid"
+
+// Reasons of PeerPod conditions set by cloud-api-adaptor
+const (
+	reasonAgentReady   = "AgentReady"
+	reasonStartFailed  = "StartFailed"
+	reasonDeleted      = "Deleted"
+	reasonDeleteFailed = "DeleteFailed"
+	reasonInterrupted  = "Interrupted"
+)
+
+// EventReasonPodVMInterrupted is the reason of the event of a pod whose instance on spot capacity is interrupted
+const EventReasonPodVMInterrupted = "PodVMInterrupted"
+
+const informerSyncTimeout = time.Minute
+
+// ppBackoff is the backoff of retrying requests for PeerPod objects
+var ppBackoff = wait.Backoff{
+	Steps:    5,
+	Duration: 200 * time.Millisecond,
+	Factor:   2.0,
+	Jitter:   0.1,
+}
+
+// PeerPodInstance is an instance recorded in the status of a PeerPod
+type PeerPodInstance struct {
+	ID           string
+	Name         string
+	IPs          []string
+	InstanceType string
+	Zone         string
+	// Attempt is the attempt that created the instance, and Placement the alternate placement it used
+	Attempt   int
+	Placement string
+	// Price is the hourly price of the instance type. Zero means unknown.
+	Price float64
+}
+
+// PeerPodService manages PeerPod objects owned by pods.
+// It finds the PeerPod object of a pod by the pod UID label or by the owner reference,
+// so it keeps working after cloud-api-adaptor restarts.
 type PeerPodService struct {
-	client        *kubernetes.Clientset
-	uclient       *rest.RESTClient // use generated client instaed
+	client        kubernetes.Interface
+	ppClient      ppclientset.Interface
+	lister        pplisters.PeerPodLister
 	cloudProvider string
-	podToPP       map[string]string // map Pod UID to owned PeerPod Name
+	backoff       wait.Backoff
 }
 
 func NewPeerPodService() (*PeerPodService, error) {
@@ -47,26 +99,55 @@ func NewPeerPodService() (*PeerPodService, error) {
 	if err != nil {
 		return nil, fmt.Errorf("NewPeerPodService: failed to create clientset: %w", err)
 	}
-	config.ContentConfig.GroupVersion = &schema.GroupVersion{Group: peerPodV1alpha1.GroupVersion.Group, Version: peerPodV1alpha1.GroupVersion.Version}
-	config.NegotiatedSerializer = scheme.Codecs.WithoutConversion()
-	config.APIPath = "/apis"
-	restClient, err := rest.UnversionedRESTClientFor(config)
+
+	ppClient, err := ppclientset.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("NewPeerPodService: failed to create PeerPod clientset: %w", err)
+	}
+
+	// The informer runs as long as cloud-api-adaptor
+	s, err := newPeerPodService(clientset, ppClient, cloudProvider, wait.NeverStop)
 	if err != nil {
-		return nil, fmt.Errorf("NewPeerPodService: failed to create UnversionedRESTClient: %s", err)
+		return nil, fmt.Errorf("NewPeerPodService: %w", err)
 	}
+
 	logger.Printf("initialized PeerPodService")
-	return &PeerPodService{client: clientset, uclient: restClient, cloudProvider: cloudProvider, podToPP: make(map[string]string)}, nil
+	return s, nil
+}
+
+// newPeerPodService creates a PeerPodService, and starts an informer of PeerPod objects that runs until stopCh is closed.
+// The informer caches only PeerPods with the pod UID label, which are created by cloud-api-adaptor.
+func newPeerPodService(client kubernetes.Interface, ppClient ppclientset.Interface, cloudProvider string, stopCh <-chan struct{}) (*PeerPodService, error) {
+
+	factory := ppinformers.NewSharedInformerFactoryWithOptions(ppClient, 0, ppinformers.WithTweakListOptions(func(options *metav1.ListOptions) {
+		options.LabelSelector = PodUIDLabel
+	}))
+	informer := factory.Confidentialcontainers().V1alpha1().PeerPods()
+	lister := informer.Lister()
+
+	factory.Start(stopCh)
+
+	ctx, cancel := context.WithTimeout(context.Background(), informerSyncTimeout)
+	defer cancel()
+	if !cache.WaitForCacheSync(ctx.Done(), informer.Informer().HasSynced) {
+		return nil, errors.New("failed to sync PeerPod informer")
+	}
+
+	return &PeerPodService{
+		client:        client,
+		ppClient:      ppClient,
+		lister:        lister,
+		cloudProvider: cloudProvider,
+		backoff:       ppBackoff,
+	}, nil
 }
 
 func (s *PeerPodService) newPeerPod(pod *v1.Pod, instanceId string) *peerPodV1alpha1.PeerPod {
 	pp := peerPodV1alpha1.PeerPod{
-		TypeMeta: metav1.TypeMeta{
-			APIVersion: peerPodV1alpha1.GroupVersion.Group + "/" + peerPodV1alpha1.GroupVersion.Version,
-			Kind:       "PeerPod",
-		},
 		ObjectMeta: metav1.ObjectMeta{
 			Name:       pod.Name + "-resource-" + rand.String(5),
 			Namespace:  pod.Namespace,
+			Labels:     map[string]string{PodUIDLabel: string(pod.UID)},
 			Finalizers: []string{ppFinalizer},
 			OwnerReferences: []metav1.OwnerReference{
 				*metav1.NewControllerRef(pod, v1.SchemeGroupVersion.WithKind("Pod")),
@@ -81,49 +162,322 @@ func (s *PeerPodService) newPeerPod(pod *v1.Pod, instanceId string) *peerPodV1al
 	return &pp
 }
 
-func (s *PeerPodService) getPod(podname string, podns string) (*v1.Pod, error) {
-	pod, err := s.client.CoreV1().Pods(podns).Get(context.TODO(), podname, metav1.GetOptions{})
+func (s *PeerPodService) getPod(ctx context.Context, podname string, podns string) (*v1.Pod, error) {
+	pod, err := s.client.CoreV1().Pods(podns).Get(ctx, podname, metav1.GetOptions{})
 	if err != nil {
 		return nil, err
 	}
 	return pod, nil
 }
 
+// retry calls fn until it succeeds, it fails with a permanent error, or the backoff is exhausted
+func (s *PeerPodService) retry(fn func() error) error {
+	return retry.OnError(s.backoff, isRetriable, fn)
+}
+
+// isRetriable returns true if err is a conflict or may be a transient failure
+func isRetriable(err error) bool {
+	return !(k8serrors.IsNotFound(err) ||
+		k8serrors.IsAlreadyExists(err) ||
+		k8serrors.IsInvalid(err) ||
+		k8serrors.IsBadRequest(err) ||
+		k8serrors.IsForbidden(err) ||
+		k8serrors.IsUnauthorized(err) ||
+		k8serrors.IsMethodNotSupported(err))
+}
+
+// findPeerPod returns the name of the PeerPod owned by a pod. If instanceID is not empty, it prefers the PeerPod of the instance,
+// which is found even if the pod is already deleted. It looks up the informer cache first, and the API server if a PeerPod
+// created recently is not in the cache yet.
+func (s *PeerPodService) findPeerPod(ctx context.Context, podname, podns, instanceID string) (string, error) {
+	pps, err := s.lister.PeerPods(podns).List(labels.Everything())
+	if err != nil {
+		return "", err
+	}
+	if pp := peerPodOfInstance(pps, instanceID); pp != nil {
+		return pp.Name, nil
+	}
+
+	var podUID types.UID
+	pod, err := s.getPod(ctx, podname, podns)
+	if err == nil {
+		podUID = pod.UID
+		if pp := selectPeerPod(pps, podUID, instanceID); pp != nil {
+			return pp.Name, nil
+		}
+	} else if !k8serrors.IsNotFound(err) || instanceID == "" {
+		return "", err
+	}
+
+	// PeerPods created by previous versions have no pod UID label, and are not cached by the informer
+	var list *peerPodV1alpha1.PeerPodList
+	err = s.retry(func() (err error) {
+		list, err = s.ppClient.ConfidentialcontainersV1alpha1().PeerPods(podns).List(ctx, metav1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	pps = nil
+	for i := range list.Items {
+		pps = append(pps, &list.Items[i])
+	}
+	if pp := peerPodOfInstance(pps, instanceID); pp != nil {
+		return pp.Name, nil
+	}
+	if podUID != "" {
+		if pp := selectPeerPod(pps, podUID, instanceID); pp != nil {
+			return pp.Name, nil
+		}
+	}
+
+	return "", fmt.Errorf("PeerPod owned by pod %s/%s is not found", podns, podname)
+}
+
+// peerPodOfInstance returns the PeerPod of instanceID, or nil if instanceID is empty or not found
+func peerPodOfInstance(pps []*peerPodV1alpha1.PeerPod, instanceID string) *peerPodV1alpha1.PeerPod {
+	if instanceID == "" {
+		return nil
+	}
+	for _, pp := range pps {
+		if pp.Spec.InstanceID == instanceID {
+			return pp
+		}
+	}
+	return nil
+}
+
+// selectPeerPod returns the PeerPod owned by a pod. It returns the PeerPod of instanceID, if any,
+// or the latest one, since peerpod-ctrl deletes old PeerPods of a pod left behind by crashes.
+func selectPeerPod(pps []*peerPodV1alpha1.PeerPod, podUID types.UID, instanceID string) *peerPodV1alpha1.PeerPod {
+	var selected *peerPodV1alpha1.PeerPod
+	for _, pp := range pps {
+		if !isOwnedBy(pp, podUID) {
+			continue
+		}
+		if instanceID != "" && pp.Spec.InstanceID == instanceID {
+			return pp
+		}
+		if selected == nil || selected.CreationTimestamp.Before(&pp.CreationTimestamp) {
+			selected = pp
+		}
+	}
+	return selected
+}
+
+func isOwnedBy(pp *peerPodV1alpha1.PeerPod, podUID types.UID) bool {
+	if uid, ok := pp.Labels[PodUIDLabel]; ok {
+		return uid == string(podUID)
+	}
+	for _, ref := range pp.OwnerReferences {
+		if ref.UID == podUID {
+			return true
+		}
+	}
+	return false
+}
+
+// updateStatus updates the status of a PeerPod with update, and retries on conflicts and transient failures
+func (s *PeerPodService) updateStatus(ctx context.Context, namespace, name string, update func(status *peerPodV1alpha1.PeerPodStatus)) error {
+	client := s.ppClient.ConfidentialcontainersV1alpha1().PeerPods(namespace)
+	return s.retry(func() error {
+		pp, err := client.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		update(&pp.Status)
+		_, err = client.UpdateStatus(ctx, pp, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// updatePeerPodStatus updates the status of the PeerPod owned by a pod
+func (s *PeerPodService) updatePeerPodStatus(podname string, podns string, update func(status *peerPodV1alpha1.PeerPodStatus)) error {
+	ctx := context.TODO()
+	ppName, err := s.findPeerPod(ctx, podname, podns, "")
+	if err != nil {
+		return err
+	}
+	return s.updateStatus(ctx, podns, ppName, update)
+}
+
 // make the pod an owner of a PeerPod
-func (s *PeerPodService) OwnPeerPod(podname string, podns string, instanceID string) error {
-	pod, err := s.getPod(podname, podns)
+func (s *PeerPodService) OwnPeerPod(podname string, podns string, instance *PeerPodInstance) error {
+	ctx := context.TODO()
+	pod, err := s.getPod(ctx, podname, podns)
 	if err != nil {
 		return err
 	}
-	pp := s.newPeerPod(pod, instanceID)
-	result := peerPodV1alpha1.PeerPod{}
-	err = s.uclient.Post().Namespace(pod.Namespace).Resource("peerPods").Body(pp).Do(context.TODO()).Into(&result)
+
+	pp := s.newPeerPod(pod, instance.ID)
+	client := s.ppClient.ConfidentialcontainersV1alpha1().PeerPods(pod.Namespace)
+	err = s.retry(func() error {
+		_, err := client.Create(ctx, pp, metav1.CreateOptions{})
+		if k8serrors.IsAlreadyExists(err) {
+			// A previous attempt created it, but its response was lost
+			return nil
+		}
+		return err
+	})
 	if err != nil {
 		return err
 	}
-	s.podToPP[string(pod.UID)] = string(pp.Name)
 	logger.Printf("%s is now owning a PeerPod object", podname)
-	return nil
+
+	// The status subresource is ignored on creation
+	return s.updateStatus(ctx, pod.Namespace, pp.Name, func(status *peerPodV1alpha1.PeerPodStatus) {
+		now := metav1.Now()
+		status.InstanceName = instance.Name
+		status.IPs = instance.IPs
+		status.InstanceType = instance.InstanceType
+		status.Zone = instance.Zone
+		status.Attempt = int32(instance.Attempt)
+		status.Placement = instance.Placement
+		if instance.Price > 0 {
+			status.Price = strconv.FormatFloat(instance.Price, 'f', -1, 64)
+		}
+		status.Phase = peerPodV1alpha1.PeerPodProvisioning
+		status.CreationTime = &now
+	})
+}
+
+// SetPeerPodRunning records that the agent of the instance of a pod is ready
+func (s *PeerPodService) SetPeerPodRunning(podname string, podns string) error {
+	return s.updatePeerPodStatus(podname, podns, func(status *peerPodV1alpha1.PeerPodStatus) {
+		now := metav1.Now()
+		status.Phase = peerPodV1alpha1.PeerPodRunning
+		status.ReadyTime = &now
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    peerPodV1alpha1.ConditionReady,
+			Status:  metav1.ConditionTrue,
+			Reason:  reasonAgentReady,
+			Message: "agent of the instance is reachable",
+		})
+	})
+}
+
+// SetPeerPodFailed records that the instance of a pod failed to start
+func (s *PeerPodService) SetPeerPodFailed(podname string, podns string, cause error) error {
+	return s.updatePeerPodStatus(podname, podns, func(status *peerPodV1alpha1.PeerPodStatus) {
+		status.Phase = peerPodV1alpha1.PeerPodFailed
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    peerPodV1alpha1.ConditionReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  reasonStartFailed,
+			Message: cause.Error(),
+		})
+	})
+}
+
+// SetPeerPodInterrupted records that the cloud provider interrupts the instance of a pod on spot capacity,
+// and emits a warning event of the pod
+func (s *PeerPodService) SetPeerPodInterrupted(podname string, podns string, instanceID string) error {
+	message := fmt.Sprintf("instance %s on spot capacity is interrupted by the cloud provider", instanceID)
+
+	statusErr := s.updatePeerPodStatus(podname, podns, func(status *peerPodV1alpha1.PeerPodStatus) {
+		status.Phase = peerPodV1alpha1.PeerPodFailed
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    peerPodV1alpha1.ConditionReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  reasonInterrupted,
+			Message: message,
+		})
+	})
+	eventErr := s.recordPodEvent(podname, podns, v1.EventTypeWarning, EventReasonPodVMInterrupted, message)
+
+	return errors.Join(statusErr, eventErr)
+}
+
+// recordPodEvent emits an event of a pod
+func (s *PeerPodService) recordPodEvent(podname, podns, eventType, reason, message string) error {
+	ctx := context.TODO()
+	pod, err := s.getPod(ctx, podname, podns)
+	if err != nil {
+		return err
+	}
+
+	now := metav1.Now()
+	event := &v1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: pod.Name + ".",
+			Namespace:    pod.Namespace,
+		},
+		InvolvedObject: v1.ObjectReference{
+			Kind:            "Pod",
+			APIVersion:      "v1",
+			Name:            pod.Name,
+			Namespace:       pod.Namespace,
+			UID:             pod.UID,
+			ResourceVersion: pod.ResourceVersion,
+		},
+		Type:           eventType,
+		Reason:         reason,
+		Message:        message,
+		Source:         v1.EventSource{Component: "cloud-api-adaptor"},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+
+	return s.retry(func() error {
+		_, err := s.client.CoreV1().Events(pod.Namespace).Create(ctx, event, metav1.CreateOptions{})
+		return err
+	})
+}
+
+// SetPeerPodDeleting records that the instance of a pod is being deleted
+func (s *PeerPodService) SetPeerPodDeleting(podname string, podns string) error {
+	return s.updatePeerPodStatus(podname, podns, func(status *peerPodV1alpha1.PeerPodStatus) {
+		status.Phase = peerPodV1alpha1.PeerPodDeleting
+	})
+}
+
+// SetPeerPodDeletionFailed records that deletion of the instance of a pod failed
+func (s *PeerPodService) SetPeerPodDeletionFailed(podname string, podns string, cause error) error {
+	return s.updatePeerPodStatus(podname, podns, func(status *peerPodV1alpha1.PeerPodStatus) {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    peerPodV1alpha1.ConditionInstanceDeleted,
+			Status:  metav1.ConditionFalse,
+			Reason:  reasonDeleteFailed,
+			Message: cause.Error(),
+		})
+	})
 }
 
 // remove finalizer from PeerPod
 func (s *PeerPodService) ReleasePeerPod(podname string, podns string, instanceID string) error {
-	pod, err := s.getPod(podname, podns)
+	ctx := context.TODO()
+	ppName, err := s.findPeerPod(ctx, podname, podns, instanceID)
 	if err != nil {
 		return err
 	}
 
-	ownedPPName, ok := s.podToPP[string(pod.UID)]
-	if !ok {
-		return errors.New("pod to PeerPod mapping not found")
+	err = s.updateStatus(ctx, podns, ppName, func(status *peerPodV1alpha1.PeerPodStatus) {
+		now := metav1.Now()
+		status.Cleaned = true
+		status.DeletionTime = &now
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    peerPodV1alpha1.ConditionInstanceDeleted,
+			Status:  metav1.ConditionTrue,
+			Reason:  reasonDeleted,
+			Message: fmt.Sprintf("instance %s is deleted by cloud-api-adaptor", instanceID),
+		})
+	})
+	if err != nil {
+		logger.Printf("failed to update the status of PeerPod %s: %v", ppName, err)
 	}
-	result := peerPodV1alpha1.PeerPod{}
+
 	patch := []byte(`[{"op": "remove", "path": "/metadata/finalizers"}]`)
-	err = s.uclient.Patch(types.JSONPatchType).Name(ownedPPName).Namespace(podns).Resource("peerPods").Body(patch).Do(context.TODO()).Into(&result)
-	if err != nil {
+	err = s.retry(func() error {
+		_, err := s.ppClient.ConfidentialcontainersV1alpha1().PeerPods(podns).Patch(ctx, ppName, types.JSONPatchType, patch, metav1.PatchOptions{})
+		return err
+	})
+	if k8serrors.IsInvalid(err) {
+		// The remove operation fails with 422 when there are no finalizers, e.g. a previous attempt removed them
+		logger.Printf("PeerPod %s has no finalizers. it is already released", ppName)
+	} else if err != nil {
 		return err
 	}
-	delete(s.podToPP, string(pod.UID))
 	logger.Printf("%s's owned PeerPod object can now be deleted", podname)
 	return nil
 }
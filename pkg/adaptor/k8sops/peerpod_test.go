@@ -0,0 +1,216 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package k8sops
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	peerPodV1alpha1 "github.com/confidential-containers/cloud-api-adaptor/peerpod-ctrl/api/v1alpha1"
+	ppfake "github.com/confidential-containers/cloud-api-adaptor/peerpod-ctrl/pkg/generated/clientset/versioned/fake"
+)
+
+func newTestPeerPodService(t *testing.T, ppClient *ppfake.Clientset, pods ...runtime.Object) *PeerPodService {
+	stopCh := make(chan struct{})
+	t.Cleanup(func() { close(stopCh) })
+
+	s, err := newPeerPodService(k8sfake.NewSimpleClientset(pods...), ppClient, "test", stopCh)
+	require.NoError(t, err)
+	s.backoff = wait.Backoff{Steps: 3, Duration: time.Millisecond}
+	return s
+}
+
+func testPod() *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default", UID: "pod-uid-1"},
+	}
+}
+
+func getPeerPod(t *testing.T, ppClient *ppfake.Clientset) *peerPodV1alpha1.PeerPod {
+	list, err := ppClient.ConfidentialcontainersV1alpha1().PeerPods("default").List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, list.Items, 1)
+	return &list.Items[0]
+}
+
+func TestPeerPodService(t *testing.T) {
+
+	ppClient := ppfake.NewSimpleClientset()
+	s := newTestPeerPodService(t, ppClient, testPod())
+
+	instance := &PeerPodInstance{ID: "i-123", Name: "podvm-nginx", IPs: []string{"192.0.2.1"}, InstanceType: "small", Zone: "zone-1", Attempt: 2, Placement: "subnet-2", Price: 0.0416}
+	require.NoError(t, s.OwnPeerPod("nginx", "default", instance))
+
+	pp := getPeerPod(t, ppClient)
+	assert.Equal(t, "i-123", pp.Spec.InstanceID)
+	assert.Equal(t, "test", pp.Spec.CloudProvider)
+	assert.Equal(t, "pod-uid-1", pp.Labels[PodUIDLabel])
+	assert.Equal(t, []string{ppFinalizer}, pp.Finalizers)
+	assert.Equal(t, "podvm-nginx", pp.Status.InstanceName)
+	assert.Equal(t, []string{"192.0.2.1"}, pp.Status.IPs)
+	assert.Equal(t, "small", pp.Status.InstanceType)
+	assert.Equal(t, "zone-1", pp.Status.Zone)
+	assert.Equal(t, int32(2), pp.Status.Attempt)
+	assert.Equal(t, "subnet-2", pp.Status.Placement)
+	assert.Equal(t, "0.0416", pp.Status.Price)
+	assert.Equal(t, peerPodV1alpha1.PeerPodProvisioning, pp.Status.Phase)
+	assert.NotNil(t, pp.Status.CreationTime)
+
+	require.NoError(t, s.SetPeerPodRunning("nginx", "default"))
+	pp = getPeerPod(t, ppClient)
+	assert.Equal(t, peerPodV1alpha1.PeerPodRunning, pp.Status.Phase)
+	assert.True(t, meta.IsStatusConditionTrue(pp.Status.Conditions, peerPodV1alpha1.ConditionReady))
+
+	require.NoError(t, s.SetPeerPodDeleting("nginx", "default"))
+	require.NoError(t, s.SetPeerPodDeletionFailed("nginx", "default", errors.New("quota exceeded")))
+	pp = getPeerPod(t, ppClient)
+	assert.Equal(t, peerPodV1alpha1.PeerPodDeleting, pp.Status.Phase)
+	assert.True(t, meta.IsStatusConditionFalse(pp.Status.Conditions, peerPodV1alpha1.ConditionInstanceDeleted))
+
+	require.NoError(t, s.ReleasePeerPod("nginx", "default", "i-123"))
+	pp = getPeerPod(t, ppClient)
+	assert.Empty(t, pp.Finalizers)
+	assert.True(t, pp.Status.Cleaned)
+	assert.True(t, meta.IsStatusConditionTrue(pp.Status.Conditions, peerPodV1alpha1.ConditionInstanceDeleted))
+}
+
+func TestPeerPodServiceInterrupted(t *testing.T) {
+
+	ppClient := ppfake.NewSimpleClientset()
+	s := newTestPeerPodService(t, ppClient, testPod())
+
+	require.NoError(t, s.OwnPeerPod("nginx", "default", &PeerPodInstance{ID: "i-123"}))
+	require.NoError(t, s.SetPeerPodRunning("nginx", "default"))
+	require.NoError(t, s.SetPeerPodInterrupted("nginx", "default", "i-123"))
+
+	pp := getPeerPod(t, ppClient)
+	assert.Equal(t, peerPodV1alpha1.PeerPodFailed, pp.Status.Phase)
+	condition := meta.FindStatusCondition(pp.Status.Conditions, peerPodV1alpha1.ConditionReady)
+	require.NotNil(t, condition)
+	assert.Equal(t, metav1.ConditionFalse, condition.Status)
+	assert.Equal(t, reasonInterrupted, condition.Reason)
+
+	events, err := s.client.CoreV1().Events("default").List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, events.Items, 1)
+	assert.Equal(t, v1.EventTypeWarning, events.Items[0].Type)
+	assert.Equal(t, EventReasonPodVMInterrupted, events.Items[0].Reason)
+	assert.Equal(t, "nginx", events.Items[0].InvolvedObject.Name)
+	assert.Equal(t, "pod-uid-1", string(events.Items[0].InvolvedObject.UID))
+}
+
+func TestPeerPodServiceRestart(t *testing.T) {
+
+	pod := testPod()
+
+	// A PeerPod created by a previous cloud-api-adaptor process without the pod UID label
+	ppClient := ppfake.NewSimpleClientset(&peerPodV1alpha1.PeerPod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "nginx-resource-abcde",
+			Namespace:       "default",
+			Finalizers:      []string{ppFinalizer},
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(pod, v1.SchemeGroupVersion.WithKind("Pod"))},
+		},
+		Spec: peerPodV1alpha1.PeerPodSpec{InstanceID: "i-123"},
+	})
+
+	s := newTestPeerPodService(t, ppClient, pod)
+
+	require.NoError(t, s.ReleasePeerPod("nginx", "default", "i-123"))
+	assert.Empty(t, getPeerPod(t, ppClient).Finalizers)
+
+	err := s.ReleasePeerPod("nginx", "other", "i-123")
+	assert.Error(t, err)
+}
+
+func TestPeerPodServiceDeletedPod(t *testing.T) {
+
+	ppClient := ppfake.NewSimpleClientset()
+	s := newTestPeerPodService(t, ppClient, testPod())
+
+	require.NoError(t, s.OwnPeerPod("nginx", "default", &PeerPodInstance{ID: "i-123"}))
+	require.Eventually(t, func() bool {
+		pps, err := s.lister.PeerPods("default").List(labels.Everything())
+		return err == nil && len(pps) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The instance of a pod is deleted after the pod
+	require.NoError(t, s.client.CoreV1().Pods("default").Delete(context.Background(), "nginx", metav1.DeleteOptions{}))
+
+	assert.Error(t, s.SetPeerPodDeleting("nginx", "default"))
+	require.NoError(t, s.ReleasePeerPod("nginx", "default", "i-123"))
+	assert.Empty(t, getPeerPod(t, ppClient).Finalizers)
+
+	// A previous attempt removed the finalizers
+	ppClient.PrependReactor("patch", "peerpods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, k8serrors.NewInvalid(peerPodV1alpha1.GroupVersion.WithKind("PeerPod").GroupKind(), "nginx-resource", nil)
+	})
+	assert.NoError(t, s.ReleasePeerPod("nginx", "default", "i-123"))
+}
+
+func TestPeerPodServiceRetry(t *testing.T) {
+
+	ppClient := ppfake.NewSimpleClientset()
+	s := newTestPeerPodService(t, ppClient, testPod())
+
+	failures := 2
+	ppClient.PrependReactor("create", "peerpods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if failures > 0 {
+			failures--
+			return true, nil, k8serrors.NewServiceUnavailable("try again")
+		}
+		return false, nil, nil
+	})
+
+	require.NoError(t, s.OwnPeerPod("nginx", "default", &PeerPodInstance{ID: "i-123"}))
+	assert.Equal(t, 0, failures)
+	assert.Equal(t, "i-123", getPeerPod(t, ppClient).Spec.InstanceID)
+
+	ppClient.PrependReactor("patch", "peerpods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, k8serrors.NewForbidden(peerPodV1alpha1.Resource("peerpods"), "", errors.New("denied"))
+	})
+
+	err := s.ReleasePeerPod("nginx", "default", "i-123")
+	assert.True(t, k8serrors.IsForbidden(err))
+}
+
+func TestSelectPeerPod(t *testing.T) {
+
+	now := time.Now()
+	newPeerPod := func(name, uid, instanceID string, created time.Time) *peerPodV1alpha1.PeerPod {
+		return &peerPodV1alpha1.PeerPod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Labels:            map[string]string{PodUIDLabel: uid},
+				CreationTimestamp: metav1.NewTime(created),
+			},
+			Spec: peerPodV1alpha1.PeerPodSpec{InstanceID: instanceID},
+		}
+	}
+
+	pps := []*peerPodV1alpha1.PeerPod{
+		newPeerPod("old", "uid-1", "i-1", now.Add(-time.Hour)),
+		newPeerPod("new", "uid-1", "i-2", now),
+		newPeerPod("other", "uid-2", "i-3", now.Add(time.Hour)),
+	}
+
+	assert.Equal(t, "new", selectPeerPod(pps, "uid-1", "").Name)
+	assert.Equal(t, "old", selectPeerPod(pps, "uid-1", "i-1").Name)
+	assert.Equal(t, "new", selectPeerPod(pps, "uid-1", "i-4").Name)
+	assert.Nil(t, selectPeerPod(pps, "uid-3", ""))
+}
@@ -0,0 +1,107 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package k8sops
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/podnetwork"
+)
+
+// PodIndexConfigMapStore persists pod indexes allocated on a worker node in a ConfigMap.
+// The data of the ConfigMap maps an index number to its owner.
+type PodIndexConfigMapStore struct {
+	client          kubernetes.Interface
+	namespace       string
+	name            string
+	resourceVersion string
+}
+
+func NewPodIndexConfigMapStore(namespace, name string) (*PodIndexConfigMapStore, error) {
+
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("NewPodIndexConfigMapStore: failed to get config: %w", err)
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("NewPodIndexConfigMapStore: failed to create clientset: %w", err)
+	}
+
+	return newPodIndexConfigMapStore(clientset, namespace, name), nil
+}
+
+func newPodIndexConfigMapStore(client kubernetes.Interface, namespace, name string) *PodIndexConfigMapStore {
+	return &PodIndexConfigMapStore{client: client, namespace: namespace, name: name}
+}
+
+func (s *PodIndexConfigMapStore) Load() (map[int]string, error) {
+
+	cm, err := s.client.CoreV1().ConfigMaps(s.namespace).Get(context.TODO(), s.name, metav1.GetOptions{})
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to get ConfigMap %s/%s: %w", s.namespace, s.name, err)
+		}
+		cm = &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      s.name,
+				Namespace: s.namespace,
+			},
+		}
+		cm, err = s.client.CoreV1().ConfigMaps(s.namespace).Create(context.TODO(), cm, metav1.CreateOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create ConfigMap %s/%s: %w", s.namespace, s.name, err)
+		}
+		logger.Printf("created ConfigMap %s/%s to store pod indexes", s.namespace, s.name)
+	}
+
+	indexes := make(map[int]string, len(cm.Data))
+	for key, owner := range cm.Data {
+		index, err := strconv.Atoi(key)
+		if err != nil {
+			logger.Printf("ignoring invalid pod index %q in ConfigMap %s/%s", key, s.namespace, s.name)
+			continue
+		}
+		indexes[index] = owner
+	}
+	s.resourceVersion = cm.ResourceVersion
+
+	return indexes, nil
+}
+
+// Save updates the ConfigMap. The update fails with podnetwork.ErrPodIndexConflict when the ConfigMap was modified since the last Load.
+func (s *PodIndexConfigMapStore) Save(indexes map[int]string) error {
+
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            s.name,
+			Namespace:       s.namespace,
+			ResourceVersion: s.resourceVersion,
+		},
+		Data: make(map[string]string, len(indexes)),
+	}
+	for index, owner := range indexes {
+		cm.Data[strconv.Itoa(index)] = owner
+	}
+
+	cm, err := s.client.CoreV1().ConfigMaps(s.namespace).Update(context.TODO(), cm, metav1.UpdateOptions{})
+	if k8serrors.IsConflict(err) {
+		return fmt.Errorf("failed to update ConfigMap %s/%s: %w: %w", s.namespace, s.name, podnetwork.ErrPodIndexConflict, err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update ConfigMap %s/%s: %w", s.namespace, s.name, err)
+	}
+	s.resourceVersion = cm.ResourceVersion
+
+	return nil
+}
@@ -0,0 +1,127 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package k8sops
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/podnetwork"
+)
+
+const (
+	testPodIndexNamespace = "confidential-containers-system"
+	testPodIndexName      = "peer-pods-pod-index-worker1"
+)
+
+func getPodIndexConfigMap(t *testing.T, client *k8sfake.Clientset) *v1.ConfigMap {
+	cm, err := client.CoreV1().ConfigMaps(testPodIndexNamespace).Get(context.Background(), testPodIndexName, metav1.GetOptions{})
+	require.NoError(t, err)
+	return cm
+}
+
+func TestPodIndexConfigMapStore(t *testing.T) {
+
+	client := k8sfake.NewSimpleClientset()
+	store := newPodIndexConfigMapStore(client, testPodIndexNamespace, testPodIndexName)
+
+	// The ConfigMap is created on the first load
+	indexes, err := store.Load()
+	require.NoError(t, err)
+	assert.Empty(t, indexes)
+	assert.Empty(t, getPodIndexConfigMap(t, client).Data)
+
+	require.NoError(t, store.Save(map[int]string{0: "/run/netns/pod0", 3: "/run/netns/pod3"}))
+	assert.Equal(t, map[string]string{"0": "/run/netns/pod0", "3": "/run/netns/pod3"}, getPodIndexConfigMap(t, client).Data)
+
+	// Another store of the same ConfigMap, e.g. after a restart, sees the saved indexes
+	indexes, err = newPodIndexConfigMapStore(client, testPodIndexNamespace, testPodIndexName).Load()
+	require.NoError(t, err)
+	assert.Equal(t, map[int]string{0: "/run/netns/pod0", 3: "/run/netns/pod3"}, indexes)
+}
+
+func TestPodIndexConfigMapStoreInvalidIndex(t *testing.T) {
+
+	client := k8sfake.NewSimpleClientset(&v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: testPodIndexName, Namespace: testPodIndexNamespace},
+		Data:       map[string]string{"1": "/run/netns/pod1", "abc": "/run/netns/pod2"},
+	})
+	store := newPodIndexConfigMapStore(client, testPodIndexNamespace, testPodIndexName)
+
+	indexes, err := store.Load()
+	require.NoError(t, err)
+	assert.Equal(t, map[int]string{1: "/run/netns/pod1"}, indexes)
+}
+
+func TestPodIndexConfigMapStoreConflict(t *testing.T) {
+
+	client := k8sfake.NewSimpleClientset()
+	store := newPodIndexConfigMapStore(client, testPodIndexNamespace, testPodIndexName)
+	allocator := podnetwork.NewPodIndexAllocator(store, 10)
+
+	index, err := allocator.Allocate("/run/netns/pod0")
+	require.NoError(t, err)
+	assert.Equal(t, 0, index)
+
+	// Another writer allocates index 1 between Load and Save of the allocator
+	conflicts := 1
+	client.PrependReactor("update", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if conflicts == 0 {
+			return false, nil, nil
+		}
+		conflicts--
+
+		// The reactor runs with the lock of the fake clientset held, so access the object tracker directly
+		gvr := v1.SchemeGroupVersion.WithResource("configmaps")
+		obj, err := client.Tracker().Get(gvr, testPodIndexNamespace, testPodIndexName)
+		if err != nil {
+			return true, nil, err
+		}
+		cm := obj.(*v1.ConfigMap).DeepCopy()
+		cm.Data["1"] = "/run/netns/other"
+		if err := client.Tracker().Update(gvr, cm, testPodIndexNamespace); err != nil {
+			return true, nil, err
+		}
+		return true, nil, k8serrors.NewConflict(v1.Resource("configmaps"), testPodIndexName, errors.New("the object has been modified"))
+	})
+
+	index, err = allocator.Allocate("/run/netns/pod2")
+	require.NoError(t, err)
+	assert.Equal(t, 2, index)
+	assert.Equal(t, 0, conflicts)
+
+	assert.Equal(t, map[string]string{
+		"0": "/run/netns/pod0",
+		"1": "/run/netns/other",
+		"2": "/run/netns/pod2",
+	}, getPodIndexConfigMap(t, client).Data)
+}
+
+func TestPodIndexConfigMapStoreConflictRetriesExhausted(t *testing.T) {
+
+	client := k8sfake.NewSimpleClientset()
+	store := newPodIndexConfigMapStore(client, testPodIndexNamespace, testPodIndexName)
+	allocator := podnetwork.NewPodIndexAllocator(store, 10)
+
+	updates := 0
+	client.PrependReactor("update", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		updates++
+		return true, nil, k8serrors.NewConflict(v1.Resource("configmaps"), testPodIndexName, errors.New("the object has been modified"))
+	})
+
+	_, err := allocator.Allocate("/run/netns/pod0")
+	assert.ErrorIs(t, err, podnetwork.ErrPodIndexConflict)
+	assert.True(t, k8serrors.IsConflict(err))
+	assert.Greater(t, updates, 1)
+}
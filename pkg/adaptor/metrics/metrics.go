@@ -0,0 +1,243 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	namespace = "cloud_api_adaptor"
+
+	URLPath = "/metrics"
+
+	ResultSuccess = "success"
+	ResultError   = "error"
+
+	OperationCreateInstance = "create_instance"
+	OperationDeleteInstance = "delete_instance"
+	OperationListInstances  = "list_instances"
+
+	// InstanceTypeOther is the instance_type label of instance types that a provider does not accept
+	InstanceTypeOther = "other"
+)
+
+var (
+	registry = prometheus.NewRegistry()
+
+	hypervisorRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "hypervisor",
+			Name:      "requests_total",
+			Help:      "Number of remote hypervisor requests by method and result",
+		},
+		[]string{"method", "result"},
+	)
+
+	hypervisorRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "hypervisor",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of remote hypervisor requests by method",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 2, 16),
+		},
+		[]string{"method"},
+	)
+
+	providerOperationDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "provider",
+			Name:      "operation_duration_seconds",
+			Help:      "Latency of cloud provider operations by cloud, operation and instance type",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 2, 16),
+		},
+		[]string{"cloud", "operation", "instance_type"},
+	)
+
+	providerOperationErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "provider",
+			Name:      "operation_errors_total",
+			Help:      "Number of failed cloud provider operations by cloud, operation and instance type",
+		},
+		[]string{"cloud", "operation", "instance_type"},
+	)
+
+	sandboxes = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "sandboxes",
+			Help:      "Number of live sandboxes",
+		},
+	)
+
+	agentProxyDialRetries = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "agent_proxy",
+			Name:      "dial_retries_total",
+			Help:      "Number of retries to establish agent proxy connections to pod VMs",
+		},
+	)
+
+	agentProxyReconnects = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "agent_proxy",
+			Name:      "reconnects_total",
+			Help:      "Number of agent proxy connections to pod VMs re-established after a failure",
+		},
+	)
+
+	warmPoolRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "warm_pool",
+			Name:      "requests_total",
+			Help:      "Number of requests for pre-provisioned instances by result (hit or miss)",
+		},
+		[]string{"result"},
+	)
+
+	warmPoolCreations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "warm_pool",
+			Name:      "instance_creations_total",
+			Help:      "Number of instance creations for a warm pool by instance type and result",
+		},
+		[]string{"instance_type", "result"},
+	)
+
+	podVMHourlyCost = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "pod_vm",
+			Name:      "hourly_cost",
+			Help:      "Sum of the hourly prices of the pod VMs of live sandboxes by instance type",
+		},
+		[]string{"instance_type"},
+	)
+
+	podVMInterruptions = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "pod_vm",
+			Name:      "interruptions_total",
+			Help:      "Number of pod VMs on spot capacity interrupted by the cloud provider",
+		},
+	)
+
+	warmPoolIdleInstances = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "warm_pool",
+			Name:      "idle_instances",
+			Help:      "Number of idle pre-provisioned instances by instance type",
+		},
+		[]string{"instance_type"},
+	)
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		hypervisorRequests,
+		hypervisorRequestDuration,
+		providerOperationDuration,
+		providerOperationErrors,
+		sandboxes,
+		agentProxyDialRetries,
+		agentProxyReconnects,
+		warmPoolRequests,
+		warmPoolCreations,
+		warmPoolIdleInstances,
+		podVMHourlyCost,
+		podVMInterruptions,
+	)
+}
+
+// Handler returns an HTTP handler that exposes metrics in the Prometheus format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+func result(err error) string {
+	if err != nil {
+		return ResultError
+	}
+	return ResultSuccess
+}
+
+// ObserveHypervisorRequest records a remote hypervisor request started at start
+func ObserveHypervisorRequest(method string, start time.Time, err error) {
+	hypervisorRequests.WithLabelValues(method, result(err)).Inc()
+	hypervisorRequestDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
+
+// ObserveProviderOperation records a cloud provider operation started at start
+func ObserveProviderOperation(cloudName, operation, instanceType string, start time.Time, err error) {
+	providerOperationDuration.WithLabelValues(cloudName, operation, instanceType).Observe(time.Since(start).Seconds())
+	if err != nil {
+		providerOperationErrors.WithLabelValues(cloudName, operation, instanceType).Inc()
+	}
+}
+
+// SetSandboxes sets the number of live sandboxes
+func SetSandboxes(n int) {
+	sandboxes.Set(float64(n))
+}
+
+// IncAgentProxyDialRetries counts a retry to establish an agent proxy connection
+func IncAgentProxyDialRetries() {
+	agentProxyDialRetries.Inc()
+}
+
+// IncAgentProxyReconnects counts an agent proxy connection re-established after a failure
+func IncAgentProxyReconnects() {
+	agentProxyReconnects.Inc()
+}
+
+// IncPodVMInterruptions counts a pod VM on spot capacity interrupted by the cloud provider
+func IncPodVMInterruptions() {
+	podVMInterruptions.Inc()
+}
+
+// ObserveWarmPoolRequest records whether a pre-provisioned instance was available for a pod
+func ObserveWarmPoolRequest(hit bool) {
+	if hit {
+		warmPoolRequests.WithLabelValues("hit").Inc()
+	} else {
+		warmPoolRequests.WithLabelValues("miss").Inc()
+	}
+}
+
+// ObserveWarmPoolCreation records an instance creation for a warm pool
+func ObserveWarmPoolCreation(instanceType string, succeeded bool) {
+	if succeeded {
+		warmPoolCreations.WithLabelValues(instanceType, ResultSuccess).Inc()
+	} else {
+		warmPoolCreations.WithLabelValues(instanceType, ResultError).Inc()
+	}
+}
+
+// SetWarmPoolIdleInstances sets the number of idle pre-provisioned instances of an instance type
+func SetWarmPoolIdleInstances(instanceType string, n int) {
+	warmPoolIdleInstances.WithLabelValues(instanceType).Set(float64(n))
+}
+
+// AddPodVMHourlyCost adds the hourly price of a pod VM of a sandbox, or subtracts it when price is negative
+func AddPodVMHourlyCost(instanceType string, price float64) {
+	podVMHourlyCost.WithLabelValues(instanceType).Add(price)
+}
@@ -0,0 +1,51 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObserveProviderOperation(t *testing.T) {
+
+	start := time.Now()
+
+	ObserveProviderOperation("aws", OperationCreateInstance, "t3.small", start, nil)
+	ObserveProviderOperation("aws", OperationCreateInstance, "t3.small", start, errors.New("quota exceeded"))
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(providerOperationErrors.WithLabelValues("aws", OperationCreateInstance, "t3.small")))
+	assert.Equal(t, 1, testutil.CollectAndCount(providerOperationDuration))
+}
+
+func TestAddPodVMHourlyCost(t *testing.T) {
+
+	AddPodVMHourlyCost("t3.medium", 0.0416)
+	AddPodVMHourlyCost("t3.medium", 0.0416)
+	AddPodVMHourlyCost("t3.medium", -0.0416)
+
+	assert.InDelta(t, 0.0416, testutil.ToFloat64(podVMHourlyCost.WithLabelValues("t3.medium")), 1e-9)
+}
+
+func TestHandler(t *testing.T) {
+
+	ObserveHypervisorRequest("StartVM", time.Now(), nil)
+	SetSandboxes(3)
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, URLPath, nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	body := recorder.Body.String()
+	assert.True(t, strings.Contains(body, `cloud_api_adaptor_hypervisor_requests_total{method="StartVM",result="success"} 1`))
+	assert.True(t, strings.Contains(body, "cloud_api_adaptor_sandboxes 3"))
+}
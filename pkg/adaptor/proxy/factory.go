@@ -21,11 +21,21 @@ type factory struct {
 	proxyTimeout  time.Duration
 }
 
-func NewFactory(pauseImage, criSocketPath string, tlsConfig *tlsutil.TLSConfig, proxyTimeout time.Duration) Factory {
+// NewFactory returns an agent proxy factory. When credsDir is not empty, automatically
+// generated TLS credentials are persisted in credsDir so that they survive restarts.
+func NewFactory(pauseImage, criSocketPath string, tlsConfig *tlsutil.TLSConfig, proxyTimeout time.Duration, credsDir string) Factory {
 
 	if tlsConfig != nil && !tlsConfig.HasCertAuth() {
 
-		certPEM, keyPEM, err := tlsutil.NewClientCertificate("cloud-api-adaptor")
+		var (
+			certPEM, keyPEM []byte
+			err             error
+		)
+		if credsDir != "" {
+			certPEM, keyPEM, err = tlsutil.LoadOrNewClientCertificate("cloud-api-adaptor", credsDir)
+		} else {
+			certPEM, keyPEM, err = tlsutil.NewClientCertificate("cloud-api-adaptor")
+		}
 		if err != nil {
 			panic(err)
 		}
@@ -37,7 +47,15 @@ func NewFactory(pauseImage, criSocketPath string, tlsConfig *tlsutil.TLSConfig,
 
 	if tlsConfig != nil && !tlsConfig.HasCA() {
 
-		s, err := tlsutil.NewCAService("agent-protocol-forwarder")
+		var (
+			s   tlsutil.CAService
+			err error
+		)
+		if credsDir != "" {
+			s, err = tlsutil.LoadOrNewCAService("agent-protocol-forwarder", credsDir)
+		} else {
+			s, err = tlsutil.NewCAService("agent-protocol-forwarder")
+		}
 		if err != nil {
 			panic(err)
 		}
@@ -4,22 +4,31 @@
 package proxy
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"errors"
 	"fmt"
-	"log"
+	"io"
 	"net"
+	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/avast/retry-go/v4"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/metrics"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/forwarder"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/agentproto"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/logging"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/tlsutil"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/tracing"
 	"github.com/containerd/ttrpc"
 	pb "github.com/kata-containers/kata-containers/src/runtime/virtcontainers/pkg/agent/protocols/grpc"
+	"go.opentelemetry.io/otel/attribute"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 	criapi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
@@ -35,7 +44,7 @@ const (
 	podvmServername = "podvm-server"
 )
 
-var logger = log.New(log.Writer(), "[adaptor/proxy] ", log.LstdFlags|log.Lmsgprefix)
+var logger = logging.New("adaptor/proxy")
 
 type criClient struct {
 	criapi.ImageServiceClient
@@ -43,8 +52,17 @@ type criClient struct {
 
 type AgentProxy interface {
 	Start(ctx context.Context, serverURL *url.URL) error
+	// Bootstrap sends a daemon config to the bootstrap endpoint of a pre-provisioned pod VM
+	Bootstrap(ctx context.Context, serverURL *url.URL, daemonJSON []byte) error
 	Ready() chan struct{}
 	Shutdown() error
+	// Done returns a channel that is closed when the agent proxy is shut down
+	Done() <-chan struct{}
+	// Interrupted returns a channel that is closed when the pod VM reports that the cloud provider
+	// interrupts its instance on spot capacity. The agent proxy is shut down then.
+	Interrupted() <-chan struct{}
+	// LinkState returns the state of the connection to the pod VM
+	LinkState() agentproto.LinkState
 	CAService() tlsutil.CAService
 	ClientCA() (certPEM []byte)
 }
@@ -61,6 +79,9 @@ type agentProxy struct {
 	proxyTimeout  time.Duration
 	criTimeout    time.Duration
 	stopOnce      sync.Once
+	linkState     atomic.Int32
+	linkUps       atomic.Int32
+	interruptedCh chan struct{}
 }
 
 func NewAgentProxy(serverName, socketPath, criSocketPath string, pauseImage string, tlsConfig *tlsutil.TLSConfig, caService tlsutil.CAService, proxyTimeout time.Duration) AgentProxy {
@@ -71,6 +92,7 @@ func NewAgentProxy(serverName, socketPath, criSocketPath string, pauseImage stri
 		criSocketPath: criSocketPath,
 		readyCh:       make(chan struct{}),
 		stopCh:        make(chan struct{}),
+		interruptedCh: make(chan struct{}),
 		proxyTimeout:  proxyTimeout,
 		criTimeout:    defaultCriTimeout,
 		pauseImage:    pauseImage,
@@ -81,6 +103,8 @@ func NewAgentProxy(serverName, socketPath, criSocketPath string, pauseImage stri
 
 func (p *agentProxy) dial(ctx context.Context, address string) (net.Conn, error) {
 
+	logger := logger.WithContext(ctx)
+
 	var conn net.Conn
 
 	var dialer interface {
@@ -117,6 +141,9 @@ func (p *agentProxy) dial(ctx context.Context, address string) (net.Conn, error)
 	ctx, cancel := context.WithTimeout(ctx, p.proxyTimeout)
 	defer cancel()
 
+	ctx, span := tracing.StartSpan(ctx, "dial agent proxy connection", attribute.String("server.address", address))
+	var retries int
+
 	logger.Printf("Trying to establish agent proxy connection to %s", address)
 	err := retry.Do(
 		func() error {
@@ -127,11 +154,18 @@ func (p *agentProxy) dial(ctx context.Context, address string) (net.Conn, error)
 		retry.Attempts(0),
 		retry.Context(ctx),
 		retry.MaxDelay(5*time.Second),
+		retry.OnRetry(func(n uint, err error) {
+			metrics.IncAgentProxyDialRetries()
+			retries++
+		}),
 	)
 
+	span.SetAttributes(attribute.Int("retries", retries))
+	tracing.EndSpan(span, err)
+
 	if err != nil {
 		err = fmt.Errorf("failed to establish agent proxy connection to %s: %w", address, err)
-		logger.Print(err)
+		logger.Error(err.Error())
 		return nil, err
 	}
 
@@ -167,6 +201,8 @@ func (p *agentProxy) initCriClient(ctx context.Context) (*criClient, error) {
 
 func (p *agentProxy) Start(ctx context.Context, serverURL *url.URL) error {
 
+	logger := logger.WithContext(ctx)
+
 	if err := os.MkdirAll(filepath.Dir(p.socketPath), os.ModePerm); err != nil {
 		return fmt.Errorf("failed to create parent directories for socket: %s", p.socketPath)
 	}
@@ -174,7 +210,7 @@ func (p *agentProxy) Start(ctx context.Context, serverURL *url.URL) error {
 		return fmt.Errorf("failed to remove %s: %w", p.socketPath, err)
 	}
 
-	logger.Printf("Listening on %s\n", p.socketPath)
+	logger.Printf("Listening on %s", p.socketPath)
 
 	listener, err := net.Listen("unix", p.socketPath)
 	if err != nil {
@@ -188,13 +224,23 @@ func (p *agentProxy) Start(ctx context.Context, serverURL *url.URL) error {
 	criClient, err := p.initCriClient(ctx)
 	if err != nil {
 		// cri client is optional currently, we ignore any errors here
-		logger.Printf("failed to init cri client, the err: %v", err)
+		logger.Warnf("failed to init cri client, the err: %v", err)
 	}
 
-	proxyService := newProxyService(dialer, criClient, p.pauseImage)
+	proxyService := newProxyService(dialer, criClient, p.pauseImage, &agentproto.RedirectorConfig{
+		KeepaliveInterval: agentproto.DefaultKeepaliveInterval,
+		OnLinkStateChange: func(state agentproto.LinkState) {
+			p.setLinkState(ctx, state)
+		},
+		OnInterruption: func() {
+			logger.Warn("pod VM reports interruption of its instance by the cloud provider")
+			close(p.interruptedCh)
+			_ = p.Shutdown()
+		},
+	})
 	defer func() {
 		if err := proxyService.Close(); err != nil {
-			logger.Printf("error closing agent proxy connection: %v", err)
+			logger.Warnf("error closing agent proxy connection: %v", err)
 		}
 	}()
 
@@ -202,7 +248,7 @@ func (p *agentProxy) Start(ctx context.Context, serverURL *url.URL) error {
 		return fmt.Errorf("error connecting to agent: %v", err)
 	}
 
-	ttrpcServer, err := ttrpc.NewServer()
+	ttrpcServer, err := ttrpc.NewServer(ttrpc.WithUnaryServerInterceptor(tracing.UnaryServerInterceptor()))
 	if err != nil {
 		return fmt.Errorf("failed to create TTRPC server: %w", err)
 	}
@@ -224,7 +270,7 @@ func (p *agentProxy) Start(ctx context.Context, serverURL *url.URL) error {
 	}()
 	defer func() {
 		if err := ttrpcServer.Shutdown(ctx); err != nil {
-			logger.Printf("error shutting down TTRPC server: %v", err)
+			logger.Warnf("error shutting down TTRPC server: %v", err)
 		}
 	}()
 
@@ -233,7 +279,7 @@ func (p *agentProxy) Start(ctx context.Context, serverURL *url.URL) error {
 	select {
 	case <-ctx.Done():
 		if err := p.Shutdown(); err != nil {
-			logger.Printf("error on shutdown: %v", err)
+			logger.Warnf("error on shutdown: %v", err)
 		}
 	case <-p.stopCh:
 	case err := <-ttrpcServerErr:
@@ -243,6 +289,75 @@ func (p *agentProxy) Start(ctx context.Context, serverURL *url.URL) error {
 	return nil
 }
 
+func (p *agentProxy) Bootstrap(ctx context.Context, serverURL *url.URL, daemonJSON []byte) error {
+
+	logger := logger.WithContext(ctx)
+
+	transport := &http.Transport{}
+	bootstrapURL := *serverURL
+	bootstrapURL.Scheme = "http"
+
+	if p.tlsConfig != nil {
+		bootstrapURL.Scheme = "https"
+		config, err := tlsutil.GetTLSConfigFor(p.tlsConfig)
+		if err != nil {
+			return fmt.Errorf("Failed to create tls config: %v", err)
+		}
+		// A bootstrap certificate is issued before the pod VM is assigned to a pod,
+		// so it has a well-known server name instead of the instance VM name
+		if p.caService != nil {
+			config.ServerName = forwarder.BootstrapServerName
+		} else {
+			config.ServerName = podvmServername
+		}
+		transport.TLSClientConfig = config
+	}
+
+	client := &http.Client{Transport: transport}
+	defer client.CloseIdleConnections()
+
+	ctx, cancel := context.WithTimeout(ctx, p.proxyTimeout)
+	defer cancel()
+
+	logger.Printf("Sending a daemon config to %s", serverURL.Host)
+
+	err := retry.Do(
+		func() error {
+			req, err := http.NewRequestWithContext(ctx, http.MethodPut, bootstrapURL.String(), bytes.NewReader(daemonJSON))
+			if err != nil {
+				return retry.Unrecoverable(err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+
+			res, err := client.Do(req)
+			if err != nil {
+				return err
+			}
+			defer res.Body.Close()
+
+			if res.StatusCode != http.StatusNoContent && res.StatusCode != http.StatusOK {
+				body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+				err := fmt.Errorf("bootstrap endpoint returned %s: %s", res.Status, bytes.TrimSpace(body))
+				if res.StatusCode >= http.StatusInternalServerError {
+					return err
+				}
+				return retry.Unrecoverable(err)
+			}
+			return nil
+		},
+		retry.Attempts(0),
+		retry.Context(ctx),
+		retry.MaxDelay(5*time.Second),
+		retry.LastErrorOnly(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to send a daemon config to %s: %w", serverURL.Host, err)
+	}
+
+	logger.Printf("sent a daemon config to %s", serverURL.Host)
+	return nil
+}
+
 func (p *agentProxy) Ready() chan struct{} {
 	return p.readyCh
 }
@@ -255,6 +370,35 @@ func (p *agentProxy) Shutdown() error {
 	return nil
 }
 
+func (p *agentProxy) Done() <-chan struct{} {
+	return p.stopCh
+}
+
+func (p *agentProxy) Interrupted() <-chan struct{} {
+	return p.interruptedCh
+}
+
+func (p *agentProxy) LinkState() agentproto.LinkState {
+	return agentproto.LinkState(p.linkState.Load())
+}
+
+func (p *agentProxy) setLinkState(ctx context.Context, state agentproto.LinkState) {
+
+	p.linkState.Store(int32(state))
+
+	logger := logger.WithContext(ctx)
+
+	switch state {
+	case agentproto.LinkUp:
+		if p.linkUps.Add(1) > 1 {
+			metrics.IncAgentProxyReconnects()
+			logger.Info("agent proxy connection is re-established")
+		}
+	case agentproto.LinkDown:
+		logger.Warn("agent proxy connection is lost")
+	}
+}
+
 func (p *agentProxy) CAService() tlsutil.CAService {
 	return p.caService
 }
@@ -19,6 +19,8 @@ import (
 	"github.com/kata-containers/kata-containers/src/runtime/virtcontainers/pkg/agent/protocols"
 	pb "github.com/kata-containers/kata-containers/src/runtime/virtcontainers/pkg/agent/protocols/grpc"
 	"google.golang.org/grpc"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/agentproto"
 )
 
 func TestNewAgentProxy(t *testing.T) {
@@ -108,6 +110,10 @@ func TestStartStop(t *testing.T) {
 	case <-proxy.Ready():
 	}
 
+	if e, a := agentproto.LinkUp, proxy.LinkState(); e != a {
+		t.Fatalf("expect %q, got %q", e, a)
+	}
+
 	conn, err := net.Dial("unix", socketPath)
 	if err != nil {
 		t.Fatalf("expect no error, got %q", err)
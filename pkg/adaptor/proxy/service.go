@@ -13,6 +13,7 @@ import (
 	"strings"
 
 	"github.com/avast/retry-go/v4"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/annotations"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/agentproto"
 	cri "github.com/containerd/containerd/pkg/cri/annotations"
 	crio "github.com/containers/podman/v4/pkg/annotations"
@@ -30,14 +31,13 @@ type proxyService struct {
 const (
 	defaultPauseImage            = "registry.k8s.io/pause:3.7"
 	kataDirectVolumesDir         = "/run/kata-containers/shared/direct-volumes"
-	volumeTargetPathKey          = "io.confidentialcontainers.org.peerpodvolumes.target_path"
 	csiPluginEscapeQualifiedName = "kubernetes.io~csi"
 	imageGuestPull               = "image_guest_pull"
 )
 
-func newProxyService(dialer func(context.Context) (net.Conn, error), criClient *criClient, pauseImage string) *proxyService {
+func newProxyService(dialer func(context.Context) (net.Conn, error), criClient *criClient, pauseImage string, config *agentproto.RedirectorConfig) *proxyService {
 
-	redirector := agentproto.NewRedirector(dialer)
+	redirector := agentproto.NewRedirectorWithConfig(dialer, config)
 
 	return &proxyService{
 		Redirector: redirector,
@@ -101,31 +101,30 @@ func (s *proxyService) getImageName(annotations map[string]string) (string, erro
 // AgentServiceService methods
 
 func (s *proxyService) CreateContainer(ctx context.Context, req *pb.CreateContainerRequest) (*types.Empty, error) {
+	logger := logger.WithContext(ctx)
+
 	var pullImageInGuest bool
 	logger.Printf("CreateContainer: containerID:%s", req.ContainerId)
 	if len(req.OCI.Mounts) > 0 {
-		logger.Print("    mounts:")
-		for i, m := range req.OCI.Mounts {
-			logger.Printf("        destination:%s source:%s type:%s", m.Destination, m.Source, m.Type)
+		logger.Debug("    mounts:")
+		for _, m := range req.OCI.Mounts {
+			logger.Debugf("        destination:%s source:%s type:%s", m.Destination, m.Source, m.Type)
 
 			if isNodePublishVolumeTargetPath(m.Source, kataDirectVolumesDir) {
-				if i > 0 {
-					req.OCI.Annotations[volumeTargetPathKey] += ","
-				}
-				req.OCI.Annotations[volumeTargetPathKey] += m.Source
+				annotations.AddVolumeTargetPath(req.OCI.Annotations, m.Source)
 			}
 		}
 	}
 	if len(req.OCI.Annotations) > 0 {
-		logger.Print("    annotations:")
+		logger.Debug("    annotations:")
 		for k, v := range req.OCI.Annotations {
-			logger.Printf("        %s: %s", k, v)
+			logger.Debugf("        %s: %s", k, v)
 		}
 	}
 	if len(req.Storages) > 0 {
-		logger.Print("    storages:")
+		logger.Debug("    storages:")
 		for _, s := range req.Storages {
-			logger.Printf("        mount_point:%s source:%s fstype:%s driver:%s", s.MountPoint, s.Source, s.Fstype, s.Driver)
+			logger.Debugf("        mount_point:%s source:%s fstype:%s driver:%s", s.MountPoint, s.Source, s.Fstype, s.Driver)
 			// remote-snapshotter in contanerd appends image_guest_pull drivers for image layer will be pulled in guest.
 			// Image will be pull in guest via image-rs according to the driver info.
 			if s.Driver == imageGuestPull {
@@ -134,9 +133,9 @@ func (s *proxyService) CreateContainer(ctx context.Context, req *pb.CreateContai
 		}
 	}
 	if len(req.Devices) > 0 {
-		logger.Print("    devices:")
+		logger.Debug("    devices:")
 		for _, d := range req.Devices {
-			logger.Printf("        container_path:%s vm_path:%s type:%s", d.ContainerPath, d.VmPath, d.Type)
+			logger.Debugf("        container_path:%s vm_path:%s type:%s", d.ContainerPath, d.VmPath, d.Type)
 		}
 	}
 
@@ -145,7 +144,7 @@ func (s *proxyService) CreateContainer(ctx context.Context, req *pb.CreateContai
 	} else {
 		imageName, err := s.getImageName(req.OCI.Annotations)
 		if err != nil {
-			logger.Printf("CreateContainer: image name is not available in CreateContainerRequest: %v", err)
+			logger.Warnf("CreateContainer: image name is not available in CreateContainerRequest: %v", err)
 		} else {
 			// Get the imageName from digest
 			if strings.HasPrefix(imageName, "sha256:") {
@@ -168,7 +167,7 @@ func (s *proxyService) CreateContainer(ctx context.Context, req *pb.CreateContai
 				func() error {
 					pullImageRes, pullImageErr := s.Redirector.PullImage(ctx, pullImageReq)
 					if pullImageErr != nil {
-						logger.Printf("CreateContainer: failed to call PullImage, probably because the image has already been pulled. ignored: %v", pullImageErr)
+						logger.Warnf("CreateContainer: failed to call PullImage, probably because the image has already been pulled. ignored: %v", pullImageErr)
 						return pullImageErr
 					}
 					logger.Printf("CreateContainer: successfully pulled image %q", pullImageRes.ImageRef)
@@ -177,7 +176,7 @@ func (s *proxyService) CreateContainer(ctx context.Context, req *pb.CreateContai
 			)
 
 			if err != nil {
-				logger.Printf("PullImage fails: %v", err)
+				logger.Errorf("PullImage fails: %v", err)
 				return nil, err
 			}
 			// kata-agent uses this annotation to fix the image bundle path
@@ -189,7 +188,7 @@ func (s *proxyService) CreateContainer(ctx context.Context, req *pb.CreateContai
 	res, err := s.Redirector.CreateContainer(ctx, req)
 
 	if err != nil {
-		logger.Printf("CreateContainer fails: %v", err)
+		logger.Errorf("CreateContainer fails: %v", err)
 	}
 
 	return res, err
@@ -208,12 +207,14 @@ func isNodePublishVolumeTargetPath(volumePath, directVolumesDir string) bool {
 
 func (s *proxyService) StartContainer(ctx context.Context, req *pb.StartContainerRequest) (*types.Empty, error) {
 
+	logger := logger.WithContext(ctx)
+
 	logger.Printf("StartContainer: containerID:%s", req.ContainerId)
 
 	res, err := s.Redirector.StartContainer(ctx, req)
 
 	if err != nil {
-		logger.Printf("StartContainer fails: %v", err)
+		logger.Errorf("StartContainer fails: %v", err)
 	}
 
 	return res, err
@@ -221,12 +222,14 @@ func (s *proxyService) StartContainer(ctx context.Context, req *pb.StartContaine
 
 func (s *proxyService) RemoveContainer(ctx context.Context, req *pb.RemoveContainerRequest) (*types.Empty, error) {
 
+	logger := logger.WithContext(ctx)
+
 	logger.Printf("RemoveContainer: containerID:%s", req.ContainerId)
 
 	res, err := s.Redirector.RemoveContainer(ctx, req)
 
 	if err != nil {
-		logger.Printf("RemoveContainer fails: %v", err)
+		logger.Errorf("RemoveContainer fails: %v", err)
 	}
 
 	return res, err
@@ -234,19 +237,21 @@ func (s *proxyService) RemoveContainer(ctx context.Context, req *pb.RemoveContai
 
 func (s *proxyService) CreateSandbox(ctx context.Context, req *pb.CreateSandboxRequest) (*types.Empty, error) {
 
+	logger := logger.WithContext(ctx)
+
 	logger.Printf("CreateSandbox: hostname:%s sandboxId:%s", req.Hostname, req.SandboxId)
 
 	if len(req.Storages) > 0 {
-		logger.Print("    storages:")
+		logger.Debug("    storages:")
 		for _, s := range req.Storages {
-			logger.Printf("        mountpoint:%s source:%s fstype:%s driver:%s", s.MountPoint, s.Source, s.Fstype, s.Driver)
+			logger.Debugf("        mountpoint:%s source:%s fstype:%s driver:%s", s.MountPoint, s.Source, s.Fstype, s.Driver)
 		}
 	}
 
 	res, err := s.Redirector.CreateSandbox(ctx, req)
 
 	if err != nil {
-		logger.Printf("CreateSandbox fails: %v", err)
+		logger.Errorf("CreateSandbox fails: %v", err)
 	}
 
 	return res, err
@@ -254,12 +259,14 @@ func (s *proxyService) CreateSandbox(ctx context.Context, req *pb.CreateSandboxR
 
 func (s *proxyService) DestroySandbox(ctx context.Context, req *pb.DestroySandboxRequest) (*types.Empty, error) {
 
+	logger := logger.WithContext(ctx)
+
 	logger.Printf("DestroySandbox")
 
 	res, err := s.Redirector.DestroySandbox(ctx, req)
 
 	if err != nil {
-		logger.Printf("DestroySandbox fails: %v", err)
+		logger.Errorf("DestroySandbox fails: %v", err)
 	}
 
 	return res, err
@@ -267,12 +274,14 @@ func (s *proxyService) DestroySandbox(ctx context.Context, req *pb.DestroySandbo
 
 func (s *proxyService) PullImage(ctx context.Context, req *pb.PullImageRequest) (*pb.PullImageResponse, error) {
 
+	logger := logger.WithContext(ctx)
+
 	logger.Printf("PullImage: image:%s containerID:%s", req.Image, req.ContainerId)
 
 	res, err := s.Redirector.PullImage(ctx, req)
 
 	if err != nil {
-		logger.Printf("PullImage fails: %v", err)
+		logger.Errorf("PullImage fails: %v", err)
 	}
 
 	return res, err
@@ -4,14 +4,22 @@
 package proxy
 
 import (
+	"context"
 	b64 "encoding/base64"
 	"errors"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"testing"
 
+	cri "github.com/containerd/containerd/pkg/cri/annotations"
+	crio "github.com/containers/podman/v4/pkg/annotations"
+	pb "github.com/kata-containers/kata-containers/src/runtime/virtcontainers/pkg/agent/protocols/grpc"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/agentproto/agenttest"
 )
 
 func TestIsNodePublishVolumeTargetPath(t *testing.T) {
@@ -54,6 +62,102 @@ func TestIsNodePublishVolumeTargetPath(t *testing.T) {
 	})
 }
 
+func TestCreateContainer(t *testing.T) {
+
+	agent, err := agenttest.Start(filepath.Join(t.TempDir(), "agent.sock"))
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, agent.Stop())
+	}()
+
+	dialer := func(ctx context.Context) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, "unix", agent.SocketPath())
+	}
+
+	for name, tc := range map[string]struct {
+		pauseImage  string
+		annotations map[string]string
+		storages    []*pb.Storage
+		image       string
+	}{
+		"containerd": {
+			annotations: map[string]string{cri.ImageName: "docker.io/library/nginx:latest"},
+			image:       "docker.io/library/nginx:latest",
+		},
+		"cri-o": {
+			annotations: map[string]string{crio.ImageName: "docker.io/library/redis:latest"},
+			image:       "docker.io/library/redis:latest",
+		},
+		"default pause image": {
+			annotations: map[string]string{cri.ContainerType: cri.ContainerTypeSandbox},
+			image:       defaultPauseImage,
+		},
+		"user's pause image": {
+			pauseImage:  "quay.io/example/pause:latest",
+			annotations: map[string]string{cri.ContainerType: cri.ContainerTypeSandbox, cri.ImageName: "registry.k8s.io/pause:3.9"},
+			image:       "quay.io/example/pause:latest",
+		},
+		"image pulled in guest": {
+			annotations: map[string]string{cri.ImageName: "docker.io/library/nginx:latest"},
+			storages:    []*pb.Storage{{Driver: imageGuestPull}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+
+			agent.Reset()
+
+			s := newProxyService(dialer, nil, tc.pauseImage, nil)
+			defer s.Close()
+
+			_, err := s.CreateContainer(context.Background(), &pb.CreateContainerRequest{
+				ContainerId: "123",
+				OCI:         &pb.Spec{Annotations: tc.annotations},
+				Storages:    tc.storages,
+			})
+			require.NoError(t, err)
+
+			createRequests := agent.Requests("CreateContainer")
+			require.Len(t, createRequests, 1)
+			annotations := createRequests[0].(*pb.CreateContainerRequest).OCI.Annotations
+
+			pullRequests := agent.Requests("PullImage")
+
+			if tc.image == "" {
+				assert.Empty(t, pullRequests)
+				assert.Equal(t, tc.annotations[cri.ImageName], annotations[cri.ImageName])
+				return
+			}
+
+			require.Len(t, pullRequests, 1)
+			assert.Equal(t, tc.image, pullRequests[0].(*pb.PullImageRequest).Image)
+			assert.Equal(t, "123", pullRequests[0].(*pb.PullImageRequest).ContainerId)
+
+			// kata agent finds the image bundle by the image name annotation
+			assert.Equal(t, tc.image, annotations[cri.ImageName])
+
+			calls := agent.Calls()
+			assert.Equal(t, "PullImage", calls[0].Method)
+			assert.Equal(t, "CreateContainer", calls[len(calls)-1].Method)
+		})
+	}
+
+	t.Run("agent failure", func(t *testing.T) {
+
+		agent.Reset()
+		agent.Fail("CreateContainer", errors.New("no space left on device"))
+
+		s := newProxyService(dialer, nil, "", nil)
+		defer s.Close()
+
+		_, err := s.CreateContainer(context.Background(), &pb.CreateContainerRequest{
+			ContainerId: "123",
+			OCI:         &pb.Spec{Annotations: map[string]string{cri.ImageName: "docker.io/library/nginx:latest"}},
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no space left on device")
+	})
+}
+
 func prepareVolumeDir(directVolumesDir, volumePath string) error {
 	volumeDir := filepath.Join(directVolumesDir, b64.URLEncoding.EncodeToString([]byte(volumePath)))
 	stat, err := os.Stat(volumeDir)
@@ -0,0 +1,183 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package adaptor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util"
+)
+
+const (
+	// DefaultReconcileInterval is the interval of garbage collection by the elected cloud-api-adaptor
+	DefaultReconcileInterval = 10 * time.Minute
+
+	reconcilerLeaseName     = "peerpod-orphan-reconciler"
+	reconcilerLeaseDuration = 15 * time.Second
+	reconcilerRenewDeadline = 10 * time.Second
+	reconcilerRetryPeriod   = 2 * time.Second
+)
+
+// orphanReconciler garbage-collects pod VM instances whose Kubernetes pod no longer exists.
+// Such instances are left behind when cloud-api-adaptor is restarted or crashes while a pod is deleted,
+// and no PeerPod object exists to clean them up.
+type orphanReconciler struct {
+	provider cloud.Provider
+	client   kubernetes.Interface
+	dryRun   bool
+}
+
+func newOrphanReconciler(provider cloud.Provider, client kubernetes.Interface, dryRun bool) *orphanReconciler {
+	return &orphanReconciler{
+		provider: provider,
+		client:   client,
+		dryRun:   dryRun,
+	}
+}
+
+// Reconcile deletes orphaned instances, and returns them. In dry-run mode, orphaned instances are only reported.
+func (r *orphanReconciler) Reconcile(ctx context.Context) ([]*cloud.Instance, error) {
+
+	instances, err := r.provider.ListInstances(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing instances: %w", err)
+	}
+	if len(instances) == 0 {
+		return nil, nil
+	}
+
+	// List pods after instances, so that an instance created in between always has its pod listed
+	pods, err := r.client.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("listing pods: %w", err)
+	}
+
+	// Pre-provisioned instances keep their names after they are assigned to pods. They are deleted by
+	// cloud-api-adaptor of their worker node, so only those of worker nodes that no longer exist are orphaned.
+	nodes, err := r.client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("listing nodes: %w", err)
+	}
+
+	var orphans []*cloud.Instance
+
+	for _, instance := range instances {
+
+		var warm bool
+		for _, node := range nodes.Items {
+			if util.InstanceNameMatchesPod(instance.Name, util.WarmPoolPodName(node.Name)) {
+				warm = true
+				break
+			}
+		}
+		if warm {
+			continue
+		}
+
+		// Pod namespaces are not encoded in instance names. An instance is kept
+		// as long as a pod with a matching name exists in any namespace.
+		var found bool
+		for _, pod := range pods.Items {
+			if util.InstanceNameMatchesPod(instance.Name, pod.Name) {
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+
+		orphans = append(orphans, instance)
+
+		if r.dryRun {
+			logger.Printf("dry-run: instance %s (%s) has no pod, and would be deleted", instance.Name, instance.ID)
+			continue
+		}
+
+		logger.Printf("deleting instance %s (%s) that has no pod", instance.Name, instance.ID)
+
+		if err := r.provider.DeleteInstance(ctx, instance.ID); err != nil {
+			logger.Printf("failed to delete orphaned instance %s (%s): %v", instance.Name, instance.ID, err)
+		}
+	}
+
+	return orphans, nil
+}
+
+// RunElected competes for a lease in namespace with cloud-api-adaptor on other worker nodes, and runs Reconcile
+// every interval while it holds the lease. Pods and instances are listed cluster-wide, so orphaned instances are
+// garbage-collected by a single cloud-api-adaptor of the cluster. RunElected returns when ctx is done.
+func (r *orphanReconciler) RunElected(ctx context.Context, namespace, identity string, interval time.Duration) error {
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Name:      reconcilerLeaseName,
+			Namespace: namespace,
+		},
+		Client:     r.client.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+	}
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   reconcilerLeaseDuration,
+		RenewDeadline:   reconcilerRenewDeadline,
+		RetryPeriod:     reconcilerRetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            reconcilerLeaseName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				logger.Printf("%s is elected to garbage-collect orphaned instances", identity)
+				r.run(ctx, interval)
+			},
+			OnStoppedLeading: func() {
+				logger.Printf("%s stopped garbage-collecting orphaned instances", identity)
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("creating a leader elector: %w", err)
+	}
+
+	// Run returns when the lease is lost. Compete for the lease again until ctx is done.
+	for ctx.Err() == nil {
+		elector.Run(ctx)
+	}
+
+	return nil
+}
+
+func (r *orphanReconciler) run(ctx context.Context, interval time.Duration) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		orphans, err := r.Reconcile(ctx)
+		switch {
+		case errors.Is(err, cloud.ErrNoClusterID) || errors.Is(err, cloud.ErrListInstancesUnsupported):
+			logger.Printf("orphaned instances are not garbage-collected: %v", err)
+			return
+		case err != nil:
+			logger.Printf("failed to garbage-collect orphaned instances: %v", err)
+		default:
+			logger.Printf("found %d orphaned instances", len(orphans))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
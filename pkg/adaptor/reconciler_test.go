@@ -0,0 +1,150 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package adaptor
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util"
+)
+
+type mockListingProvider struct {
+	mockProvider
+	instances []*cloud.Instance
+	deleted   []string
+	listErr   error
+	mutex     sync.Mutex
+}
+
+func (p *mockListingProvider) ListInstances(ctx context.Context) ([]*cloud.Instance, error) {
+	return p.instances, p.listErr
+}
+
+func (p *mockListingProvider) DeleteInstance(ctx context.Context, instanceID string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.deleted = append(p.deleted, instanceID)
+	return nil
+}
+
+func (p *mockListingProvider) getDeleted() []string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return append([]string(nil), p.deleted...)
+}
+
+func TestOrphanReconciler(t *testing.T) {
+
+	sandboxID := "0123456789abcdef"
+
+	newProvider := func() *mockListingProvider {
+		return &mockListingProvider{
+			instances: []*cloud.Instance{
+				{ID: "i-1", Name: util.GenerateInstanceName("nginx", sandboxID, 63)},
+				{ID: "i-2", Name: util.GenerateInstanceName("busybox", sandboxID, 63)},
+			},
+		}
+	}
+
+	client := fake.NewSimpleClientset(&v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default"},
+	})
+
+	provider := newProvider()
+	orphans, err := newOrphanReconciler(provider, client, true).Reconcile(context.Background())
+	require.NoError(t, err)
+	require.Len(t, orphans, 1)
+	assert.Equal(t, "i-2", orphans[0].ID)
+	assert.Empty(t, provider.deleted, "dry-run must not delete instances")
+
+	provider = newProvider()
+	orphans, err = newOrphanReconciler(provider, client, false).Reconcile(context.Background())
+	require.NoError(t, err)
+	require.Len(t, orphans, 1)
+	assert.Equal(t, []string{"i-2"}, provider.deleted)
+
+}
+
+func TestOrphanReconcilerWarmPool(t *testing.T) {
+
+	sandboxID := "0123456789abcdef"
+
+	provider := &mockListingProvider{
+		instances: []*cloud.Instance{
+			{ID: "i-1", Name: util.GenerateInstanceName(util.WarmPoolPodName("worker1"), sandboxID, 63)},
+			{ID: "i-2", Name: util.GenerateInstanceName(util.WarmPoolPodName("worker2"), sandboxID, 63)},
+		},
+	}
+
+	client := fake.NewSimpleClientset(&v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker1"},
+	})
+
+	// Pre-provisioned instances of an existing worker node are kept, whether or not they are assigned to pods
+	orphans, err := newOrphanReconciler(provider, client, false).Reconcile(context.Background())
+	require.NoError(t, err)
+	require.Len(t, orphans, 1)
+	assert.Equal(t, "i-2", orphans[0].ID)
+	assert.Equal(t, []string{"i-2"}, provider.deleted)
+}
+
+func TestOrphanReconcilerRunElected(t *testing.T) {
+
+	provider := &mockListingProvider{
+		instances: []*cloud.Instance{
+			{ID: "i-1", Name: util.GenerateInstanceName("nginx", "0123456789abcdef", 63)},
+		},
+	}
+	client := fake.NewSimpleClientset()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- newOrphanReconciler(provider, client, false).RunElected(ctx, "confidential-containers-system", "worker1", time.Hour)
+	}()
+
+	assert.Eventually(t, func() bool { return len(provider.getDeleted()) > 0 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"i-1"}, provider.getDeleted())
+
+	lease, err := client.CoordinationV1().Leases("confidential-containers-system").Get(context.Background(), reconcilerLeaseName, metav1.GetOptions{})
+	require.NoError(t, err)
+	require.NotNil(t, lease.Spec.HolderIdentity)
+	assert.Equal(t, "worker1", *lease.Spec.HolderIdentity)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunElected does not return after its context is done")
+	}
+}
+
+func TestOrphanReconcilerRunUnsupported(t *testing.T) {
+
+	provider := &mockListingProvider{listErr: cloud.ErrNoClusterID}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		newOrphanReconciler(provider, fake.NewSimpleClientset(), false).run(context.Background(), time.Millisecond)
+	}()
+
+	// Reconciliation stops without a cluster ID, instead of retrying at every interval
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("reconciler keeps running without a cluster ID")
+	}
+}
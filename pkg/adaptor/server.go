@@ -5,8 +5,10 @@ package adaptor
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"path/filepath"
 	"sync"
@@ -16,22 +18,31 @@ import (
 	pbHypervisor "github.com/kata-containers/kata-containers/src/runtime/protocols/hypervisor"
 
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/cloud"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/deviceplugin"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/k8sops"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/metrics"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/proxy"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/adaptor/vminfo"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/podnetwork"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/logging"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/tlsutil"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/tracing"
 	pbPodVMInfo "github.com/confidential-containers/cloud-api-adaptor/proto/podvminfo"
 )
 
-var logger = log.New(log.Writer(), "[adaptor] ", log.LstdFlags|log.Lmsgprefix)
+var logger = logging.New("adaptor")
 
 const (
-	DefaultSocketPath = "/run/peerpod/hypervisor.sock"
-	DefaultPodsDir    = "/run/peerpod/pods"
+	DefaultSocketPath  = "/run/peerpod/hypervisor.sock"
+	DefaultPodsDir     = "/run/peerpod/pods"
+	DefaultMetricsAddr = ""
+
+	tlsCredsDirName = ".tls"
 )
 
 type ServerConfig struct {
 	TLSConfig               *tlsutil.TLSConfig
+	CloudName               string
 	SocketPath              string
 	CriSocketPath           string
 	PauseImage              string
@@ -40,6 +51,18 @@ type ServerConfig struct {
 	ProxyTimeout            time.Duration
 	AAKBCParams             string
 	EnableCloudConfigVerify bool
+	ReconcileOrphans        bool
+	ReconcileDryRun         bool
+	ReconcileInterval       time.Duration
+	ReconcileNamespace      string
+	NodeName                string
+	WarmPool                cloud.WarmPoolConfig
+	MetricsAddr             string
+	PodsLimit               int
+	DevicePlugin            bool
+	CreateAttempts          int
+	Prices                  cloud.PriceCatalog
+	OverridePolicy          cloud.OverridePolicy
 }
 
 type Server interface {
@@ -58,17 +81,29 @@ type server struct {
 	socketPath              string
 	stopOnce                sync.Once
 	enableCloudConfigVerify bool
+	reconciler              *orphanReconciler
+	reconcileInterval       time.Duration
+	reconcileNamespace      string
+	nodeName                string
+	metricsAddr             string
+	devicePlugin            *deviceplugin.Plugin
 }
 
 func NewServer(provider cloud.Provider, cfg *ServerConfig, workerNode podnetwork.WorkerNode) Server {
 
 	logger.Printf("server config: %#v", cfg)
 
-	agentFactory := proxy.NewFactory(cfg.PauseImage, cfg.CriSocketPath, cfg.TLSConfig, cfg.ProxyTimeout)
-	cloudService := cloud.NewService(provider, agentFactory, workerNode, cfg.PodsDir, cfg.ForwarderPort, cfg.AAKBCParams)
+	provider = cloud.NewInstrumentedProvider(cfg.CloudName, provider)
+
+	// Automatically generated TLS credentials are stored along with sandbox states,
+	// so that pod VMs created before a restart remain reachable after the restart
+	credsDir := filepath.Join(cfg.PodsDir, tlsCredsDirName)
+
+	agentFactory := proxy.NewFactory(cfg.PauseImage, cfg.CriSocketPath, cfg.TLSConfig, cfg.ProxyTimeout, credsDir)
+	cloudService := cloud.NewService(provider, agentFactory, workerNode, cfg.PodsDir, cfg.ForwarderPort, cfg.AAKBCParams, &cfg.WarmPool, cfg.Prices, cfg.OverridePolicy, cfg.PodsLimit, cfg.CreateAttempts)
 	vmInfoService := vminfo.NewService(cloudService)
 
-	return &server{
+	s := &server{
 		socketPath:              cfg.SocketPath,
 		cloudService:            cloudService,
 		vmInfoService:           vmInfoService,
@@ -76,7 +111,26 @@ func NewServer(provider cloud.Provider, cfg *ServerConfig, workerNode podnetwork
 		readyCh:                 make(chan struct{}),
 		stopCh:                  make(chan struct{}),
 		enableCloudConfigVerify: cfg.EnableCloudConfigVerify,
+		reconcileInterval:       cfg.ReconcileInterval,
+		reconcileNamespace:      cfg.ReconcileNamespace,
+		nodeName:                cfg.NodeName,
+		metricsAddr:             cfg.MetricsAddr,
+	}
+
+	if cfg.DevicePlugin {
+		s.devicePlugin = deviceplugin.New(deviceplugin.DefaultPluginDir, cfg.PodsLimit, cloudService.PodsCapacity, deviceplugin.DefaultCheckInterval)
+	}
+
+	if cfg.ReconcileOrphans {
+		client, err := k8sops.NewInClusterClientset()
+		if err != nil {
+			logger.Printf("failed to create a k8s client, orphaned instances will not be garbage-collected: %v", err)
+		} else {
+			s.reconciler = newOrphanReconciler(provider, client, cfg.ReconcileDryRun)
+		}
 	}
+
+	return s
 }
 
 func (s *server) Start(ctx context.Context) (err error) {
@@ -87,7 +141,7 @@ func (s *server) Start(ctx context.Context) (err error) {
 		}
 	}
 
-	ttRpc, err := ttrpc.NewServer()
+	ttRpc, err := ttrpc.NewServer(ttrpc.WithUnaryServerInterceptor(tracing.UnaryServerInterceptor()))
 	if err != nil {
 		return err
 	}
@@ -120,10 +174,53 @@ func (s *server) Start(ctx context.Context) (err error) {
 		}
 	}()
 
+	if s.metricsAddr != "" {
+		metricsServer, err := s.startMetricsServer()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := metricsServer.Shutdown(context.Background()); err != nil {
+				logger.Printf("error shutting down metrics server: %v", err)
+			}
+		}()
+	}
+
 	close(s.readyCh)
 
 	logger.Printf("server started")
 
+	if s.reconciler != nil {
+		reconcilerCtx, cancel := context.WithCancel(ctx)
+		reconcilerDone := make(chan struct{})
+		go func() {
+			defer close(reconcilerDone)
+			if err := s.reconciler.RunElected(reconcilerCtx, s.reconcileNamespace, s.nodeName, s.reconcileInterval); err != nil {
+				logger.Printf("failed to garbage-collect orphaned instances: %v", err)
+			}
+		}()
+		// Release the lease before the server stops
+		defer func() {
+			cancel()
+			<-reconcilerDone
+		}()
+	}
+
+	if s.devicePlugin != nil {
+		devicePluginCtx, cancel := context.WithCancel(ctx)
+		devicePluginDone := make(chan struct{})
+		go func() {
+			defer close(devicePluginDone)
+			if err := s.devicePlugin.Run(devicePluginCtx); err != nil {
+				logger.Printf("failed to run the device plugin of %s: %v", deviceplugin.ResourceName, err)
+			}
+		}()
+		defer func() {
+			cancel()
+			<-devicePluginDone
+		}()
+	}
+
 	select {
 	case <-ctx.Done():
 		shutdownErr := s.Shutdown()
@@ -136,6 +233,32 @@ func (s *server) Start(ctx context.Context) (err error) {
 	return err
 }
 
+func (s *server) startMetricsServer() (*http.Server, error) {
+
+	listener, err := net.Listen("tcp", s.metricsAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s for metrics: %w", s.metricsAddr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(metrics.URLPath, metrics.Handler())
+
+	metricsServer := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		if err := metricsServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Printf("error running metrics server: %v", err)
+		}
+	}()
+
+	logger.Printf("serving metrics on %s%s", listener.Addr(), metrics.URLPath)
+
+	return metricsServer, nil
+}
+
 func (s *server) Shutdown() error {
 	s.stopOnce.Do(func() {
 		close(s.stopCh)
@@ -190,6 +190,10 @@ func (n *mockWorkerNode) Teardown(nsPath string, config *tunneler.Config) error
 	return nil
 }
 
+func (n *mockWorkerNode) Release(config *tunneler.Config) error {
+	return nil
+}
+
 type mockProvider struct {
 	primaryIP   string
 	secondaryIP string
@@ -224,6 +228,10 @@ func (p *mockProvider) DeleteInstance(ctx context.Context, instanceID string) er
 	return nil
 }
 
+func (p *mockProvider) ListInstances(ctx context.Context) ([]*cloud.Instance, error) {
+	return nil, nil
+}
+
 func (p *mockProvider) Teardown() error {
 	return nil
 }
@@ -81,9 +81,9 @@ func TestShim(t *testing.T) {
 	case "", "mock":
 		workerNode = &mockWorkerNode{}
 	case "routing":
-		workerNode = podnetwork.NewWorkerNode("routing", "ens4", 0, 0)
+		workerNode = podnetwork.NewWorkerNode("routing", "ens4", 0, 0, nil)
 	default:
-		workerNode = podnetwork.NewWorkerNode(t, "", 0, 0)
+		workerNode = podnetwork.NewWorkerNode(t, "", 0, 0, nil)
 	}
 
 	serverConfig := &ServerConfig{
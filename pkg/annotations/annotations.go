@@ -0,0 +1,70 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package annotations defines the annotations of peer pods that the webhook, cloud-api-adaptor,
+// agent-protocol-forwarder and the CSI wrapper exchange, and parses and validates their values.
+//
+// The package is a module of its own without dependencies, so that every component can use it.
+// Annotations of a version are only ever added, never changed or removed. A change that breaks
+// consumers of existing annotations requires a new Version.
+package annotations
+
+import (
+	"fmt"
+)
+
+// Version is the version of the annotations defined by this package
+const Version = "v1"
+
+const (
+	// VersionKey is the version of the annotations of a pod, set by the webhook.
+	// Pods without it are assumed to use the current Version.
+	VersionKey = "kata.peerpods.io/annotations-version"
+
+	// InstanceType is the default instance type the webhook records on a pod.
+	// It documents the policy of the webhook, and is not used to select an instance type.
+	InstanceType = "kata.peerpods.io/instance_type"
+	// OriginalResources holds the resource specs of the containers removed by the webhook, in JSON by container name
+	OriginalResources = "kata.peerpods.io/original-resources"
+	// Changes lists the resource specs the webhook added to the containers
+	Changes = "kata.peerpods.io/changes"
+
+	// MachineType is the instance type of the pod VM. The Kata annotation refers to the machine type of
+	// local hypervisors, and to the instance type (flavor) of the cloud for the remote hypervisor.
+	MachineType = "io.katacontainers.config.hypervisor.machine_type"
+	// VCPUs is the number of vCPUs of the pod VM
+	VCPUs = "io.katacontainers.config.hypervisor.default_vcpus"
+	// Memory is the memory of the pod VM in MiB
+	Memory = "io.katacontainers.config.hypervisor.default_memory"
+	// GPUs is the number of GPUs of the pod VM. The webhook sets it from the nvidia.com/gpu resources of the containers.
+	GPUs = "io.katacontainers.config.hypervisor.default_gpus"
+	// GPUModel is the GPU model of the pod VM, such as "V100". It matches instance types whose GPU model contains it, ignoring case.
+	GPUModel = "io.katacontainers.config.hypervisor.default_gpu_model"
+	// Spot requests a pod VM on interruptible capacity, such as AWS or Azure Spot instances, when set to "true".
+	// cloud-api-adaptor fails a pod when the cloud provider interrupts its pod VM.
+	Spot = "kata.peerpods.io/spot"
+	// SpotMaxPrice is the maximum hourly price of a pod VM on spot capacity in US dollars, such as "0.05".
+	// Without it, a pod VM on spot capacity costs up to the on-demand price.
+	SpotMaxPrice = "kata.peerpods.io/spot-max-price"
+
+	// ImageID overrides the image of the pod VM that the cloud provider is configured with, such as an AMI ID on AWS
+	ImageID = "kata.peerpods.io/image-id"
+	// SubnetID overrides the subnet of the pod VM that the cloud provider is configured with
+	SubnetID = "kata.peerpods.io/subnet-id"
+	// SecurityGroupIDs are the comma-separated security groups of the pod VM, which replace the configured ones
+	SecurityGroupIDs = "kata.peerpods.io/security-group-ids"
+	// Tags are comma-separated tags in the form key=value, which are added to the configured tags of the pod VM
+	Tags = "kata.peerpods.io/tags"
+
+	// VolumeTargetPaths are the comma-separated target paths of CSI volumes of a container that the CSI wrapper
+	// publishes in the pod VM. cloud-api-adaptor sets it in CreateContainer requests to the pod VM.
+	VolumeTargetPaths = "io.confidentialcontainers.org.peerpodvolumes.target_path"
+)
+
+// CheckVersion returns an error if the annotations of a pod are of an unsupported version
+func CheckVersion(annotations map[string]string) error {
+	if version, ok := annotations[VersionKey]; ok && version != Version {
+		return fmt.Errorf("annotation %s: unsupported version %q, expected %q", VersionKey, version, Version)
+	}
+	return nil
+}
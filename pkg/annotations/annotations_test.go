@@ -0,0 +1,219 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package annotations
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePodVM(t *testing.T) {
+
+	for name, tc := range map[string]struct {
+		annotations map[string]string
+		podVM       PodVM
+		err         string
+	}{
+		"none": {
+			annotations: map[string]string{},
+		},
+		"instance type only": {
+			annotations: map[string]string{MachineType: "t2.small"},
+			podVM:       PodVM{InstanceType: "t2.small"},
+		},
+		"vCPUs and memory": {
+			annotations: map[string]string{VCPUs: "2", Memory: "2048"},
+			podVM:       PodVM{VCPUs: 2, Memory: 2048},
+		},
+		"GPUs": {
+			annotations: map[string]string{VCPUs: "8", Memory: "61440", GPUs: "1", GPUModel: "V100"},
+			podVM:       PodVM{VCPUs: 8, Memory: 61440, GPUs: 1, GPUModel: "V100"},
+		},
+		"spot": {
+			annotations: map[string]string{MachineType: "t3.small", Spot: "true", SpotMaxPrice: "0.01"},
+			podVM:       PodVM{InstanceType: "t3.small", Spot: true, SpotMaxPrice: 0.01},
+		},
+		"empty values": {
+			annotations: map[string]string{MachineType: "", VCPUs: "", Memory: ""},
+		},
+		"webhook instance type is ignored": {
+			annotations: map[string]string{InstanceType: "t2.small"},
+		},
+		"current version": {
+			annotations: map[string]string{VersionKey: Version, MachineType: "t2.small"},
+			podVM:       PodVM{InstanceType: "t2.small"},
+		},
+		"unsupported version": {
+			annotations: map[string]string{VersionKey: "v0"},
+			err:         `annotation kata.peerpods.io/annotations-version: unsupported version "v0"`,
+		},
+		"invalid vCPUs": {
+			annotations: map[string]string{VCPUs: "invalid"},
+			err:         `annotation io.katacontainers.config.hypervisor.default_vcpus: "invalid" is not an integer`,
+		},
+		"invalid memory": {
+			annotations: map[string]string{Memory: "2Gi"},
+			err:         `annotation io.katacontainers.config.hypervisor.default_memory: "2Gi" is not an integer`,
+		},
+		"negative memory": {
+			annotations: map[string]string{Memory: "-1"},
+			err:         "annotation io.katacontainers.config.hypervisor.default_memory: negative memory",
+		},
+		"invalid GPUs": {
+			annotations: map[string]string{GPUs: "one"},
+			err:         `annotation io.katacontainers.config.hypervisor.default_gpus: "one" is not an integer`,
+		},
+		"GPU model without GPUs": {
+			annotations: map[string]string{GPUModel: "V100"},
+			err:         `annotation io.katacontainers.config.hypervisor.default_gpu_model: GPU model "V100" without GPUs`,
+		},
+		"invalid spot": {
+			annotations: map[string]string{Spot: "yes"},
+			err:         `annotation kata.peerpods.io/spot: "yes" is not a boolean`,
+		},
+		"negative spot max price": {
+			annotations: map[string]string{Spot: "true", SpotMaxPrice: "-0.5"},
+			err:         "annotation kata.peerpods.io/spot-max-price: invalid price",
+		},
+		"spot max price without spot": {
+			annotations: map[string]string{SpotMaxPrice: "0.01"},
+			err:         "annotation kata.peerpods.io/spot-max-price: maximum price without kata.peerpods.io/spot",
+		},
+		"invalid instance type": {
+			annotations: map[string]string{MachineType: "t2.small,t2.medium"},
+			err:         "annotation io.katacontainers.config.hypervisor.machine_type: invalid instance type",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			podVM, err := ParsePodVM(tc.annotations)
+			if tc.err != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tc.err) {
+					t.Fatalf("expect error %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+			if podVM != tc.podVM {
+				t.Errorf("expect %#v, got %#v", tc.podVM, podVM)
+			}
+		})
+	}
+}
+
+func TestPodVMSet(t *testing.T) {
+
+	podVM := PodVM{InstanceType: "t2.small", VCPUs: 2}
+	podVM.Default(PodVM{InstanceType: "t2.medium", VCPUs: 4, Memory: 4096})
+
+	if expected := (PodVM{InstanceType: "t2.small", VCPUs: 2, Memory: 4096}); podVM != expected {
+		t.Fatalf("expect %#v, got %#v", expected, podVM)
+	}
+
+	annotations := map[string]string{VCPUs: "1"}
+	podVM.Set(annotations)
+
+	expected := map[string]string{MachineType: "t2.small", VCPUs: "1", Memory: "4096"}
+	if !reflect.DeepEqual(annotations, expected) {
+		t.Errorf("expect %v, got %v", expected, annotations)
+	}
+}
+
+func TestParseOverrides(t *testing.T) {
+
+	for name, tc := range map[string]struct {
+		annotations map[string]string
+		overrides   Overrides
+		keys        []string
+		err         string
+	}{
+		"none": {
+			annotations: map[string]string{MachineType: "t2.small"},
+		},
+		"all": {
+			annotations: map[string]string{ImageID: "ami-1", SubnetID: "subnet-1", SecurityGroupIDs: "sg-1, sg-2", Tags: "team=a, cost-center=42"},
+			overrides: Overrides{ImageID: "ami-1", SubnetID: "subnet-1", SecurityGroupIDs: []string{"sg-1", "sg-2"},
+				Tags: map[string]string{"team": "a", "cost-center": "42"}},
+			keys: []string{ImageID, SubnetID, SecurityGroupIDs, Tags},
+		},
+		"subnet only": {
+			annotations: map[string]string{SubnetID: "subnet-1", ImageID: ""},
+			overrides:   Overrides{SubnetID: "subnet-1"},
+			keys:        []string{SubnetID},
+		},
+		"empty tag value": {
+			annotations: map[string]string{Tags: "team="},
+			overrides:   Overrides{Tags: map[string]string{"team": ""}},
+			keys:        []string{Tags},
+		},
+		"invalid image ID": {
+			annotations: map[string]string{ImageID: "ami-1,ami-2"},
+			err:         `annotation kata.peerpods.io/image-id: invalid ID "ami-1,ami-2"`,
+		},
+		"empty security group ID": {
+			annotations: map[string]string{SecurityGroupIDs: "sg-1,,sg-2"},
+			err:         `annotation kata.peerpods.io/security-group-ids: invalid security group ID ""`,
+		},
+		"duplicate security group ID": {
+			annotations: map[string]string{SecurityGroupIDs: "sg-1,sg-1"},
+			err:         `annotation kata.peerpods.io/security-group-ids: duplicate security group ID "sg-1"`,
+		},
+		"invalid tag": {
+			annotations: map[string]string{Tags: "team"},
+			err:         `annotation kata.peerpods.io/tags: invalid tag "team", expected key=value`,
+		},
+		"duplicate tag": {
+			annotations: map[string]string{Tags: "team=a,team=b"},
+			err:         `annotation kata.peerpods.io/tags: duplicate tag "team"`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			overrides, err := ParseOverrides(tc.annotations)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("expect error %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+			if !reflect.DeepEqual(overrides, tc.overrides) {
+				t.Errorf("expect %#v, got %#v", tc.overrides, overrides)
+			}
+			if keys := overrides.Keys(); !reflect.DeepEqual(keys, tc.keys) {
+				t.Errorf("expect keys %q, got %q", tc.keys, keys)
+			}
+			for _, key := range tc.keys {
+				if !IsOverride(key) {
+					t.Errorf("expect %s to be an override", key)
+				}
+			}
+		})
+	}
+
+	if IsOverride(MachineType) {
+		t.Errorf("expect %s not to be an override", MachineType)
+	}
+}
+
+func TestVolumeTargetPaths(t *testing.T) {
+
+	annotations := map[string]string{}
+	if paths := GetVolumeTargetPaths(annotations); len(paths) != 0 {
+		t.Fatalf("expect no paths, got %q", paths)
+	}
+
+	AddVolumeTargetPath(annotations, "/a")
+	AddVolumeTargetPath(annotations, "/b")
+
+	if annotations[VolumeTargetPaths] != "/a,/b" {
+		t.Errorf("expect annotation %q, got %q", "/a,/b", annotations[VolumeTargetPaths])
+	}
+	if paths := GetVolumeTargetPaths(annotations); !reflect.DeepEqual(paths, []string{"/a", "/b"}) {
+		t.Errorf("expect paths [/a /b], got %q", paths)
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/confidential-containers/cloud-api-adaptor/pkg/annotations
+
+go 1.20
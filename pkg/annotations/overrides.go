@@ -0,0 +1,99 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package annotations
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Overrides are the settings of the cloud provider that the annotations of a pod override for its pod VM.
+// Zero values mean not overridden. cloud-api-adaptor rejects overrides that its operator did not allow.
+type Overrides struct {
+	ImageID          string
+	SubnetID         string
+	SecurityGroupIDs []string
+	Tags             map[string]string
+}
+
+// IsOverride returns true if key is an annotation that overrides a setting of the cloud provider
+func IsOverride(key string) bool {
+	switch key {
+	case ImageID, SubnetID, SecurityGroupIDs, Tags:
+		return true
+	}
+	return false
+}
+
+// ParseOverrides parses the override annotations of a pod. Malformed values are errors.
+func ParseOverrides(annotations map[string]string) (Overrides, error) {
+
+	var o Overrides
+
+	var err error
+	if o.ImageID, err = parseID(annotations, ImageID); err != nil {
+		return o, err
+	}
+	if o.SubnetID, err = parseID(annotations, SubnetID); err != nil {
+		return o, err
+	}
+
+	if s := annotations[SecurityGroupIDs]; s != "" {
+		for _, id := range strings.Split(s, ",") {
+			id = strings.TrimSpace(id)
+			if id == "" || strings.ContainsAny(id, " \t\n") {
+				return o, fmt.Errorf("annotation %s: invalid security group ID %q", SecurityGroupIDs, id)
+			}
+			for _, prev := range o.SecurityGroupIDs {
+				if id == prev {
+					return o, fmt.Errorf("annotation %s: duplicate security group ID %q", SecurityGroupIDs, id)
+				}
+			}
+			o.SecurityGroupIDs = append(o.SecurityGroupIDs, id)
+		}
+	}
+
+	if s := annotations[Tags]; s != "" {
+		o.Tags = map[string]string{}
+		for _, pair := range strings.Split(s, ",") {
+			key, value, ok := strings.Cut(pair, "=")
+			key = strings.TrimSpace(key)
+			if !ok || key == "" {
+				return o, fmt.Errorf("annotation %s: invalid tag %q, expected key=value", Tags, pair)
+			}
+			if _, ok := o.Tags[key]; ok {
+				return o, fmt.Errorf("annotation %s: duplicate tag %q", Tags, key)
+			}
+			o.Tags[key] = strings.TrimSpace(value)
+		}
+	}
+
+	return o, nil
+}
+
+// Keys returns the annotations of the overridden settings
+func (o Overrides) Keys() []string {
+	var keys []string
+	if o.ImageID != "" {
+		keys = append(keys, ImageID)
+	}
+	if o.SubnetID != "" {
+		keys = append(keys, SubnetID)
+	}
+	if len(o.SecurityGroupIDs) > 0 {
+		keys = append(keys, SecurityGroupIDs)
+	}
+	if len(o.Tags) > 0 {
+		keys = append(keys, Tags)
+	}
+	return keys
+}
+
+func parseID(annotations map[string]string, key string) (string, error) {
+	id := annotations[key]
+	if strings.ContainsAny(id, " \t\n,") {
+		return "", fmt.Errorf("annotation %s: invalid ID %q", key, id)
+	}
+	return id, nil
+}
@@ -0,0 +1,141 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package annotations
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// PodVM is the pod VM requested by the annotations of a pod. Zero values mean not requested.
+type PodVM struct {
+	InstanceType string
+	VCPUs        int64
+	// Memory in MiB
+	Memory   int64
+	GPUs     int64
+	GPUModel string
+	// Spot requests interruptible capacity at up to SpotMaxPrice per hour. Zero SpotMaxPrice means up to the on-demand price.
+	Spot         bool
+	SpotMaxPrice float64
+}
+
+// ParsePodVM parses the pod VM annotations of a pod. Malformed values are errors.
+func ParsePodVM(annotations map[string]string) (PodVM, error) {
+
+	var vm PodVM
+
+	if err := CheckVersion(annotations); err != nil {
+		return vm, err
+	}
+
+	vm.InstanceType = annotations[MachineType]
+	vm.GPUModel = annotations[GPUModel]
+
+	var err error
+	if vm.VCPUs, err = parseCount(annotations, VCPUs); err != nil {
+		return vm, err
+	}
+	if vm.Memory, err = parseCount(annotations, Memory); err != nil {
+		return vm, err
+	}
+	if vm.GPUs, err = parseCount(annotations, GPUs); err != nil {
+		return vm, err
+	}
+	if s := annotations[Spot]; s != "" {
+		if vm.Spot, err = strconv.ParseBool(s); err != nil {
+			return vm, fmt.Errorf("annotation %s: %q is not a boolean", Spot, s)
+		}
+	}
+	if s := annotations[SpotMaxPrice]; s != "" {
+		if vm.SpotMaxPrice, err = strconv.ParseFloat(s, 64); err != nil {
+			return vm, fmt.Errorf("annotation %s: %q is not a number", SpotMaxPrice, s)
+		}
+	}
+
+	return vm, vm.Validate()
+}
+
+// Validate returns an error if a field of the pod VM is invalid
+func (vm PodVM) Validate() error {
+	if strings.ContainsAny(vm.InstanceType, " \t\n,") {
+		return fmt.Errorf("annotation %s: invalid instance type %q", MachineType, vm.InstanceType)
+	}
+	if vm.VCPUs < 0 {
+		return fmt.Errorf("annotation %s: negative number of vCPUs %d", VCPUs, vm.VCPUs)
+	}
+	if vm.Memory < 0 {
+		return fmt.Errorf("annotation %s: negative memory %d", Memory, vm.Memory)
+	}
+	if vm.GPUs < 0 {
+		return fmt.Errorf("annotation %s: negative number of GPUs %d", GPUs, vm.GPUs)
+	}
+	if vm.GPUModel != "" && vm.GPUs == 0 {
+		return fmt.Errorf("annotation %s: GPU model %q without GPUs", GPUModel, vm.GPUModel)
+	}
+	if vm.SpotMaxPrice < 0 || math.IsNaN(vm.SpotMaxPrice) || math.IsInf(vm.SpotMaxPrice, 0) {
+		return fmt.Errorf("annotation %s: invalid price %v", SpotMaxPrice, vm.SpotMaxPrice)
+	}
+	if vm.SpotMaxPrice != 0 && !vm.Spot {
+		return fmt.Errorf("annotation %s: maximum price without %s", SpotMaxPrice, Spot)
+	}
+	return nil
+}
+
+// Default sets the fields of the pod VM that are not requested to the fields of defaults
+func (vm *PodVM) Default(defaults PodVM) {
+	if vm.InstanceType == "" {
+		vm.InstanceType = defaults.InstanceType
+	}
+	if vm.VCPUs == 0 {
+		vm.VCPUs = defaults.VCPUs
+	}
+	if vm.Memory == 0 {
+		vm.Memory = defaults.Memory
+	}
+	if vm.GPUs == 0 {
+		vm.GPUs = defaults.GPUs
+	}
+	if vm.GPUModel == "" {
+		vm.GPUModel = defaults.GPUModel
+	}
+}
+
+// Set sets the annotations of the requested fields of the pod VM, unless they are already set
+func (vm PodVM) Set(annotations map[string]string) {
+	setDefault := func(key, value string) {
+		if _, ok := annotations[key]; !ok {
+			annotations[key] = value
+		}
+	}
+	if vm.InstanceType != "" {
+		setDefault(MachineType, vm.InstanceType)
+	}
+	if vm.VCPUs > 0 {
+		setDefault(VCPUs, strconv.FormatInt(vm.VCPUs, 10))
+	}
+	if vm.Memory > 0 {
+		setDefault(Memory, strconv.FormatInt(vm.Memory, 10))
+	}
+	if vm.GPUs > 0 {
+		setDefault(GPUs, strconv.FormatInt(vm.GPUs, 10))
+	}
+	if vm.GPUModel != "" {
+		setDefault(GPUModel, vm.GPUModel)
+	}
+}
+
+func parseCount(annotations map[string]string, key string) (int64, error) {
+	s, ok := annotations[key]
+	if !ok || s == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("annotation %s: %q is not an integer", key, s)
+	}
+	return n, nil
+}
@@ -0,0 +1,42 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package annotations
+
+import (
+	"strings"
+)
+
+const (
+	// StorageClassParameter is the parameter of a storage class whose volumes the CSI wrapper publishes in pod VMs
+	StorageClassParameter = "peerpod"
+
+	// Labels of PeerpodVolume objects of the CSI wrapper
+	LabelVolumeName   = "volumeName"
+	LabelNodeID       = "nodeID"
+	LabelPodUID       = "podUid"
+	LabelVMID         = "vmID"
+	LabelPodName      = "podName"
+	LabelPodNamespace = "podNamespace"
+	LabelPodNodeName  = "podNodeName"
+)
+
+// GetVolumeTargetPaths returns the target paths of the CSI volumes of a container
+func GetVolumeTargetPaths(annotations map[string]string) []string {
+	var paths []string
+	for _, path := range strings.Split(annotations[VolumeTargetPaths], ",") {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// AddVolumeTargetPath adds a target path of a CSI volume to the annotations of a container
+func AddVolumeTargetPath(annotations map[string]string, path string) {
+	if paths := annotations[VolumeTargetPaths]; paths != "" {
+		annotations[VolumeTargetPaths] = paths + "," + path
+	} else {
+		annotations[VolumeTargetPaths] = path
+	}
+}
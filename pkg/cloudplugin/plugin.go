@@ -0,0 +1,323 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package cloudplugin is an SDK for writing cloud provider plugins of cloud-api-adaptor.
+//
+// A plugin implements Provider, and calls Serve in its main function.
+// cloud-api-adaptor launches the plugin binary, or connects to a plugin that is already running,
+// when it runs with the "external" cloud provider.
+package cloudplugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/confidential-containers/cloud-api-adaptor/proto/cloudprovider/v1"
+)
+
+const (
+	// ProtocolVersion is the version of the plugin protocol implemented by this package
+	ProtocolVersion = 1
+
+	// SocketEnv is the environment variable that specifies the path of the unix socket a plugin listens on.
+	// cloud-api-adaptor sets it when it launches a plugin binary.
+	SocketEnv = "CLOUD_PROVIDER_PLUGIN_SOCKET"
+
+	// DefaultSocketPath is the socket path used when SocketEnv is not set
+	DefaultSocketPath = "/run/peerpod/cloud-provider.sock"
+)
+
+// ErrProtocolVersion is returned when cloud-api-adaptor and a plugin speak different protocol versions
+var ErrProtocolVersion = errors.New("plugin protocol version mismatch")
+
+// Instance is a pod VM instance
+type Instance struct {
+	ID   string
+	Name string
+	IPs  []netip.Addr
+	// InstanceType and Zone are the actual instance type and zone of an instance, if known
+	InstanceType string
+	Zone         string
+}
+
+// InstanceTypeSpec specifies the instance type of a pod VM
+type InstanceTypeSpec struct {
+	InstanceType string
+	VCPUs        int64
+	// Memory is the memory size in MiB
+	Memory int64
+	Arch   string
+	GPUs   int64
+	// GPUModel is the GPU model requested by a pod. Any GPU model if empty.
+	GPUModel string
+	// Placement is one of the placements of a PlacementLister. Empty means the default placement.
+	Placement string
+	// ExcludedInstanceTypes failed for lack of capacity, and must not be selected by VCPUs, Memory and GPUs
+	ExcludedInstanceTypes []string
+	// Spot requests an instance on interruptible capacity at up to SpotMaxPrice per hour, if the plugin
+	// implements SpotProvider. Zero SpotMaxPrice means up to the on-demand price.
+	Spot         bool
+	SpotMaxPrice float64
+	// ImageID, SubnetID, SecurityGroupIDs and Tags override the settings of the plugin for the pod VM of a pod,
+	// if the plugin implements OverrideProvider. Empty values keep the settings of the plugin.
+	ImageID          string
+	SubnetID         string
+	SecurityGroupIDs []string
+	Tags             map[string]string
+}
+
+// Provider is implemented by a cloud provider plugin. It corresponds to the Provider interface of
+// cloud-api-adaptor, except that the user data of an instance is passed as generated cloud-init data.
+type Provider interface {
+	CreateInstance(ctx context.Context, podName, sandboxID, userData string, spec InstanceTypeSpec) (*Instance, error)
+	DeleteInstance(ctx context.Context, instanceID string) error
+	// ListInstances returns pod VM instances created by the plugin for the cluster of cloud-api-adaptor.
+	// Instances of other clusters that share the same cloud account must not be returned.
+	ListInstances(ctx context.Context) ([]*Instance, error)
+	Teardown() error
+	ConfigVerifier() error
+}
+
+// OverrideProvider is optionally implemented by a plugin that overrides its settings for the pod VM of a pod
+type OverrideProvider interface {
+	// SupportedOverrides returns the override annotations of pods, such as kata.peerpods.io/subnet-id,
+	// whose fields of InstanceTypeSpec CreateInstance applies
+	SupportedOverrides() []string
+}
+
+// PlacementLister is optionally implemented by a plugin that can create instances in alternate placements,
+// such as subnets or zones
+type PlacementLister interface {
+	// Placements returns the alternate placements to try, in order, when the default placement has no capacity
+	Placements() []string
+}
+
+// SpotProvider is optionally implemented by a plugin that can create instances on spot capacity
+type SpotProvider interface {
+	// SupportsSpot returns true if CreateInstance creates an instance on spot capacity when InstanceTypeSpec.Spot is set
+	SupportsSpot() bool
+}
+
+// CapacityError is returned by CreateInstance when the cloud has no capacity for an instance type, or when the
+// quota of the cloud account is exceeded. cloud-api-adaptor retries with other placements and instance types.
+type CapacityError struct {
+	// QuotaExceeded is true if the quota of the cloud account is exceeded, and false if the cloud has no capacity
+	QuotaExceeded bool
+	// InstanceType and Placement are the instance type and placement that failed
+	InstanceType string
+	Placement    string
+	// Err is the error returned by the cloud
+	Err error
+}
+
+func (e *CapacityError) Error() string {
+	cause := "insufficient capacity"
+	if e.QuotaExceeded {
+		cause = "quota exceeded"
+	}
+	return fmt.Sprintf("%s for instance type %q in placement %q: %v", cause, e.InstanceType, e.Placement, e.Err)
+}
+
+func (e *CapacityError) Unwrap() error {
+	return e.Err
+}
+
+// Serve serves provider as a plugin named name on the socket specified by SocketEnv,
+// until the process receives SIGINT or SIGTERM
+func Serve(name string, provider Provider) error {
+
+	socketPath := os.Getenv(SocketEnv)
+	if socketPath == "" {
+		socketPath = DefaultSocketPath
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return ServeContext(ctx, name, socketPath, provider)
+}
+
+// ServeContext serves provider as a plugin named name on socketPath until ctx is canceled
+func ServeContext(ctx context.Context, name, socketPath string, provider Provider) error {
+
+	if err := os.MkdirAll(filepath.Dir(socketPath), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create parent directories for socket %s: %w", socketPath, err)
+	}
+	if err := os.Remove(socketPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove %s: %w", socketPath, err)
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", socketPath, err)
+	}
+
+	grpcServer := grpc.NewServer()
+	pb.RegisterCloudProviderServer(grpcServer, &server{name: name, provider: provider})
+
+	serverErr := make(chan error, 1)
+	go func() {
+		defer close(serverErr)
+
+		if err := grpcServer.Serve(listener); err != nil {
+			serverErr <- err
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		grpcServer.GracefulStop()
+		return nil
+	case err := <-serverErr:
+		return fmt.Errorf("error running plugin server: %w", err)
+	}
+}
+
+type server struct {
+	pb.UnimplementedCloudProviderServer
+
+	name     string
+	provider Provider
+}
+
+func (s *server) Handshake(ctx context.Context, req *pb.HandshakeRequest) (*pb.HandshakeResponse, error) {
+
+	if req.ProtocolVersion != ProtocolVersion {
+		return nil, status.Errorf(codes.FailedPrecondition, "%v: cloud-api-adaptor speaks version %d, plugin %s speaks version %d", ErrProtocolVersion, req.ProtocolVersion, s.name, ProtocolVersion)
+	}
+
+	res := &pb.HandshakeResponse{
+		ProtocolVersion: ProtocolVersion,
+		Name:            s.name,
+	}
+	if provider, ok := s.provider.(OverrideProvider); ok {
+		res.SupportedOverrides = provider.SupportedOverrides()
+	}
+	if provider, ok := s.provider.(PlacementLister); ok {
+		res.Placements = provider.Placements()
+	}
+	if provider, ok := s.provider.(SpotProvider); ok {
+		res.SupportsSpot = provider.SupportsSpot()
+	}
+	return res, nil
+}
+
+func (s *server) CreateInstance(ctx context.Context, req *pb.CreateInstanceRequest) (*pb.CreateInstanceResponse, error) {
+
+	var spec InstanceTypeSpec
+	if req.Spec != nil {
+		spec = InstanceTypeSpec{
+			InstanceType: req.Spec.InstanceType,
+			VCPUs:        req.Spec.VCPUs,
+			Memory:       req.Spec.Memory,
+			Arch:         req.Spec.Arch,
+			GPUs:         req.Spec.GPUs,
+			GPUModel:     req.Spec.GPUModel,
+			Placement:    req.Spec.Placement,
+
+			ExcludedInstanceTypes: req.Spec.ExcludedInstanceTypes,
+
+			Spot:         req.Spec.Spot,
+			SpotMaxPrice: req.Spec.SpotMaxPrice,
+
+			ImageID:          req.Spec.ImageID,
+			SubnetID:         req.Spec.SubnetID,
+			SecurityGroupIDs: req.Spec.SecurityGroupIDs,
+			Tags:             req.Spec.Tags,
+		}
+	}
+
+	instance, err := s.provider.CreateInstance(ctx, req.PodName, req.SandboxID, req.UserData, spec)
+	if err != nil {
+		var capacityErr *CapacityError
+		if errors.As(err, &capacityErr) {
+			return nil, capacityStatus(err, capacityErr)
+		}
+		return nil, err
+	}
+
+	return &pb.CreateInstanceResponse{Instance: toProto(instance)}, nil
+}
+
+func (s *server) DeleteInstance(ctx context.Context, req *pb.DeleteInstanceRequest) (*pb.DeleteInstanceResponse, error) {
+
+	if err := s.provider.DeleteInstance(ctx, req.InstanceID); err != nil {
+		return nil, err
+	}
+
+	return &pb.DeleteInstanceResponse{}, nil
+}
+
+func (s *server) ListInstances(ctx context.Context, req *pb.ListInstancesRequest) (*pb.ListInstancesResponse, error) {
+
+	instances, err := s.provider.ListInstances(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &pb.ListInstancesResponse{}
+	for _, instance := range instances {
+		res.Instances = append(res.Instances, toProto(instance))
+	}
+	return res, nil
+}
+
+func (s *server) VerifyConfig(ctx context.Context, req *pb.VerifyConfigRequest) (*pb.VerifyConfigResponse, error) {
+
+	if err := s.provider.ConfigVerifier(); err != nil {
+		return nil, err
+	}
+
+	return &pb.VerifyConfigResponse{}, nil
+}
+
+func (s *server) Teardown(ctx context.Context, req *pb.TeardownRequest) (*pb.TeardownResponse, error) {
+
+	if err := s.provider.Teardown(); err != nil {
+		return nil, err
+	}
+
+	return &pb.TeardownResponse{}, nil
+}
+
+// capacityStatus returns a ResourceExhausted error with the details of a capacity error
+func capacityStatus(err error, capacityErr *CapacityError) error {
+	st, detailErr := status.New(codes.ResourceExhausted, err.Error()).WithDetails(&pb.CapacityError{
+		QuotaExceeded: capacityErr.QuotaExceeded,
+		InstanceType:  capacityErr.InstanceType,
+		Placement:     capacityErr.Placement,
+	})
+	if detailErr != nil {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return st.Err()
+}
+
+func toProto(instance *Instance) *pb.Instance {
+
+	if instance == nil {
+		return nil
+	}
+
+	res := &pb.Instance{
+		ID:           instance.ID,
+		Name:         instance.Name,
+		InstanceType: instance.InstanceType,
+		Zone:         instance.Zone,
+	}
+	for _, ip := range instance.IPs {
+		res.IPs = append(res.IPs, ip.String())
+	}
+	return res
+}
@@ -0,0 +1,245 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package cloudplugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/confidential-containers/cloud-api-adaptor/proto/cloudprovider/v1"
+)
+
+type mockProvider struct {
+	userData string
+	spec     InstanceTypeSpec
+	deleted  []string
+}
+
+func (p *mockProvider) CreateInstance(ctx context.Context, podName, sandboxID, userData string, spec InstanceTypeSpec) (*Instance, error) {
+	p.userData = userData
+	p.spec = spec
+	return &Instance{
+		ID:   "i-123",
+		Name: "podvm-" + podName + "-" + sandboxID,
+		IPs:  []netip.Addr{netip.MustParseAddr("192.0.2.1")},
+		Zone: "zone-1",
+	}, nil
+}
+
+func (p *mockProvider) DeleteInstance(ctx context.Context, instanceID string) error {
+	if instanceID != "i-123" {
+		return status.Errorf(codes.NotFound, "instance %s is not found", instanceID)
+	}
+	p.deleted = append(p.deleted, instanceID)
+	return nil
+}
+
+func (p *mockProvider) ListInstances(ctx context.Context) ([]*Instance, error) {
+	return []*Instance{{ID: "i-123", Name: "podvm-nginx-0123456789"}}, nil
+}
+
+func (p *mockProvider) Teardown() error {
+	return nil
+}
+
+func (p *mockProvider) ConfigVerifier() error {
+	return errors.New("credentials are missing")
+}
+
+type overrideProvider struct {
+	mockProvider
+}
+
+func (p *overrideProvider) SupportedOverrides() []string {
+	return []string{"kata.peerpods.io/subnet-id"}
+}
+
+type capacityProvider struct {
+	mockProvider
+}
+
+func (p *capacityProvider) Placements() []string {
+	return []string{"zone-2", "zone-3"}
+}
+
+func (p *capacityProvider) CreateInstance(ctx context.Context, podName, sandboxID, userData string, spec InstanceTypeSpec) (*Instance, error) {
+	p.spec = spec
+	return nil, fmt.Errorf("creating instance: %w", &CapacityError{QuotaExceeded: true, InstanceType: "t3.large", Placement: spec.Placement, Err: errors.New("VcpuLimitExceeded")})
+}
+
+type spotProvider struct {
+	mockProvider
+}
+
+func (p *spotProvider) SupportsSpot() bool {
+	return true
+}
+
+func startPlugin(t *testing.T, provider Provider) pb.CloudProviderClient {
+
+	socketPath := filepath.Join(t.TempDir(), "plugin.sock")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- ServeContext(ctx, "mock", socketPath, provider)
+	}()
+
+	conn, err := grpc.Dial("unix:"+socketPath, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		conn.Close()
+		cancel()
+		assert.NoError(t, <-errCh)
+	})
+
+	return pb.NewCloudProviderClient(conn)
+}
+
+func TestHandshake(t *testing.T) {
+
+	ctx := context.Background()
+	client := startPlugin(t, &mockProvider{})
+
+	res, err := client.Handshake(ctx, &pb.HandshakeRequest{ProtocolVersion: ProtocolVersion}, grpc.WaitForReady(true))
+	require.NoError(t, err)
+	assert.Equal(t, "mock", res.Name)
+	assert.EqualValues(t, ProtocolVersion, res.ProtocolVersion)
+
+	assert.Empty(t, res.SupportedOverrides)
+	assert.False(t, res.SupportsSpot)
+
+	_, err = client.Handshake(ctx, &pb.HandshakeRequest{ProtocolVersion: ProtocolVersion + 1})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestHandshakeOverrides(t *testing.T) {
+
+	ctx := context.Background()
+	provider := &overrideProvider{}
+	client := startPlugin(t, provider)
+
+	res, err := client.Handshake(ctx, &pb.HandshakeRequest{ProtocolVersion: ProtocolVersion}, grpc.WaitForReady(true))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"kata.peerpods.io/subnet-id"}, res.SupportedOverrides)
+
+	_, err = client.CreateInstance(ctx, &pb.CreateInstanceRequest{
+		PodName:   "nginx",
+		SandboxID: "0123456789",
+		Spec: &pb.InstanceTypeSpec{
+			InstanceType:     "t3.small",
+			ImageID:          "ami-1",
+			SubnetID:         "subnet-1",
+			SecurityGroupIDs: []string{"sg-1", "sg-2"},
+			Tags:             map[string]string{"team": "a"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, InstanceTypeSpec{
+		InstanceType:     "t3.small",
+		ImageID:          "ami-1",
+		SubnetID:         "subnet-1",
+		SecurityGroupIDs: []string{"sg-1", "sg-2"},
+		Tags:             map[string]string{"team": "a"},
+	}, provider.spec)
+}
+
+func TestSpot(t *testing.T) {
+
+	ctx := context.Background()
+	provider := &spotProvider{}
+	client := startPlugin(t, provider)
+
+	res, err := client.Handshake(ctx, &pb.HandshakeRequest{ProtocolVersion: ProtocolVersion}, grpc.WaitForReady(true))
+	require.NoError(t, err)
+	assert.True(t, res.SupportsSpot)
+
+	_, err = client.CreateInstance(ctx, &pb.CreateInstanceRequest{
+		PodName:   "nginx",
+		SandboxID: "0123456789",
+		Spec:      &pb.InstanceTypeSpec{InstanceType: "t3.small", Spot: true, SpotMaxPrice: 0.05},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, InstanceTypeSpec{InstanceType: "t3.small", Spot: true, SpotMaxPrice: 0.05}, provider.spec)
+}
+
+func TestCapacityError(t *testing.T) {
+
+	ctx := context.Background()
+	provider := &capacityProvider{}
+	client := startPlugin(t, provider)
+
+	res, err := client.Handshake(ctx, &pb.HandshakeRequest{ProtocolVersion: ProtocolVersion}, grpc.WaitForReady(true))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"zone-2", "zone-3"}, res.Placements)
+
+	_, err = client.CreateInstance(ctx, &pb.CreateInstanceRequest{
+		PodName:   "nginx",
+		SandboxID: "0123456789",
+		Spec:      &pb.InstanceTypeSpec{VCPUs: 4, Placement: "zone-2", ExcludedInstanceTypes: []string{"t3.xlarge"}},
+	})
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	assert.Equal(t, codes.ResourceExhausted, st.Code())
+	assert.Contains(t, st.Message(), "VcpuLimitExceeded")
+	require.Len(t, st.Details(), 1)
+	detail, ok := st.Details()[0].(*pb.CapacityError)
+	require.True(t, ok)
+	assert.True(t, detail.QuotaExceeded)
+	assert.Equal(t, "t3.large", detail.InstanceType)
+	assert.Equal(t, "zone-2", detail.Placement)
+	assert.Equal(t, InstanceTypeSpec{VCPUs: 4, Placement: "zone-2", ExcludedInstanceTypes: []string{"t3.xlarge"}}, provider.spec)
+}
+
+func TestProvider(t *testing.T) {
+
+	ctx := context.Background()
+	provider := &mockProvider{}
+	client := startPlugin(t, provider)
+
+	created, err := client.CreateInstance(ctx, &pb.CreateInstanceRequest{
+		PodName:   "nginx",
+		SandboxID: "0123456789",
+		UserData:  "#cloud-config\n",
+		Spec:      &pb.InstanceTypeSpec{InstanceType: "t3.small", VCPUs: 2, Memory: 2048, GPUs: 1, GPUModel: "nvidia-tesla-t4"},
+	}, grpc.WaitForReady(true))
+	require.NoError(t, err)
+	assert.Equal(t, "i-123", created.Instance.ID)
+	assert.Equal(t, "podvm-nginx-0123456789", created.Instance.Name)
+	assert.Equal(t, []string{"192.0.2.1"}, created.Instance.IPs)
+	assert.Equal(t, "zone-1", created.Instance.Zone)
+	assert.Equal(t, "#cloud-config\n", provider.userData)
+	assert.Equal(t, InstanceTypeSpec{InstanceType: "t3.small", VCPUs: 2, Memory: 2048, GPUs: 1, GPUModel: "nvidia-tesla-t4"}, provider.spec)
+
+	listed, err := client.ListInstances(ctx, &pb.ListInstancesRequest{})
+	require.NoError(t, err)
+	require.Len(t, listed.Instances, 1)
+	assert.Equal(t, "i-123", listed.Instances[0].ID)
+
+	_, err = client.DeleteInstance(ctx, &pb.DeleteInstanceRequest{InstanceID: "i-123"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"i-123"}, provider.deleted)
+
+	_, err = client.DeleteInstance(ctx, &pb.DeleteInstanceRequest{InstanceID: "i-456"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.VerifyConfig(ctx, &pb.VerifyConfigRequest{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "credentials are missing")
+
+	_, err = client.Teardown(ctx, &pb.TeardownRequest{})
+	assert.NoError(t, err)
+}
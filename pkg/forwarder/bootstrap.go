@@ -0,0 +1,179 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package forwarder
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/tlsutil"
+)
+
+const (
+	DefaultBootstrapConfigPath = "/peerpod/bootstrap.json"
+	BootstrapURLPath           = "/bootstrap"
+
+	// The TLS certificate of a bootstrap endpoint must have this as SAN, since the pod name of
+	// a pre-provisioned pod VM is not known when its certificate is issued.
+	BootstrapServerName = "podvm-bootstrap"
+
+	maxBootstrapRequestSize  = 1 << 20
+	bootstrapShutdownTimeout = time.Second
+)
+
+// BootstrapConfig is the initial configuration of a pre-provisioned pod VM.
+// A pre-provisioned pod VM waits for its daemon config from a bootstrap endpoint,
+// instead of receiving the daemon config via cloud-init.
+type BootstrapConfig struct {
+	TLSServerKey  string `json:"tls-server-key,omitempty"`
+	TLSServerCert string `json:"tls-server-cert,omitempty"`
+	TLSClientCA   string `json:"tls-client-ca,omitempty"`
+}
+
+// Bootstrap serves a bootstrap endpoint on listenAddr until a daemon config is received,
+// and writes the daemon config to configPath. Bootstrap returns after the endpoint is closed,
+// so that listenAddr can be reused by the daemon. A daemon config is accepted only from a client
+// with a certificate of the client CA, since it determines the pod that this pod VM runs.
+func Bootstrap(ctx context.Context, spec *BootstrapConfig, listenAddr string, tlsConfig *tlsutil.TLSConfig, configPath string) error {
+
+	if tlsConfig == nil {
+		return errors.New("bootstrap endpoint requires TLS")
+	}
+
+	// Do not modify tlsConfig, since it is reused by the daemon with credentials in the daemon config
+	config := *tlsConfig
+	if !config.HasCertAuth() {
+		config.CertData = []byte(spec.TLSServerCert)
+		config.KeyData = []byte(spec.TLSServerKey)
+	}
+	if !config.HasCA() {
+		config.CAData = []byte(spec.TLSClientCA)
+	}
+	if !config.HasCertAuth() || !config.HasCA() {
+		return errors.New("bootstrap endpoint requires a server certificate and a client CA")
+	}
+
+	c, err := tlsutil.GetTLSConfigFor(&config)
+	if err != nil {
+		return fmt.Errorf("failed to create tls config for bootstrap: %w", err)
+	}
+
+	listener, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		return fmt.Errorf("failed to create a bootstrap listener: %w", err)
+	}
+	listener = tls.NewListener(listener, c)
+
+	var mutex sync.Mutex
+	doneCh := make(chan struct{})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(BootstrapURLPath, func(w http.ResponseWriter, r *http.Request) {
+
+		if r.Method != http.MethodPut {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBootstrapRequestSize))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to read a daemon config: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		var config Config
+		if err := json.Unmarshal(data, &config); err != nil {
+			http.Error(w, fmt.Sprintf("failed to decode a daemon config: %v", err), http.StatusBadRequest)
+			return
+		}
+		if config.PodName == "" || config.PodNamespace == "" {
+			http.Error(w, "pod name and namespace are missing in a daemon config", http.StatusBadRequest)
+			return
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		select {
+		case <-doneCh:
+			http.Error(w, "daemon config is already received", http.StatusConflict)
+			return
+		default:
+		}
+
+		if err := writeFileAtomically(configPath, data); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		logger.Printf("received a daemon config for pod %s in namespace %s", config.PodName, config.PodNamespace)
+
+		w.WriteHeader(http.StatusNoContent)
+		close(doneCh)
+	})
+
+	httpServer := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	serverErr := make(chan error)
+	go func() {
+		defer close(serverErr)
+
+		if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- fmt.Errorf("error running bootstrap server: %w", err)
+		}
+	}()
+
+	logger.Printf("waiting for a daemon config on %s%s", listener.Addr(), BootstrapURLPath)
+
+	var result error
+
+	select {
+	case <-ctx.Done():
+		result = ctx.Err()
+	case err := <-serverErr:
+		return err
+	case <-doneCh:
+	}
+
+	// Shutdown waits for connections that have not sent any request for a few seconds,
+	// so close them forcibly after in-flight responses are sent
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), bootstrapShutdownTimeout)
+	defer cancel()
+
+	if err := httpServer.Shutdown(shutdownCtx); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		logger.Warnf("error shutting down bootstrap server: %v", err)
+	}
+	httpServer.Close()
+
+	return result
+}
+
+func writeFileAtomically(path string, data []byte) error {
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create a directory for %s: %w", path, err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to rename %s to %s: %w", tmpPath, path, err)
+	}
+	return nil
+}
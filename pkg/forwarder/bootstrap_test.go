@@ -0,0 +1,124 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package forwarder
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/tlsutil"
+)
+
+func TestBootstrap(t *testing.T) {
+
+	// Find a free port
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	listenAddr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	configPath := filepath.Join(t.TempDir(), "daemon.json")
+
+	caService, err := tlsutil.NewCAService("test")
+	require.NoError(t, err)
+	serverCertPEM, serverKeyPEM, err := caService.Issue(BootstrapServerName)
+	require.NoError(t, err)
+	clientCertPEM, clientKeyPEM, err := tlsutil.NewClientCertificate("test")
+	require.NoError(t, err)
+
+	spec := &BootstrapConfig{
+		TLSServerCert: string(serverCertPEM),
+		TLSServerKey:  string(serverKeyPEM),
+		TLSClientCA:   string(clientCertPEM),
+	}
+
+	errCh := make(chan error)
+	go func() {
+		defer close(errCh)
+		if err := Bootstrap(context.Background(), spec, listenAddr, &tlsutil.TLSConfig{}, configPath); err != nil {
+			errCh <- err
+		}
+	}()
+
+	url := "https://" + listenAddr + BootstrapURLPath
+
+	rootCAs := x509.NewCertPool()
+	require.True(t, rootCAs.AppendCertsFromPEM(caService.RootCertificate()))
+	clientCert, err := tls.X509KeyPair(clientCertPEM, clientKeyPEM)
+	require.NoError(t, err)
+
+	newClient := func(certs ...tls.Certificate) *http.Client {
+		return &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{
+					RootCAs:      rootCAs,
+					ServerName:   BootstrapServerName,
+					Certificates: certs,
+				},
+			},
+		}
+	}
+
+	put := func(client *http.Client, body string) (*http.Response, error) {
+		req, err := http.NewRequest(http.MethodPut, url, bytes.NewBufferString(body))
+		if err != nil {
+			return nil, err
+		}
+		return client.Do(req)
+	}
+
+	client := newClient(clientCert)
+
+	var res *http.Response
+	require.Eventually(t, func() bool {
+		res, err = put(client, `{"pod-name":""}`)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	daemonJSON := `{"pod-name":"mypod","pod-namespace":"default"}`
+
+	// A client without a certificate of the client CA cannot send a daemon config
+	_, err = put(newClient(), daemonJSON)
+	assert.Error(t, err)
+
+	res, err = put(client, daemonJSON)
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+
+	select {
+	case err := <-errCh:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("bootstrap endpoint is not closed")
+	}
+
+	data, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, daemonJSON, string(data))
+}
+
+func TestBootstrapWithoutTLS(t *testing.T) {
+
+	configPath := filepath.Join(t.TempDir(), "daemon.json")
+
+	err := Bootstrap(context.Background(), &BootstrapConfig{}, "127.0.0.1:0", nil, configPath)
+	assert.ErrorContains(t, err, "requires TLS")
+
+	err = Bootstrap(context.Background(), &BootstrapConfig{}, "127.0.0.1:0", &tlsutil.TLSConfig{}, configPath)
+	assert.ErrorContains(t, err, "requires a server certificate and a client CA")
+}
@@ -8,7 +8,6 @@ import (
 	"crypto/tls"
 	"errors"
 	"fmt"
-	"log"
 	"net"
 	"sync"
 
@@ -18,10 +17,13 @@ import (
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/forwarder/interceptor"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/podnetwork"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/podnetwork/tunneler"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/logging"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/tlsutil"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/tracing"
 )
 
-var logger = log.New(log.Writer(), "[forwarder] ", log.LstdFlags|log.Lmsgprefix)
+var logger = logging.New("forwarder")
 
 const (
 	DefaultListenHost          = "0.0.0.0"
@@ -46,6 +48,14 @@ type Config struct {
 	AAKBCParams string `json:"aa-kbc-params,omitempty"`
 
 	AuthJson string `json:"auth-json,omitempty"`
+
+	// Spot is true if the pod VM runs on spot capacity. The pod VM then reports interruption notices
+	// of the cloud provider to cloud-api-adaptor.
+	Spot bool `json:"spot,omitempty"`
+}
+
+func (c Config) Redact() Config {
+	return *util.RedactStruct(&c, "TLSServerKey", "AuthJson").(*Config)
 }
 
 type Daemon interface {
@@ -97,7 +107,7 @@ func (d *daemon) Start(ctx context.Context) error {
 	}
 	defer func() {
 		if err := d.podNode.Teardown(); err != nil {
-			logger.Printf("failed to tear down pod network: %v", err)
+			logger.Errorf("failed to tear down pod network: %v", err)
 		}
 	}()
 
@@ -117,7 +127,7 @@ func (d *daemon) Start(ctx context.Context) error {
 
 		listener, err = tls.Listen("tcp", d.listenAddr, tlsConfig)
 		if err != nil {
-			logger.Printf("failed to create tls agent-protocol-forwarder listener: %v", err)
+			logger.Errorf("failed to create tls agent-protocol-forwarder listener: %v", err)
 			return err
 		}
 	} else {
@@ -125,14 +135,14 @@ func (d *daemon) Start(ctx context.Context) error {
 
 		listener, err = net.Listen("tcp", d.listenAddr)
 		if err != nil {
-			logger.Printf("failed to create agent-protocol-forwarder listener: %v", err)
+			logger.Errorf("failed to create agent-protocol-forwarder listener: %v", err)
 			return err
 		}
 	}
 
 	d.listenAddr = listener.Addr().String()
 
-	ttrpcServer, err := ttrpc.NewServer()
+	ttrpcServer, err := ttrpc.NewServer(ttrpc.WithUnaryServerInterceptor(tracing.UnaryServerInterceptor()))
 	if err != nil {
 		return fmt.Errorf("failed to create TTRPC server: %w", err)
 	}
@@ -151,10 +161,10 @@ func (d *daemon) Start(ctx context.Context) error {
 	}()
 	defer func() {
 		if err := ttrpcServer.Shutdown(ctx); err != nil {
-			logger.Printf("error shutting down TTRPC server: %v", err)
+			logger.Warnf("error shutting down TTRPC server: %v", err)
 		}
 		if err := d.interceptor.Close(); err != nil {
-			logger.Printf("error shutting down kata agent interceptor: %v", err)
+			logger.Warnf("error shutting down kata agent interceptor: %v", err)
 		}
 	}()
 
@@ -5,7 +5,9 @@ package forwarder
 
 import (
 	"context"
+	"fmt"
 	"net"
+	"strings"
 	"testing"
 	"time"
 
@@ -28,12 +30,18 @@ func dummyDialer(ctx context.Context) (net.Conn, error) {
 	return &mockConn{}, nil
 }
 
+type mockInterceptor struct {
+	agentproto.Redirector
+}
+
+func (*mockInterceptor) Interrupt() {}
+
 func TestNew(t *testing.T) {
 
 	config := &Config{}
 	tlsConfig := tlsutil.TLSConfig{}
 
-	ret := NewDaemon(config, DefaultListenAddr, &tlsConfig, agentproto.NewRedirector(dummyDialer), &mockPodNode{})
+	ret := NewDaemon(config, DefaultListenAddr, &tlsConfig, &mockInterceptor{agentproto.NewRedirector(dummyDialer)}, &mockPodNode{})
 	if ret == nil {
 		t.Fatal("Expect non nil, got nil")
 	}
@@ -57,7 +65,7 @@ func TestNew(t *testing.T) {
 func TestStart(t *testing.T) {
 
 	d := &daemon{
-		interceptor: agentproto.NewRedirector(dummyDialer),
+		interceptor: &mockInterceptor{agentproto.NewRedirector(dummyDialer)},
 		podNode:     &mockPodNode{},
 		readyCh:     make(chan struct{}),
 		stopCh:      make(chan struct{}),
@@ -100,3 +108,23 @@ func (n *mockPodNode) Setup() error {
 func (n *mockPodNode) Teardown() error {
 	return nil
 }
+
+func TestConfigMasking(t *testing.T) {
+	authJSON := `{"auths":{"quay.io":{"auth":"c2VjcmV0"}}}`
+	podName := "nginx"
+	config := Config{
+		PodName:  podName,
+		AuthJson: authJSON,
+	}
+
+	logline := fmt.Sprintf("%v", config.Redact())
+	if strings.Contains(logline, authJSON) {
+		t.Errorf("%s contains the auth json: %s", logline, authJSON)
+	}
+	if !strings.Contains(logline, podName) {
+		t.Errorf("%s doesn't contain the pod name: %s", logline, podName)
+	}
+	if config.AuthJson != authJSON {
+		t.Errorf("Original AuthJson field value has been overwritten")
+	}
+}
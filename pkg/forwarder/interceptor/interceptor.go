@@ -6,10 +6,10 @@ package interceptor
 import (
 	"context"
 	"fmt"
-	"log"
 	"net"
 	"os"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/avast/retry-go/v4"
@@ -17,26 +17,33 @@ import (
 	pb "github.com/kata-containers/kata-containers/src/runtime/virtcontainers/pkg/agent/protocols/grpc"
 	"github.com/moby/sys/mountinfo"
 	"github.com/opencontainers/runtime-spec/specs-go"
+	"go.opentelemetry.io/otel/attribute"
 
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/annotations"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/agentproto"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/logging"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/tracing"
 )
 
 const (
-	volumeTargetPathKey = "io.confidentialcontainers.org.peerpodvolumes.target_path"
 	volumeCheckInterval = 5 * time.Second
 	volumeCheckTimeout  = 3 * time.Minute
 )
 
-var logger = log.New(log.Writer(), "[forwarder/interceptor] ", log.LstdFlags|log.Lmsgprefix)
+var logger = logging.New("forwarder/interceptor")
 
 type Interceptor interface {
 	agentproto.Redirector
+	// Interrupt records that the cloud provider interrupts the instance of this pod VM.
+	// Check requests for agentproto.InterruptionService report it to cloud-api-adaptor.
+	Interrupt()
 }
 
 type interceptor struct {
 	agentproto.Redirector
 
-	nsPath string
+	nsPath      string
+	interrupted atomic.Bool
 }
 
 func dial(ctx context.Context, agentSocket string) (net.Conn, error) {
@@ -58,7 +65,7 @@ func dial(ctx context.Context, agentSocket string) (net.Conn, error) {
 
 	if err != nil {
 		err = fmt.Errorf("failed to establish agent connection to %s: %w", agentSocket, err)
-		logger.Print(err)
+		logger.Error(err.Error())
 		return nil, err
 	}
 
@@ -80,6 +87,25 @@ func NewInterceptor(agentSocket, nsPath string) Interceptor {
 	}
 }
 
+func (i *interceptor) Interrupt() {
+	if !i.interrupted.Swap(true) {
+		logger.Warn("instance of the pod VM is interrupted by the cloud provider")
+	}
+}
+
+// Check answers requests for agentproto.InterruptionService itself, and forwards other requests to kata agent
+func (i *interceptor) Check(ctx context.Context, req *pb.CheckRequest) (*pb.HealthCheckResponse, error) {
+
+	if req.Service != agentproto.InterruptionService {
+		return i.Redirector.Check(ctx, req)
+	}
+
+	if i.interrupted.Load() {
+		return &pb.HealthCheckResponse{Status: pb.HealthCheckResponse_NOT_SERVING}, nil
+	}
+	return &pb.HealthCheckResponse{Status: pb.HealthCheckResponse_SERVING}, nil
+}
+
 func (i *interceptor) CreateContainer(ctx context.Context, req *pb.CreateContainerRequest) (*types.Empty, error) {
 
 	logger.Printf("CreateContainer: containerID:%s", req.ContainerId)
@@ -90,25 +116,26 @@ func (i *interceptor) CreateContainer(ctx context.Context, req *pb.CreateContain
 		Path: i.nsPath,
 	})
 
-	logger.Printf("    namespaces:")
+	logger.Debug("    namespaces:")
 	for _, ns := range req.OCI.Linux.Namespaces {
-		logger.Printf("    %s: %q", ns.Type, ns.Path)
+		logger.Debugf("    %s: %q", ns.Type, ns.Path)
 	}
 
-	volumeTargetPath := req.OCI.Annotations[volumeTargetPathKey]
-	volumeTargetPathSlice := strings.Split(volumeTargetPath, ",")
+	volumeTargetPathSlice := annotations.GetVolumeTargetPaths(req.OCI.Annotations)
 	if len(req.OCI.Mounts) > 0 {
 		for _, m := range req.OCI.Mounts {
 			if _, err := os.Stat(m.Source); os.IsNotExist(err) && m.Type == "bind" {
 				logger.Printf("mount source %s doesn't exist, try to create", m.Source)
 				if err = os.MkdirAll(m.Source, os.ModePerm); err != nil {
-					logger.Printf("Failed to create dir: %v", err)
+					logger.Errorf("Failed to create dir: %v", err)
 				}
 			}
 			for _, s := range volumeTargetPathSlice {
 				if isTargetPath(m.Source, strings.TrimSpace(s)) {
 					logger.Printf("Waiting for device mounted to: %s", m.Source)
-					err := waitForDeviceMounted(ctx, m.Source)
+					spanCtx, span := tracing.StartSpan(ctx, "wait for device mount", attribute.String("mount.source", m.Source))
+					err := waitForDeviceMounted(spanCtx, m.Source)
+					tracing.EndSpan(span, err)
 					if err != nil {
 						return nil, err
 					}
@@ -120,7 +147,7 @@ func (i *interceptor) CreateContainer(ctx context.Context, req *pb.CreateContain
 	res, err := i.Redirector.CreateContainer(ctx, req)
 
 	if err != nil {
-		logger.Printf("CreateContainer failed with error: %v", err)
+		logger.Errorf("CreateContainer failed with error: %v", err)
 	}
 
 	return res, err
@@ -139,7 +166,7 @@ func waitForDeviceMounted(ctx context.Context, path string) error {
 		func() error {
 			isMounted, err := mountinfo.Mounted(path)
 			if err != nil {
-				logger.Printf("Mounted check error: %v", err)
+				logger.Warnf("Mounted check error: %v", err)
 				return err
 			}
 
@@ -148,7 +175,7 @@ func waitForDeviceMounted(ctx context.Context, path string) error {
 				return nil
 			} else {
 				err = fmt.Errorf("Device has not been mounted to %s", path)
-				logger.Print(err)
+				logger.Debug(err.Error())
 				return err
 			}
 		},
@@ -159,7 +186,7 @@ func waitForDeviceMounted(ctx context.Context, path string) error {
 
 	if err != nil {
 		err = fmt.Errorf("Timeout waiting for device to mount to %s: %w", path, err)
-		logger.Print(err)
+		logger.Error(err.Error())
 		return err
 	}
 
@@ -174,7 +201,7 @@ func (i *interceptor) StartContainer(ctx context.Context, req *pb.StartContainer
 	res, err := i.Redirector.StartContainer(ctx, req)
 
 	if err != nil {
-		logger.Printf("StartContainer failed with error: %v", err)
+		logger.Errorf("StartContainer failed with error: %v", err)
 	}
 
 	return res, err
@@ -187,7 +214,7 @@ func (i *interceptor) RemoveContainer(ctx context.Context, req *pb.RemoveContain
 	res, err := i.Redirector.RemoveContainer(ctx, req)
 
 	if err != nil {
-		logger.Printf("RemoveContainer failed with error: %v", err)
+		logger.Errorf("RemoveContainer failed with error: %v", err)
 	}
 	return res, err
 }
@@ -197,21 +224,20 @@ func (i *interceptor) CreateSandbox(ctx context.Context, req *pb.CreateSandboxRe
 	logger.Printf("CreateSandbox: hostname:%s sandboxId:%s", req.Hostname, req.SandboxId)
 
 	if len(req.Dns) > 0 {
-		logger.Print("    dns:")
+		logger.Debug("    dns:")
 		for _, d := range req.Dns {
-			logger.Printf("        %s", d)
+			logger.Debugf("        %s", d)
 		}
 
-		logger.Print("      Eliminated the DNS setting above from CreateSandboxRequest to stop updating /etc/resolv.conf on the peer pod VM")
-		logger.Print("      See https://github.com/confidential-containers/cloud-api-adaptor/issues/98 for the details.")
-		logger.Println()
+		logger.Debug("      Eliminated the DNS setting above from CreateSandboxRequest to stop updating /etc/resolv.conf on the peer pod VM")
+		logger.Debug("      See https://github.com/confidential-containers/cloud-api-adaptor/issues/98 for the details.")
 		req.Dns = nil
 	}
 
 	res, err := i.Redirector.CreateSandbox(ctx, req)
 
 	if err != nil {
-		logger.Printf("CreateSandbox failed with error: %v", err)
+		logger.Errorf("CreateSandbox failed with error: %v", err)
 	}
 
 	return res, err
@@ -224,7 +250,7 @@ func (i *interceptor) DestroySandbox(ctx context.Context, req *pb.DestroySandbox
 	res, err := i.Redirector.DestroySandbox(ctx, req)
 
 	if err != nil {
-		logger.Printf("DestroySandbox failed with error: %v", err)
+		logger.Errorf("DestroySandbox failed with error: %v", err)
 	}
 
 	return res, err
@@ -237,7 +263,7 @@ func (i *interceptor) PullImage(ctx context.Context, req *pb.PullImageRequest) (
 	res, err := i.Redirector.PullImage(ctx, req)
 
 	if err != nil {
-		logger.Printf("PullImage failed with error: %v", err)
+		logger.Errorf("PullImage failed with error: %v", err)
 	}
 
 	return res, err
@@ -4,9 +4,18 @@
 package interceptor
 
 import (
+	"context"
+	"os"
+	"path/filepath"
 	"testing"
 
+	pb "github.com/kata-containers/kata-containers/src/runtime/virtcontainers/pkg/agent/protocols/grpc"
+	"github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/agentproto"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/agentproto/agenttest"
 )
 
 func TestNewInterceptor(t *testing.T) {
@@ -27,3 +36,82 @@ func TestIsTargetPath(t *testing.T) {
 	assert.False(t, isTargetPath(path, "mock path"))
 	assert.True(t, isTargetPath(path, "/path/to/target"))
 }
+
+func TestCreateContainer(t *testing.T) {
+
+	dir := t.TempDir()
+
+	agent, err := agenttest.Start(filepath.Join(dir, "agent.sock"))
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, agent.Stop())
+	}()
+
+	nsPath := "/run/netns/podns"
+	mountSource := filepath.Join(dir, "volume")
+
+	i := NewInterceptor(agent.SocketPath(), nsPath)
+	defer i.Close()
+
+	req := &pb.CreateContainerRequest{
+		ContainerId: "123",
+		OCI: &pb.Spec{
+			Linux: &pb.Linux{
+				Namespaces: []pb.LinuxNamespace{
+					{Type: string(specs.PIDNamespace)},
+				},
+			},
+			Mounts: []pb.Mount{
+				{Source: mountSource, Destination: "/data", Type: "bind"},
+			},
+		},
+	}
+
+	_, err = i.CreateContainer(context.Background(), req)
+	require.NoError(t, err)
+
+	requests := agent.Requests("CreateContainer")
+	require.Len(t, requests, 1)
+
+	received := requests[0].(*pb.CreateContainerRequest)
+	assert.Equal(t, "123", received.ContainerId)
+	assert.Equal(t, []pb.LinuxNamespace{
+		{Type: string(specs.PIDNamespace)},
+		{Type: string(specs.NetworkNamespace), Path: nsPath},
+	}, received.OCI.Linux.Namespaces)
+
+	// A missing source of a bind mount is created
+	_, err = os.Stat(mountSource)
+	assert.NoError(t, err)
+}
+
+func TestCheckInterruption(t *testing.T) {
+
+	agent, err := agenttest.Start(filepath.Join(t.TempDir(), "agent.sock"))
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, agent.Stop())
+	}()
+
+	i := NewInterceptor(agent.SocketPath(), "")
+	defer i.Close()
+
+	ctx := context.Background()
+	interruption := &pb.CheckRequest{Service: agentproto.InterruptionService}
+
+	res, err := i.Check(ctx, interruption)
+	require.NoError(t, err)
+	assert.Equal(t, pb.HealthCheckResponse_SERVING, res.Status)
+
+	i.Interrupt()
+
+	res, err = i.Check(ctx, interruption)
+	require.NoError(t, err)
+	assert.Equal(t, pb.HealthCheckResponse_NOT_SERVING, res.Status)
+
+	// Other checks are answered by kata agent
+	res, err = i.Check(ctx, &pb.CheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, pb.HealthCheckResponse_SERVING, res.Status)
+	assert.Len(t, agent.Requests("Check"), 1)
+}
@@ -0,0 +1,101 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package interruption watches the instance metadata service of a pod VM on spot capacity
+// for interruption notices of the cloud provider.
+package interruption
+
+import (
+	"context"
+	"time"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/logging"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/metadata"
+)
+
+// DefaultPollInterval is the interval of requests to the instance metadata service. AWS notifies Spot instances
+// two minutes before interruption, and Azure notifies Spot VMs at least 30 seconds before eviction.
+const DefaultPollInterval = 5 * time.Second
+
+var logger = logging.New("forwarder/interruption")
+
+// noticeFunc returns an interruption notice if the instance is being interrupted, or an empty string otherwise
+type noticeFunc func(ctx context.Context) (string, error)
+
+// Watcher polls the instance metadata service of the cloud the pod VM runs on,
+// and calls onInterruption when the instance is being interrupted
+type Watcher struct {
+	interval       time.Duration
+	onInterruption func()
+	detect         func(ctx context.Context) noticeFunc
+	readyCh        chan struct{}
+}
+
+// NewWatcher returns a watcher that calls onInterruption once when the instance of the pod VM is being interrupted
+func NewWatcher(onInterruption func()) *Watcher {
+	return &Watcher{
+		interval:       DefaultPollInterval,
+		onInterruption: onInterruption,
+		detect:         detectCloud,
+		readyCh:        make(chan struct{}),
+	}
+}
+
+// detectCloud returns the interruption notice of the cloud the pod VM runs on, or nil if the cloud has none
+func detectCloud(ctx context.Context) noticeFunc {
+
+	if metadata.IsAzure(ctx) {
+		return func(ctx context.Context) (string, error) {
+			return metadata.GetPreemptEvent(ctx, metadata.AzureScheduledEventsImdsUrl)
+		}
+	}
+
+	if metadata.IsAWS(ctx) {
+		return func(ctx context.Context) (string, error) {
+			return metadata.GetSpotInstanceAction(ctx, metadata.AWSSpotInstanceActionImdsUrl)
+		}
+	}
+
+	return nil
+}
+
+func (w *Watcher) Start(ctx context.Context) error {
+
+	notice := w.detect(ctx)
+	close(w.readyCh)
+
+	if notice == nil {
+		logger.Warn("interruption notices are not supported on this cloud, an interrupted pod VM is detected as unreachable")
+		return nil
+	}
+
+	logger.Info("watching for interruption notices", "interval", w.interval)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		n, err := notice(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Debug("failed to get interruption notice", logging.KeyError, err)
+			}
+			continue
+		}
+		if n != "" {
+			logger.Warn("received an interruption notice", "notice", n)
+			w.onInterruption()
+			return nil
+		}
+	}
+}
+
+func (w *Watcher) Ready() chan struct{} {
+	return w.readyCh
+}
@@ -0,0 +1,82 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package interruption
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatcher(t *testing.T) {
+
+	notices := []string{"", "", "terminate"}
+	polls := 0
+
+	interrupted := make(chan struct{})
+	w := NewWatcher(func() { close(interrupted) })
+	w.interval = time.Millisecond
+	w.detect = func(ctx context.Context) noticeFunc {
+		return func(ctx context.Context) (string, error) {
+			polls++
+			if polls == 1 {
+				return "", errors.New("metadata service is unavailable")
+			}
+			return notices[polls-2], nil
+		}
+	}
+
+	errCh := make(chan error)
+	go func() {
+		errCh <- w.Start(context.Background())
+	}()
+
+	<-w.Ready()
+
+	select {
+	case <-interrupted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("watcher does not report interruption")
+	}
+	require.NoError(t, <-errCh)
+	assert.Equal(t, 4, polls)
+}
+
+func TestWatcherUnsupportedCloud(t *testing.T) {
+
+	w := NewWatcher(func() { t.Fatal("unexpected interruption") })
+	w.detect = func(ctx context.Context) noticeFunc { return nil }
+
+	require.NoError(t, w.Start(context.Background()))
+
+	select {
+	case <-w.Ready():
+	default:
+		t.Fatal("watcher is not ready")
+	}
+}
+
+func TestWatcherCancel(t *testing.T) {
+
+	w := NewWatcher(func() { t.Fatal("unexpected interruption") })
+	w.interval = time.Millisecond
+	w.detect = func(ctx context.Context) noticeFunc {
+		return func(ctx context.Context) (string, error) { return "", nil }
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error)
+	go func() {
+		errCh <- w.Start(ctx)
+	}()
+
+	<-w.Ready()
+	cancel()
+
+	require.NoError(t, <-errCh)
+}
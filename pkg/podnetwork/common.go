@@ -5,16 +5,16 @@ package podnetwork
 
 import (
 	"fmt"
-	"log"
 	"math"
 
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/podnetwork/tunneler"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/podnetwork/tunneler/routing"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/podnetwork/tunneler/vxlan"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/logging"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/netops"
 )
 
-var logger = log.New(log.Writer(), "[podnetwork] ", log.LstdFlags|log.Lmsgprefix)
+var logger = logging.New("podnetwork")
 
 func init() {
 	tunneler.Register("routing", routing.NewWorkerNodeTunneler, routing.NewPodNodeTunneler)
@@ -0,0 +1,200 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package podnetwork
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	PodIndexStoreMemory    = "memory"
+	PodIndexStoreFile      = "file"
+	PodIndexStoreConfigMap = "configmap"
+
+	DefaultPodIndexStore = PodIndexStoreFile
+	DefaultPodIndexFile  = "/run/peerpod/pod-index.json"
+)
+
+// ErrPodIndexExhausted is returned when no more pod index is available in the range of an allocator
+var ErrPodIndexExhausted = errors.New("pod index range is exhausted")
+
+// ErrPodIndexConflict is returned by a pod index store when indexes are modified by another writer since they were loaded
+var ErrPodIndexConflict = errors.New("pod indexes are modified concurrently")
+
+// maxPodIndexConflictRetries is the number of times an allocator reloads pod indexes on conflicts
+const maxPodIndexConflictRetries = 5
+
+// PodIndexAllocator manages a unique index number for each pod VM on a worker node.
+// The index number is used to derive a VXLAN ID of a pod VM.
+type PodIndexAllocator interface {
+	// Allocate returns an index number owned by owner. When owner already owns an index number, the same index is returned.
+	Allocate(owner string) (int, error)
+	// Release makes an index number available again
+	Release(index int) error
+}
+
+// PodIndexStore persists allocated pod indexes and their owners
+type PodIndexStore interface {
+	Load() (map[int]string, error)
+	// Save stores indexes. It may fail with ErrPodIndexConflict when the stored indexes are modified since the last Load.
+	Save(indexes map[int]string) error
+}
+
+type podIndexAllocator struct {
+	store    PodIndexStore
+	maxIndex int
+	mutex    sync.Mutex
+}
+
+// NewPodIndexAllocator returns a pod index allocator that hands out index numbers from 0 to maxIndex,
+// and persists allocated indexes in store
+func NewPodIndexAllocator(store PodIndexStore, maxIndex int) PodIndexAllocator {
+	return &podIndexAllocator{
+		store:    store,
+		maxIndex: maxIndex,
+	}
+}
+
+func (a *podIndexAllocator) Allocate(owner string) (int, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	allocated := -1
+	err := a.update(func(indexes map[int]string) (bool, error) {
+		for index, o := range indexes {
+			if o == owner {
+				allocated = index
+				return false, nil
+			}
+		}
+		for index := 0; index <= a.maxIndex; index++ {
+			if _, ok := indexes[index]; ok {
+				continue
+			}
+			indexes[index] = owner
+			allocated = index
+			return true, nil
+		}
+		return false, fmt.Errorf("%w: all %d indexes are in use", ErrPodIndexExhausted, a.maxIndex+1)
+	})
+	if err != nil {
+		return -1, err
+	}
+	return allocated, nil
+}
+
+func (a *podIndexAllocator) Release(index int) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return a.update(func(indexes map[int]string) (bool, error) {
+		if _, ok := indexes[index]; !ok {
+			logger.Warnf("pod index %d is not allocated", index)
+			return false, nil
+		}
+		delete(indexes, index)
+		return true, nil
+	})
+}
+
+// update loads indexes, modifies them with fn, and saves them if fn reports a change.
+// It starts over with freshly loaded indexes when the store reports a conflict.
+func (a *podIndexAllocator) update(fn func(indexes map[int]string) (changed bool, err error)) error {
+	for retries := 0; ; retries++ {
+		indexes, err := a.store.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load allocated pod indexes: %w", err)
+		}
+
+		changed, err := fn(indexes)
+		if err != nil || !changed {
+			return err
+		}
+
+		err = a.store.Save(indexes)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, ErrPodIndexConflict) || retries >= maxPodIndexConflictRetries {
+			return fmt.Errorf("failed to save allocated pod indexes: %w", err)
+		}
+		logger.Debugf("retrying to save pod indexes after a conflict: %v", err)
+	}
+}
+
+type memoryPodIndexStore struct {
+	indexes map[int]string
+}
+
+// NewMemoryPodIndexStore returns a pod index store that does not persist indexes across restarts
+func NewMemoryPodIndexStore() PodIndexStore {
+	return &memoryPodIndexStore{indexes: make(map[int]string)}
+}
+
+func (s *memoryPodIndexStore) Load() (map[int]string, error) {
+	indexes := make(map[int]string, len(s.indexes))
+	for index, owner := range s.indexes {
+		indexes[index] = owner
+	}
+	return indexes, nil
+}
+
+func (s *memoryPodIndexStore) Save(indexes map[int]string) error {
+	s.indexes = make(map[int]string, len(indexes))
+	for index, owner := range indexes {
+		s.indexes[index] = owner
+	}
+	return nil
+}
+
+type filePodIndexStore struct {
+	path string
+}
+
+// NewFilePodIndexStore returns a pod index store that persists indexes in a JSON file
+func NewFilePodIndexStore(path string) PodIndexStore {
+	return &filePodIndexStore{path: path}
+}
+
+func (s *filePodIndexStore) Load() (map[int]string, error) {
+	indexes := make(map[int]string)
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return indexes, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", s.path, err)
+	}
+
+	if err := json.Unmarshal(data, &indexes); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", s.path, err)
+	}
+	return indexes, nil
+}
+
+func (s *filePodIndexStore) Save(indexes map[int]string) error {
+	data, err := json.MarshalIndent(indexes, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to encode pod indexes: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create a directory for %s: %w", s.path, err)
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("failed to rename %s to %s: %w", tmpPath, s.path, err)
+	}
+	return nil
+}
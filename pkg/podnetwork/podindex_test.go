@@ -0,0 +1,72 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package podnetwork
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPodIndexAllocator(t *testing.T) {
+
+	allocator := NewPodIndexAllocator(NewMemoryPodIndexStore(), 2)
+
+	index0, err := allocator.Allocate("/run/netns/pod0")
+	require.NoError(t, err)
+	assert.Equal(t, 0, index0)
+
+	index1, err := allocator.Allocate("/run/netns/pod1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, index1)
+
+	// The same owner gets the same index
+	index, err := allocator.Allocate("/run/netns/pod0")
+	require.NoError(t, err)
+	assert.Equal(t, index0, index)
+
+	_, err = allocator.Allocate("/run/netns/pod2")
+	require.NoError(t, err)
+
+	_, err = allocator.Allocate("/run/netns/pod3")
+	assert.True(t, errors.Is(err, ErrPodIndexExhausted))
+
+	// A released index is reused
+	require.NoError(t, allocator.Release(index1))
+
+	index, err = allocator.Allocate("/run/netns/pod3")
+	require.NoError(t, err)
+	assert.Equal(t, index1, index)
+}
+
+func TestFilePodIndexStore(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "pod-index.json")
+
+	allocator := NewPodIndexAllocator(NewFilePodIndexStore(path), 100)
+
+	for _, owner := range []string{"/run/netns/pod0", "/run/netns/pod1", "/run/netns/pod2"} {
+		_, err := allocator.Allocate(owner)
+		require.NoError(t, err)
+	}
+	require.NoError(t, allocator.Release(1))
+
+	// Simulate a restart
+	allocator = NewPodIndexAllocator(NewFilePodIndexStore(path), 100)
+
+	index, err := allocator.Allocate("/run/netns/pod2")
+	require.NoError(t, err)
+	assert.Equal(t, 2, index)
+
+	index, err = allocator.Allocate("/run/netns/pod3")
+	require.NoError(t, err)
+	assert.Equal(t, 1, index)
+
+	index, err = allocator.Allocate("/run/netns/pod4")
+	require.NoError(t, err)
+	assert.Equal(t, 3, index)
+}
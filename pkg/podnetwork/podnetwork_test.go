@@ -83,7 +83,7 @@ func TestWorkerNode(t *testing.T) {
 
 		err := workerNodeNS.Run(func() error {
 
-			workerNode := NewWorkerNode(mockTunnelType, hostInterface, 0, 0)
+			workerNode := NewWorkerNode(mockTunnelType, hostInterface, 0, 0, nil)
 			require.NotNil(t, workerNode, "hostInterface=%q", hostInterface)
 
 			config, err := workerNode.Inspect(workerPodNS.Path())
@@ -47,7 +47,7 @@ func (n *podNode) Setup() error {
 	}
 	defer func() {
 		if err := hostNS.Close(); err != nil {
-			logger.Printf("failed to close the host network namespace: %v", err)
+			logger.Errorf("failed to close the host network namespace: %v", err)
 		}
 	}()
 
@@ -87,7 +87,7 @@ func (n *podNode) Setup() error {
 	}
 	defer func() {
 		if err := podNS.Close(); err != nil {
-			logger.Printf("failed to close a network namespace: %q", podNS.Path())
+			logger.Errorf("failed to close a network namespace: %q", podNS.Path())
 		}
 	}()
 
@@ -111,7 +111,7 @@ func (n *podNode) Teardown() error {
 	}
 	defer func() {
 		if err := hostNS.Close(); err != nil {
-			logger.Printf("failed to close the host network namespace: %v", err)
+			logger.Errorf("failed to close the host network namespace: %v", err)
 		}
 	}()
 
@@ -150,7 +150,7 @@ func detectPrimaryInterface(hostNS netops.Namespace, timeout time.Duration) (str
 		case <-ticker.C:
 		}
 
-		logger.Printf("failed to identify the host primary interface: %v (retrying...)", err)
+		logger.Warnf("failed to identify the host primary interface: %v (retrying...)", err)
 	}
 }
 
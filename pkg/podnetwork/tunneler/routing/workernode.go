@@ -6,18 +6,18 @@ package routing
 import (
 	"errors"
 	"fmt"
-	"log"
 	"net/netip"
 	"os"
 	"time"
 
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/podnetwork/tunneler"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/logging"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/netops"
 	"github.com/vishvananda/netlink"
 	"golang.org/x/sys/unix"
 )
 
-var logger = log.New(log.Writer(), "[tunneler/routing] ", log.LstdFlags|log.Lmsgprefix)
+var logger = logging.New("tunneler/routing")
 
 const (
 	sourceRouteTablePriority = 505
@@ -139,8 +139,8 @@ func (t *workerNodeTunneler) Setup(nsPath string, podNodeIPs []netip.Addr, confi
 	}
 
 	logger.Printf("Create a veth pair between host and Pod network namespace %s", nsPath)
-	logger.Printf("    Host: %s", veth.Name())
-	logger.Printf("    Pod:  %s", secondPodInterface)
+	logger.Debugf("    Host: %s", veth.Name())
+	logger.Debugf("    Pod:  %s", secondPodInterface)
 
 	podIP := config.PodIP
 	if !config.PodIP.IsValid() {
@@ -6,19 +6,20 @@ package vxlan
 import (
 	"errors"
 	"fmt"
-	"log"
 	"net/netip"
 	"os"
 
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/podnetwork/tunneler"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/logging"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/netops"
 )
 
-var logger = log.New(log.Writer(), "[tunneler/vxlan] ", log.LstdFlags|log.Lmsgprefix)
+var logger = logging.New("tunneler/vxlan")
 
 const (
 	DefaultVXLANPort         = 4789
 	DefaultVXLANMinID        = 555000
+	MaxVXLANID               = 1<<24 - 1
 	hostVxlanInterfacePrefix = "ppvxlan"
 	secondPodInterface       = "vxlan1"
 )
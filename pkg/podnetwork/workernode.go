@@ -6,9 +6,9 @@ package podnetwork
 import (
 	"fmt"
 	"net/netip"
-	"sync"
 
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/podnetwork/tunneler"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/podnetwork/tunneler/vxlan"
 	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/netops"
 )
 
@@ -18,6 +18,8 @@ type WorkerNode interface {
 	Inspect(nsPath string) (*tunneler.Config, error)
 	Setup(nsPath string, podNodeIPs []netip.Addr, config *tunneler.Config) error
 	Teardown(nsPath string, config *tunneler.Config) error
+	// Release frees resources allocated by Inspect for a pod network that is not set up
+	Release(config *tunneler.Config) error
 }
 
 type workerNode struct {
@@ -25,41 +27,43 @@ type workerNode struct {
 	hostInterface string
 	vxlanPort     int
 	vxlanMinID    int
+	podIndexes    PodIndexAllocator
 }
 
-// TODO: Pod index is reset when this process restarts.
-// We need to manage a persistent unique index number for each pod VM
-var podIndexManager podIndex
+// NewWorkerNode returns a worker node. When podIndexes is nil, pod indexes are managed in memory,
+// and they are reset when this process restarts.
+func NewWorkerNode(tunnelType, hostInterface string, vxlanPort, vxlanMinID int, podIndexes PodIndexAllocator) WorkerNode {
 
-type podIndex struct {
-	index int
-	mutex sync.Mutex
-}
-
-func (p *podIndex) Get() int {
-	p.mutex.Lock()
-	defer p.mutex.Unlock()
-
-	index := p.index
-	p.index++
-	return index
-}
-
-func NewWorkerNode(tunnelType, hostInterface string, vxlanPort, vxlanMinID int) WorkerNode {
+	if podIndexes == nil {
+		podIndexes = NewPodIndexAllocator(NewMemoryPodIndexStore(), vxlan.MaxVXLANID-vxlanMinID)
+	}
 
 	return &workerNode{
 		tunnelType:    tunnelType,
 		hostInterface: hostInterface,
 		vxlanPort:     vxlanPort,
 		vxlanMinID:    vxlanMinID,
+		podIndexes:    podIndexes,
 	}
 }
 
-func (n *workerNode) Inspect(nsPath string) (*tunneler.Config, error) {
+func (n *workerNode) Inspect(nsPath string) (config *tunneler.Config, err error) {
 
-	config := &tunneler.Config{
+	index, err := n.podIndexes.Allocate(nsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate a pod index for %s: %w", nsPath, err)
+	}
+	defer func() {
+		if err != nil {
+			if err := n.podIndexes.Release(index); err != nil {
+				logger.Errorf("failed to release pod index %d: %v", index, err)
+			}
+		}
+	}()
+
+	config = &tunneler.Config{
 		TunnelType: n.tunnelType,
-		Index:      podIndexManager.Get(),
+		Index:      index,
 	}
 
 	hostNS, err := netops.OpenCurrentNamespace()
@@ -68,7 +72,7 @@ func (n *workerNode) Inspect(nsPath string) (*tunneler.Config, error) {
 	}
 	defer func() {
 		if err := hostNS.Close(); err != nil {
-			logger.Printf("failed to close the host network namespace: %v", err)
+			logger.Errorf("failed to close the host network namespace: %v", err)
 		}
 	}()
 
@@ -94,7 +98,7 @@ func (n *workerNode) Inspect(nsPath string) (*tunneler.Config, error) {
 		return nil, fmt.Errorf("failed to get IP address on %s (netns: %s): %w", hostInterface, hostNS.Path(), err)
 	}
 	if len(addrs) != 1 {
-		logger.Printf("more than one IP address (%v) assigned on %s (netns: %s)", addrs, hostInterface, hostNS.Path())
+		logger.Warnf("more than one IP address (%v) assigned on %s (netns: %s)", addrs, hostInterface, hostNS.Path())
 	}
 	// Use the first IP as the workerNodeIP
 	// TBD: Might be faster to retrieve using K8s downward API
@@ -106,7 +110,7 @@ func (n *workerNode) Inspect(nsPath string) (*tunneler.Config, error) {
 	}
 	defer func() {
 		if err := podNS.Close(); err != nil {
-			logger.Printf("failed to close a network namespace: %q", podNS.Path())
+			logger.Errorf("failed to close a network namespace: %q", podNS.Path())
 		}
 	}()
 
@@ -120,7 +124,7 @@ func (n *workerNode) Inspect(nsPath string) (*tunneler.Config, error) {
 		return nil, err
 	}
 
-	logger.Printf("routes on netns %s", nsPath)
+	logger.Debugf("routes on netns %s", nsPath)
 	for _, r := range routes {
 		var dst, gw, dev string
 		if r.Destination.IsValid() {
@@ -134,7 +138,7 @@ func (n *workerNode) Inspect(nsPath string) (*tunneler.Config, error) {
 		if r.Device != "" {
 			dev = "dev " + r.Device
 		}
-		logger.Printf("    %s %s %s", dst, gw, dev)
+		logger.Debugf("    %s %s %s", dst, gw, dev)
 	}
 
 	podLink, err := podNS.LinkFind(podInterface)
@@ -150,7 +154,7 @@ func (n *workerNode) Inspect(nsPath string) (*tunneler.Config, error) {
 	config.PodIP = podIP
 	config.PodHwAddr, err = podLink.GetHardwareAddr()
 	if err != nil {
-		logger.Printf("failed to get Mac address of the Pod interface")
+		logger.Errorf("failed to get Mac address of the Pod interface")
 		return nil, fmt.Errorf("failed to get Mac address for Pod interface %s: %w", podInterface, err)
 	}
 
@@ -195,6 +199,13 @@ func (n *workerNode) Setup(nsPath string, podNodeIPs []netip.Addr, config *tunne
 
 func (n *workerNode) Teardown(nsPath string, config *tunneler.Config) error {
 
+	// Release the pod index even if the tunnel cannot be torn down, since the pod VM is being deleted
+	defer func() {
+		if err := n.Release(config); err != nil {
+			logger.Errorf("%v", err)
+		}
+	}()
+
 	tun, err := tunneler.WorkerNodeTunneler(n.tunnelType)
 	if err != nil {
 		return fmt.Errorf("failed to get tunneler: %w", err)
@@ -206,7 +217,7 @@ func (n *workerNode) Teardown(nsPath string, config *tunneler.Config) error {
 	}
 	defer func() {
 		if err := hostNS.Close(); err != nil {
-			logger.Printf("failed to close the host network namespace: %v", err)
+			logger.Errorf("failed to close the host network namespace: %v", err)
 		}
 	}()
 
@@ -226,6 +237,15 @@ func (n *workerNode) Teardown(nsPath string, config *tunneler.Config) error {
 	return nil
 }
 
+func (n *workerNode) Release(config *tunneler.Config) error {
+
+	if err := n.podIndexes.Release(config.Index); err != nil {
+		return fmt.Errorf("failed to release pod index %d: %w", config.Index, err)
+	}
+
+	return nil
+}
+
 func getPodIP(podLink netops.Link) (netip.Prefix, error) {
 
 	prefixes, err := podLink.GetAddr()
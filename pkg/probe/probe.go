@@ -4,13 +4,14 @@
 package probe
 
 import (
-	"log"
 	"net/http"
 	"os"
 	"time"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/logging"
 )
 
-var logger = log.New(log.Writer(), "[probe/probe] ", log.LstdFlags|log.Lmsgprefix)
+var logger = logging.New("probe/probe")
 var podsReadizProbesDone bool
 var checker Checker
 var startTime time.Time
@@ -0,0 +1,220 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package agenttest provides a stub kata agent for testing components that talk to kata agent over TTRPC.
+package agenttest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/containerd/ttrpc"
+	pb "github.com/kata-containers/kata-containers/src/runtime/virtcontainers/pkg/agent/protocols/grpc"
+)
+
+// AgentVersion is the agent version that the stub agent reports by default
+const AgentVersion = "agenttest"
+
+// Response is a scripted result of a method call
+type Response struct {
+	// Message is the response message. The default response of the method is used if nil
+	Message interface{}
+	// Err is returned to the client instead of a response message if not nil
+	Err error
+	// Delay is the time to wait before responding
+	Delay time.Duration
+}
+
+// Request is a request received by the stub agent
+type Request struct {
+	// Method is the name of the method, such as "CreateContainer" and "PullImage"
+	Method string
+	// Message is the decoded request message, such as *pb.CreateContainerRequest
+	Message interface{}
+}
+
+// Agent is a stub kata agent that serves AgentService, Image and Health services on a unix socket.
+// By default, every method succeeds with an empty response. Responses can be scripted per method,
+// and all received requests are recorded.
+type Agent struct {
+	socketPath string
+	server     *ttrpc.Server
+	listener   net.Listener
+	errCh      chan error
+	stopOnce   sync.Once
+
+	mutex     sync.Mutex
+	responses map[string][]Response
+	requests  []Request
+}
+
+// Start starts a stub kata agent listening on socketPath
+func Start(socketPath string) (*Agent, error) {
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", socketPath, err)
+	}
+
+	server, err := ttrpc.NewServer()
+	if err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to create TTRPC server: %w", err)
+	}
+
+	agent := &Agent{
+		socketPath: socketPath,
+		server:     server,
+		listener:   listener,
+		errCh:      make(chan error, 1),
+		responses:  make(map[string][]Response),
+	}
+
+	pb.RegisterAgentServiceService(server, agent)
+	pb.RegisterImageService(server, agent)
+	pb.RegisterHealthService(server, agent)
+
+	go func() {
+		defer close(agent.errCh)
+
+		if err := server.Serve(context.Background(), listener); err != nil && !errors.Is(err, ttrpc.ErrServerClosed) {
+			agent.errCh <- err
+		}
+	}()
+
+	return agent, nil
+}
+
+// SocketPath returns the path of the unix socket that the stub agent listens on
+func (a *Agent) SocketPath() string {
+	return a.socketPath
+}
+
+// Script sets responses of method. The responses are returned in order, and the last one is repeated.
+// Calling Script with no responses restores the default response.
+func (a *Agent) Script(method string, responses ...Response) {
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if len(responses) == 0 {
+		delete(a.responses, method)
+		return
+	}
+	a.responses[method] = append([]Response(nil), responses...)
+}
+
+// Fail makes method return err
+func (a *Agent) Fail(method string, err error) {
+	a.Script(method, Response{Err: err})
+}
+
+// Requests returns messages of the requests of method in order of arrival
+func (a *Agent) Requests(method string) []interface{} {
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	var messages []interface{}
+	for _, req := range a.requests {
+		if req.Method == method {
+			messages = append(messages, req.Message)
+		}
+	}
+	return messages
+}
+
+// Calls returns all requests in order of arrival
+func (a *Agent) Calls() []Request {
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return append([]Request(nil), a.requests...)
+}
+
+// Reset clears recorded requests and scripted responses
+func (a *Agent) Reset() {
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.requests = nil
+	a.responses = make(map[string][]Response)
+}
+
+// Stop stops the stub agent
+func (a *Agent) Stop() error {
+
+	var err error
+
+	a.stopOnce.Do(func() {
+		if e := a.server.Close(); e != nil {
+			err = e
+		}
+		// The server does not know the listener yet, if Serve has not started
+		if e := a.listener.Close(); e != nil && !errors.Is(e, net.ErrClosed) {
+			err = e
+		}
+		if e := <-a.errCh; e != nil {
+			err = e
+		}
+	})
+
+	return err
+}
+
+// next records a request and returns the response to it
+func (a *Agent) next(method string, req interface{}) Response {
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.requests = append(a.requests, Request{Method: method, Message: req})
+
+	responses := a.responses[method]
+	if len(responses) == 0 {
+		return Response{}
+	}
+	if len(responses) > 1 {
+		a.responses[method] = responses[1:]
+	}
+	return responses[0]
+}
+
+// handle returns a response of method to req, or defaultRes if no response message is scripted
+func handle[T any](ctx context.Context, a *Agent, method string, req interface{}, defaultRes T) (T, error) {
+
+	var zero T
+
+	res := a.next(method, req)
+
+	if res.Delay > 0 {
+		timer := time.NewTimer(res.Delay)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if res.Err != nil {
+		return zero, res.Err
+	}
+
+	if res.Message == nil {
+		return defaultRes, nil
+	}
+
+	msg, ok := res.Message.(T)
+	if !ok {
+		return zero, fmt.Errorf("scripted response of %s is %T, not %T", method, res.Message, zero)
+	}
+	return msg, nil
+}
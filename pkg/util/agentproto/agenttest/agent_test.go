@@ -0,0 +1,130 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package agenttest
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/containerd/ttrpc"
+	pb "github.com/kata-containers/kata-containers/src/runtime/virtcontainers/pkg/agent/protocols/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startAgent(t *testing.T) (*Agent, *ttrpc.Client) {
+
+	agent, err := Start(filepath.Join(t.TempDir(), "agent.sock"))
+	require.NoError(t, err)
+
+	conn, err := net.Dial("unix", agent.SocketPath())
+	require.NoError(t, err)
+
+	client := ttrpc.NewClient(conn)
+
+	t.Cleanup(func() {
+		client.Close()
+		assert.NoError(t, agent.Stop())
+	})
+
+	return agent, client
+}
+
+func TestDefaultResponses(t *testing.T) {
+
+	ctx := context.Background()
+	agent, client := startAgent(t)
+
+	check, err := pb.NewHealthClient(client).Check(ctx, &pb.CheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, pb.HealthCheckResponse_SERVING, check.Status)
+
+	version, err := pb.NewHealthClient(client).Version(ctx, &pb.CheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, AgentVersion, version.AgentVersion)
+
+	_, err = pb.NewAgentServiceClient(client).CreateContainer(ctx, &pb.CreateContainerRequest{ContainerId: "123"})
+	require.NoError(t, err)
+
+	_, err = pb.NewImageClient(client).PullImage(ctx, &pb.PullImageRequest{Image: "nginx"})
+	require.NoError(t, err)
+
+	calls := agent.Calls()
+	require.Len(t, calls, 4)
+	assert.Equal(t, []string{"Check", "Version", "CreateContainer", "PullImage"}, []string{calls[0].Method, calls[1].Method, calls[2].Method, calls[3].Method})
+
+	requests := agent.Requests("CreateContainer")
+	require.Len(t, requests, 1)
+	assert.Equal(t, "123", requests[0].(*pb.CreateContainerRequest).ContainerId)
+}
+
+func TestScript(t *testing.T) {
+
+	ctx := context.Background()
+	agent, client := startAgent(t)
+	imageClient := pb.NewImageClient(client)
+
+	agent.Script("PullImage",
+		Response{Message: &pb.PullImageResponse{ImageRef: "first"}},
+		Response{Message: &pb.PullImageResponse{ImageRef: "second"}},
+	)
+
+	for _, expected := range []string{"first", "second", "second"} {
+		res, err := imageClient.PullImage(ctx, &pb.PullImageRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, expected, res.ImageRef)
+	}
+
+	agent.Script("PullImage")
+
+	res, err := imageClient.PullImage(ctx, &pb.PullImageRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, res.ImageRef)
+
+	agent.Script("PullImage", Response{Message: &pb.CheckRequest{}})
+
+	_, err = imageClient.PullImage(ctx, &pb.PullImageRequest{})
+	assert.Error(t, err, "scripted response of a wrong type")
+
+	agent.Reset()
+	assert.Empty(t, agent.Calls())
+}
+
+func TestFail(t *testing.T) {
+
+	ctx := context.Background()
+	agent, client := startAgent(t)
+
+	agent.Fail("StartContainer", errors.New("container not found"))
+
+	_, err := pb.NewAgentServiceClient(client).StartContainer(ctx, &pb.StartContainerRequest{ContainerId: "123"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "container not found")
+
+	assert.Len(t, agent.Requests("StartContainer"), 1)
+}
+
+func TestDelay(t *testing.T) {
+
+	agent, client := startAgent(t)
+
+	agent.Script("CreateSandbox", Response{Delay: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := pb.NewAgentServiceClient(client).CreateSandbox(ctx, &pb.CreateSandboxRequest{})
+	assert.Error(t, err)
+
+	agent.Script("CreateSandbox", Response{Delay: 10 * time.Millisecond})
+
+	start := time.Now()
+	_, err = pb.NewAgentServiceClient(client).CreateSandbox(context.Background(), &pb.CreateSandboxRequest{})
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond)
+}
@@ -0,0 +1,184 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package agenttest
+
+import (
+	"context"
+
+	"github.com/gogo/protobuf/types"
+	"github.com/kata-containers/kata-containers/src/runtime/virtcontainers/pkg/agent/protocols"
+	pb "github.com/kata-containers/kata-containers/src/runtime/virtcontainers/pkg/agent/protocols/grpc"
+)
+
+var (
+	_ pb.AgentServiceService = (*Agent)(nil)
+	_ pb.ImageService        = (*Agent)(nil)
+	_ pb.HealthService       = (*Agent)(nil)
+)
+
+// AgentServiceService methods
+
+func (a *Agent) CreateContainer(ctx context.Context, req *pb.CreateContainerRequest) (*types.Empty, error) {
+	return handle(ctx, a, "CreateContainer", req, &types.Empty{})
+}
+
+func (a *Agent) StartContainer(ctx context.Context, req *pb.StartContainerRequest) (*types.Empty, error) {
+	return handle(ctx, a, "StartContainer", req, &types.Empty{})
+}
+
+func (a *Agent) RemoveContainer(ctx context.Context, req *pb.RemoveContainerRequest) (*types.Empty, error) {
+	return handle(ctx, a, "RemoveContainer", req, &types.Empty{})
+}
+
+func (a *Agent) ExecProcess(ctx context.Context, req *pb.ExecProcessRequest) (*types.Empty, error) {
+	return handle(ctx, a, "ExecProcess", req, &types.Empty{})
+}
+
+func (a *Agent) SignalProcess(ctx context.Context, req *pb.SignalProcessRequest) (*types.Empty, error) {
+	return handle(ctx, a, "SignalProcess", req, &types.Empty{})
+}
+
+func (a *Agent) WaitProcess(ctx context.Context, req *pb.WaitProcessRequest) (*pb.WaitProcessResponse, error) {
+	return handle(ctx, a, "WaitProcess", req, &pb.WaitProcessResponse{})
+}
+
+func (a *Agent) UpdateContainer(ctx context.Context, req *pb.UpdateContainerRequest) (*types.Empty, error) {
+	return handle(ctx, a, "UpdateContainer", req, &types.Empty{})
+}
+
+func (a *Agent) UpdateEphemeralMounts(ctx context.Context, req *pb.UpdateEphemeralMountsRequest) (*types.Empty, error) {
+	return handle(ctx, a, "UpdateEphemeralMounts", req, &types.Empty{})
+}
+
+func (a *Agent) StatsContainer(ctx context.Context, req *pb.StatsContainerRequest) (*pb.StatsContainerResponse, error) {
+	return handle(ctx, a, "StatsContainer", req, &pb.StatsContainerResponse{})
+}
+
+func (a *Agent) PauseContainer(ctx context.Context, req *pb.PauseContainerRequest) (*types.Empty, error) {
+	return handle(ctx, a, "PauseContainer", req, &types.Empty{})
+}
+
+func (a *Agent) ResumeContainer(ctx context.Context, req *pb.ResumeContainerRequest) (*types.Empty, error) {
+	return handle(ctx, a, "ResumeContainer", req, &types.Empty{})
+}
+
+func (a *Agent) WriteStdin(ctx context.Context, req *pb.WriteStreamRequest) (*pb.WriteStreamResponse, error) {
+	return handle(ctx, a, "WriteStdin", req, &pb.WriteStreamResponse{})
+}
+
+func (a *Agent) ReadStdout(ctx context.Context, req *pb.ReadStreamRequest) (*pb.ReadStreamResponse, error) {
+	return handle(ctx, a, "ReadStdout", req, &pb.ReadStreamResponse{})
+}
+
+func (a *Agent) ReadStderr(ctx context.Context, req *pb.ReadStreamRequest) (*pb.ReadStreamResponse, error) {
+	return handle(ctx, a, "ReadStderr", req, &pb.ReadStreamResponse{})
+}
+
+func (a *Agent) CloseStdin(ctx context.Context, req *pb.CloseStdinRequest) (*types.Empty, error) {
+	return handle(ctx, a, "CloseStdin", req, &types.Empty{})
+}
+
+func (a *Agent) TtyWinResize(ctx context.Context, req *pb.TtyWinResizeRequest) (*types.Empty, error) {
+	return handle(ctx, a, "TtyWinResize", req, &types.Empty{})
+}
+
+func (a *Agent) UpdateInterface(ctx context.Context, req *pb.UpdateInterfaceRequest) (*protocols.Interface, error) {
+	return handle(ctx, a, "UpdateInterface", req, &protocols.Interface{})
+}
+
+func (a *Agent) UpdateRoutes(ctx context.Context, req *pb.UpdateRoutesRequest) (*pb.Routes, error) {
+	return handle(ctx, a, "UpdateRoutes", req, &pb.Routes{})
+}
+
+func (a *Agent) ListInterfaces(ctx context.Context, req *pb.ListInterfacesRequest) (*pb.Interfaces, error) {
+	return handle(ctx, a, "ListInterfaces", req, &pb.Interfaces{})
+}
+
+func (a *Agent) ListRoutes(ctx context.Context, req *pb.ListRoutesRequest) (*pb.Routes, error) {
+	return handle(ctx, a, "ListRoutes", req, &pb.Routes{})
+}
+
+func (a *Agent) AddARPNeighbors(ctx context.Context, req *pb.AddARPNeighborsRequest) (*types.Empty, error) {
+	return handle(ctx, a, "AddARPNeighbors", req, &types.Empty{})
+}
+
+func (a *Agent) GetIPTables(ctx context.Context, req *pb.GetIPTablesRequest) (*pb.GetIPTablesResponse, error) {
+	return handle(ctx, a, "GetIPTables", req, &pb.GetIPTablesResponse{})
+}
+
+func (a *Agent) SetIPTables(ctx context.Context, req *pb.SetIPTablesRequest) (*pb.SetIPTablesResponse, error) {
+	return handle(ctx, a, "SetIPTables", req, &pb.SetIPTablesResponse{})
+}
+
+func (a *Agent) GetMetrics(ctx context.Context, req *pb.GetMetricsRequest) (*pb.Metrics, error) {
+	return handle(ctx, a, "GetMetrics", req, &pb.Metrics{})
+}
+
+func (a *Agent) CreateSandbox(ctx context.Context, req *pb.CreateSandboxRequest) (*types.Empty, error) {
+	return handle(ctx, a, "CreateSandbox", req, &types.Empty{})
+}
+
+func (a *Agent) DestroySandbox(ctx context.Context, req *pb.DestroySandboxRequest) (*types.Empty, error) {
+	return handle(ctx, a, "DestroySandbox", req, &types.Empty{})
+}
+
+func (a *Agent) OnlineCPUMem(ctx context.Context, req *pb.OnlineCPUMemRequest) (*types.Empty, error) {
+	return handle(ctx, a, "OnlineCPUMem", req, &types.Empty{})
+}
+
+func (a *Agent) ReseedRandomDev(ctx context.Context, req *pb.ReseedRandomDevRequest) (*types.Empty, error) {
+	return handle(ctx, a, "ReseedRandomDev", req, &types.Empty{})
+}
+
+func (a *Agent) GetGuestDetails(ctx context.Context, req *pb.GuestDetailsRequest) (*pb.GuestDetailsResponse, error) {
+	return handle(ctx, a, "GetGuestDetails", req, &pb.GuestDetailsResponse{})
+}
+
+func (a *Agent) MemHotplugByProbe(ctx context.Context, req *pb.MemHotplugByProbeRequest) (*types.Empty, error) {
+	return handle(ctx, a, "MemHotplugByProbe", req, &types.Empty{})
+}
+
+func (a *Agent) SetGuestDateTime(ctx context.Context, req *pb.SetGuestDateTimeRequest) (*types.Empty, error) {
+	return handle(ctx, a, "SetGuestDateTime", req, &types.Empty{})
+}
+
+func (a *Agent) CopyFile(ctx context.Context, req *pb.CopyFileRequest) (*types.Empty, error) {
+	return handle(ctx, a, "CopyFile", req, &types.Empty{})
+}
+
+func (a *Agent) GetOOMEvent(ctx context.Context, req *pb.GetOOMEventRequest) (*pb.OOMEvent, error) {
+	return handle(ctx, a, "GetOOMEvent", req, &pb.OOMEvent{})
+}
+
+func (a *Agent) AddSwap(ctx context.Context, req *pb.AddSwapRequest) (*types.Empty, error) {
+	return handle(ctx, a, "AddSwap", req, &types.Empty{})
+}
+
+func (a *Agent) GetVolumeStats(ctx context.Context, req *pb.VolumeStatsRequest) (*pb.VolumeStatsResponse, error) {
+	return handle(ctx, a, "GetVolumeStats", req, &pb.VolumeStatsResponse{})
+}
+
+func (a *Agent) ResizeVolume(ctx context.Context, req *pb.ResizeVolumeRequest) (*types.Empty, error) {
+	return handle(ctx, a, "ResizeVolume", req, &types.Empty{})
+}
+
+func (a *Agent) RemoveStaleVirtiofsShareMounts(ctx context.Context, req *pb.RemoveStaleVirtiofsShareMountsRequest) (*types.Empty, error) {
+	return handle(ctx, a, "RemoveStaleVirtiofsShareMounts", req, &types.Empty{})
+}
+
+// ImageService methods
+
+func (a *Agent) PullImage(ctx context.Context, req *pb.PullImageRequest) (*pb.PullImageResponse, error) {
+	return handle(ctx, a, "PullImage", req, &pb.PullImageResponse{})
+}
+
+// HealthService methods
+
+func (a *Agent) Check(ctx context.Context, req *pb.CheckRequest) (*pb.HealthCheckResponse, error) {
+	return handle(ctx, a, "Check", req, &pb.HealthCheckResponse{Status: pb.HealthCheckResponse_SERVING})
+}
+
+func (a *Agent) Version(ctx context.Context, req *pb.CheckRequest) (*pb.VersionCheckResponse, error) {
+	return handle(ctx, a, "Version", req, &pb.VersionCheckResponse{AgentVersion: AgentVersion, GrpcVersion: pb.APIVersion})
+}
@@ -9,14 +9,73 @@ import (
 	"fmt"
 	"net"
 	"sync"
+	"time"
 
 	"github.com/containerd/ttrpc"
 	"github.com/gogo/protobuf/types"
 	"github.com/kata-containers/kata-containers/src/runtime/virtcontainers/pkg/agent/protocols"
 
 	pb "github.com/kata-containers/kata-containers/src/runtime/virtcontainers/pkg/agent/protocols/grpc"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/logging"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/tracing"
+)
+
+const (
+	// DefaultKeepaliveInterval is the recommended interval of keepalive requests
+	DefaultKeepaliveInterval = 30 * time.Second
+
+	defaultKeepaliveTimeout = 10 * time.Second
+	minRedialBackoff        = 100 * time.Millisecond
+	maxRedialBackoff        = 10 * time.Second
 )
 
+// InterruptionService is the service of HealthService.Check requests with which cloud-api-adaptor asks
+// agent-protocol-forwarder whether the cloud provider interrupts the spot instance of a pod VM.
+// The response status is NOT_SERVING when the instance is being interrupted.
+const InterruptionService = "kata.peerpods.io/interruption"
+
+var logger = logging.New("agentproto")
+
+// LinkState is the state of the connection to kata agent
+type LinkState int32
+
+const (
+	// LinkDown means that the connection is not established yet, or is broken and will be re-established
+	LinkDown LinkState = iota
+	// LinkUp means that the connection is established
+	LinkUp
+	// LinkClosed means that the redirector is closed
+	LinkClosed
+)
+
+func (s LinkState) String() string {
+	switch s {
+	case LinkDown:
+		return "down"
+	case LinkUp:
+		return "up"
+	case LinkClosed:
+		return "closed"
+	}
+	return fmt.Sprintf("unknown(%d)", int32(s))
+}
+
+// readOnlyMethods are methods that are safe to call again when their connection is broken
+// before a response is received. A request that changes the state of a pod VM, e.g. SetIPTables
+// or PullImage, may have been applied before the connection is broken, so it is never replayed.
+var readOnlyMethods = map[string]bool{
+	"Check":           true,
+	"Version":         true,
+	"StatsContainer":  true,
+	"ListInterfaces":  true,
+	"ListRoutes":      true,
+	"GetIPTables":     true,
+	"GetMetrics":      true,
+	"GetGuestDetails": true,
+	"GetVolumeStats":  true,
+}
+
 type Redirector interface {
 	pb.AgentServiceService
 	pb.ImageService
@@ -26,387 +85,611 @@ type Redirector interface {
 	Close() error
 }
 
+// RedirectorConfig is a configuration of a redirector
+type RedirectorConfig struct {
+	// KeepaliveInterval is the interval of HealthService.Check requests sent to detect a broken connection.
+	// Keepalive is disabled if zero.
+	KeepaliveInterval time.Duration
+	// KeepaliveTimeout is the time to wait for a response to a keepalive request
+	KeepaliveTimeout time.Duration
+	// OnLinkStateChange is called when the state of the connection changes.
+	// It must not call methods of the redirector.
+	OnLinkStateChange func(state LinkState)
+	// OnInterruption is called once when a keepalive response reports that the instance of the pod VM is interrupted.
+	// It must not call methods of the redirector.
+	OnInterruption func()
+}
+
 type redirector struct {
-	agentClient *client
-	ttrpcClient *ttrpc.Client
-	dialer      func(context.Context) (net.Conn, error)
-	once        sync.Once
+	dialer func(context.Context) (net.Conn, error)
+	config RedirectorConfig
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mutex         sync.Mutex
+	agentClient   *client
+	state         LinkState
+	dialing       chan struct{}
+	failures      int
+	redialAt      time.Time
+	keepaliveOnce sync.Once
+	interruptOnce sync.Once
 }
 
 type client struct {
 	pb.AgentServiceService
 	pb.ImageService
 	pb.HealthService
+
+	ttrpcClient *ttrpc.Client
 }
 
+// NewRedirector returns a redirector that connects to kata agent using dialer.
+// A broken connection is re-established on the next request.
 func NewRedirector(dialer func(context.Context) (net.Conn, error)) Redirector {
 
-	return &redirector{
+	return NewRedirectorWithConfig(dialer, nil)
+}
+
+// NewRedirectorWithConfig returns a redirector like NewRedirector. In addition, the redirector
+// sends keepalive requests, and reports the state of the connection as specified in config.
+func NewRedirectorWithConfig(dialer func(context.Context) (net.Conn, error), config *RedirectorConfig) Redirector {
+
+	s := &redirector{
 		dialer: dialer,
 	}
+	if config != nil {
+		s.config = *config
+	}
+	if s.config.KeepaliveTimeout == 0 {
+		s.config.KeepaliveTimeout = defaultKeepaliveTimeout
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+
+	return s
 }
 
 func (s *redirector) Connect(ctx context.Context) error {
 
-	var err error
+	_, err := s.connect(ctx)
+	return err
+}
 
-	s.once.Do(func() {
+// connect returns the current agent client, or establishes a new connection if there is no connection.
+// Concurrent callers share a single dial.
+func (s *redirector) connect(ctx context.Context) (*client, error) {
 
-		conn, e := s.dialer(ctx)
-		if e != nil {
-			err = e
-			return
+	for {
+		s.mutex.Lock()
+
+		if s.state == LinkClosed {
+			s.mutex.Unlock()
+			return nil, errors.New("agent connection is closed")
+		}
+
+		if s.agentClient != nil {
+			c := s.agentClient
+			s.mutex.Unlock()
+			return c, nil
 		}
 
-		s.ttrpcClient = ttrpc.NewClient(conn)
+		if dialing := s.dialing; dialing != nil {
+			s.mutex.Unlock()
 
-		s.agentClient = &client{
-			AgentServiceService: pb.NewAgentServiceClient(s.ttrpcClient),
-			ImageService:        pb.NewImageClient(s.ttrpcClient),
-			HealthService:       pb.NewHealthClient(s.ttrpcClient),
+			select {
+			case <-ctx.Done():
+				return nil, fmt.Errorf("agent connection is not established: %w", ctx.Err())
+			case <-dialing:
+			}
+			continue
 		}
-	})
 
+		dialing := make(chan struct{})
+		s.dialing = dialing
+		backoff := time.Until(s.redialAt)
+
+		s.mutex.Unlock()
+
+		c, err := s.dial(ctx, backoff)
+
+		s.mutex.Lock()
+		s.dialing = nil
+		close(dialing)
+		s.mutex.Unlock()
+
+		if err != nil {
+			return nil, fmt.Errorf("agent connection is not established: %w", err)
+		}
+
+		return c, nil
+	}
+}
+
+// dial waits for backoff, and establishes a new connection
+func (s *redirector) dial(ctx context.Context, backoff time.Duration) (*client, error) {
+
+	if backoff > 0 {
+		timer := time.NewTimer(backoff)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	conn, err := s.dialer(ctx)
 	if err != nil {
-		return fmt.Errorf("agent connection is not established: %w", err)
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		// Redial with exponential backoff while the agent is unreachable
+		delay := maxRedialBackoff
+		if s.failures < 7 {
+			delay = minRedialBackoff << s.failures
+		}
+		if delay > maxRedialBackoff {
+			delay = maxRedialBackoff
+		}
+		s.failures++
+		s.redialAt = time.Now().Add(delay)
+
+		return nil, err
 	}
 
-	if s.agentClient == nil {
-		return errors.New("agent connection is not established")
+	closedCh := make(chan struct{})
+
+	ttrpcClient := ttrpc.NewClient(conn,
+		ttrpc.WithUnaryClientInterceptor(tracing.UnaryClientInterceptor()),
+		ttrpc.WithOnClose(func() { close(closedCh) }),
+	)
+
+	c := &client{
+		AgentServiceService: pb.NewAgentServiceClient(ttrpcClient),
+		ImageService:        pb.NewImageClient(ttrpcClient),
+		HealthService:       pb.NewHealthClient(ttrpcClient),
+		ttrpcClient:         ttrpcClient,
 	}
 
-	return nil
-}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 
-func (s *redirector) Close() error {
-	client := s.ttrpcClient
-	if client == nil {
-		return nil
+	if s.state == LinkClosed {
+		ttrpcClient.Close()
+		return nil, errors.New("agent connection is closed")
 	}
-	return client.Close()
-}
 
-// AgentServiceService methods
+	s.agentClient = c
+	s.failures = 0
+	s.redialAt = time.Time{}
+	s.setState(LinkUp)
 
-func (s *redirector) CreateContainer(ctx context.Context, req *pb.CreateContainerRequest) (res *types.Empty, err error) {
+	go func() {
+		<-closedCh
+		s.disconnect(c)
+	}()
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
+	if s.config.KeepaliveInterval > 0 {
+		s.keepaliveOnce.Do(func() {
+			go s.keepalive()
+		})
 	}
-	return s.agentClient.CreateContainer(ctx, req)
+
+	return c, nil
 }
 
-func (s *redirector) StartContainer(ctx context.Context, req *pb.StartContainerRequest) (res *types.Empty, err error) {
+// disconnect closes c if it is the current agent client, so that the next request establishes a new connection
+func (s *redirector) disconnect(c *client) {
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
+	s.mutex.Lock()
+
+	if s.agentClient != c {
+		s.mutex.Unlock()
+		return
 	}
-	return s.agentClient.StartContainer(ctx, req)
+
+	s.agentClient = nil
+	s.setState(LinkDown)
+
+	s.mutex.Unlock()
+
+	logger.Warn("agent connection is broken")
+
+	c.ttrpcClient.Close()
 }
 
-func (s *redirector) RemoveContainer(ctx context.Context, req *pb.RemoveContainerRequest) (res *types.Empty, err error) {
+// setState must be called with s.mutex held
+func (s *redirector) setState(state LinkState) {
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
+	if s.state == state {
+		return
+	}
+	s.state = state
+
+	if s.config.OnLinkStateChange != nil {
+		s.config.OnLinkStateChange(state)
 	}
-	return s.agentClient.RemoveContainer(ctx, req)
 }
 
-func (s *redirector) ExecProcess(ctx context.Context, req *pb.ExecProcessRequest) (res *types.Empty, err error) {
+// keepalive periodically sends HealthService.Check requests to detect a broken connection, and re-establishes it.
+// The requests also ask for interruption of the instance of the pod VM.
+func (s *redirector) keepalive() {
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
+	ticker := time.NewTicker(s.config.KeepaliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		s.mutex.Lock()
+		c := s.agentClient
+		s.mutex.Unlock()
+
+		if c == nil {
+			ctx, cancel := context.WithTimeout(s.ctx, s.config.KeepaliveInterval)
+			if _, err := s.connect(ctx); err != nil && s.ctx.Err() == nil {
+				logger.Debug("failed to re-establish agent connection", logging.KeyError, err)
+			}
+			cancel()
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(s.ctx, s.config.KeepaliveTimeout)
+		res, err := c.Check(ctx, &pb.CheckRequest{Service: InterruptionService})
+		cancel()
+
+		if err == nil && res.Status == pb.HealthCheckResponse_NOT_SERVING && s.config.OnInterruption != nil {
+			s.interruptOnce.Do(s.config.OnInterruption)
+		}
+
+		if err != nil && s.ctx.Err() == nil && (errors.Is(err, ttrpc.ErrClosed) || errors.Is(err, context.DeadlineExceeded)) {
+			logger.Warn("agent keepalive failed", logging.KeyError, err)
+			s.disconnect(c)
+		}
 	}
-	return s.agentClient.ExecProcess(ctx, req)
 }
 
-func (s *redirector) SignalProcess(ctx context.Context, req *pb.SignalProcessRequest) (res *types.Empty, err error) {
+func (s *redirector) Close() error {
+
+	s.mutex.Lock()
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
+	if s.state == LinkClosed {
+		s.mutex.Unlock()
+		return nil
 	}
-	return s.agentClient.SignalProcess(ctx, req)
-}
 
-func (s *redirector) WaitProcess(ctx context.Context, req *pb.WaitProcessRequest) (res *pb.WaitProcessResponse, err error) {
+	c := s.agentClient
+	s.agentClient = nil
+	s.setState(LinkClosed)
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
+	s.mutex.Unlock()
+
+	s.cancel()
+
+	if c == nil {
+		return nil
 	}
-	return s.agentClient.WaitProcess(ctx, req)
+	return c.ttrpcClient.Close()
 }
 
-func (s *redirector) UpdateContainer(ctx context.Context, req *pb.UpdateContainerRequest) (res *types.Empty, err error) {
+// call calls method using the current agent client. If the connection is broken, the method is
+// called again with a new connection only if the method is read-only.
+func call[T any](ctx context.Context, s *redirector, method string, f func(c *client) (T, error)) (T, error) {
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
+	var zero T
+
+	c, err := s.connect(ctx)
+	if err != nil {
+		return zero, err
 	}
-	return s.agentClient.UpdateContainer(ctx, req)
-}
 
-func (s *redirector) UpdateEphemeralMounts(ctx context.Context, req *pb.UpdateEphemeralMountsRequest) (res *types.Empty, err error) {
+	res, err := f(c)
+	if err == nil || !errors.Is(err, ttrpc.ErrClosed) {
+		return res, err
+	}
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
+	s.disconnect(c)
+
+	if !readOnlyMethods[method] {
+		return res, err
 	}
-	return s.agentClient.UpdateEphemeralMounts(ctx, req)
-}
 
-func (s *redirector) StatsContainer(ctx context.Context, req *pb.StatsContainerRequest) (res *pb.StatsContainerResponse, err error) {
+	logger.WithContext(ctx).Info("replaying a request on a new agent connection", "method", method)
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
+	if c, err = s.connect(ctx); err != nil {
+		return zero, err
 	}
-	return s.agentClient.StatsContainer(ctx, req)
+	return f(c)
 }
 
-func (s *redirector) PauseContainer(ctx context.Context, req *pb.PauseContainerRequest) (res *types.Empty, err error) {
+// AgentServiceService methods
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
-	}
-	return s.agentClient.PauseContainer(ctx, req)
+func (s *redirector) CreateContainer(ctx context.Context, req *pb.CreateContainerRequest) (*types.Empty, error) {
+
+	return call(ctx, s, "CreateContainer", func(c *client) (*types.Empty, error) {
+		return c.CreateContainer(ctx, req)
+	})
 }
 
-func (s *redirector) ResumeContainer(ctx context.Context, req *pb.ResumeContainerRequest) (res *types.Empty, err error) {
+func (s *redirector) StartContainer(ctx context.Context, req *pb.StartContainerRequest) (*types.Empty, error) {
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
-	}
-	return s.agentClient.ResumeContainer(ctx, req)
+	return call(ctx, s, "StartContainer", func(c *client) (*types.Empty, error) {
+		return c.StartContainer(ctx, req)
+	})
 }
 
-func (s *redirector) RemoveStaleVirtiofsShareMounts(ctx context.Context, req *pb.RemoveStaleVirtiofsShareMountsRequest) (res *types.Empty, err error) {
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
-	}
-	return s.agentClient.RemoveStaleVirtiofsShareMounts(ctx, req)
+func (s *redirector) RemoveContainer(ctx context.Context, req *pb.RemoveContainerRequest) (*types.Empty, error) {
+
+	return call(ctx, s, "RemoveContainer", func(c *client) (*types.Empty, error) {
+		return c.RemoveContainer(ctx, req)
+	})
 }
 
-func (s *redirector) WriteStdin(ctx context.Context, req *pb.WriteStreamRequest) (res *pb.WriteStreamResponse, err error) {
+func (s *redirector) ExecProcess(ctx context.Context, req *pb.ExecProcessRequest) (*types.Empty, error) {
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
-	}
-	return s.agentClient.WriteStdin(ctx, req)
+	return call(ctx, s, "ExecProcess", func(c *client) (*types.Empty, error) {
+		return c.ExecProcess(ctx, req)
+	})
 }
 
-func (s *redirector) ReadStdout(ctx context.Context, req *pb.ReadStreamRequest) (res *pb.ReadStreamResponse, err error) {
+func (s *redirector) SignalProcess(ctx context.Context, req *pb.SignalProcessRequest) (*types.Empty, error) {
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
-	}
-	return s.agentClient.ReadStdout(ctx, req)
+	return call(ctx, s, "SignalProcess", func(c *client) (*types.Empty, error) {
+		return c.SignalProcess(ctx, req)
+	})
 }
 
-func (s *redirector) ReadStderr(ctx context.Context, req *pb.ReadStreamRequest) (res *pb.ReadStreamResponse, err error) {
+func (s *redirector) WaitProcess(ctx context.Context, req *pb.WaitProcessRequest) (*pb.WaitProcessResponse, error) {
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
-	}
-	return s.agentClient.ReadStderr(ctx, req)
+	return call(ctx, s, "WaitProcess", func(c *client) (*pb.WaitProcessResponse, error) {
+		return c.WaitProcess(ctx, req)
+	})
 }
 
-func (s *redirector) CloseStdin(ctx context.Context, req *pb.CloseStdinRequest) (res *types.Empty, err error) {
+func (s *redirector) UpdateContainer(ctx context.Context, req *pb.UpdateContainerRequest) (*types.Empty, error) {
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
-	}
-	return s.agentClient.CloseStdin(ctx, req)
+	return call(ctx, s, "UpdateContainer", func(c *client) (*types.Empty, error) {
+		return c.UpdateContainer(ctx, req)
+	})
 }
 
-func (s *redirector) TtyWinResize(ctx context.Context, req *pb.TtyWinResizeRequest) (res *types.Empty, err error) {
+func (s *redirector) UpdateEphemeralMounts(ctx context.Context, req *pb.UpdateEphemeralMountsRequest) (*types.Empty, error) {
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
-	}
-	return s.agentClient.TtyWinResize(ctx, req)
+	return call(ctx, s, "UpdateEphemeralMounts", func(c *client) (*types.Empty, error) {
+		return c.UpdateEphemeralMounts(ctx, req)
+	})
 }
 
-func (s *redirector) UpdateInterface(ctx context.Context, req *pb.UpdateInterfaceRequest) (res *protocols.Interface, err error) {
+func (s *redirector) StatsContainer(ctx context.Context, req *pb.StatsContainerRequest) (*pb.StatsContainerResponse, error) {
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
-	}
-	return s.agentClient.UpdateInterface(ctx, req)
+	return call(ctx, s, "StatsContainer", func(c *client) (*pb.StatsContainerResponse, error) {
+		return c.StatsContainer(ctx, req)
+	})
 }
 
-func (s *redirector) UpdateRoutes(ctx context.Context, req *pb.UpdateRoutesRequest) (res *pb.Routes, err error) {
+func (s *redirector) PauseContainer(ctx context.Context, req *pb.PauseContainerRequest) (*types.Empty, error) {
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
-	}
-	return s.agentClient.UpdateRoutes(ctx, req)
+	return call(ctx, s, "PauseContainer", func(c *client) (*types.Empty, error) {
+		return c.PauseContainer(ctx, req)
+	})
 }
 
-func (s *redirector) ListInterfaces(ctx context.Context, req *pb.ListInterfacesRequest) (res *pb.Interfaces, err error) {
+func (s *redirector) ResumeContainer(ctx context.Context, req *pb.ResumeContainerRequest) (*types.Empty, error) {
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
-	}
-	return s.agentClient.ListInterfaces(ctx, req)
+	return call(ctx, s, "ResumeContainer", func(c *client) (*types.Empty, error) {
+		return c.ResumeContainer(ctx, req)
+	})
 }
 
-func (s *redirector) ListRoutes(ctx context.Context, req *pb.ListRoutesRequest) (res *pb.Routes, err error) {
+func (s *redirector) RemoveStaleVirtiofsShareMounts(ctx context.Context, req *pb.RemoveStaleVirtiofsShareMountsRequest) (*types.Empty, error) {
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
-	}
-	return s.agentClient.ListRoutes(ctx, req)
+	return call(ctx, s, "RemoveStaleVirtiofsShareMounts", func(c *client) (*types.Empty, error) {
+		return c.RemoveStaleVirtiofsShareMounts(ctx, req)
+	})
 }
 
-func (s *redirector) AddARPNeighbors(ctx context.Context, req *pb.AddARPNeighborsRequest) (res *types.Empty, err error) {
+func (s *redirector) WriteStdin(ctx context.Context, req *pb.WriteStreamRequest) (*pb.WriteStreamResponse, error) {
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
-	}
-	return s.agentClient.AddARPNeighbors(ctx, req)
+	return call(ctx, s, "WriteStdin", func(c *client) (*pb.WriteStreamResponse, error) {
+		return c.WriteStdin(ctx, req)
+	})
 }
 
-func (s *redirector) GetIPTables(ctx context.Context, req *pb.GetIPTablesRequest) (res *pb.GetIPTablesResponse, err error) {
+func (s *redirector) ReadStdout(ctx context.Context, req *pb.ReadStreamRequest) (*pb.ReadStreamResponse, error) {
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
-	}
-	return s.agentClient.GetIPTables(ctx, req)
+	return call(ctx, s, "ReadStdout", func(c *client) (*pb.ReadStreamResponse, error) {
+		return c.ReadStdout(ctx, req)
+	})
 }
 
-func (s *redirector) SetIPTables(ctx context.Context, req *pb.SetIPTablesRequest) (res *pb.SetIPTablesResponse, err error) {
+func (s *redirector) ReadStderr(ctx context.Context, req *pb.ReadStreamRequest) (*pb.ReadStreamResponse, error) {
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
-	}
-	return s.agentClient.SetIPTables(ctx, req)
+	return call(ctx, s, "ReadStderr", func(c *client) (*pb.ReadStreamResponse, error) {
+		return c.ReadStderr(ctx, req)
+	})
 }
 
-func (s *redirector) GetMetrics(ctx context.Context, req *pb.GetMetricsRequest) (res *pb.Metrics, err error) {
+func (s *redirector) CloseStdin(ctx context.Context, req *pb.CloseStdinRequest) (*types.Empty, error) {
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
-	}
-	return s.agentClient.GetMetrics(ctx, req)
+	return call(ctx, s, "CloseStdin", func(c *client) (*types.Empty, error) {
+		return c.CloseStdin(ctx, req)
+	})
 }
 
-func (s *redirector) CreateSandbox(ctx context.Context, req *pb.CreateSandboxRequest) (res *types.Empty, err error) {
+func (s *redirector) TtyWinResize(ctx context.Context, req *pb.TtyWinResizeRequest) (*types.Empty, error) {
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
-	}
-	return s.agentClient.CreateSandbox(ctx, req)
+	return call(ctx, s, "TtyWinResize", func(c *client) (*types.Empty, error) {
+		return c.TtyWinResize(ctx, req)
+	})
 }
 
-func (s *redirector) DestroySandbox(ctx context.Context, req *pb.DestroySandboxRequest) (res *types.Empty, err error) {
+func (s *redirector) UpdateInterface(ctx context.Context, req *pb.UpdateInterfaceRequest) (*protocols.Interface, error) {
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
-	}
-	return s.agentClient.DestroySandbox(ctx, req)
+	return call(ctx, s, "UpdateInterface", func(c *client) (*protocols.Interface, error) {
+		return c.UpdateInterface(ctx, req)
+	})
 }
 
-func (s *redirector) OnlineCPUMem(ctx context.Context, req *pb.OnlineCPUMemRequest) (res *types.Empty, err error) {
+func (s *redirector) UpdateRoutes(ctx context.Context, req *pb.UpdateRoutesRequest) (*pb.Routes, error) {
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
-	}
-	return s.agentClient.OnlineCPUMem(ctx, req)
+	return call(ctx, s, "UpdateRoutes", func(c *client) (*pb.Routes, error) {
+		return c.UpdateRoutes(ctx, req)
+	})
 }
 
-func (s *redirector) ReseedRandomDev(ctx context.Context, req *pb.ReseedRandomDevRequest) (res *types.Empty, err error) {
+func (s *redirector) ListInterfaces(ctx context.Context, req *pb.ListInterfacesRequest) (*pb.Interfaces, error) {
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
-	}
-	return s.agentClient.ReseedRandomDev(ctx, req)
+	return call(ctx, s, "ListInterfaces", func(c *client) (*pb.Interfaces, error) {
+		return c.ListInterfaces(ctx, req)
+	})
 }
 
-func (s *redirector) GetGuestDetails(ctx context.Context, req *pb.GuestDetailsRequest) (res *pb.GuestDetailsResponse, err error) {
+func (s *redirector) ListRoutes(ctx context.Context, req *pb.ListRoutesRequest) (*pb.Routes, error) {
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
-	}
-	return s.agentClient.GetGuestDetails(ctx, req)
+	return call(ctx, s, "ListRoutes", func(c *client) (*pb.Routes, error) {
+		return c.ListRoutes(ctx, req)
+	})
 }
 
-func (s *redirector) MemHotplugByProbe(ctx context.Context, req *pb.MemHotplugByProbeRequest) (res *types.Empty, err error) {
+func (s *redirector) AddARPNeighbors(ctx context.Context, req *pb.AddARPNeighborsRequest) (*types.Empty, error) {
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
-	}
-	return s.agentClient.MemHotplugByProbe(ctx, req)
+	return call(ctx, s, "AddARPNeighbors", func(c *client) (*types.Empty, error) {
+		return c.AddARPNeighbors(ctx, req)
+	})
 }
 
-func (s *redirector) SetGuestDateTime(ctx context.Context, req *pb.SetGuestDateTimeRequest) (res *types.Empty, err error) {
+func (s *redirector) GetIPTables(ctx context.Context, req *pb.GetIPTablesRequest) (*pb.GetIPTablesResponse, error) {
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
-	}
-	return s.agentClient.SetGuestDateTime(ctx, req)
+	return call(ctx, s, "GetIPTables", func(c *client) (*pb.GetIPTablesResponse, error) {
+		return c.GetIPTables(ctx, req)
+	})
 }
 
-func (s *redirector) CopyFile(ctx context.Context, req *pb.CopyFileRequest) (res *types.Empty, err error) {
+func (s *redirector) SetIPTables(ctx context.Context, req *pb.SetIPTablesRequest) (*pb.SetIPTablesResponse, error) {
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
-	}
-	return s.agentClient.CopyFile(ctx, req)
+	return call(ctx, s, "SetIPTables", func(c *client) (*pb.SetIPTablesResponse, error) {
+		return c.SetIPTables(ctx, req)
+	})
 }
 
-func (s *redirector) GetOOMEvent(ctx context.Context, req *pb.GetOOMEventRequest) (res *pb.OOMEvent, err error) {
+func (s *redirector) GetMetrics(ctx context.Context, req *pb.GetMetricsRequest) (*pb.Metrics, error) {
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
-	}
-	return s.agentClient.GetOOMEvent(ctx, req)
+	return call(ctx, s, "GetMetrics", func(c *client) (*pb.Metrics, error) {
+		return c.GetMetrics(ctx, req)
+	})
 }
 
-func (s *redirector) AddSwap(ctx context.Context, req *pb.AddSwapRequest) (res *types.Empty, err error) {
+func (s *redirector) CreateSandbox(ctx context.Context, req *pb.CreateSandboxRequest) (*types.Empty, error) {
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
-	}
-	return s.agentClient.AddSwap(ctx, req)
+	return call(ctx, s, "CreateSandbox", func(c *client) (*types.Empty, error) {
+		return c.CreateSandbox(ctx, req)
+	})
 }
 
-func (s *redirector) GetVolumeStats(ctx context.Context, req *pb.VolumeStatsRequest) (res *pb.VolumeStatsResponse, err error) {
+func (s *redirector) DestroySandbox(ctx context.Context, req *pb.DestroySandboxRequest) (*types.Empty, error) {
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
-	}
-	return s.agentClient.GetVolumeStats(ctx, req)
+	return call(ctx, s, "DestroySandbox", func(c *client) (*types.Empty, error) {
+		return c.DestroySandbox(ctx, req)
+	})
 }
 
-func (s *redirector) ResizeVolume(ctx context.Context, req *pb.ResizeVolumeRequest) (res *types.Empty, err error) {
+func (s *redirector) OnlineCPUMem(ctx context.Context, req *pb.OnlineCPUMemRequest) (*types.Empty, error) {
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
-	}
-	return s.agentClient.ResizeVolume(ctx, req)
+	return call(ctx, s, "OnlineCPUMem", func(c *client) (*types.Empty, error) {
+		return c.OnlineCPUMem(ctx, req)
+	})
+}
+
+func (s *redirector) ReseedRandomDev(ctx context.Context, req *pb.ReseedRandomDevRequest) (*types.Empty, error) {
+
+	return call(ctx, s, "ReseedRandomDev", func(c *client) (*types.Empty, error) {
+		return c.ReseedRandomDev(ctx, req)
+	})
+}
+
+func (s *redirector) GetGuestDetails(ctx context.Context, req *pb.GuestDetailsRequest) (*pb.GuestDetailsResponse, error) {
+
+	return call(ctx, s, "GetGuestDetails", func(c *client) (*pb.GuestDetailsResponse, error) {
+		return c.GetGuestDetails(ctx, req)
+	})
+}
+
+func (s *redirector) MemHotplugByProbe(ctx context.Context, req *pb.MemHotplugByProbeRequest) (*types.Empty, error) {
+
+	return call(ctx, s, "MemHotplugByProbe", func(c *client) (*types.Empty, error) {
+		return c.MemHotplugByProbe(ctx, req)
+	})
+}
+
+func (s *redirector) SetGuestDateTime(ctx context.Context, req *pb.SetGuestDateTimeRequest) (*types.Empty, error) {
+
+	return call(ctx, s, "SetGuestDateTime", func(c *client) (*types.Empty, error) {
+		return c.SetGuestDateTime(ctx, req)
+	})
+}
+
+func (s *redirector) CopyFile(ctx context.Context, req *pb.CopyFileRequest) (*types.Empty, error) {
+
+	return call(ctx, s, "CopyFile", func(c *client) (*types.Empty, error) {
+		return c.CopyFile(ctx, req)
+	})
+}
+
+func (s *redirector) GetOOMEvent(ctx context.Context, req *pb.GetOOMEventRequest) (*pb.OOMEvent, error) {
+
+	return call(ctx, s, "GetOOMEvent", func(c *client) (*pb.OOMEvent, error) {
+		return c.GetOOMEvent(ctx, req)
+	})
+}
+
+func (s *redirector) AddSwap(ctx context.Context, req *pb.AddSwapRequest) (*types.Empty, error) {
+
+	return call(ctx, s, "AddSwap", func(c *client) (*types.Empty, error) {
+		return c.AddSwap(ctx, req)
+	})
+}
+
+func (s *redirector) GetVolumeStats(ctx context.Context, req *pb.VolumeStatsRequest) (*pb.VolumeStatsResponse, error) {
+
+	return call(ctx, s, "GetVolumeStats", func(c *client) (*pb.VolumeStatsResponse, error) {
+		return c.GetVolumeStats(ctx, req)
+	})
+}
+
+func (s *redirector) ResizeVolume(ctx context.Context, req *pb.ResizeVolumeRequest) (*types.Empty, error) {
+
+	return call(ctx, s, "ResizeVolume", func(c *client) (*types.Empty, error) {
+		return c.ResizeVolume(ctx, req)
+	})
 }
 
 // ImageService method
 
-func (s *redirector) PullImage(ctx context.Context, req *pb.PullImageRequest) (res *pb.PullImageResponse, err error) {
+func (s *redirector) PullImage(ctx context.Context, req *pb.PullImageRequest) (*pb.PullImageResponse, error) {
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
-	}
-	return s.agentClient.PullImage(ctx, req)
+	return call(ctx, s, "PullImage", func(c *client) (*pb.PullImageResponse, error) {
+		return c.PullImage(ctx, req)
+	})
 }
 
 // HealthService methods
 
-func (s *redirector) Check(ctx context.Context, req *pb.CheckRequest) (res *pb.HealthCheckResponse, err error) {
+func (s *redirector) Check(ctx context.Context, req *pb.CheckRequest) (*pb.HealthCheckResponse, error) {
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
-	}
-	return s.agentClient.Check(ctx, req)
+	return call(ctx, s, "Check", func(c *client) (*pb.HealthCheckResponse, error) {
+		return c.Check(ctx, req)
+	})
 }
 
-func (s *redirector) Version(ctx context.Context, req *pb.CheckRequest) (res *pb.VersionCheckResponse, err error) {
+func (s *redirector) Version(ctx context.Context, req *pb.CheckRequest) (*pb.VersionCheckResponse, error) {
 
-	if err := s.Connect(ctx); err != nil {
-		return nil, err
-	}
-	return s.agentClient.Version(ctx, req)
+	return call(ctx, s, "Version", func(c *client) (*pb.VersionCheckResponse, error) {
+		return c.Version(ctx, req)
+	})
 }
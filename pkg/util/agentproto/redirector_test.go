@@ -0,0 +1,205 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package agentproto
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/kata-containers/kata-containers/src/runtime/virtcontainers/pkg/agent/protocols/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/agentproto/agenttest"
+)
+
+type stateRecorder struct {
+	mutex  sync.Mutex
+	states []LinkState
+}
+
+func (r *stateRecorder) record(state LinkState) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.states = append(r.states, state)
+}
+
+func (r *stateRecorder) get() []LinkState {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]LinkState(nil), r.states...)
+}
+
+func (r *stateRecorder) last() LinkState {
+	states := r.get()
+	if len(states) == 0 {
+		return LinkDown
+	}
+	return states[len(states)-1]
+}
+
+func startAgent(t *testing.T, socketPath string) *agenttest.Agent {
+
+	agent, err := agenttest.Start(socketPath)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		assert.NoError(t, agent.Stop())
+	})
+
+	return agent
+}
+
+func unixDialer(socketPath string) func(context.Context) (net.Conn, error) {
+	return func(ctx context.Context) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+	}
+}
+
+func TestReconnect(t *testing.T) {
+
+	ctx := context.Background()
+	socketPath := filepath.Join(t.TempDir(), "agent.sock")
+
+	agent := startAgent(t, socketPath)
+
+	recorder := &stateRecorder{}
+	r := NewRedirectorWithConfig(unixDialer(socketPath), &RedirectorConfig{OnLinkStateChange: recorder.record})
+	defer r.Close()
+
+	_, err := r.Check(ctx, &pb.CheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, []LinkState{LinkUp}, recorder.get())
+
+	// Restart the agent
+	require.NoError(t, agent.Stop())
+	require.Eventually(t, func() bool { return recorder.last() == LinkDown }, 5*time.Second, 10*time.Millisecond)
+
+	agent = startAgent(t, socketPath)
+
+	_, err = r.CreateContainer(ctx, &pb.CreateContainerRequest{ContainerId: "123"})
+	require.NoError(t, err)
+	assert.Len(t, agent.Requests("CreateContainer"), 1)
+	assert.Equal(t, []LinkState{LinkUp, LinkDown, LinkUp}, recorder.get())
+}
+
+func TestRedialBackoff(t *testing.T) {
+
+	ctx := context.Background()
+	socketPath := filepath.Join(t.TempDir(), "agent.sock")
+
+	r := NewRedirector(unixDialer(socketPath))
+	defer r.Close()
+
+	// The agent is not ready yet
+	_, err := r.Check(ctx, &pb.CheckRequest{})
+	assert.Error(t, err)
+	start := time.Now()
+
+	startAgent(t, socketPath)
+
+	_, err = r.Check(ctx, &pb.CheckRequest{})
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), minRedialBackoff/2)
+}
+
+func TestKeepalive(t *testing.T) {
+
+	socketPath := filepath.Join(t.TempDir(), "agent.sock")
+
+	agent := startAgent(t, socketPath)
+
+	recorder := &stateRecorder{}
+	r := NewRedirectorWithConfig(unixDialer(socketPath), &RedirectorConfig{
+		KeepaliveInterval: 20 * time.Millisecond,
+		KeepaliveTimeout:  10 * time.Millisecond,
+		OnLinkStateChange: recorder.record,
+	})
+	defer r.Close()
+
+	require.NoError(t, r.Connect(context.Background()))
+
+	require.Eventually(t, func() bool { return len(agent.Requests("Check")) >= 2 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []LinkState{LinkUp}, recorder.get())
+
+	// The agent stops responding
+	agent.Script("Check", agenttest.Response{Delay: time.Second})
+
+	require.Eventually(t, func() bool {
+		states := recorder.get()
+		return len(states) >= 3 && states[1] == LinkDown && states[2] == LinkUp
+	}, 5*time.Second, 10*time.Millisecond, "keepalive detects a broken connection and re-establishes it")
+}
+
+func TestKeepaliveInterruption(t *testing.T) {
+
+	socketPath := filepath.Join(t.TempDir(), "agent.sock")
+
+	agent := startAgent(t, socketPath)
+
+	interrupted := make(chan struct{})
+	r := NewRedirectorWithConfig(unixDialer(socketPath), &RedirectorConfig{
+		KeepaliveInterval: 20 * time.Millisecond,
+		OnInterruption:    func() { close(interrupted) },
+	})
+	defer r.Close()
+
+	require.NoError(t, r.Connect(context.Background()))
+
+	require.Eventually(t, func() bool { return len(agent.Requests("Check")) >= 2 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, InterruptionService, agent.Requests("Check")[0].(*pb.CheckRequest).Service)
+
+	// The pod VM reports interruption. OnInterruption is called only once, though every keepalive reports it.
+	agent.Script("Check",
+		agenttest.Response{Message: &pb.HealthCheckResponse{Status: pb.HealthCheckResponse_NOT_SERVING}},
+		agenttest.Response{Message: &pb.HealthCheckResponse{Status: pb.HealthCheckResponse_NOT_SERVING}})
+
+	select {
+	case <-interrupted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("keepalive does not report interruption")
+	}
+	require.Eventually(t, func() bool { return len(agent.Requests("Check")) >= 5 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestClose(t *testing.T) {
+
+	ctx := context.Background()
+	socketPath := filepath.Join(t.TempDir(), "agent.sock")
+
+	startAgent(t, socketPath)
+
+	recorder := &stateRecorder{}
+	r := NewRedirectorWithConfig(unixDialer(socketPath), &RedirectorConfig{OnLinkStateChange: recorder.record})
+
+	require.NoError(t, r.Connect(ctx))
+	require.NoError(t, r.Close())
+	require.NoError(t, r.Close())
+
+	_, err := r.Check(ctx, &pb.CheckRequest{})
+	assert.Error(t, err)
+	assert.Equal(t, []LinkState{LinkUp, LinkClosed}, recorder.get())
+}
+
+func TestReadOnlyMethods(t *testing.T) {
+
+	for _, method := range []string{"Check", "Version", "StatsContainer", "ListInterfaces", "ListRoutes", "GetIPTables", "GetMetrics", "GetGuestDetails", "GetVolumeStats"} {
+		assert.True(t, readOnlyMethods[method], method)
+	}
+
+	// Requests that change the state of a pod VM must not be replayed
+	for _, method := range []string{"CreateContainer", "PullImage", "SetIPTables", "UpdateInterface", "UpdateRoutes", "SetGuestDateTime", "TtyWinResize"} {
+		assert.False(t, readOnlyMethods[method], method)
+	}
+}
+
+func TestLinkStateString(t *testing.T) {
+	assert.Equal(t, "up", LinkUp.String())
+	assert.Equal(t, "down", LinkDown.String())
+	assert.Equal(t, "closed", LinkClosed.String())
+}
@@ -1,16 +1,19 @@
 package util
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"strconv"
 	"strings"
 
 	cri "github.com/containerd/containerd/pkg/cri/annotations"
-	hypannotations "github.com/kata-containers/kata-containers/src/runtime/virtcontainers/pkg/annotations"
 )
 
 const (
 	podvmNamePrefix = "podvm"
+
+	// PodVMNamePrefix is the prefix of instance names generated by GenerateInstanceName
+	PodVMNamePrefix = podvmNamePrefix + "-"
 )
 
 func sanitize(input string) string {
@@ -47,6 +50,37 @@ func GenerateInstanceName(podName, sandboxID string, podvmNameMax int) string {
 	return instanceName
 }
 
+// IsPodVMName reports whether name is in the form of instance names generated by GenerateInstanceName
+func IsPodVMName(name string) bool {
+
+	rest, ok := strings.CutPrefix(name, PodVMNamePrefix)
+	return ok && strings.Contains(rest, "-")
+}
+
+// InstanceNameMatchesPod reports whether an instance named instanceName may have been generated
+// by GenerateInstanceName for a pod named podName. Since a pod name may be truncated in an instance name,
+// an instance name may match more than one pod name.
+func InstanceNameMatchesPod(instanceName, podName string) bool {
+
+	if !IsPodVMName(instanceName) {
+		return false
+	}
+
+	rest := strings.TrimPrefix(instanceName, PodVMNamePrefix)
+	podNamePart := rest[:strings.LastIndex(rest, "-")]
+
+	return strings.HasPrefix(sanitize(podName), podNamePart)
+}
+
+// WarmPoolPodName returns the name used in place of a pod name to generate names of instances
+// pre-provisioned by cloud-api-adaptor on a worker node named nodeName. The node name is hashed,
+// since it may be longer than an instance name.
+func WarmPoolPodName(nodeName string) string {
+
+	sum := sha256.Sum256([]byte(nodeName))
+	return "warm-" + hex.EncodeToString(sum[:])[:8]
+}
+
 func GetPodName(annotations map[string]string) string {
 
 	sandboxName := annotations[cri.SandboxName]
@@ -65,48 +99,6 @@ func GetPodNamespace(annotations map[string]string) string {
 	return annotations[cri.SandboxNamespace]
 }
 
-// Method to get instance type from annotation
-func GetInstanceTypeFromAnnotation(annotations map[string]string) string {
-	// The machine_type annotation in Kata refers to VM type
-	// For example machine_type for Kata/Qemu refers to pc, q35, microvm etc.
-	// We use the same annotation for Kata/remote to refer to cloud instance type (flavor)
-	return annotations[hypannotations.MachineType]
-}
-
-// Method to get vCPU and memory from annotations
-func GetCPUAndMemoryFromAnnotation(annotations map[string]string) (int64, int64) {
-
-	var vcpuInt, memoryInt int64
-	var err error
-
-	vcpu, ok := annotations[hypannotations.DefaultVCPUs]
-	if ok {
-		vcpuInt, err = strconv.ParseInt(vcpu, 10, 64)
-		if err != nil {
-			fmt.Printf("Error converting vcpu to int64. Defaulting to 0: %v\n", err)
-			vcpuInt = 0
-		}
-	} else {
-		vcpuInt = 0
-	}
-
-	memory, ok := annotations[hypannotations.DefaultMemory]
-	if ok {
-		// Use strconv.ParseInt to convert string to int64
-		memoryInt, err = strconv.ParseInt(memory, 10, 64)
-		if err != nil {
-			fmt.Printf("Error converting memory to int64. Defaulting to 0: %v\n", err)
-			memoryInt = 0
-		}
-
-	} else {
-		memoryInt = 0
-	}
-
-	// Return vCPU and memory
-	return vcpuInt, memoryInt
-}
-
 // Method to check if a string exists in a slice
 func Contains(slice []string, s string) bool {
 	for _, item := range slice {
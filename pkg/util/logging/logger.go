@@ -0,0 +1,93 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package logging
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"golang.org/x/exp/slog"
+)
+
+// Logger is a leveled logger that attaches structured fields to each line.
+// Print, Printf and Println log at the info level, so that a Logger can replace a log.Logger.
+type Logger struct {
+	logger *slog.Logger
+}
+
+// New returns a logger of a component, e.g. "adaptor/cloud"
+func New(component string) *Logger {
+	return &Logger{
+		logger: slog.New(root).With(KeyComponent, component),
+	}
+}
+
+// With returns a logger that attaches fields specified as alternating keys and values
+func (l *Logger) With(args ...any) *Logger {
+	if len(args) == 0 {
+		return l
+	}
+	return &Logger{
+		logger: l.logger.With(args...),
+	}
+}
+
+// WithContext returns a logger that attaches fields stored in ctx by WithFields
+func (l *Logger) WithContext(ctx context.Context) *Logger {
+	return l.With(fieldsFrom(ctx)...)
+}
+
+func (l *Logger) log(level slog.Level, msg string, args ...any) {
+	l.logger.Log(context.Background(), level, msg, args...)
+}
+
+func (l *Logger) logf(level slog.Level, format string, v ...any) {
+	if !l.logger.Enabled(context.Background(), level) {
+		return
+	}
+	l.logger.Log(context.Background(), level, fmt.Sprintf(format, v...))
+}
+
+func (l *Logger) Debug(msg string, args ...any) { l.log(slog.LevelDebug, msg, args...) }
+func (l *Logger) Info(msg string, args ...any)  { l.log(slog.LevelInfo, msg, args...) }
+func (l *Logger) Warn(msg string, args ...any)  { l.log(slog.LevelWarn, msg, args...) }
+func (l *Logger) Error(msg string, args ...any) { l.log(slog.LevelError, msg, args...) }
+
+func (l *Logger) Debugf(format string, v ...any) { l.logf(slog.LevelDebug, format, v...) }
+func (l *Logger) Infof(format string, v ...any)  { l.logf(slog.LevelInfo, format, v...) }
+func (l *Logger) Warnf(format string, v ...any)  { l.logf(slog.LevelWarn, format, v...) }
+func (l *Logger) Errorf(format string, v ...any) { l.logf(slog.LevelError, format, v...) }
+
+func (l *Logger) Print(v ...any)                 { l.log(slog.LevelInfo, fmt.Sprint(v...)) }
+func (l *Logger) Printf(format string, v ...any) { l.logf(slog.LevelInfo, format, v...) }
+func (l *Logger) Println(v ...any)               { l.log(slog.LevelInfo, fmt.Sprint(v...)) }
+
+// Fatalf logs at the error level and exits
+func (l *Logger) Fatalf(format string, v ...any) {
+	l.logf(slog.LevelError, format, v...)
+	os.Exit(1)
+}
+
+type fieldsKey struct{}
+
+// WithFields returns a context that carries fields for loggers returned by WithContext
+func WithFields(ctx context.Context, args ...any) context.Context {
+	fields := append(append([]any{}, fieldsFrom(ctx)...), args...)
+	return context.WithValue(ctx, fieldsKey{}, fields)
+}
+
+func fieldsFrom(ctx context.Context) []any {
+	fields, _ := ctx.Value(fieldsKey{}).([]any)
+	return fields
+}
+
+// CopyFields returns dst with the fields of src
+func CopyFields(dst, src context.Context) context.Context {
+	fields := fieldsFrom(src)
+	if len(fields) == 0 {
+		return dst
+	}
+	return WithFields(dst, fields...)
+}
@@ -0,0 +1,154 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"golang.org/x/exp/slog"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+
+	DefaultFormat = FormatText
+	DefaultLevel  = "info"
+
+	// Keys of fields common to all components
+	KeyComponent  = "component"
+	KeySandboxID  = "sandbox_id"
+	KeyPod        = "pod"
+	KeyNamespace  = "namespace"
+	KeyInstanceID = "instance_id"
+	KeyError      = "err"
+)
+
+// Config specifies the format and the minimum level of log output
+type Config struct {
+	// Format is either text or json
+	Format string
+	// Level is one of debug, info, warn and error
+	Level string
+}
+
+// root is the handler of all loggers. Loggers are usually created at package initialization,
+// so root forwards records to a handler that is configured later by Setup.
+var root = &rootHandler{
+	handler: newHandler(os.Stderr, FormatText, slog.LevelInfo),
+}
+
+func init() {
+	// Send output of the standard log package to root
+	slog.SetDefault(slog.New(root))
+}
+
+// Setup configures the format and the level of log output of all loggers
+func Setup(w io.Writer, config *Config) error {
+
+	format := config.Format
+	if format == "" {
+		format = DefaultFormat
+	}
+	if format != FormatText && format != FormatJSON {
+		return fmt.Errorf("unknown log format: %q", config.Format)
+	}
+
+	var level slog.Level
+	if config.Level != "" {
+		if err := level.UnmarshalText([]byte(config.Level)); err != nil {
+			return fmt.Errorf("invalid log level %q: %w", config.Level, err)
+		}
+	}
+
+	root.set(newHandler(w, format, level))
+
+	return nil
+}
+
+// AddFields adds fields to all log records, e.g. the pod of a process that serves a single pod
+func AddFields(args ...any) {
+	root.mutex.Lock()
+	defer root.mutex.Unlock()
+	root.handler = slog.New(root.handler).With(args...).Handler()
+}
+
+func newHandler(w io.Writer, format string, level slog.Level) slog.Handler {
+
+	opts := slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redactAttr,
+	}
+
+	if format == FormatJSON {
+		return opts.NewJSONHandler(w)
+	}
+	return opts.NewTextHandler(w)
+}
+
+type rootHandler struct {
+	handler slog.Handler
+	mutex   sync.RWMutex
+}
+
+func (h *rootHandler) get() slog.Handler {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return h.handler
+}
+
+func (h *rootHandler) set(handler slog.Handler) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.handler = handler
+}
+
+func (h *rootHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.get().Enabled(ctx, level)
+}
+
+func (h *rootHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.get().Handle(ctx, r)
+}
+
+func (h *rootHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &derivedHandler{
+		derive: func(handler slog.Handler) slog.Handler { return handler.WithAttrs(attrs) },
+	}
+}
+
+func (h *rootHandler) WithGroup(name string) slog.Handler {
+	return &derivedHandler{
+		derive: func(handler slog.Handler) slog.Handler { return handler.WithGroup(name) },
+	}
+}
+
+// derivedHandler applies attributes and groups to the current handler of root
+type derivedHandler struct {
+	derive func(slog.Handler) slog.Handler
+}
+
+func (h *derivedHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return root.Enabled(ctx, level)
+}
+
+func (h *derivedHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.derive(root.get()).Handle(ctx, r)
+}
+
+func (h *derivedHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &derivedHandler{
+		derive: func(handler slog.Handler) slog.Handler { return h.derive(handler).WithAttrs(attrs) },
+	}
+}
+
+func (h *derivedHandler) WithGroup(name string) slog.Handler {
+	return &derivedHandler{
+		derive: func(handler slog.Handler) slog.Handler { return h.derive(handler).WithGroup(name) },
+	}
+}
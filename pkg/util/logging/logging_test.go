@@ -0,0 +1,143 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testConfig struct {
+	User   string
+	APIKey string
+}
+
+func (c testConfig) Redact() testConfig {
+	c.APIKey = redacted
+	return c
+}
+
+func setupTest(t *testing.T, config *Config) *bytes.Buffer {
+
+	var buf bytes.Buffer
+	require.NoError(t, Setup(&buf, config))
+
+	t.Cleanup(func() {
+		root.set(newHandler(os.Stderr, FormatText, 0))
+	})
+
+	return &buf
+}
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var m map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &m), line)
+		lines = append(lines, m)
+	}
+	return lines
+}
+
+func TestLogger(t *testing.T) {
+
+	// Loggers are created before Setup
+	logger := New("test")
+
+	buf := setupTest(t, &Config{Format: FormatJSON, Level: "debug"})
+
+	ctx := WithFields(context.Background(), KeySandboxID, "abc", KeyPod, "nginx")
+	ctx = WithFields(ctx, KeyNamespace, "default")
+
+	logger.WithContext(ctx).With(KeyInstanceID, "i-1").Info("created", "name", "podvm-nginx")
+	logger.Debugf("value: %d", 1)
+	logger.Printf("printed %s", "line")
+
+	lines := decodeLines(t, buf)
+	require.Len(t, lines, 3)
+
+	assert.Equal(t, "INFO", lines[0]["level"])
+	assert.Equal(t, "created", lines[0]["msg"])
+	assert.Equal(t, "test", lines[0][KeyComponent])
+	assert.Equal(t, "abc", lines[0][KeySandboxID])
+	assert.Equal(t, "nginx", lines[0][KeyPod])
+	assert.Equal(t, "default", lines[0][KeyNamespace])
+	assert.Equal(t, "i-1", lines[0][KeyInstanceID])
+	assert.Equal(t, "podvm-nginx", lines[0]["name"])
+
+	assert.Equal(t, "DEBUG", lines[1]["level"])
+	assert.Equal(t, "value: 1", lines[1]["msg"])
+
+	assert.Equal(t, "INFO", lines[2]["level"])
+	assert.Equal(t, "printed line", lines[2]["msg"])
+}
+
+func TestLevel(t *testing.T) {
+
+	buf := setupTest(t, &Config{Format: FormatText, Level: "warn"})
+
+	logger := New("test")
+	logger.Info("hidden")
+	logger.Warnf("shown %d", 1)
+	logger.Error("failed", KeyError, "boom")
+
+	out := buf.String()
+	assert.NotContains(t, out, "hidden")
+	assert.Contains(t, out, `level=WARN msg="shown 1" component=test`)
+	assert.Contains(t, out, "level=ERROR msg=failed component=test err=boom")
+}
+
+func TestStandardLog(t *testing.T) {
+
+	buf := setupTest(t, &Config{Format: FormatJSON})
+
+	log.Printf("from %s", "log")
+
+	lines := decodeLines(t, buf)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "from log", lines[0]["msg"])
+}
+
+func TestRedact(t *testing.T) {
+
+	buf := setupTest(t, &Config{Format: FormatJSON})
+
+	logger := New("test")
+	logger.Info("config", "config", testConfig{User: "admin", APIKey: "key"}, "password", "pass", "empty_token", "")
+	logger.Info("config", "config", &testConfig{User: "admin", APIKey: "key"})
+	logger.Info("config", "config", (*testConfig)(nil))
+
+	out := buf.String()
+	assert.NotContains(t, out, `"key"`)
+	assert.NotContains(t, out, `"pass"`)
+
+	lines := decodeLines(t, buf)
+	require.Len(t, lines, 3)
+	assert.Equal(t, map[string]any{"User": "admin", "APIKey": redacted}, lines[0]["config"])
+	assert.Equal(t, redacted, lines[0]["password"])
+	assert.Equal(t, "", lines[0]["empty_token"])
+	assert.Equal(t, map[string]any{"User": "admin", "APIKey": redacted}, lines[1]["config"])
+	assert.Nil(t, lines[2]["config"])
+}
+
+func TestSetup(t *testing.T) {
+
+	setupTest(t, &Config{})
+
+	var buf bytes.Buffer
+	assert.Error(t, Setup(&buf, &Config{Format: "xml"}))
+	assert.Error(t, Setup(&buf, &Config{Level: "verbose"}))
+}
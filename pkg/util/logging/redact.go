@@ -0,0 +1,68 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package logging
+
+import (
+	"reflect"
+	"strings"
+
+	"golang.org/x/exp/slog"
+)
+
+const redacted = "**********"
+
+// Substrings of keys whose string values are never logged
+var sensitiveKeys = []string{"password", "secret", "apikey", "api_key", "token", "private_key", "auth_json"}
+
+// redactAttr replaces values that have a Redact method with their redacted copies,
+// and values of sensitive keys with a fixed string
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+
+	if a.Value.Kind() == slog.KindAny {
+		if v, ok := callRedact(a.Value.Any()); ok {
+			return slog.Any(a.Key, v)
+		}
+	}
+
+	if a.Value.Kind() == slog.KindString && isSensitiveKey(a.Key) && a.Value.String() != "" {
+		return slog.String(a.Key, redacted)
+	}
+
+	return a
+}
+
+// callRedact calls a method Redact of v, like Config.Redact of cloud providers, that returns a copy of v without secrets
+func callRedact(v any) (any, bool) {
+
+	if v == nil {
+		return nil, false
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer && rv.IsNil() {
+		return nil, false
+	}
+
+	method := rv.MethodByName("Redact")
+	if !method.IsValid() {
+		return nil, false
+	}
+
+	t := method.Type()
+	if t.NumIn() != 0 || t.NumOut() != 1 {
+		return nil, false
+	}
+
+	return method.Call(nil)[0].Interface(), true
+}
+
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package metadata detects the cloud a pod VM runs on, and gets interruption notices from the instance metadata
+// service of the cloud. It does not depend on the SDKs of the clouds, so that the pod VM binaries stay small.
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+const (
+	// Ref: https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/instance-identity-documents.html
+	AWSImdsUrl = "http://169.254.169.254/latest/dynamic/instance-identity/document"
+	// Ref: https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/spot-instance-termination-notices.html
+	AWSSpotInstanceActionImdsUrl = "http://169.254.169.254/latest/meta-data/spot/instance-action"
+
+	AzureImdsUrl = "http://169.254.169.254/metadata/instance/compute?api-version=2021-01-01"
+	// Ref: https://learn.microsoft.com/en-us/azure/virtual-machines/linux/scheduled-events
+	AzureScheduledEventsImdsUrl = "http://169.254.169.254/metadata/scheduledevents?api-version=2020-07-01"
+)
+
+// Method to check if the VM is running on AWS
+// by checking if the AWS IMDS endpoint is reachable
+// If the VM is running on AWS, return true
+func IsAWS(ctx context.Context) bool {
+
+	// Create a new HTTP client
+	client := &http.Client{}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, AWSImdsUrl, nil)
+	if err != nil {
+		fmt.Printf("failed to create request: %s\n", err)
+		return false
+	}
+
+	// Send the request and retrieve the response
+	resp, err := client.Do(req)
+	if err != nil {
+		fmt.Printf("failed to send request: %s\n", err)
+		return false
+	}
+	defer resp.Body.Close()
+
+	// Check if the response was successful
+	return resp.StatusCode == http.StatusOK
+}
+
+// Method to retrieve the interruption notice of a Spot instance from the instance metadata service
+// The notice is a JSON document such as {"action": "terminate", "time": "2017-09-18T08:22:00Z"}
+// Return an empty string if the instance is not being interrupted
+func GetSpotInstanceAction(ctx context.Context, url string) (string, error) {
+
+	// Create a new HTTP client
+	client := &http.Client{}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %s", err)
+	}
+
+	// Send the request and retrieve the response
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %s", err)
+	}
+	defer resp.Body.Close()
+
+	// The instance action is not found until an interruption is scheduled
+	if resp.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to retrieve spot instance action: %s", resp.Status)
+	}
+
+	// Read the response body and return it as a string
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response body: %s", err)
+	}
+
+	return string(body), nil
+}
+
+// Method to check if the VM is running on Azure
+// by checking if the Azure IMDS endpoint is reachable
+// Set Metadata:true header to confirm that the VM is running on Azure
+// If the VM is running on Azure, return true
+func IsAzure(ctx context.Context) bool {
+
+	// Create a new HTTP client
+	client := &http.Client{}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, AzureImdsUrl, nil)
+	if err != nil {
+		fmt.Printf("failed to create request: %s\n", err)
+		return false
+	}
+	// Add the required headers to the request
+	req.Header.Add("Metadata", "true")
+
+	// Send the request and retrieve the response
+	resp, err := client.Do(req)
+	if err != nil {
+		fmt.Printf("failed to send request: %s\n", err)
+		return false
+	}
+	defer resp.Body.Close()
+
+	// Check if the response was successful
+	return resp.StatusCode == http.StatusOK
+}
+
+// scheduledEvents is a response of the scheduled events endpoint of the instance metadata service
+type scheduledEvents struct {
+	Events []struct {
+		EventId   string
+		EventType string
+		NotBefore string
+	}
+}
+
+// Method to retrieve the eviction notice of a Spot VM from the scheduled events of the instance metadata service
+// Return a description of the Preempt event, or an empty string if the VM is not being evicted
+func GetPreemptEvent(ctx context.Context, url string) (string, error) {
+
+	// Create a new HTTP client
+	client := &http.Client{}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %s", err)
+	}
+	// Add the required headers to the request
+	req.Header.Add("Metadata", "true")
+
+	// Send the request and retrieve the response
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %s", err)
+	}
+	defer resp.Body.Close()
+
+	// Check if the response was successful
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to retrieve scheduled events: %s", resp.Status)
+	}
+
+	var events scheduledEvents
+	if err := json.NewDecoder(resp.Body).Decode(&events); err != nil {
+		return "", fmt.Errorf("failed to decode scheduled events: %s", err)
+	}
+
+	for _, event := range events.Events {
+		if event.EventType == "Preempt" {
+			return fmt.Sprintf("Preempt event %s not before %s", event.EventId, event.NotBefore), nil
+		}
+	}
+
+	return "", nil
+}
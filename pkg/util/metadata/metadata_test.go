@@ -0,0 +1,60 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package metadata
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetPreemptEvent(t *testing.T) {
+
+	var response string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata") != "true" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	response = `{"DocumentIncarnation": 1, "Events": [{"EventId": "1", "EventType": "Freeze", "NotBefore": "Mon, 19 Sep 2016 18:29:47 GMT"}]}`
+	event, err := GetPreemptEvent(context.Background(), server.URL)
+	if err != nil || event != "" {
+		t.Fatalf("expect no Preempt event, got %q and %v", event, err)
+	}
+
+	response = `{"DocumentIncarnation": 2, "Events": [{"EventId": "2", "EventType": "Preempt", "NotBefore": "Mon, 19 Sep 2016 18:29:47 GMT"}]}`
+	event, err = GetPreemptEvent(context.Background(), server.URL)
+	if err != nil || event != "Preempt event 2 not before Mon, 19 Sep 2016 18:29:47 GMT" {
+		t.Fatalf("expect a Preempt event, got %q and %v", event, err)
+	}
+}
+
+func TestGetSpotInstanceAction(t *testing.T) {
+
+	var response string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if response == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	action, err := GetSpotInstanceAction(context.Background(), server.URL)
+	if err != nil || action != "" {
+		t.Fatalf("expect no instance action, got %q and %v", action, err)
+	}
+
+	response = `{"action": "terminate", "time": "2017-09-18T08:22:00Z"}`
+	action, err = GetSpotInstanceAction(context.Background(), server.URL)
+	if err != nil || action != response {
+		t.Fatalf("expect an instance action, got %q and %v", action, err)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package tlsutil
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	caCertFileName     = "ca.crt"
+	caKeyFileName      = "ca.key"
+	clientCertFileName = "client.crt"
+	clientKeyFileName  = "client.key"
+)
+
+// LoadOrNewCAService returns a CA service whose certificate and key are persisted in dir.
+// A new CA is generated and stored in dir when no CA exists there yet, so that server
+// certificates issued before a restart of cloud-api-adaptor remain verifiable after the restart.
+func LoadOrNewCAService(orgName, dir string) (CAService, error) {
+
+	certPEM, keyPEM, err := loadOrGenerate(dir, caCertFileName, caKeyFileName, func() ([]byte, []byte, error) {
+		return generateCertificate(orgName, "", nil, nil, false, true)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up a CA service for %q: %w", orgName, err)
+	}
+
+	s := &caService{
+		orgName: orgName,
+		certPEM: certPEM,
+		keyPEM:  keyPEM,
+	}
+
+	return s, nil
+}
+
+// LoadOrNewClientCertificate returns a self-signed client certificate and its private key persisted in dir.
+// A new certificate is generated and stored in dir when no certificate exists there yet.
+func LoadOrNewClientCertificate(orgName, dir string) (certPEM, keyPEM []byte, err error) {
+
+	certPEM, keyPEM, err = loadOrGenerate(dir, clientCertFileName, clientKeyFileName, func() ([]byte, []byte, error) {
+		return NewClientCertificate(orgName)
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to set up a client certificate for %q: %w", orgName, err)
+	}
+
+	return certPEM, keyPEM, nil
+}
+
+func loadOrGenerate(dir, certFileName, keyFileName string, generate func() ([]byte, []byte, error)) (certPEM, keyPEM []byte, err error) {
+
+	certPath := filepath.Join(dir, certFileName)
+	keyPath := filepath.Join(dir, keyFileName)
+
+	certPEM, certErr := os.ReadFile(certPath)
+	keyPEM, keyErr := os.ReadFile(keyPath)
+
+	if certErr == nil && keyErr == nil {
+		if _, err := decodePEM(certPEM); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode %s: %w", certPath, err)
+		}
+		if _, err := decodePEM(keyPEM); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode %s: %w", keyPath, err)
+		}
+		return certPEM, keyPEM, nil
+	}
+
+	for _, err := range []error{certErr, keyErr} {
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, nil, err
+		}
+	}
+
+	certPEM, keyPEM, err = generate()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	if err := os.WriteFile(keyPath, keyPEM, 0600); err != nil {
+		return nil, nil, fmt.Errorf("failed to store %s: %w", keyPath, err)
+	}
+	if err := os.WriteFile(certPath, certPEM, 0600); err != nil {
+		return nil, nil, fmt.Errorf("failed to store %s: %w", certPath, err)
+	}
+
+	return certPEM, keyPEM, nil
+}
@@ -0,0 +1,45 @@
+// Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package tlsutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadOrNewCAService(t *testing.T) {
+
+	dir := t.TempDir()
+
+	s1, err := LoadOrNewCAService("agent-protocol-forwarder", dir)
+	require.NoError(t, err)
+
+	s2, err := LoadOrNewCAService("agent-protocol-forwarder", dir)
+	require.NoError(t, err)
+
+	assert.Equal(t, s1.RootCertificate(), s2.RootCertificate())
+
+	// A server certificate issued before a restart must be verifiable with the reloaded CA
+	serverCertPEM, serverKeyPEM, err := s1.Issue("server1")
+	require.NoError(t, err)
+
+	_, err = GetTLSConfigFor(&TLSConfig{CAData: s2.RootCertificate(), CertData: serverCertPEM, KeyData: serverKeyPEM})
+	assert.NoError(t, err)
+}
+
+func TestLoadOrNewClientCertificate(t *testing.T) {
+
+	dir := t.TempDir()
+
+	certPEM1, keyPEM1, err := LoadOrNewClientCertificate("cloud-api-adaptor", dir)
+	require.NoError(t, err)
+
+	certPEM2, keyPEM2, err := LoadOrNewClientCertificate("cloud-api-adaptor", dir)
+	require.NoError(t, err)
+
+	assert.Equal(t, certPEM1, certPEM2)
+	assert.Equal(t, keyPEM1, keyPEM2)
+}
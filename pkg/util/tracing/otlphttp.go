@@ -0,0 +1,121 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	defaultOTLPEndpoint = "http://localhost:4318"
+	otlpTracesPath      = "/v1/traces"
+	otlpTimeout         = 10 * time.Second
+
+	// resourceSpansField is the field number of resource_spans in ExportTraceServiceRequest
+	resourceSpansField = 1
+)
+
+// otlpHTTPClient uploads spans to an OTLP/HTTP receiver in the binary protobuf encoding.
+// The OTLP exporters of OpenTelemetry depend on grpc-gateway, which does not build with the version
+// of genproto that ttrpc requires, so export requests are encoded here.
+type otlpHTTPClient struct {
+	url        string
+	httpClient *http.Client
+}
+
+// newOTLPHTTPClient returns a client that sends spans to endpoint. When endpoint is empty,
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT, OTEL_EXPORTER_OTLP_ENDPOINT or their default is used.
+func newOTLPHTTPClient(endpoint string) *otlpHTTPClient {
+	return &otlpHTTPClient{
+		url:        otlpTracesURL(endpoint),
+		httpClient: &http.Client{Timeout: otlpTimeout},
+	}
+}
+
+// otlpTracesURL returns the URL of the traces endpoint of an OTLP/HTTP receiver.
+// An endpoint without a scheme, such as collector:4318, is taken as an HTTP receiver.
+func otlpTracesURL(endpoint string) string {
+
+	if endpoint == "" {
+		if url := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"); url != "" {
+			// A signal-specific endpoint is used as is
+			return url
+		}
+		endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+	if endpoint == "" {
+		endpoint = defaultOTLPEndpoint
+	}
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "http://" + endpoint
+	}
+
+	return strings.TrimSuffix(endpoint, "/") + otlpTracesPath
+}
+
+func (c *otlpHTTPClient) Start(ctx context.Context) error {
+	return nil
+}
+
+func (c *otlpHTTPClient) Stop(ctx context.Context) error {
+	c.httpClient.CloseIdleConnections()
+	return nil
+}
+
+func (c *otlpHTTPClient) UploadTraces(ctx context.Context, protoSpans []*tracepb.ResourceSpans) error {
+
+	body, err := marshalExportTraceServiceRequest(protoSpans)
+	if err != nil {
+		return fmt.Errorf("encoding spans: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating a request to %s: %w", c.url, err)
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending spans to %s: %w", c.url, err)
+	}
+	defer res.Body.Close()
+
+	// Drain the body, so that the connection can be reused
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("sending spans to %s: %s", c.url, res.Status)
+	}
+
+	return nil
+}
+
+// marshalExportTraceServiceRequest encodes an ExportTraceServiceRequest of OTLP that contains resourceSpans
+func marshalExportTraceServiceRequest(resourceSpans []*tracepb.ResourceSpans) ([]byte, error) {
+
+	var b []byte
+
+	for _, rs := range resourceSpans {
+		msg, err := proto.Marshal(rs)
+		if err != nil {
+			return nil, err
+		}
+		b = protowire.AppendTag(b, resourceSpansField, protowire.BytesType)
+		b = protowire.AppendBytes(b, msg)
+	}
+
+	return b, nil
+}
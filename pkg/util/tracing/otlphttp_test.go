@@ -0,0 +1,105 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package tracing
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// unmarshalExportTraceServiceRequest decodes resource spans of an ExportTraceServiceRequest
+func unmarshalExportTraceServiceRequest(t *testing.T, b []byte) []*tracepb.ResourceSpans {
+
+	var resourceSpans []*tracepb.ResourceSpans
+
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		require.GreaterOrEqual(t, n, 0)
+		require.Equal(t, protowire.Number(resourceSpansField), num)
+		require.Equal(t, protowire.BytesType, typ)
+		b = b[n:]
+
+		msg, n := protowire.ConsumeBytes(b)
+		require.GreaterOrEqual(t, n, 0)
+		b = b[n:]
+
+		var rs tracepb.ResourceSpans
+		require.NoError(t, proto.Unmarshal(msg, &rs))
+		resourceSpans = append(resourceSpans, &rs)
+	}
+
+	return resourceSpans
+}
+
+func TestOTLPHTTPClient(t *testing.T) {
+
+	requests := make(chan []*tracepb.ResourceSpans, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, otlpTracesPath, r.URL.Path)
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		requests <- unmarshalExportTraceServiceRequest(t, body)
+	}))
+	defer server.Close()
+
+	exporter, err := otlptrace.New(context.Background(), newOTLPHTTPClient(server.URL))
+	require.NoError(t, err)
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	_, span := provider.Tracer("test").Start(context.Background(), "create instance")
+	span.End()
+
+	resourceSpans := <-requests
+	require.Len(t, resourceSpans, 1)
+	require.Len(t, resourceSpans[0].ScopeSpans, 1)
+	require.Len(t, resourceSpans[0].ScopeSpans[0].Spans, 1)
+	assert.Equal(t, "create instance", resourceSpans[0].ScopeSpans[0].Spans[0].Name)
+
+	require.NoError(t, provider.Shutdown(context.Background()))
+}
+
+func TestOTLPHTTPClientError(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := newOTLPHTTPClient(server.URL)
+	err := client.UploadTraces(context.Background(), []*tracepb.ResourceSpans{{}})
+	assert.ErrorContains(t, err, "503")
+}
+
+func TestOTLPTracesURL(t *testing.T) {
+
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+
+	assert.Equal(t, "http://localhost:4318/v1/traces", otlpTracesURL(""))
+	assert.Equal(t, "http://collector:4318/v1/traces", otlpTracesURL("collector:4318"))
+	assert.Equal(t, "https://collector:4318/v1/traces", otlpTracesURL("https://collector:4318/"))
+
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://collector:4318")
+	assert.Equal(t, "http://collector:4318/v1/traces", otlpTracesURL(""))
+	assert.Equal(t, "http://other:4318/v1/traces", otlpTracesURL("other:4318"))
+
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "http://collector:4318/custom/traces")
+	assert.Equal(t, "http://collector:4318/custom/traces", otlpTracesURL(""))
+}
@@ -0,0 +1,125 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/util/logging"
+)
+
+var logger = logging.New("util/tracing")
+
+const (
+	ExporterNone = "none"
+	ExporterOTLP = "otlp"
+	ExporterFile = "file"
+
+	DefaultExporter = ExporterNone
+
+	instrumentationName = "github.com/confidential-containers/cloud-api-adaptor"
+)
+
+// Config specifies where spans are exported
+type Config struct {
+	// Exporter is one of none, otlp and file
+	Exporter string
+	// Endpoint is the URL or host:port of an OTLP/HTTP receiver. When empty,
+	// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT, OTEL_EXPORTER_OTLP_ENDPOINT or their default is used.
+	Endpoint string
+	// FilePath is the path of a file to which spans are written in JSON by the file exporter
+	FilePath string
+}
+
+// Setup installs a global tracer provider that exports spans as configured, and returns a function to flush and stop it.
+// The W3C trace context propagator is always installed, so that trace context is forwarded even when spans are not exported.
+func Setup(ctx context.Context, serviceName string, config *Config) (shutdown func(context.Context) error, err error) {
+
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var exporter sdktrace.SpanExporter
+	var file *os.File
+
+	switch config.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		exporter, err = otlptrace.New(ctx, newOTLPHTTPClient(config.Endpoint))
+		if err != nil {
+			return nil, fmt.Errorf("creating an OTLP trace exporter: %w", err)
+		}
+	case ExporterFile:
+		if config.FilePath == "" {
+			return nil, errors.New("a file path is required for the file trace exporter")
+		}
+		file, err = os.OpenFile(config.FilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("opening %s: %w", config.FilePath, err)
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("creating a file trace exporter: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown trace exporter: %q", config.Exporter)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+
+	logger.Printf("exporting spans of %s with the %s exporter", serviceName, config.Exporter)
+
+	shutdown = func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if file != nil {
+			if e := file.Close(); e != nil && err == nil {
+				err = e
+			}
+		}
+		return err
+	}
+
+	return shutdown, nil
+}
+
+// Tracer returns a tracer of the global tracer provider
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// StartSpan starts a span as a child of a span in ctx, if any
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// EndSpan records err in span if err is not nil, and ends span
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// DetachContext returns a background context that carries the span of ctx, but not its cancellation or deadline
+func DetachContext(ctx context.Context) context.Context {
+	return trace.ContextWithSpan(context.Background(), trace.SpanFromContext(ctx))
+}
@@ -0,0 +1,95 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package tracing
+
+import (
+	"context"
+
+	"github.com/containerd/ttrpc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// metadataCarrier adapts ttrpc metadata to a propagation.TextMapCarrier
+type metadataCarrier ttrpc.MD
+
+var _ propagation.TextMapCarrier = metadataCarrier{}
+
+func (c metadataCarrier) Get(key string) string {
+	if values, ok := ttrpc.MD(c).Get(key); ok && len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	ttrpc.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// UnaryServerInterceptor returns a ttrpc server interceptor that starts a server span for each request.
+// The span continues a trace propagated in the ttrpc metadata of the request, if any.
+func UnaryServerInterceptor() ttrpc.UnaryServerInterceptor {
+	return func(ctx context.Context, unmarshal ttrpc.Unmarshaler, info *ttrpc.UnaryServerInfo, method ttrpc.Method) (interface{}, error) {
+
+		// A request context derives from the context of the server, which may carry a span of an unrelated request.
+		// Continue only a trace propagated by the client.
+		ctx = trace.ContextWithSpanContext(ctx, trace.SpanContext{})
+
+		if md, ok := ttrpc.GetMetadata(ctx); ok {
+			ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+		}
+
+		ctx, span := Tracer().Start(ctx, info.FullMethod,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(attribute.String("rpc.system", "ttrpc")))
+
+		res, err := method(ctx, unmarshal)
+		EndSpan(span, err)
+
+		return res, err
+	}
+}
+
+// UnaryClientInterceptor returns a ttrpc client interceptor that starts a client span for each request,
+// and propagates its trace context in the ttrpc metadata of the request.
+func UnaryClientInterceptor() ttrpc.UnaryClientInterceptor {
+	return func(ctx context.Context, req *ttrpc.Request, res *ttrpc.Response, info *ttrpc.UnaryClientInfo, invoker ttrpc.Invoker) error {
+
+		ctx, span := Tracer().Start(ctx, info.FullMethod,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attribute.String("rpc.system", "ttrpc")))
+
+		md := ttrpc.MD{}
+		otel.GetTextMapPropagator().Inject(ctx, metadataCarrier(md))
+
+		// Replace trace context forwarded from an upstream request, if any
+		for key, values := range md {
+			kept := req.Metadata[:0]
+			for _, kv := range req.Metadata {
+				if kv.Key != key {
+					kept = append(kept, kv)
+				}
+			}
+			req.Metadata = kept
+			for _, value := range values {
+				req.Metadata = append(req.Metadata, &ttrpc.KeyValue{Key: key, Value: value})
+			}
+		}
+
+		err := invoker(ctx, req, res)
+		EndSpan(span, err)
+
+		return err
+	}
+}
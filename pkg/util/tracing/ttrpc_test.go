@@ -0,0 +1,123 @@
+// (C) Copyright Confidential Containers Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package tracing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containerd/ttrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func setupTestTracer(t *testing.T) *tracetest.SpanRecorder {
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	t.Cleanup(func() {
+		_ = provider.Shutdown(context.Background())
+	})
+
+	return recorder
+}
+
+func TestInterceptors(t *testing.T) {
+
+	recorder := setupTestTracer(t)
+
+	ctx, parent := StartSpan(context.Background(), "parent")
+
+	// A stale trace context forwarded from an upstream request
+	req := &ttrpc.Request{
+		Metadata: []*ttrpc.KeyValue{
+			{Key: "traceparent", Value: "00-0123456789abcdef0123456789abcdef-0123456789abcdef-01"},
+			{Key: "other", Value: "value"},
+		},
+	}
+
+	var serverSpan trace.SpanContext
+
+	invoker := func(ctx context.Context, req *ttrpc.Request, res *ttrpc.Response) error {
+
+		// Emulate the metadata that a ttrpc server attaches to a request context
+		md := ttrpc.MD{}
+		for _, kv := range req.Metadata {
+			md.Append(kv.Key, kv.Value)
+		}
+		values, _ := md.Get("traceparent")
+		assert.Len(t, values, 1)
+
+		serverCtx := ttrpc.WithMetadata(context.Background(), md)
+
+		info := &ttrpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+		method := func(ctx context.Context, unmarshal func(interface{}) error) (interface{}, error) {
+			serverSpan = trace.SpanContextFromContext(ctx)
+			return nil, nil
+		}
+
+		_, err := UnaryServerInterceptor()(serverCtx, nil, info, method)
+		return err
+	}
+
+	info := &ttrpc.UnaryClientInfo{FullMethod: "/test.Service/Method"}
+	err := UnaryClientInterceptor()(ctx, req, &ttrpc.Response{}, info, invoker)
+	require.NoError(t, err)
+
+	parent.End()
+
+	assert.Contains(t, req.Metadata, &ttrpc.KeyValue{Key: "other", Value: "value"})
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+
+	server, client := spans[0], spans[1]
+
+	assert.Equal(t, "/test.Service/Method", server.Name())
+	assert.Equal(t, trace.SpanKindServer, server.SpanKind())
+	assert.Equal(t, trace.SpanKindClient, client.SpanKind())
+
+	assert.Equal(t, parent.SpanContext().TraceID(), client.SpanContext().TraceID())
+	assert.Equal(t, parent.SpanContext().SpanID(), client.Parent().SpanID())
+
+	assert.Equal(t, client.SpanContext().TraceID(), serverSpan.TraceID())
+	assert.Equal(t, client.SpanContext().SpanID(), server.Parent().SpanID())
+	assert.True(t, server.Parent().IsRemote())
+}
+
+func TestSetup(t *testing.T) {
+
+	shutdown, err := Setup(context.Background(), "test", &Config{Exporter: ExporterNone})
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+
+	_, err = Setup(context.Background(), "test", &Config{Exporter: ExporterFile})
+	assert.Error(t, err)
+
+	path := filepath.Join(t.TempDir(), "spans.json")
+	shutdown, err = Setup(context.Background(), "test", &Config{Exporter: ExporterFile, FilePath: path})
+	require.NoError(t, err)
+
+	_, span := StartSpan(context.Background(), "test span")
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "test span")
+
+	_, err = Setup(context.Background(), "test", &Config{Exporter: "unknown"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,310 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v3.21.12
+// source: cloudprovider/v1/cloudprovider.proto
+
+package v1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	CloudProvider_Handshake_FullMethodName      = "/cloudprovider.v1.CloudProvider/Handshake"
+	CloudProvider_CreateInstance_FullMethodName = "/cloudprovider.v1.CloudProvider/CreateInstance"
+	CloudProvider_DeleteInstance_FullMethodName = "/cloudprovider.v1.CloudProvider/DeleteInstance"
+	CloudProvider_ListInstances_FullMethodName  = "/cloudprovider.v1.CloudProvider/ListInstances"
+	CloudProvider_VerifyConfig_FullMethodName   = "/cloudprovider.v1.CloudProvider/VerifyConfig"
+	CloudProvider_Teardown_FullMethodName       = "/cloudprovider.v1.CloudProvider/Teardown"
+)
+
+// CloudProviderClient is the client API for CloudProvider service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type CloudProviderClient interface {
+	// Handshake is called before any other method to check that cloud-api-adaptor and the plugin
+	// speak the same protocol version
+	Handshake(ctx context.Context, in *HandshakeRequest, opts ...grpc.CallOption) (*HandshakeResponse, error)
+	// CreateInstance creates a pod VM instance
+	CreateInstance(ctx context.Context, in *CreateInstanceRequest, opts ...grpc.CallOption) (*CreateInstanceResponse, error)
+	// DeleteInstance deletes a pod VM instance
+	DeleteInstance(ctx context.Context, in *DeleteInstanceRequest, opts ...grpc.CallOption) (*DeleteInstanceResponse, error)
+	// ListInstances returns pod VM instances created by the plugin for the cluster of cloud-api-adaptor.
+	// Instances of other clusters that share the same cloud account must not be returned.
+	ListInstances(ctx context.Context, in *ListInstancesRequest, opts ...grpc.CallOption) (*ListInstancesResponse, error)
+	// VerifyConfig checks the configuration of the plugin, such as credentials of the cloud
+	VerifyConfig(ctx context.Context, in *VerifyConfigRequest, opts ...grpc.CallOption) (*VerifyConfigResponse, error)
+	// Teardown releases resources of the plugin before cloud-api-adaptor exits
+	Teardown(ctx context.Context, in *TeardownRequest, opts ...grpc.CallOption) (*TeardownResponse, error)
+}
+
+type cloudProviderClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCloudProviderClient(cc grpc.ClientConnInterface) CloudProviderClient {
+	return &cloudProviderClient{cc}
+}
+
+func (c *cloudProviderClient) Handshake(ctx context.Context, in *HandshakeRequest, opts ...grpc.CallOption) (*HandshakeResponse, error) {
+	out := new(HandshakeResponse)
+	err := c.cc.Invoke(ctx, CloudProvider_Handshake_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cloudProviderClient) CreateInstance(ctx context.Context, in *CreateInstanceRequest, opts ...grpc.CallOption) (*CreateInstanceResponse, error) {
+	out := new(CreateInstanceResponse)
+	err := c.cc.Invoke(ctx, CloudProvider_CreateInstance_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cloudProviderClient) DeleteInstance(ctx context.Context, in *DeleteInstanceRequest, opts ...grpc.CallOption) (*DeleteInstanceResponse, error) {
+	out := new(DeleteInstanceResponse)
+	err := c.cc.Invoke(ctx, CloudProvider_DeleteInstance_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cloudProviderClient) ListInstances(ctx context.Context, in *ListInstancesRequest, opts ...grpc.CallOption) (*ListInstancesResponse, error) {
+	out := new(ListInstancesResponse)
+	err := c.cc.Invoke(ctx, CloudProvider_ListInstances_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cloudProviderClient) VerifyConfig(ctx context.Context, in *VerifyConfigRequest, opts ...grpc.CallOption) (*VerifyConfigResponse, error) {
+	out := new(VerifyConfigResponse)
+	err := c.cc.Invoke(ctx, CloudProvider_VerifyConfig_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cloudProviderClient) Teardown(ctx context.Context, in *TeardownRequest, opts ...grpc.CallOption) (*TeardownResponse, error) {
+	out := new(TeardownResponse)
+	err := c.cc.Invoke(ctx, CloudProvider_Teardown_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CloudProviderServer is the server API for CloudProvider service.
+// All implementations must embed UnimplementedCloudProviderServer
+// for forward compatibility
+type CloudProviderServer interface {
+	// Handshake is called before any other method to check that cloud-api-adaptor and the plugin
+	// speak the same protocol version
+	Handshake(context.Context, *HandshakeRequest) (*HandshakeResponse, error)
+	// CreateInstance creates a pod VM instance
+	CreateInstance(context.Context, *CreateInstanceRequest) (*CreateInstanceResponse, error)
+	// DeleteInstance deletes a pod VM instance
+	DeleteInstance(context.Context, *DeleteInstanceRequest) (*DeleteInstanceResponse, error)
+	// ListInstances returns pod VM instances created by the plugin for the cluster of cloud-api-adaptor.
+	// Instances of other clusters that share the same cloud account must not be returned.
+	ListInstances(context.Context, *ListInstancesRequest) (*ListInstancesResponse, error)
+	// VerifyConfig checks the configuration of the plugin, such as credentials of the cloud
+	VerifyConfig(context.Context, *VerifyConfigRequest) (*VerifyConfigResponse, error)
+	// Teardown releases resources of the plugin before cloud-api-adaptor exits
+	Teardown(context.Context, *TeardownRequest) (*TeardownResponse, error)
+	mustEmbedUnimplementedCloudProviderServer()
+}
+
+// UnimplementedCloudProviderServer must be embedded to have forward compatible implementations.
+type UnimplementedCloudProviderServer struct {
+}
+
+func (UnimplementedCloudProviderServer) Handshake(context.Context, *HandshakeRequest) (*HandshakeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Handshake not implemented")
+}
+func (UnimplementedCloudProviderServer) CreateInstance(context.Context, *CreateInstanceRequest) (*CreateInstanceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateInstance not implemented")
+}
+func (UnimplementedCloudProviderServer) DeleteInstance(context.Context, *DeleteInstanceRequest) (*DeleteInstanceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteInstance not implemented")
+}
+func (UnimplementedCloudProviderServer) ListInstances(context.Context, *ListInstancesRequest) (*ListInstancesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListInstances not implemented")
+}
+func (UnimplementedCloudProviderServer) VerifyConfig(context.Context, *VerifyConfigRequest) (*VerifyConfigResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method VerifyConfig not implemented")
+}
+func (UnimplementedCloudProviderServer) Teardown(context.Context, *TeardownRequest) (*TeardownResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Teardown not implemented")
+}
+func (UnimplementedCloudProviderServer) mustEmbedUnimplementedCloudProviderServer() {}
+
+// UnsafeCloudProviderServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CloudProviderServer will
+// result in compilation errors.
+type UnsafeCloudProviderServer interface {
+	mustEmbedUnimplementedCloudProviderServer()
+}
+
+func RegisterCloudProviderServer(s grpc.ServiceRegistrar, srv CloudProviderServer) {
+	s.RegisterService(&CloudProvider_ServiceDesc, srv)
+}
+
+func _CloudProvider_Handshake_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HandshakeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CloudProviderServer).Handshake(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CloudProvider_Handshake_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CloudProviderServer).Handshake(ctx, req.(*HandshakeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CloudProvider_CreateInstance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateInstanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CloudProviderServer).CreateInstance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CloudProvider_CreateInstance_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CloudProviderServer).CreateInstance(ctx, req.(*CreateInstanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CloudProvider_DeleteInstance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteInstanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CloudProviderServer).DeleteInstance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CloudProvider_DeleteInstance_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CloudProviderServer).DeleteInstance(ctx, req.(*DeleteInstanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CloudProvider_ListInstances_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListInstancesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CloudProviderServer).ListInstances(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CloudProvider_ListInstances_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CloudProviderServer).ListInstances(ctx, req.(*ListInstancesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CloudProvider_VerifyConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifyConfigRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CloudProviderServer).VerifyConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CloudProvider_VerifyConfig_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CloudProviderServer).VerifyConfig(ctx, req.(*VerifyConfigRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CloudProvider_Teardown_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TeardownRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CloudProviderServer).Teardown(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CloudProvider_Teardown_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CloudProviderServer).Teardown(ctx, req.(*TeardownRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// CloudProvider_ServiceDesc is the grpc.ServiceDesc for CloudProvider service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CloudProvider_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "cloudprovider.v1.CloudProvider",
+	HandlerType: (*CloudProviderServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Handshake",
+			Handler:    _CloudProvider_Handshake_Handler,
+		},
+		{
+			MethodName: "CreateInstance",
+			Handler:    _CloudProvider_CreateInstance_Handler,
+		},
+		{
+			MethodName: "DeleteInstance",
+			Handler:    _CloudProvider_DeleteInstance_Handler,
+		},
+		{
+			MethodName: "ListInstances",
+			Handler:    _CloudProvider_ListInstances_Handler,
+		},
+		{
+			MethodName: "VerifyConfig",
+			Handler:    _CloudProvider_VerifyConfig_Handler,
+		},
+		{
+			MethodName: "Teardown",
+			Handler:    _CloudProvider_Teardown_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "cloudprovider/v1/cloudprovider.proto",
+}
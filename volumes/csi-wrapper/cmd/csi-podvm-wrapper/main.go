@@ -8,6 +8,7 @@ import (
 	"flag"
 	"os"
 
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/annotations"
 	"github.com/confidential-containers/cloud-api-adaptor/volumes/csi-wrapper/pkg/apis/peerpodvolume/v1alpha1"
 	"github.com/confidential-containers/cloud-api-adaptor/volumes/csi-wrapper/pkg/config"
 	peerpodvolumeV1alpha1 "github.com/confidential-containers/cloud-api-adaptor/volumes/csi-wrapper/pkg/generated/peerpodvolume/clientset/versioned"
@@ -68,7 +69,7 @@ func main() {
 		}
 	}()
 
-	labelSelector := labels.SelectorFromSet(map[string]string{"podUid": string(podUid)})
+	labelSelector := labels.SelectorFromSet(map[string]string{annotations.LabelPodUID: string(podUid)})
 	options := metav1.ListOptions{
 		LabelSelector: labelSelector.String(),
 	}
@@ -83,9 +84,9 @@ func main() {
 		savedPeerpodvolume.Spec.PodName = podName
 		savedPeerpodvolume.Spec.PodNamespace = podNamespace
 		savedPeerpodvolume.Spec.NodeName = podNodeName
-		savedPeerpodvolume.Labels["podName"] = podName
-		savedPeerpodvolume.Labels["podNamespace"] = podNamespace
-		savedPeerpodvolume.Labels["podNodeName"] = podNodeName
+		savedPeerpodvolume.Labels[annotations.LabelPodName] = podName
+		savedPeerpodvolume.Labels[annotations.LabelPodNamespace] = podNamespace
+		savedPeerpodvolume.Labels[annotations.LabelPodNodeName] = podNodeName
 		updatedPeerpodvolume, err := peerPodVolumeClient.ConfidentialcontainersV1alpha1().PeerpodVolumes(cfg.Namespace).Update(context.Background(), &savedPeerpodvolume, metav1.UpdateOptions{})
 		if err != nil {
 			glog.Fatalf("Error happens while Update podName and podNamespace to PeerpodVolume, err: %v", err.Error())
@@ -4,6 +4,7 @@ go 1.20
 
 require (
 	github.com/confidential-containers/cloud-api-adaptor v0.8.0
+	github.com/confidential-containers/cloud-api-adaptor/pkg/annotations v0.0.0-00010101000000-000000000000
 	github.com/container-storage-interface/spec v1.8.0
 	github.com/containerd/ttrpc v1.1.0
 	github.com/gofrs/uuid v4.4.0+incompatible
@@ -65,3 +66,5 @@ require (
 // The following line is a workaround for the issue descrined in https://github.com/containerd/ttrpc/issues/62
 // We can remove this workaround when Kata stop using github.com/gogo/protobuf
 replace google.golang.org/genproto => google.golang.org/genproto v0.0.0-20180817151627-c66870c02cf8
+
+replace github.com/confidential-containers/cloud-api-adaptor/pkg/annotations => ../../pkg/annotations
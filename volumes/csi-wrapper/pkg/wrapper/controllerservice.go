@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/annotations"
 	"github.com/confidential-containers/cloud-api-adaptor/volumes/csi-wrapper/pkg/apis/peerpodvolume/v1alpha1"
 	peerpodvolumeV1alpha1 "github.com/confidential-containers/cloud-api-adaptor/volumes/csi-wrapper/pkg/apis/peerpodvolume/v1alpha1"
 	peerpodvolume "github.com/confidential-containers/cloud-api-adaptor/volumes/csi-wrapper/pkg/generated/peerpodvolume/clientset/versioned"
@@ -39,7 +40,7 @@ const (
 	PublishInfoRequestID = "request-id"
 
 	// Parameter key for Peer Pod from StorageClass
-	PeerpodParamKey = "peerpod"
+	PeerpodParamKey = annotations.StorageClassParameter
 )
 
 type ControllerService struct {
@@ -87,7 +88,7 @@ func (s *ControllerService) CreateVolume(ctx context.Context, req *csi.CreateVol
 			volumeID := res.GetVolume().VolumeId
 			normalizedVolumeID := utils.NormalizeVolumeID(volumeID)
 			labels := map[string]string{
-				"volumeName": volumeName,
+				annotations.LabelVolumeName: volumeName,
 			}
 			newPeerpodvolume := &v1alpha1.PeerpodVolume{
 				ObjectMeta: metav1.ObjectMeta{
@@ -176,7 +177,7 @@ func (s *ControllerService) ControllerPublishVolume(ctx context.Context, req *cs
 		resJsonString := resBuf.String()
 		glog.Infof("ControllerPublishVolumeResponse JSON string: %s\n", resJsonString)
 
-		savedPeerpodvolume.Labels["nodeID"] = nodeID
+		savedPeerpodvolume.Labels[annotations.LabelNodeID] = nodeID
 		savedPeerpodvolume.Spec.NodeID = nodeID
 		savedPeerpodvolume.Spec.WrapperControllerPublishVolumeReq = string(reqJsonString)
 		savedPeerpodvolume.Spec.WrapperControllerPublishVolumeRes = string(resJsonString)
@@ -225,7 +226,7 @@ func (s *ControllerService) ControllerUnpublishVolume(ctx context.Context, req *
 		}
 
 		labels := map[string]string{
-			"volumeName": savedPeerpodvolume.Spec.VolumeName,
+			annotations.LabelVolumeName: savedPeerpodvolume.Spec.VolumeName,
 		}
 		savedPeerpodvolume.Labels = labels
 		savedPeerpodvolume.Spec.NodeID = ""
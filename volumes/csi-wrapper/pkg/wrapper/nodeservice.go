@@ -11,6 +11,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/annotations"
 	"github.com/confidential-containers/cloud-api-adaptor/proto/podvminfo"
 	"github.com/confidential-containers/cloud-api-adaptor/volumes/csi-wrapper/pkg/apis/peerpodvolume/v1alpha1"
 	peerpodvolumeV1alpha1 "github.com/confidential-containers/cloud-api-adaptor/volumes/csi-wrapper/pkg/apis/peerpodvolume/v1alpha1"
@@ -123,9 +124,9 @@ func (s *NodeService) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 		glog.Infof("NodePublishVolumeRequest JSON string: %s\n", nodePublishVolumeRequest)
 		savedPeerpodvolume.Spec.TargetPath = targetPath
 		podUid, volumeName := s.getPodUIDandVolumeName(targetPath)
-		savedPeerpodvolume.Labels["podUid"] = podUid
+		savedPeerpodvolume.Labels[annotations.LabelPodUID] = podUid
 		savedPeerpodvolume.Spec.PodUid = podUid
-		if volumeName != savedPeerpodvolume.Labels["volumeName"] {
+		if volumeName != savedPeerpodvolume.Labels[annotations.LabelVolumeName] {
 			glog.Error("The volume name from target path doesn't match with the CRD")
 			return
 		}
@@ -367,7 +368,7 @@ func (s *NodeService) SyncHandler(peerPodVolume *peerpodvolumeV1alpha1.PeerpodVo
 			vmID := res.VMID
 			glog.Infof("Got the vm instance id from cloud-api-adaptor podVMInfoService vmID:%v", vmID)
 			peerPodVolume.Spec.VMID = vmID
-			peerPodVolume.Labels["vmID"] = utils.NormalizeVMID(vmID)
+			peerPodVolume.Labels[annotations.LabelVMID] = utils.NormalizeVMID(vmID)
 			updatedPeerPodVolume, err := s.PeerpodvolumeClient.ConfidentialcontainersV1alpha1().PeerpodVolumes(s.Namespace).Update(context.Background(), peerPodVolume, metav1.UpdateOptions{})
 			if err != nil {
 				glog.Errorf("Error happens while Update vmID to PeerpodVolume, err: %v", err.Error())
@@ -3,10 +3,12 @@ module github.com/confidential-containers/cloud-api-adaptor/webhook
 go 1.20
 
 require (
+	github.com/confidential-containers/cloud-api-adaptor/pkg/annotations v0.0.0-00010101000000-000000000000
 	k8s.io/api v0.24.2
 	k8s.io/apimachinery v0.24.2
 	k8s.io/client-go v0.24.2
 	sigs.k8s.io/controller-runtime v0.12.2
+	sigs.k8s.io/yaml v1.3.0
 )
 
 require (
@@ -75,7 +77,8 @@ require (
 	k8s.io/utils v0.0.0-20220210201930-3a6ce19ff2f9 // indirect
 	sigs.k8s.io/json v0.0.0-20211208200746-9f7c6b3444d2 // indirect
 	sigs.k8s.io/structured-merge-diff/v4 v4.2.1 // indirect
-	sigs.k8s.io/yaml v1.3.0 // indirect
 )
 
 replace github.com/prometheus/client_golang => github.com/prometheus/client_golang v1.14.0
+
+replace github.com/confidential-containers/cloud-api-adaptor/pkg/annotations => ../pkg/annotations
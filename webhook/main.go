@@ -17,10 +17,13 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
 
 	"github.com/confidential-containers/cloud-api-adaptor/webhook/pkg/mutating_webhook"
+	"github.com/confidential-containers/cloud-api-adaptor/webhook/pkg/policy"
+	"github.com/confidential-containers/cloud-api-adaptor/webhook/pkg/validating_webhook"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -28,10 +31,12 @@ import (
 
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	//+kubebuilder:scaffold:imports
 )
@@ -51,11 +56,16 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var policyConfigMap string
+	var policyNamespace string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&policyConfigMap, "policy-configmap", "peer-pods-webhook-policy", "The name of the ConfigMap of the peer pods policy.")
+	flag.StringVar(&policyNamespace, "policy-namespace", os.Getenv("POD_NAMESPACE"),
+		"The namespace of the ConfigMap of the peer pods policy. Defaults to the POD_NAMESPACE environment variable.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -77,8 +87,30 @@ func main() {
 		os.Exit(1)
 	}
 
+	if policyNamespace == "" {
+		setupLog.Info("policy-namespace is not set")
+		os.Exit(1)
+	}
+	clientset, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "unable to create clientset")
+		os.Exit(1)
+	}
+	if env := policy.DeprecatedEnv(); len(env) > 0 {
+		setupLog.Info("deprecated environment variables are set, move their settings to the policy configmap",
+			"env", env, "configmap", policyConfigMap, "namespace", policyNamespace)
+	}
+	policyStore := policy.NewStore()
+	if err := mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		return policyStore.Watch(ctx, clientset, policyNamespace, policyConfigMap)
+	})); err != nil {
+		setupLog.Error(err, "unable to set up policy watch")
+		os.Exit(1)
+	}
+
 	setupLog.Info("Setting up webhook server")
-	mgr.GetWebhookServer().Register("/mutate-v1-pod", &webhook.Admission{Handler: &mutating_webhook.PodMutator{Client: mgr.GetClient()}})
+	mgr.GetWebhookServer().Register("/mutate-v1-pod", &webhook.Admission{Handler: &mutating_webhook.PodMutator{Client: mgr.GetClient(), Policy: policyStore}})
+	mgr.GetWebhookServer().Register("/validate-v1-pod", &webhook.Admission{Handler: &validating_webhook.PodValidator{Client: mgr.GetClient(), Policy: policyStore}})
 
 	//+kubebuilder:scaffold:builder
 
@@ -90,6 +122,10 @@ func main() {
 		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
+	if err := mgr.AddReadyzCheck("policy", policyStore.Ready); err != nil {
+		setupLog.Error(err, "unable to set up policy check")
+		os.Exit(1)
+	}
 
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
@@ -19,22 +19,28 @@ package mutating_webhook
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/confidential-containers/cloud-api-adaptor/webhook/pkg/policy"
 )
 
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
 // +kubebuilder:webhook:admissionReviewVersions=v1,path=/mutate-v1-pod,mutating=true,failurePolicy=fail,groups="",resources=pods,verbs=create;update,versions=v1,name=mwebhook.peerpods.io,sideEffects=None
 
 // podMutator mutates Pods
 type PodMutator struct {
 	Client  client.Client
+	Policy  *policy.Store
 	decoder *admission.Decoder
 }
 
-// podMutator adds peer-pod extended resource to the pod spec add removes all other resource specs
+// podMutator adds peer-pod extended resource to the pod spec add removes all other resource specs.
+// The vCPUs and memory of the pod VM are derived from the removed resource specs.
 func (a *PodMutator) Handle(ctx context.Context, req admission.Request) admission.Response {
 	pod := &corev1.Pod{}
 
@@ -43,7 +49,19 @@ func (a *PodMutator) Handle(ctx context.Context, req admission.Request) admissio
 		return admission.Errored(http.StatusBadRequest, err)
 	}
 
-	mutatedPod, _ := removePodResourceSpec(pod)
+	p := a.Policy.Get()
+	if !p.IsPeerPod(pod) {
+		return admission.Allowed("not a peer pod")
+	}
+	constraints, _, err := ConstraintsFor(ctx, a.Client, p, req.Namespace, pod)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	mutatedPod, err := removePodResourceSpec(pod, p, constraints)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
 	marshaledPod, err := json.Marshal(mutatedPod)
 	if err != nil {
 		return admission.Errored(http.StatusInternalServerError, err)
@@ -52,6 +70,21 @@ func (a *PodMutator) Handle(ctx context.Context, req admission.Request) admissio
 	return admission.PatchResponseFromRaw(req.Object.Raw, marshaledPod)
 }
 
+// ConstraintsFor returns the policy constraints of a pod, and the name of the matching rule, if any.
+// The namespace is only read when a rule selects namespaces by their labels.
+func ConstraintsFor(ctx context.Context, c client.Client, p *policy.Policy, namespace string, pod *corev1.Pod) (policy.Constraints, string, error) {
+	var namespaceLabels map[string]string
+	if p.NeedsNamespaceLabels() {
+		ns := &corev1.Namespace{}
+		if err := c.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+			return policy.Constraints{}, "", fmt.Errorf("failed to get namespace %s: %w", namespace, err)
+		}
+		namespaceLabels = ns.Labels
+	}
+	constraints, rule := p.ConstraintsFor(namespace, namespaceLabels, pod)
+	return constraints, rule, nil
+}
+
 // podMutator implements admission.DecoderInjector.
 // A decoder will be automatically injected.
 
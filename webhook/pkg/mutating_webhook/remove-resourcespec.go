@@ -1,42 +1,44 @@
 package mutating_webhook
 
 import (
-	"os"
+	"encoding/json"
+	"fmt"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
-)
 
-const (
-	RUNTIME_CLASS_NAME_DEFAULT       = "kata-remote"
-	POD_VM_ANNOTATION_INSTANCE_TYPE  = "kata.peerpods.io/instance_type"
-	POD_VM_INSTANCE_TYPE_DEFAULT     = "t2.small"
-	POD_VM_EXTENDED_RESOURCE_DEFAULT = "kata.peerpods.io/vm"
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/annotations"
+	"github.com/confidential-containers/cloud-api-adaptor/webhook/pkg/policy"
+	"github.com/confidential-containers/cloud-api-adaptor/webhook/pkg/utils"
 )
 
 // remove the POD resource spec
-func removePodResourceSpec(pod *corev1.Pod) (*corev1.Pod, error) {
-	var runtimeClassName string
+func removePodResourceSpec(pod *corev1.Pod, p *policy.Policy, constraints policy.Constraints) (*corev1.Pod, error) {
 	mpod := pod.DeepCopy()
 
-	if runtimeClassName = os.Getenv("TARGET_RUNTIMECLASS"); runtimeClassName == "" {
-		runtimeClassName = RUNTIME_CLASS_NAME_DEFAULT
-	}
 	// Mutate only if the POD is using specific runtimeClass
-	if mpod.Spec.RuntimeClassName == nil || *mpod.Spec.RuntimeClassName != runtimeClassName {
+	if !p.IsPeerPod(mpod) {
 		return mpod, nil
 	}
 
-	var podVmInstanceType string
-	if podVmInstanceType = os.Getenv("POD_VM_INSTANCE_TYPE"); podVmInstanceType == "" {
-		podVmInstanceType = POD_VM_INSTANCE_TYPE_DEFAULT
-	}
-
 	if mpod.Annotations == nil {
 		mpod.Annotations = map[string]string{}
 	}
 
-	mpod.Annotations[POD_VM_ANNOTATION_INSTANCE_TYPE] = podVmInstanceType
+	// An unsupported version set by the user is kept, and rejected by the validating webhook
+	if _, ok := mpod.Annotations[annotations.VersionKey]; !ok {
+		mpod.Annotations[annotations.VersionKey] = annotations.Version
+	}
+
+	// Keep the instance type requested by the pod, which the validating webhook checks against the policy
+	if _, ok := mpod.Annotations[annotations.InstanceType]; !ok {
+		mpod.Annotations[annotations.InstanceType] = constraints.DefaultInstanceType
+	}
+
+	// Size the pod VM by the resource specs before they are removed
+	if err := setPodVMResourceAnnotations(mpod); err != nil {
+		return nil, err
+	}
 
 	// Remove all resource specs
 	for idx := range mpod.Spec.Containers {
@@ -48,21 +50,65 @@ func removePodResourceSpec(pod *corev1.Pod) (*corev1.Pod, error) {
 	}
 
 	// Add peer-pod resource to one container
-	mpod.Spec.Containers[0].Resources = defaultContainerResourceRequirements()
+	mpod.Spec.Containers[0].Resources = defaultContainerResourceRequirements(p.ExtendedResource)
 	return mpod, nil
 }
 
+// GPU resource of the NVIDIA device plugin, which is translated into the GPUs of the pod VM
+const gpuResource corev1.ResourceName = "nvidia.com/gpu"
+
+// setPodVMResourceAnnotations sets the vCPUs, memory and GPUs of the pod VM from the resources of the containers,
+// and keeps their original resource specs in an annotation. Annotations set by the user are not changed.
+func setPodVMResourceAnnotations(pod *corev1.Pod) error {
+
+	// The pod VM has to fit the limits, or the requests if they are larger or no limits are set
+	milliCPUs := maxInt64(utils.GetResourceRequest(pod, corev1.ResourceCPU), utils.GetResourceLimit(pod, corev1.ResourceCPU))
+	memory := maxInt64(utils.GetResourceRequest(pod, corev1.ResourceMemory), utils.GetResourceLimit(pod, corev1.ResourceMemory))
+
+	// GPUs are extended resources, whose requests equal their limits if both are set
+	gpus := maxInt64(utils.GetResourceRequest(pod, gpuResource), utils.GetResourceLimit(pod, gpuResource))
+
+	mib := int64(1024 * 1024)
+	podVM := annotations.PodVM{
+		VCPUs:  (milliCPUs + 999) / 1000,
+		Memory: (memory + mib - 1) / mib,
+		GPUs:   gpus,
+	}
+	podVM.Set(pod.Annotations)
+
+	original := map[string]corev1.ResourceRequirements{}
+	for _, containers := range [][]corev1.Container{pod.Spec.InitContainers, pod.Spec.Containers} {
+		for _, container := range containers {
+			if len(container.Resources.Requests) > 0 || len(container.Resources.Limits) > 0 {
+				original[container.Name] = container.Resources
+			}
+		}
+	}
+	// Resources of a mutated pod are already removed
+	if _, ok := pod.Annotations[annotations.OriginalResources]; ok || len(original) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(original)
+	if err != nil {
+		return fmt.Errorf("failed to marshal resources of containers: %w", err)
+	}
+	pod.Annotations[annotations.OriginalResources] = string(data)
+	return nil
+}
+
+func maxInt64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
 // defaultContainerResourceRequirements returns the default requirements for a container
-func defaultContainerResourceRequirements() corev1.ResourceRequirements {
+func defaultContainerResourceRequirements(podVmExtResource string) corev1.ResourceRequirements {
 	requirements := corev1.ResourceRequirements{}
 	requirements.Requests = corev1.ResourceList{}
 	requirements.Limits = corev1.ResourceList{}
 
-	var podVmExtResource string
-	if podVmExtResource = os.Getenv("POD_VM_EXTENDED_RESOURCE"); podVmExtResource == "" {
-		podVmExtResource = POD_VM_EXTENDED_RESOURCE_DEFAULT
-	}
-
 	requirements.Requests[corev1.ResourceName(podVmExtResource)] = resource.MustParse("1")
 	requirements.Limits[corev1.ResourceName(podVmExtResource)] = resource.MustParse("1")
 	return requirements
@@ -0,0 +1,238 @@
+package policy
+
+import (
+	"fmt"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	DefaultRuntimeClassName = "kata-remote"
+	DefaultInstanceType     = "t2.small"
+	DefaultExtendedResource = "kata.peerpods.io/vm"
+	// Key of the policy in the data of the policy ConfigMap
+	ConfigMapKey = "policy.yaml"
+)
+
+// Environment variables that configured the webhook before the policy. They are deprecated, and are the defaults
+// of the settings that the policy leaves unset, so that deployments that set them keep working until they migrate.
+const (
+	RuntimeClassNameEnv = "TARGET_RUNTIMECLASS"
+	InstanceTypeEnv     = "POD_VM_INSTANCE_TYPE"
+	ExtendedResourceEnv = "POD_VM_EXTENDED_RESOURCE"
+)
+
+// DeprecatedEnv returns the deprecated environment variables that are set
+func DeprecatedEnv() []string {
+	var set []string
+	for _, key := range []string{RuntimeClassNameEnv, InstanceTypeEnv, ExtendedResourceEnv} {
+		if os.Getenv(key) != "" {
+			set = append(set, key)
+		}
+	}
+	return set
+}
+
+// Policy configures how the webhooks mutate and validate peer pods
+type Policy struct {
+	// RuntimeClassName is the runtime class of peer pods. Pods of other runtime classes are not changed.
+	RuntimeClassName string `json:"runtimeClassName,omitempty"`
+	// ExtendedResource is the extended resource requested by peer pods
+	ExtendedResource string `json:"extendedResource,omitempty"`
+	// Defaults are the constraints of peer pods that no rule matches, and of settings that matching rules leave unset
+	Defaults Constraints `json:"defaults,omitempty"`
+	// Rules are the constraints of peer pods in specific namespaces. The first matching rule applies.
+	Rules []Rule `json:"rules,omitempty"`
+}
+
+// Rule selects peer pods by namespace and labels
+type Rule struct {
+	// Name of the rule shown in rejections
+	Name string `json:"name"`
+	// Namespaces the rule applies to. Any namespace if empty.
+	Namespaces []string `json:"namespaces,omitempty"`
+	// NamespaceSelector selects namespaces by their labels. Any namespace if empty.
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// PodSelector selects pods by their labels. Any pod if empty.
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+
+	Constraints `json:",inline"`
+
+	namespaceSelector labels.Selector
+	podSelector       labels.Selector
+}
+
+// Constraints are the settings of peer pods enforced by the webhooks
+type Constraints struct {
+	// DefaultInstanceType is set on peer pods that do not request an instance type
+	DefaultInstanceType string `json:"defaultInstanceType,omitempty"`
+	// AllowedInstanceTypes are the instance types peer pods may request. Any instance type if empty.
+	AllowedInstanceTypes []string `json:"allowedInstanceTypes,omitempty"`
+	// MaxVCPUs is the maximum number of vCPUs of a pod VM. No limit if 0.
+	MaxVCPUs int64 `json:"maxVCPUs,omitempty"`
+	// MaxMemory is the maximum memory of a pod VM in MiB. No limit if 0.
+	MaxMemory int64 `json:"maxMemory,omitempty"`
+	// RequiredAnnotations are the annotations peer pods must have
+	RequiredAnnotations []string `json:"requiredAnnotations,omitempty"`
+}
+
+// Default returns the policy that applies when no policy is configured
+func Default() *Policy {
+	p := &Policy{}
+	p.setDefaults()
+	return p
+}
+
+// Parse parses a policy in YAML, and checks that it is valid
+func Parse(data []byte) (*Policy, error) {
+	p := &Policy{}
+	if err := yaml.UnmarshalStrict(data, p); err != nil {
+		return nil, fmt.Errorf("failed to parse policy: %w", err)
+	}
+	p.setDefaults()
+
+	if err := p.Defaults.validate(); err != nil {
+		return nil, fmt.Errorf("invalid defaults: %w", err)
+	}
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if rule.Name == "" {
+			return nil, fmt.Errorf("rule %d has no name", i)
+		}
+		// Rules are validated with the settings they inherit from the defaults, which they are enforced with
+		merged := rule.merge(p.Defaults)
+		if err := merged.validate(); err != nil {
+			return nil, fmt.Errorf("invalid rule %s: %w", rule.Name, err)
+		}
+		var err error
+		if rule.namespaceSelector, err = selector(rule.NamespaceSelector); err != nil {
+			return nil, fmt.Errorf("invalid namespaceSelector of rule %s: %w", rule.Name, err)
+		}
+		if rule.podSelector, err = selector(rule.PodSelector); err != nil {
+			return nil, fmt.Errorf("invalid podSelector of rule %s: %w", rule.Name, err)
+		}
+	}
+	return p, nil
+}
+
+func (p *Policy) setDefaults() {
+	if p.RuntimeClassName == "" {
+		p.RuntimeClassName = getEnv(RuntimeClassNameEnv, DefaultRuntimeClassName)
+	}
+	if p.ExtendedResource == "" {
+		p.ExtendedResource = getEnv(ExtendedResourceEnv, DefaultExtendedResource)
+	}
+	if p.Defaults.DefaultInstanceType == "" {
+		p.Defaults.DefaultInstanceType = getEnv(InstanceTypeEnv, DefaultInstanceType)
+	}
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func (c *Constraints) validate() error {
+	if c.MaxVCPUs < 0 {
+		return fmt.Errorf("maxVCPUs is negative")
+	}
+	if c.MaxMemory < 0 {
+		return fmt.Errorf("maxMemory is negative")
+	}
+	if c.DefaultInstanceType != "" && !c.Allows(c.DefaultInstanceType) {
+		return fmt.Errorf("defaultInstanceType %s is not an allowed instance type", c.DefaultInstanceType)
+	}
+	return nil
+}
+
+func selector(labelSelector *metav1.LabelSelector) (labels.Selector, error) {
+	if labelSelector == nil {
+		return labels.Everything(), nil
+	}
+	return metav1.LabelSelectorAsSelector(labelSelector)
+}
+
+// IsPeerPod returns true if the pod runs with the runtime class of peer pods
+func (p *Policy) IsPeerPod(pod *corev1.Pod) bool {
+	return pod.Spec.RuntimeClassName != nil && *pod.Spec.RuntimeClassName == p.RuntimeClassName
+}
+
+// NeedsNamespaceLabels returns true if a rule selects namespaces by their labels
+func (p *Policy) NeedsNamespaceLabels() bool {
+	for _, rule := range p.Rules {
+		if rule.NamespaceSelector != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// ConstraintsFor returns the constraints of a pod in a namespace with the given labels, and the name of the matching
+// rule, if any. Settings that the matching rule leaves unset are taken from the defaults.
+func (p *Policy) ConstraintsFor(namespace string, namespaceLabels map[string]string, pod *corev1.Pod) (Constraints, string) {
+	for _, rule := range p.Rules {
+		if !rule.matches(namespace, namespaceLabels, pod) {
+			continue
+		}
+		return rule.merge(p.Defaults), rule.Name
+	}
+	return p.Defaults, ""
+}
+
+// merge returns the constraints of the rule, with the settings it leaves unset taken from defaults
+func (r *Rule) merge(defaults Constraints) Constraints {
+	c := r.Constraints
+	// Allowed instance types are inherited first, so that the default instance type is resolved against them
+	if c.AllowedInstanceTypes == nil {
+		c.AllowedInstanceTypes = defaults.AllowedInstanceTypes
+	}
+	if c.DefaultInstanceType == "" {
+		c.DefaultInstanceType = defaults.DefaultInstanceType
+		if !c.Allows(c.DefaultInstanceType) {
+			c.DefaultInstanceType = c.AllowedInstanceTypes[0]
+		}
+	}
+	if c.MaxVCPUs == 0 {
+		c.MaxVCPUs = defaults.MaxVCPUs
+	}
+	if c.MaxMemory == 0 {
+		c.MaxMemory = defaults.MaxMemory
+	}
+	if c.RequiredAnnotations == nil {
+		c.RequiredAnnotations = defaults.RequiredAnnotations
+	}
+	return c
+}
+
+func (r *Rule) matches(namespace string, namespaceLabels map[string]string, pod *corev1.Pod) bool {
+	if len(r.Namespaces) > 0 && !contains(r.Namespaces, namespace) {
+		return false
+	}
+	if r.namespaceSelector != nil && !r.namespaceSelector.Matches(labels.Set(namespaceLabels)) {
+		return false
+	}
+	if r.podSelector != nil && !r.podSelector.Matches(labels.Set(pod.Labels)) {
+		return false
+	}
+	return true
+}
+
+// Allows returns true if instanceType is an allowed instance type
+func (c *Constraints) Allows(instanceType string) bool {
+	return len(c.AllowedInstanceTypes) == 0 || contains(c.AllowedInstanceTypes, instanceType)
+}
+
+func contains(slice []string, s string) bool {
+	for _, item := range slice {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,169 @@
+package policy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const testPolicy = `
+runtimeClassName: kata-remote-test
+defaults:
+  defaultInstanceType: t3.small
+  allowedInstanceTypes: [t3.small, t3.medium, t3.large]
+  maxVCPUs: 2
+rules:
+- name: team-a
+  namespaces: [team-a]
+  maxVCPUs: 8
+  maxMemory: 16384
+- name: gpu
+  namespaceSelector:
+    matchLabels:
+      gpu: "true"
+  podSelector:
+    matchExpressions:
+    - {key: app, operator: In, values: [training]}
+  defaultInstanceType: p3.2xlarge
+  allowedInstanceTypes: [p3.2xlarge]
+  requiredAnnotations: [cost-center]
+`
+
+func TestParse(t *testing.T) {
+	p, err := Parse([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if p.RuntimeClassName != "kata-remote-test" {
+		t.Errorf("expect runtime class kata-remote-test, got %s", p.RuntimeClassName)
+	}
+	if p.ExtendedResource != DefaultExtendedResource {
+		t.Errorf("expect extended resource %s, got %s", DefaultExtendedResource, p.ExtendedResource)
+	}
+	if !p.NeedsNamespaceLabels() {
+		t.Errorf("expect namespace labels to be needed")
+	}
+
+	p, err = Parse(nil)
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if !reflect.DeepEqual(p, Default()) {
+		t.Errorf("expect default policy, got %#v", p)
+	}
+
+	for name, tc := range map[string]struct {
+		policy string
+		err    string
+	}{
+		"unknown field":      {"defaults:\n  maxCPUs: 2\n", `unknown field "maxCPUs"`},
+		"negative vCPUs":     {"defaults:\n  maxVCPUs: -1\n", "maxVCPUs is negative"},
+		"no rule name":       {"rules:\n- namespaces: [a]\n", "rule 0 has no name"},
+		"disallowed default": {"rules:\n- name: a\n  defaultInstanceType: a\n  allowedInstanceTypes: [b]\n", "invalid rule a: defaultInstanceType a"},
+		"inherited allowed":  {"defaults:\n  defaultInstanceType: a\n  allowedInstanceTypes: [a, b]\nrules:\n- name: c\n  defaultInstanceType: c\n", "invalid rule c: defaultInstanceType c"},
+		"invalid selector":   {"rules:\n- name: a\n  podSelector:\n    matchExpressions:\n    - {key: app, operator: Foo}\n", "invalid podSelector of rule a"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := Parse([]byte(tc.policy))
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("expect error %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestDeprecatedEnv(t *testing.T) {
+	t.Setenv(RuntimeClassNameEnv, "kata-remote-env")
+	t.Setenv(InstanceTypeEnv, "t3.micro")
+
+	if env := DeprecatedEnv(); !reflect.DeepEqual(env, []string{RuntimeClassNameEnv, InstanceTypeEnv}) {
+		t.Errorf("expect deprecated environment variables %s and %s, got %q", RuntimeClassNameEnv, InstanceTypeEnv, env)
+	}
+
+	// Deprecated environment variables are the defaults of settings that the policy leaves unset
+	p := Default()
+	if p.RuntimeClassName != "kata-remote-env" || p.Defaults.DefaultInstanceType != "t3.micro" || p.ExtendedResource != DefaultExtendedResource {
+		t.Errorf("expect settings of the environment, got %#v", p)
+	}
+
+	p, err := Parse([]byte("runtimeClassName: kata-remote\n"))
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if p.RuntimeClassName != "kata-remote" || p.Defaults.DefaultInstanceType != "t3.micro" {
+		t.Errorf("expect runtime class of the policy and instance type of the environment, got %#v", p)
+	}
+}
+
+func TestConstraintsFor(t *testing.T) {
+	p, err := Parse([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	training := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "training"}}}
+
+	for name, tc := range map[string]struct {
+		namespace       string
+		namespaceLabels map[string]string
+		pod             *corev1.Pod
+		rule            string
+		constraints     Constraints
+	}{
+		"defaults": {
+			namespace: "default",
+			pod:       &corev1.Pod{},
+			constraints: Constraints{
+				DefaultInstanceType:  "t3.small",
+				AllowedInstanceTypes: []string{"t3.small", "t3.medium", "t3.large"},
+				MaxVCPUs:             2,
+			},
+		},
+		"namespace": {
+			namespace: "team-a",
+			pod:       training,
+			rule:      "team-a",
+			constraints: Constraints{
+				DefaultInstanceType:  "t3.small",
+				AllowedInstanceTypes: []string{"t3.small", "t3.medium", "t3.large"},
+				MaxVCPUs:             8,
+				MaxMemory:            16384,
+			},
+		},
+		"namespace and pod selectors": {
+			namespace:       "ml",
+			namespaceLabels: map[string]string{"gpu": "true"},
+			pod:             training,
+			rule:            "gpu",
+			constraints: Constraints{
+				DefaultInstanceType:  "p3.2xlarge",
+				AllowedInstanceTypes: []string{"p3.2xlarge"},
+				MaxVCPUs:             2,
+				RequiredAnnotations:  []string{"cost-center"},
+			},
+		},
+		"pod selector does not match": {
+			namespace:       "ml",
+			namespaceLabels: map[string]string{"gpu": "true"},
+			pod:             &corev1.Pod{},
+			constraints: Constraints{
+				DefaultInstanceType:  "t3.small",
+				AllowedInstanceTypes: []string{"t3.small", "t3.medium", "t3.large"},
+				MaxVCPUs:             2,
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			constraints, rule := p.ConstraintsFor(tc.namespace, tc.namespaceLabels, tc.pod)
+			if rule != tc.rule {
+				t.Errorf("expect rule %q, got %q", tc.rule, rule)
+			}
+			if !reflect.DeepEqual(constraints, tc.constraints) {
+				t.Errorf("expect constraints %#v, got %#v", tc.constraints, constraints)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var log = ctrl.Log.WithName("policy")
+
+// Store holds the current policy
+type Store struct {
+	mutex  sync.RWMutex
+	policy *Policy
+	synced bool
+}
+
+// NewStore returns a store that holds the default policy
+func NewStore() *Store {
+	return &Store{policy: Default()}
+}
+
+// Get returns the current policy
+func (s *Store) Get() *Policy {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.policy
+}
+
+// Set replaces the current policy
+func (s *Store) Set(p *Policy) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.policy = p
+}
+
+// Watch loads the policy from a ConfigMap into the store whenever the ConfigMap changes, until ctx is done.
+// An invalid policy is logged and ignored, so that the previous policy stays in effect.
+// The default policy applies while the ConfigMap does not exist.
+func (s *Store) Watch(ctx context.Context, clientset kubernetes.Interface, namespace, name string) error {
+
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+		}))
+
+	informer := factory.Core().V1().ConfigMaps().Informer()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			s.load(obj.(*corev1.ConfigMap))
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			s.load(obj.(*corev1.ConfigMap))
+		},
+		DeleteFunc: func(interface{}) {
+			log.Info("policy configmap is deleted, using the default policy", "namespace", namespace, "name", name)
+			s.Set(Default())
+		},
+	})
+
+	factory.Start(ctx.Done())
+	if cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		s.mutex.Lock()
+		s.synced = true
+		s.mutex.Unlock()
+	}
+	<-ctx.Done()
+	return nil
+}
+
+// Ready is a readiness check that fails until the policy ConfigMap is loaded, or known not to exist
+func (s *Store) Ready(_ *http.Request) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if !s.synced {
+		return errors.New("policy is not loaded yet")
+	}
+	return nil
+}
+
+func (s *Store) load(cm *corev1.ConfigMap) {
+	p, err := Parse([]byte(cm.Data[ConfigMapKey]))
+	if err != nil {
+		log.Error(err, "ignoring invalid policy", "namespace", cm.Namespace, "name", cm.Name)
+		return
+	}
+	log.Info("loaded policy", "namespace", cm.Namespace, "name", cm.Name, "rules", len(p.Rules))
+	s.Set(p)
+}
@@ -7,10 +7,23 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+
+	peerpodannotations "github.com/confidential-containers/cloud-api-adaptor/pkg/annotations"
 )
 
 // GetResourceRequestQuantity finds and returns the request quantity for a specific resource.
 func GetResourceRequestQuantity(pod *corev1.Pod, resourceName corev1.ResourceName) resource.Quantity {
+	return getResourceQuantity(pod, resourceName, func(r corev1.ResourceRequirements) corev1.ResourceList { return r.Requests })
+}
+
+// GetResourceLimitQuantity finds and returns the limit quantity for a specific resource.
+func GetResourceLimitQuantity(pod *corev1.Pod, resourceName corev1.ResourceName) resource.Quantity {
+	return getResourceQuantity(pod, resourceName, func(r corev1.ResourceRequirements) corev1.ResourceList { return r.Limits })
+}
+
+// getResourceQuantity sums up the quantities of a resource of the containers, and takes the larger one of the
+// sum and the largest quantity of the init containers, since init containers run one after another.
+func getResourceQuantity(pod *corev1.Pod, resourceName corev1.ResourceName, list func(corev1.ResourceRequirements) corev1.ResourceList) resource.Quantity {
 	requestQuantity := resource.Quantity{}
 
 	switch resourceName {
@@ -23,13 +36,13 @@ func GetResourceRequestQuantity(pod *corev1.Pod, resourceName corev1.ResourceNam
 	}
 
 	for _, container := range pod.Spec.Containers {
-		if rQuantity, ok := container.Resources.Requests[resourceName]; ok {
+		if rQuantity, ok := list(container.Resources)[resourceName]; ok {
 			requestQuantity.Add(rQuantity)
 		}
 	}
 
 	for _, container := range pod.Spec.InitContainers {
-		if rQuantity, ok := container.Resources.Requests[resourceName]; ok {
+		if rQuantity, ok := list(container.Resources)[resourceName]; ok {
 			if requestQuantity.Cmp(rQuantity) < 0 {
 				requestQuantity = rQuantity.DeepCopy()
 			}
@@ -58,6 +71,18 @@ func GetResourceRequest(pod *corev1.Pod, resource corev1.ResourceName) int64 {
 	return requestQuantity.Value()
 }
 
+// GetResourceLimit finds and returns the limit value for a specific resource.
+func GetResourceLimit(pod *corev1.Pod, resource corev1.ResourceName) int64 {
+
+	limitQuantity := GetResourceLimitQuantity(pod, resource)
+
+	if resource == corev1.ResourceCPU {
+		return limitQuantity.MilliValue()
+	}
+
+	return limitQuantity.Value()
+}
+
 // MergePodResourceRequirements merges enumerated requirements with default requirements
 // it annotates the pod with information about what requirements were modified
 func MergePodResourceRequirements(pod *corev1.Pod, defaultRequirements *corev1.ResourceRequirements) {
@@ -76,7 +101,7 @@ func MergePodResourceRequirements(pod *corev1.Pod, defaultRequirements *corev1.R
 			pod.ObjectMeta.Annotations = make(map[string]string)
 		}
 		val := strings.Join(annotations, "; ")
-		pod.ObjectMeta.Annotations["kata.peerpods.io/changes"] = val
+		pod.ObjectMeta.Annotations[peerpodannotations.Changes] = val
 	}
 }
 
@@ -0,0 +1,112 @@
+package validating_webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/confidential-containers/cloud-api-adaptor/pkg/annotations"
+	"github.com/confidential-containers/cloud-api-adaptor/webhook/pkg/mutating_webhook"
+	"github.com/confidential-containers/cloud-api-adaptor/webhook/pkg/policy"
+)
+
+// +kubebuilder:webhook:admissionReviewVersions=v1,path=/validate-v1-pod,mutating=false,failurePolicy=fail,groups="",resources=pods,verbs=create,versions=v1,name=vwebhook.peerpods.io,sideEffects=None
+
+// PodValidator rejects peer pods that break the policy
+type PodValidator struct {
+	Client  client.Client
+	Policy  *policy.Store
+	decoder *admission.Decoder
+}
+
+// Handle checks a peer pod, after it is mutated, against the policy constraints of its namespace
+func (v *PodValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	pod := &corev1.Pod{}
+
+	err := v.decoder.Decode(req, pod)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	p := v.Policy.Get()
+	if !p.IsPeerPod(pod) {
+		return admission.Allowed("not a peer pod")
+	}
+
+	constraints, rule, err := mutating_webhook.ConstraintsFor(ctx, v.Client, p, req.Namespace, pod)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	if violations := validatePod(pod, constraints); len(violations) > 0 {
+		source := "the default peer pods policy"
+		if rule != "" {
+			source = fmt.Sprintf("peer pods policy rule %s", rule)
+		}
+		return admission.Denied(fmt.Sprintf("pod violates %s: %s", source, strings.Join(violations, "; ")))
+	}
+	return admission.Allowed("")
+}
+
+// validatePod returns the reasons why a pod violates the constraints
+func validatePod(pod *corev1.Pod, constraints policy.Constraints) []string {
+	var violations []string
+
+	podVM, err := annotations.ParsePodVM(pod.Annotations)
+	if err != nil {
+		violations = append(violations, err.Error())
+	}
+	// Whether overrides are allowed is up to cloud-api-adaptor, which knows its cloud provider and override policy
+	if _, err := annotations.ParseOverrides(pod.Annotations); err != nil {
+		violations = append(violations, err.Error())
+	}
+
+	for _, key := range []string{annotations.InstanceType, annotations.MachineType} {
+		if instanceType, ok := pod.Annotations[key]; ok && !constraints.Allows(instanceType) {
+			violations = append(violations, fmt.Sprintf("instance type %s of annotation %s is not allowed, allowed instance types are %s",
+				instanceType, key, strings.Join(constraints.AllowedInstanceTypes, ", ")))
+		}
+	}
+
+	// Limits are not checked against malformed annotations
+	if err == nil {
+		for _, limit := range []struct {
+			name  string
+			value int64
+			max   int64
+		}{
+			{"vCPUs", podVM.VCPUs, constraints.MaxVCPUs},
+			{"MiB of memory", podVM.Memory, constraints.MaxMemory},
+		} {
+			if limit.max > 0 && limit.value > limit.max {
+				violations = append(violations, fmt.Sprintf("%d %s exceed the maximum of %d", limit.value, limit.name, limit.max))
+			}
+		}
+	}
+
+	var missing []string
+	for _, key := range constraints.RequiredAnnotations {
+		if _, ok := pod.Annotations[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		violations = append(violations, fmt.Sprintf("required annotations are missing: %s", strings.Join(missing, ", ")))
+	}
+
+	return violations
+}
+
+// PodValidator implements admission.DecoderInjector.
+// A decoder will be automatically injected.
+
+// InjectDecoder injects the decoder.
+func (v *PodValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
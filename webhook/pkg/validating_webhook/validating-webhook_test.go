@@ -0,0 +1,99 @@
+package validating_webhook
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/confidential-containers/cloud-api-adaptor/webhook/pkg/policy"
+)
+
+func TestValidatePod(t *testing.T) {
+	constraints := policy.Constraints{
+		AllowedInstanceTypes: []string{"t3.small", "t3.medium"},
+		MaxVCPUs:             2,
+		MaxMemory:            4096,
+		RequiredAnnotations:  []string{"cost-center", "owner"},
+	}
+
+	newPod := func(annotations map[string]string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}}
+	}
+
+	for name, tc := range map[string]struct {
+		pod        *corev1.Pod
+		violations []string
+	}{
+		"valid": {
+			pod: newPod(map[string]string{
+				"kata.peerpods.io/instance_type":                     "t3.medium",
+				"io.katacontainers.config.hypervisor.default_vcpus":  "2",
+				"io.katacontainers.config.hypervisor.default_memory": "4096",
+				"cost-center": "1234",
+				"owner":       "alice",
+			}),
+		},
+		"all violations": {
+			pod: newPod(map[string]string{
+				"kata.peerpods.io/instance_type":                     "t3.small",
+				"io.katacontainers.config.hypervisor.machine_type":   "m5.large",
+				"io.katacontainers.config.hypervisor.default_vcpus":  "4",
+				"io.katacontainers.config.hypervisor.default_memory": "8192",
+				"owner": "alice",
+			}),
+			violations: []string{
+				"instance type m5.large of annotation io.katacontainers.config.hypervisor.machine_type is not allowed",
+				"4 vCPUs exceed the maximum of 2",
+				"8192 MiB of memory exceed the maximum of 4096",
+				"required annotations are missing: cost-center",
+			},
+		},
+		"malformed annotations": {
+			pod: newPod(map[string]string{
+				"kata.peerpods.io/annotations-version":               "v1",
+				"io.katacontainers.config.hypervisor.default_vcpus":  "4",
+				"io.katacontainers.config.hypervisor.default_memory": "lots",
+				"cost-center": "1234",
+				"owner":       "alice",
+			}),
+			violations: []string{
+				`annotation io.katacontainers.config.hypervisor.default_memory: "lots" is not an integer`,
+			},
+		},
+		"malformed overrides": {
+			pod: newPod(map[string]string{
+				"kata.peerpods.io/subnet-id": "subnet-1",
+				"kata.peerpods.io/tags":      "team",
+				"cost-center":                "1234",
+				"owner":                      "alice",
+			}),
+			violations: []string{
+				`annotation kata.peerpods.io/tags: invalid tag "team", expected key=value`,
+			},
+		},
+		"unsupported version": {
+			pod: newPod(map[string]string{
+				"kata.peerpods.io/annotations-version": "v2",
+				"cost-center":                          "1234",
+				"owner":                                "alice",
+			}),
+			violations: []string{
+				`annotation kata.peerpods.io/annotations-version: unsupported version "v2"`,
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			violations := validatePod(tc.pod, constraints)
+			if len(violations) != len(tc.violations) {
+				t.Fatalf("expect %d violations, got %q", len(tc.violations), violations)
+			}
+			for i, violation := range violations {
+				if !strings.HasPrefix(violation, tc.violations[i]) {
+					t.Errorf("expect violation %q, got %q", tc.violations[i], violation)
+				}
+			}
+		})
+	}
+}
@@ -39,6 +39,18 @@ assert_pod_mutated() {
         [ $expect_vm_requests -eq $actual_vm_requests ]
 }
 
+# Replace the peer pods policy, and wait for the webhook to load it.
+#
+# Parameters:
+# 	$1: the policy in YAML
+#
+set_policy() {
+	kubectl create configmap peer-pods-webhook-policy -n peer-pods-webhook-system \
+		--from-literal=policy.yaml="$1" --dry-run=client -o yaml | \
+		kubectl apply -f -
+	sleep 5
+}
+
 setup_file() {
 	export project_dir="$(cd ${BATS_TEST_DIRNAME}/../.. && pwd)"
 	echo "Create runtimeClass"
@@ -51,6 +63,7 @@ setup() {
 
 teardown() {
 	kubectl delete -f "$pod_file" || true
+	set_policy ""
 }
 
 @test "$test_tags test it can mutate a pod" {
@@ -58,6 +71,42 @@ teardown() {
 	assert_pod_mutated "t2.small" 1 1
 }
 
+@test "$test_tags test it sizes the pod VM from the resources" {
+	kubectl apply -f "$pod_file"
+
+	local actual_vcpus=$(kubectl get -f "$pod_file" \
+		-o jsonpath='{.metadata.annotations.io\.katacontainers\.config\.hypervisor\.default_vcpus}')
+	echo "vCPUs expected: 1, actual: $actual_vcpus"
+	[ "$actual_vcpus" == "1" ]
+
+	local actual_memory=$(kubectl get -f "$pod_file" \
+		-o jsonpath='{.metadata.annotations.io\.katacontainers\.config\.hypervisor\.default_memory}')
+	echo "Memory expected: 2048, actual: $actual_memory"
+	[ "$actual_memory" == "2048" ]
+
+	kubectl get -f "$pod_file" \
+		-o jsonpath='{.metadata.annotations.kata\.peerpods\.io/original-resources}' | \
+		grep '"limits":{"cpu":"1","memory":"2Gi"}'
+
+	local actual_version=$(kubectl get -f "$pod_file" \
+		-o jsonpath='{.metadata.annotations.kata\.peerpods\.io/annotations-version}')
+	echo "Annotations version expected: v1, actual: $actual_version"
+	[ "$actual_version" == "v1" ]
+}
+
+@test "$test_tags test it sets the GPUs of the pod VM from nvidia.com/gpu" {
+	cat "$pod_file" | sed -e 's/^\(\s*\)memory: 2Gi/&\n\1nvidia.com\/gpu: 1/' | \
+		kubectl apply -f -
+
+	local actual_gpus=$(kubectl get -f "$pod_file" \
+		-o jsonpath='{.metadata.annotations.io\.katacontainers\.config\.hypervisor\.default_gpus}')
+	echo "GPUs expected: 1, actual: $actual_gpus"
+	[ "$actual_gpus" == "1" ]
+
+	! kubectl get -f "$pod_file" -o jsonpath='{.spec.containers[0].resources}' | \
+		grep nvidia.com/gpu
+}
+
 @test "$test_tags test it should not mutate non-peerpods" {
 	echo "Create a pod without runtimeClassName"
 	cat "$pod_file" | sed -e 's/^\s*runtimeClassName:.*//' | \
@@ -68,7 +117,6 @@ teardown() {
 }
 
 @test "$test_tags test default parameters can be changed" {
-	skip "TODO: This test is not passing"
 	local runtimeclass="kata-wh-test"
 	local instance_type='t2.micro'
 
@@ -85,11 +133,9 @@ teardown() {
 	    cpu: "250m"
 	EOF
 
-	kubectl set env deployment/peer-pods-webhook-controller-manager \
-		-n peer-pods-webhook-system TARGET_RUNTIMECLASS="$runtimeclass"
-
-	kubectl set env deployment/peer-pods-webhook-controller-manager \
-		-n peer-pods-webhook-system POD_VM_INSTANCE_TYPE="$instance_type"
+	set_policy "runtimeClassName: ${runtimeclass}
+defaults:
+  defaultInstanceType: ${instance_type}"
 
 	cat "$pod_file" | sed -e 's/^\(\s*runtimeClassName:\).*/\1 '${runtimeclass}'/' | \
 		kubectl apply -f -
@@ -97,3 +143,17 @@ teardown() {
 	kubectl get -f $pod_file -o json
 	assert_pod_mutated "$instance_type" 1 1
 }
+
+@test "$test_tags test it rejects pods that break the policy" {
+	set_policy "defaults:
+  allowedInstanceTypes: [t2.small]
+  maxVCPUs: 1
+  maxMemory: 1024
+  requiredAnnotations: [cost-center]"
+
+	run kubectl apply -f "$pod_file"
+	echo "$output"
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"2048 MiB of memory exceed the maximum of 1024"* ]]
+	[[ "$output" == *"required annotations are missing: cost-center"* ]]
+}